@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"poke/internal/client"
+	"syscall"
+)
+
+const usage = `usage: client [flags] <command> [args]
+
+commands:
+  cancel <job_id>   remove a queued job or stop a running one
+
+flags:
+`
+
+// main dispatches client subcommands against a poke HTTP listener.
+func main() {
+	flags := flag.NewFlagSet("client", flag.ExitOnError)
+	baseURL := flags.String("url", envOr("POKE_URL", "http://127.0.0.1:8008"), "poke listener URL (env POKE_URL)")
+	token := flags.String("token", os.Getenv("POKE_API_TOKEN"), "API token (env POKE_API_TOKEN)")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(os.Args[1:]) // ExitOnError
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := &client.Client{BaseURL: *baseURL, Token: *token}
+	if err := run(ctx, c, flags.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "client: %v\n", err)
+		if _, ok := err.(usageError); ok {
+			flags.Usage()
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// run executes a single subcommand and prints the resulting job as JSON.
+func run(ctx context.Context, c *client.Client, args []string) error {
+	if len(args) == 0 {
+		return usageError("missing command")
+	}
+
+	switch args[0] {
+	case "cancel":
+		if len(args) != 2 {
+			return usageError("cancel requires exactly one job id")
+		}
+		job, err := c.CancelJob(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		return printJSON(job)
+	default:
+		return usageError(fmt.Sprintf("unknown command %q", args[0]))
+	}
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func envOr(name string, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"poke/internal/server"
+	"poke/internal/server/audit"
+)
+
+const auditUsage = `usage: server audit verify [-c config] [-key-file file] [path]
+
+Checks the hash chain of the audit file at path, or of the audit.path
+configured in the server config, and its head checkpoint. A keyed log is
+checked with -key-file, or with the audit.key_file of the server config.
+
+flags:
+`
+
+// runAudit runs an audit subcommand and returns the process exit code.
+func runAudit(args []string) int {
+	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	shortFlag := flags.String("c", "", "path to poke server config file")
+	longFlag := flags.String("config", "", "path to poke server config file")
+	keyFlag := flags.String("key-file", "", "path to the HMAC key of a keyed audit log")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), auditUsage)
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 || args[0] != "verify" {
+		flags.Usage()
+		return 2
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := auditConfig(flags.Arg(0), *keyFlag, *shortFlag, *longFlag)
+	if err == nil {
+		err = verifyAudit(os.Stdout, cfg)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit verify: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// auditConfig returns the audit file and key to verify: path and keyFile when
+// path is given, the audit config of the server config otherwise. keyFile
+// overrides the configured key file.
+func auditConfig(path string, keyFile string, shortFlag string, longFlag string) (audit.Config, error) {
+	if path != "" {
+		return audit.Config{Path: path, KeyFile: keyFile}, nil
+	}
+	configPath, err := selectConfigPath(shortFlag, longFlag)
+	if err != nil {
+		return audit.Config{}, err
+	}
+	cfg, err := server.Load(configPath)
+	if err != nil {
+		return audit.Config{}, err
+	}
+	if !cfg.Audit.Enabled {
+		return audit.Config{}, errors.New("audit is not enabled in " + configPath)
+	}
+	if keyFile != "" {
+		cfg.Audit.KeyFile = keyFile
+	}
+	return cfg.Audit, nil
+}
+
+// verifyAudit verifies the audit file of cfg and reports the result to out.
+func verifyAudit(out io.Writer, cfg audit.Config) error {
+	var key []byte
+	if cfg.KeyFile != "" {
+		loaded, err := audit.LoadKey(cfg.KeyFile)
+		if err != nil {
+			return err
+		}
+		key = loaded
+	}
+	summary, err := audit.Verify(cfg.Path, key)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s: ok, %d records, last hash %s\n", cfg.Path, summary.Records, summary.LastHash)
+	return err
+}
@@ -11,10 +11,26 @@ import (
 	"poke/internal/server"
 	serverlogging "poke/internal/server/logging"
 	"syscall"
+	"time"
 )
 
-// main wires CLI flags into server startup.
+const shutdownTimeout = 30 * time.Second // Budget for draining queued requests on shutdown.
+
+// subcommands run instead of the server when named by the first argument.
+var subcommands = map[string]func(args []string) int{
+	"audit":    runAudit,
+	"schema":   runSchema,
+	"validate": runValidate,
+}
+
+// main wires CLI flags into server startup, or runs a subcommand.
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
 	bootstrapLogger, err := serverlogging.New(serverlogging.Config{})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "logging init: %v\n", err)
@@ -27,7 +43,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	cfg, err := loadConfig(configPath)
+	cfg, err := server.Load(configPath)
 	if err != nil {
 		bootstrapLogger.Error("config load failed", "event", "config_load_failed", "error", err)
 		os.Exit(1)
@@ -42,8 +58,11 @@ func main() {
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
+	serverlogging.ReopenOnSignal(ctx)
+	serverlogging.ToggleDebugOnSignal(ctx)
 
-	runtime, err := server.Start(ctx, cfg)
+	// The runtime outlives ctx so Close can drain it after the signal.
+	runtime, err := server.Start(context.Background(), cfg)
 	if err != nil {
 		logger.Error("server start failed", "event", "server_start_failed", "error", err)
 		os.Exit(1)
@@ -52,8 +71,16 @@ func main() {
 	logger.Info("server started", "event", "server_started", "config_path", configPath)
 
 	<-ctx.Done()
-	close(runtime.RequestChannel)
+	stop() // a second signal terminates without waiting for the drain
 	logger.Info("server shutting down", "event", "server_shutting_down")
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := runtime.Close(closeCtx); err != nil {
+		logger.Warn("server shutdown incomplete", "event", "server_shutdown_incomplete", "error", err)
+	}
+	logger.Info("server stopped", "event", "server_stopped")
+	_ = serverlogging.Close()
 }
 
 // resolveConfigPath parses flags and selects the configuration file path.
@@ -62,15 +89,21 @@ func resolveConfigPath() (string, error) {
 	longFlag := flag.String("config", "", "path to poke server config file")
 	flag.Parse()
 
-	if *shortFlag != "" && *longFlag != "" && *shortFlag != *longFlag {
-		return "", fmt.Errorf("conflicting config flags: -c=%s --config=%s", *shortFlag, *longFlag)
+	return selectConfigPath(*shortFlag, *longFlag)
+}
+
+// selectConfigPath picks the path given by -c or --config, or the first
+// existing default configuration path.
+func selectConfigPath(shortFlag string, longFlag string) (string, error) {
+	if shortFlag != "" && longFlag != "" && shortFlag != longFlag {
+		return "", fmt.Errorf("conflicting config flags: -c=%s --config=%s", shortFlag, longFlag)
 	}
 
-	if *shortFlag != "" {
-		return *shortFlag, nil
+	if shortFlag != "" {
+		return shortFlag, nil
 	}
-	if *longFlag != "" {
-		return *longFlag, nil
+	if longFlag != "" {
+		return longFlag, nil
 	}
 
 	return findDefaultConfigPath()
@@ -103,12 +136,3 @@ func findDefaultConfigPath() (string, error) {
 
 	return "", fmt.Errorf("config file not found; searched: %v", candidates)
 }
-
-// loadConfig reads and parses the server configuration file.
-func loadConfig(path string) (server.Config, error) {
-	data, err := os.ReadFile(path) // #nosec G304 -- by design, comes as CLI arg
-	if err != nil {
-		return server.Config{}, err
-	}
-	return server.Parse(data)
-}
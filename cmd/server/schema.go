@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"poke/internal/server"
+)
+
+const schemaUsage = `usage: server schema
+
+Prints the JSON Schema of the server config format.
+`
+
+// runSchema prints the config JSON Schema and returns the process exit code.
+func runSchema(args []string) int {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), schemaUsage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	if _, err := os.Stdout.Write(server.Schema()); err != nil {
+		fmt.Fprintf(os.Stderr, "server schema: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"poke/internal/server"
+)
+
+const validateUsage = `usage: server validate [-c config] [-skip-ports] [path]
+
+Checks the server config at path, or at -c/--config or the default search
+path, without starting the server. Besides parsing it, checks that command
+executables exist and listener ports are free. Prints every problem as
+path:line:column: message and exits with 1 if there are any. Included
+files are checked too.
+
+flags:
+`
+
+// runValidate runs the validate subcommand and returns the process exit code.
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	shortFlag := flags.String("c", "", "path to poke server config file")
+	longFlag := flags.String("config", "", "path to poke server config file")
+	skipPorts := flags.Bool("skip-ports", false, "do not check that listener ports are free")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), validateUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if path == "" {
+		var err error
+		if path, err = selectConfigPath(*shortFlag, *longFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "validate: %v\n", err)
+			return 1
+		}
+	}
+	ok, err := validateConfig(os.Stdout, path, server.ValidateOptions{SkipPorts: *skipPorts, Path: path})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "validate: %v\n", err)
+		return 1
+	}
+	if !ok {
+		return 1
+	}
+	return 0
+}
+
+// validateConfig reports the problems of the config at path to out and
+// whether there were none.
+func validateConfig(out io.Writer, path string, opts server.ValidateOptions) (bool, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- by design, comes as CLI arg
+	if err != nil {
+		return false, err
+	}
+
+	problems := server.Validate(data, opts)
+	for _, problem := range problems {
+		file := cmp.Or(problem.File, path)
+		separator := ":"
+		if problem.Line == 0 {
+			separator = ": "
+		}
+		if _, err := fmt.Fprintf(out, "%s%s%s\n", file, separator, problem); err != nil {
+			return false, err
+		}
+	}
+	if len(problems) > 0 {
+		return false, nil
+	}
+	_, err = fmt.Fprintf(out, "%s: ok\n", path)
+	return err == nil, err
+}
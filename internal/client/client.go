@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"poke/internal/server/jobs"
+	"strings"
+	"time"
+)
+
+const (
+	apiTokenHeader   = "X-Poke-API-Token" // #nosec G101 -- Header key identifier, not a secret.
+	authMethodHeader = "X-Poke-Auth-Method"
+	apiTokenMethod   = "api_token"
+	defaultTimeout   = 10 * time.Second
+)
+
+// Client talks to the poke HTTP listener.
+type Client struct {
+	BaseURL string       // listener URL, e.g. http://127.0.0.1:8008
+	Token   string       // API token sent with the `api_token` auth method
+	HTTP    *http.Client // optional, defaults to a client with a 10s timeout
+}
+
+// StatusError reports an unexpected HTTP response status.
+type StatusError struct {
+	StatusCode int
+	Job        *jobs.Job // decoded job body when the server returned one
+}
+
+func (e *StatusError) Error() string {
+	if e.Job != nil && e.StatusCode == http.StatusConflict {
+		return fmt.Sprintf("job %s already %s", e.Job.ID, e.Job.State)
+	}
+	return fmt.Sprintf("unexpected status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// CancelJob asks the server to remove a queued job or stop a running one.
+func (c *Client) CancelJob(ctx context.Context, id string) (jobs.Job, error) {
+	return c.doJob(ctx, http.MethodDelete, id, http.StatusOK, http.StatusAccepted)
+}
+
+func (c *Client) doJob(ctx context.Context, method string, id string, ok ...int) (jobs.Job, error) {
+	if strings.TrimSpace(id) == "" {
+		return jobs.Job{}, fmt.Errorf("job id must not be empty")
+	}
+	endpoint := strings.TrimRight(c.BaseURL, "/") + "/jobs/" + url.PathEscape(id)
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	if c.Token != "" {
+		req.Header.Set(authMethodHeader, apiTokenMethod)
+		req.Header.Set(apiTokenHeader, c.Token)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	defer resp.Body.Close() //nolint:errcheck // Response body is fully consumed below.
+
+	job, decodeErr := decodeJob(resp.Body)
+	for _, status := range ok {
+		if resp.StatusCode == status {
+			return job, decodeErr
+		}
+	}
+
+	statusErr := &StatusError{StatusCode: resp.StatusCode}
+	if decodeErr == nil {
+		statusErr.Job = &job
+	}
+	return job, statusErr
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return &http.Client{Timeout: defaultTimeout}
+}
+
+func decodeJob(body io.Reader) (jobs.Job, error) {
+	var job jobs.Job
+	if err := json.NewDecoder(body).Decode(&job); err != nil {
+		return jobs.Job{}, fmt.Errorf("decode job: %w", err)
+	}
+	return job, nil
+}
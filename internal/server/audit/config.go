@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const minKeyBytes = 32 // Shortest HMAC key accepted from key_file.
+
+// Config selects the audit file, see docs/configuration/audit.md.
+//
+// The zero value is disabled; an `audit` block enables auditing unless it sets
+// `enabled: false`.
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path,omitempty"`     // absolute path of the audit file
+	KeyFile string `yaml:"key_file,omitempty"` // HMAC key for the chain, empty = unkeyed SHA-256
+}
+
+// UnmarshalYAML parses audit config and validates the path of an enabled config.
+func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type configInput struct {
+		Enabled *bool  `yaml:"enabled"`
+		Path    string `yaml:"path"`
+		KeyFile string `yaml:"key_file"`
+	}
+
+	var in configInput
+	if err := unmarshal(&in); err != nil {
+		return err
+	}
+
+	*cfg = Config{
+		Enabled: in.Enabled == nil || *in.Enabled,
+		Path:    strings.TrimSpace(in.Path),
+		KeyFile: strings.TrimSpace(in.KeyFile),
+	}
+	return cfg.validate()
+}
+
+// validate requires an absolute path when auditing is enabled.
+func (cfg Config) validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Path == "" {
+		return fmt.Errorf("audit requires path")
+	}
+	if !filepath.IsAbs(cfg.Path) {
+		return fmt.Errorf("audit path %q must be absolute", cfg.Path)
+	}
+	if cfg.KeyFile != "" && !filepath.IsAbs(cfg.KeyFile) {
+		return fmt.Errorf("audit key_file %q must be absolute", cfg.KeyFile)
+	}
+	return nil
+}
+
+// key reads the HMAC key of cfg, nil when no key file is configured.
+func (cfg Config) key() ([]byte, error) {
+	if cfg.KeyFile == "" {
+		return nil, nil
+	}
+	return LoadKey(cfg.KeyFile)
+}
+
+// LoadKey reads an HMAC key from path, ignoring surrounding whitespace.
+func LoadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- by design, comes from config or CLI arg
+	if err != nil {
+		return nil, fmt.Errorf("read audit key: %w", err)
+	}
+	key := bytes.TrimSpace(data)
+	if len(key) < minKeyBytes {
+		return nil, fmt.Errorf("audit key %s must hold at least %d bytes", path, minKeyBytes)
+	}
+	return key, nil
+}
+
+// HeadPath returns the checkpoint file kept next to the audit file at path.
+func HeadPath(path string) string {
+	return path + ".head"
+}
@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is returned by Append after Close.
+var ErrClosed = errors.New("audit log is closed")
+
+// Log appends hash-chained records to the audit file.
+//
+// Every record is synced to disk before the head checkpoint next to the file
+// is replaced, so a crash can leave the head at most one record behind.
+//
+// A nil *Log, and the default log until SetDefault is called, is disabled:
+// Append and Close do nothing.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	seq  uint64 // seq of the last record
+	last string // hash of the last record
+	size int64  // file offset after the last record
+	key  []byte // HMAC key, nil for an unkeyed chain
+}
+
+var defaultLog atomic.Pointer[Log]
+
+// Default returns the log set with SetDefault, nil when auditing is disabled.
+func Default() *Log {
+	return defaultLog.Load()
+}
+
+// SetDefault makes l the log used by Write; nil disables auditing.
+func SetDefault(l *Log) {
+	defaultLog.Store(l)
+}
+
+// Write appends rec to the default log.
+//
+// Failures are logged rather than returned so an audit outage does not fail
+// the request or job being recorded.
+func Write(rec Record) {
+	if err := Default().Append(rec); err != nil {
+		slog.Default().With("component", "audit").Error("audit write failed", "event", "audit_write_failed", "audit_event", rec.Event, "job_id", rec.JobID, "command_id", rec.CommandID, "error", err)
+	}
+}
+
+// Open verifies the audit file at cfg.Path and opens it for appending, or
+// returns nil when auditing is disabled.
+//
+// A file that fails Verify is not appended to. The one exception is a head
+// checkpoint left one record behind by a crash, which is brought up to date.
+func Open(cfg Config) (*Log, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o750); err != nil {
+		return nil, fmt.Errorf("create audit directory: %w", err)
+	}
+
+	key, err := cfg.key()
+	if err != nil {
+		return nil, err
+	}
+	summary, err := Verify(cfg.Path, key)
+	if errors.Is(err, ErrHeadBehind) {
+		slog.Default().With("component", "audit").Warn("audit head behind last record, updating", "event", "audit_head_repaired", "path", cfg.Path, "records", summary.Records)
+		err = writeHead(cfg.Path, head{Seq: summary.Records, Hash: summary.LastHash})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open audit file: %w", err)
+	}
+
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600) // #nosec G304 -- by design, comes from config
+	if err != nil {
+		return nil, fmt.Errorf("open audit file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("open audit file: %w", err)
+	}
+	return &Log{path: cfg.Path, file: file, seq: summary.Records, last: summary.LastHash, size: info.Size(), key: key}, nil
+}
+
+// Append chains rec to the last record and durably writes it before returning.
+func (l *Log) Append(rec Record) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrClosed
+	}
+
+	rec.Seq = l.seq + 1
+	rec.Time = time.Now().UTC()
+	rec.PrevHash = l.last
+	hash, err := rec.hash(l.key)
+	if err != nil {
+		return err
+	}
+	rec.Hash = hash
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := l.write(append(data, '\n')); err != nil {
+		return err
+	}
+	l.seq = rec.Seq
+	l.last = rec.Hash
+	return writeHead(l.path, head{Seq: l.seq, Hash: l.last})
+}
+
+// write appends line and syncs it. A failed write or sync truncates the file
+// back to the end of the last record, so a torn line cannot break the chain
+// for the records after it. If that fails as well the log is closed instead
+// of chaining onto a damaged file.
+func (l *Log) write(line []byte) error {
+	_, err := l.file.Write(line)
+	if err != nil {
+		err = fmt.Errorf("write audit file: %w", err)
+	} else if err = l.file.Sync(); err != nil {
+		err = fmt.Errorf("sync audit file: %w", err)
+	}
+	if err == nil {
+		l.size += int64(len(line))
+		return nil
+	}
+
+	if truncErr := l.rollback(); truncErr != nil {
+		_ = l.file.Close()
+		l.file = nil
+		return fmt.Errorf("%w; audit log closed, removing the partial record failed: %w", err, truncErr)
+	}
+	return err
+}
+
+// rollback durably cuts the file back to the end of the last record.
+func (l *Log) rollback() error {
+	if err := l.file.Truncate(l.size); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Close releases the audit file; later appends fail with ErrClosed.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
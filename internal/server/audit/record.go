@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	EventRequest   = "request"   // a listener accepted or rejected a command request
+	EventExecution = "execution" // the dispatcher finished a job
+	EventCancel    = "cancel"    // a caller asked to cancel a job
+
+	DecisionAccepted = "accepted"
+	DecisionRejected = "rejected"
+
+	AdmissionReplayed  = "replayed"  // an idempotency key matched an earlier job
+	AdmissionCoalesced = "coalesced" // merged into an identical queued job
+)
+
+// GenesisHash is the prev_hash of the first record in an audit file.
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// Record is one audit entry.
+//
+// Seq, Time, PrevHash and Hash are assigned by Log.Append. Hash covers the
+// JSON encoding of every other field, PrevHash included, so editing,
+// reordering or removing a record breaks the chain. With a key, Hash is an
+// HMAC that cannot be recomputed without the key.
+type Record struct {
+	Seq   uint64    `json:"seq"`
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"` // EventRequest, EventExecution or EventCancel
+
+	// Request and cancel decisions.
+	Decision   string      `json:"decision,omitempty"`  // DecisionAccepted or DecisionRejected
+	Status     int         `json:"status,omitempty"`    // response status sent to the caller
+	Admission  string      `json:"admission,omitempty"` // set when no new job was created
+	Listener   string      `json:"listener,omitempty"`
+	SourceIP   string      `json:"source_ip,omitempty"`
+	Parameters *Parameters `json:"parameters,omitempty"`
+
+	// Shared by all events.
+	Principal string `json:"principal,omitempty"`
+	CommandID string `json:"command_id,omitempty"`
+	JobID     string `json:"job_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"` // correlation ID, see X-Request-ID
+
+	// Execution outcomes.
+	State      string     `json:"state,omitempty"`
+	Outcome    string     `json:"outcome,omitempty"`
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	Attempts   int        `json:"attempts,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CanceledBy string     `json:"canceled_by,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// Parameters are the caller-controlled inputs of a command request.
+//
+// The payload itself is not recorded, only its size and digest, so secrets
+// fed to commands on stdin do not end up in the audit file.
+type Parameters struct {
+	Priority       *int       `json:"priority,omitempty"`
+	RunAt          *time.Time `json:"run_at,omitempty"`
+	IdempotencyKey string     `json:"idempotency_key,omitempty"`
+	PayloadBytes   int        `json:"payload_bytes,omitempty"`
+	PayloadSHA256  string     `json:"payload_sha256,omitempty"`
+}
+
+// NewParameters describes a request's payload, priority override, schedule and
+// idempotency key.
+func NewParameters(payload []byte, priority *int, runAt time.Time, idempotencyKey string) *Parameters {
+	params := &Parameters{Priority: priority, IdempotencyKey: idempotencyKey}
+	if !runAt.IsZero() {
+		at := runAt.UTC()
+		params.RunAt = &at
+	}
+	if len(payload) > 0 {
+		sum := sha256.Sum256(payload)
+		params.PayloadBytes = len(payload)
+		params.PayloadSHA256 = hex.EncodeToString(sum[:])
+	}
+	return params
+}
+
+// hash returns the hex SHA-256 of rec encoded without its Hash field, or its
+// HMAC-SHA256 under key when the log is keyed.
+func (rec Record) hash(key []byte) (string, error) {
+	rec.Hash = ""
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	if len(key) == 0 {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrHeadBehind reports a head checkpoint that matches the record before the
+// last one, as left by a crash between writing a record and its checkpoint.
+var ErrHeadBehind = errors.New("audit head is one record behind")
+
+// Summary describes the records read from an audit file.
+type Summary struct {
+	Records  uint64 // intact records
+	LastHash string // hash of the last record, GenesisHash for an empty file
+	prevHash string // hash of the record before the last one
+	key      []byte // HMAC key of a keyed log, nil otherwise
+}
+
+// head is the checkpoint of the last record, kept in HeadPath.
+//
+// The chain alone cannot reveal records cut from the end of the file; the
+// head remembers how far it reached.
+type head struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// Verify checks every record of the audit file at path against its hash and
+// its predecessor, then the last record against the head checkpoint. key is
+// the HMAC key the log was written with, nil for an unkeyed log.
+//
+// The returned error names the first line that fails. A missing file with no
+// head is an empty, valid log.
+func Verify(path string, key []byte) (Summary, error) {
+	summary, err := verifyChain(path, key)
+	if err != nil {
+		return summary, err
+	}
+	return summary, verifyHead(path, summary)
+}
+
+// verifyChain reads the audit file at path record by record.
+func verifyChain(path string, key []byte) (Summary, error) {
+	summary := Summary{LastHash: GenesisHash, key: key}
+	file, err := os.Open(path) // #nosec G304 -- by design, comes from config or CLI arg
+	if errors.Is(err, os.ErrNotExist) {
+		return summary, nil
+	}
+	if err != nil {
+		return summary, fmt.Errorf("open audit file: %w", err)
+	}
+	defer file.Close() //nolint:errcheck // Read-only handle.
+
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(data) == 0 {
+			return summary, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return summary, fmt.Errorf("audit %s line %d: incomplete record, the file was truncated mid-record", path, line)
+		}
+		if err != nil {
+			return summary, fmt.Errorf("read audit file: %w", err)
+		}
+		if err := summary.add(data); err != nil {
+			return summary, fmt.Errorf("audit %s line %d: %w", path, line, err)
+		}
+	}
+}
+
+// add checks the record encoded in data follows the records seen so far.
+func (s *Summary) add(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var rec Record
+	if err := decoder.Decode(&rec); err != nil {
+		return fmt.Errorf("invalid record: %w", err)
+	}
+
+	if rec.Seq != s.Records+1 {
+		return fmt.Errorf("seq %d, want %d: records were removed or reordered", rec.Seq, s.Records+1)
+	}
+	if rec.PrevHash != s.LastHash {
+		return fmt.Errorf("seq %d: prev_hash does not match the previous record", rec.Seq)
+	}
+	hash, err := rec.hash(s.key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(hash), []byte(rec.Hash)) {
+		return fmt.Errorf("seq %d: hash mismatch, the record was modified or written with another key", rec.Seq)
+	}
+
+	s.Records++
+	s.prevHash = s.LastHash
+	s.LastHash = rec.Hash
+	return nil
+}
+
+// verifyHead compares the head checkpoint with the last record in summary.
+func verifyHead(path string, summary Summary) error {
+	h, err := readHead(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if summary.Records == 0 {
+			return nil
+		}
+		return fmt.Errorf("audit head %s is missing", HeadPath(path))
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case h.Seq == summary.Records && h.Hash == summary.LastHash:
+		return nil
+	case h.Seq > summary.Records:
+		return fmt.Errorf("audit %s is truncated: head is at seq %d, the file ends at seq %d", path, h.Seq, summary.Records)
+	case h.Seq+1 == summary.Records && h.Hash == summary.prevHash:
+		return fmt.Errorf("%w: head is at seq %d, the file ends at seq %d", ErrHeadBehind, h.Seq, summary.Records)
+	default:
+		return fmt.Errorf("audit head %s does not match seq %d", HeadPath(path), h.Seq)
+	}
+}
+
+func readHead(path string) (head, error) {
+	data, err := os.ReadFile(HeadPath(path)) // #nosec G304 -- derived from the audit path
+	if err != nil {
+		return head{}, err
+	}
+	var h head
+	if err := json.Unmarshal(data, &h); err != nil {
+		return head{}, fmt.Errorf("audit head %s: %w", HeadPath(path), err)
+	}
+	return h, nil
+}
+
+// writeHead atomically replaces the head checkpoint of the audit file at path.
+func writeHead(path string, h head) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(HeadPath(path))+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write audit head: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // Already renamed on success.
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write audit head: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write audit head: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write audit head: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), HeadPath(path)); err != nil {
+		return fmt.Errorf("write audit head: %w", err)
+	}
+	return nil
+}
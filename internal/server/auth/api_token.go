@@ -104,6 +104,11 @@ func (cfg *APITokenConfig) Validate(ctx *AuthContext) error {
 	return nil
 }
 
+// Secrets returns the configured token so it can be redacted.
+func (cfg *APITokenConfig) Secrets() []string {
+	return []string{cfg.token}
+}
+
 // resolveAPITokenSource selects and resolves the configured token source.
 func resolveAPITokenSource(rawToken *string, rawEnv *string, rawFile *string) (apiTokenSource, error) {
 	kind, err := selectAPITokenSourceKind(rawToken, rawEnv, rawFile)
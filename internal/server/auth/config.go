@@ -2,6 +2,7 @@ package auth
 
 import (
 	"fmt"
+	"poke/internal/server/request"
 
 	"github.com/goccy/go-yaml"
 )
@@ -11,6 +12,13 @@ import (
 // Configuration is loaded from each listener's `auth` node (see docs/configuration/auth.md).
 type Auth struct {
 	Validators map[string]Validator
+	Priorities map[string]PriorityRange // request priorities each auth kind may ask for
+}
+
+// PriorityRange bounds the per-request priority overrides a principal may ask for.
+type PriorityRange struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
 }
 
 // Validator checks a request-scoped AuthContext against a configured credential.
@@ -18,9 +26,14 @@ type Validator interface {
 	Validate(ctx *AuthContext) error
 }
 
+// SecretHolder is implemented by validators whose credentials must never be logged.
+type SecretHolder interface {
+	Secrets() []string
+}
+
 // UnmarshalYAML parses the `auth` block and instantiates validators by auth kind.
 func (auth *Auth) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	*auth = Auth{Validators: map[string]Validator{}}
+	*auth = Auth{Validators: map[string]Validator{}, Priorities: map[string]PriorityRange{}}
 
 	var raw map[string]interface{}
 	if err := unmarshal(&raw); err != nil {
@@ -32,6 +45,14 @@ func (auth *Auth) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 	validators := make(map[string]Validator, len(raw))
 	for authKind, rawConfig := range raw {
+		rawConfig, priorities, err := splitPriorityRange(rawConfig)
+		if err != nil {
+			return fmt.Errorf("auth %s: %w", authKind, err)
+		}
+		if priorities != nil {
+			auth.Priorities[authKind] = *priorities
+		}
+
 		switch authKind {
 		case AuthTypeAPIToken:
 			cfg := new(APITokenConfig)
@@ -67,6 +88,87 @@ func (auth *Auth) Validate(ctx *AuthContext) error {
 	return validator.Validate(ctx)
 }
 
+// Secrets returns the credentials of all configured validators.
+func (auth *Auth) Secrets() []string {
+	if auth == nil {
+		return nil
+	}
+	var out []string
+	for _, validator := range auth.Validators {
+		if holder, ok := validator.(SecretHolder); ok {
+			out = append(out, holder.Secrets()...)
+		}
+	}
+	return out
+}
+
+// AllowsPriority reports whether callers authenticated by authKind may request priority.
+//
+// Auth kinds without a configured `priority` range may not override priorities.
+func (auth *Auth) AllowsPriority(authKind string, priority int) bool {
+	if auth == nil {
+		return false
+	}
+	r, ok := auth.Priorities[authKind]
+	return ok && priority >= r.Min && priority <= r.Max
+}
+
+// UnmarshalYAML parses a `priority` range; omitted bounds default to the full range.
+func (r *PriorityRange) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type priorityRangeInput struct {
+		Min *int `yaml:"min"`
+		Max *int `yaml:"max"`
+	}
+
+	var in priorityRangeInput
+	if err := unmarshal(&in); err != nil {
+		return err
+	}
+
+	*r = PriorityRange{Min: request.MinPriority, Max: request.MaxPriority}
+	if in.Min != nil {
+		r.Min = *in.Min
+	}
+	if in.Max != nil {
+		r.Max = *in.Max
+	}
+	if err := request.ValidatePriority(r.Min); err != nil {
+		return fmt.Errorf("priority min: %w", err)
+	}
+	if err := request.ValidatePriority(r.Max); err != nil {
+		return fmt.Errorf("priority max: %w", err)
+	}
+	if r.Min > r.Max {
+		return fmt.Errorf("priority min %d must not exceed max %d", r.Min, r.Max)
+	}
+	return nil
+}
+
+// splitPriorityRange removes the generic `priority` key from an auth-kind node,
+// so the remaining node only carries validator-specific settings.
+func splitPriorityRange(rawConfig interface{}) (interface{}, *PriorityRange, error) {
+	node, ok := rawConfig.(map[string]interface{})
+	if !ok {
+		return rawConfig, nil, nil
+	}
+	rawRange, exists := node["priority"]
+	if !exists {
+		return rawConfig, nil, nil
+	}
+
+	rest := make(map[string]interface{}, len(node)-1)
+	for k, v := range node {
+		if k != "priority" {
+			rest[k] = v
+		}
+	}
+	priorities := new(PriorityRange)
+	if err := decodeAuthConfig(rawRange, priorities); err != nil {
+		return nil, nil, err
+	}
+	return rest, priorities, nil
+}
+
 // decodeAuthConfig unmarshals a per-auth-kind config node into target.
 //
 // This helper exists because Auth.UnmarshalYAML first parses the `auth` node into a
@@ -16,6 +16,25 @@ type AuthContext struct {
 	APIToken string
 }
 
+// Principal identifies the authenticated caller as "<listener>/<auth kind>".
+//
+// Tokens are configured per listener and method, so this is the finest
+// identity poke can attribute an action to.
+func (ctx AuthContext) Principal() string {
+	return ctx.ListenerType + "/" + ctx.AuthKind
+}
+
+// AdminPrincipal identifies a caller authenticated for the admin routes as
+// "<listener>/admin/<auth kind>", apart from command callers.
+func (ctx AuthContext) AdminPrincipal() string {
+	return ctx.ListenerType + "/admin/" + ctx.AuthKind
+}
+
+// AnonymousPrincipal identifies callers of a listener without configured auth.
+func AnonymousPrincipal(listenerType string) string {
+	return listenerType + "/anonymous"
+}
+
 // NewAPITokenContext constructs an AuthContext for API token authentication.
 func NewAPITokenContext(listenerType string, apiToken string) AuthContext {
 	return AuthContext{
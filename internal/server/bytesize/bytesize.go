@@ -0,0 +1,76 @@
+// Package bytesize parses sizes in bytes written as integers or with a binary
+// unit suffix, for config fields shared by several packages.
+package bytesize
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size is a size in bytes configured as an integer or with a binary unit
+// suffix (K, M, G, T; optionally followed by `i` and/or `B`).
+type Size uint64
+
+var units = map[string]uint64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// UnmarshalYAML parses sizes such as `1048576`, `512M` or `2GiB`.
+// Numeric YAML values are stringified before parsing.
+func (size *Size) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	text := ""
+	if raw != nil {
+		text = fmt.Sprint(raw)
+	}
+	parsed, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*size = parsed
+	return nil
+}
+
+// MarshalYAML renders sizes as plain byte counts.
+func (size Size) MarshalYAML() (interface{}, error) {
+	return uint64(size), nil
+}
+
+// Parse parses a size with an optional binary unit suffix.
+func Parse(raw string) (Size, error) {
+	value := strings.ToUpper(strings.TrimSpace(raw))
+	if value == "" {
+		return 0, fmt.Errorf("size must not be empty")
+	}
+
+	digits := strings.TrimRightFunc(value, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	unit := strings.TrimSpace(strings.TrimPrefix(value, digits))
+	unit = strings.TrimSuffix(unit, "B")
+	unit = strings.TrimSuffix(unit, "I")
+
+	multiplier, ok := units[unit]
+	if !ok || digits == "" {
+		return 0, fmt.Errorf("invalid size %q", raw)
+	}
+
+	n, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", raw, err)
+	}
+	if n > math.MaxUint64/multiplier {
+		return 0, fmt.Errorf("size %q overflows", raw)
+	}
+	return Size(n * multiplier), nil
+}
@@ -2,27 +2,51 @@ package server
 
 import (
 	"fmt"
+	"poke/internal/server/audit"
 	"poke/internal/server/dispatch"
+	"poke/internal/server/health"
+	"poke/internal/server/jobs"
 	"poke/internal/server/listener"
 	"poke/internal/server/logging"
+	"poke/internal/server/metrics"
+	"poke/internal/server/redact"
+	"poke/internal/server/tracing"
 
 	"github.com/goccy/go-yaml"
 )
 
 // Config represents the top-level server configuration.
 type Config struct {
-	Commands  dispatch.CommandRegistry `yaml:"commands"`
-	Listeners listener.ListenerConfig  `yaml:"listeners"`
-	Logging   logging.Config           `yaml:"logging"`
+	Include   []string                  `yaml:"include"` // files and globs Load merges commands from
+	Commands  dispatch.CommandRegistry  `yaml:"commands"`
+	Workflows dispatch.WorkflowRegistry `yaml:"workflows"`
+	Listeners listener.ListenerConfig   `yaml:"listeners"`
+	Logging   logging.Config            `yaml:"logging"`
+	Queue     jobs.QueueConfig          `yaml:"queue"`
+	Metrics   metrics.Config            `yaml:"metrics"`
+	Tracing   tracing.Config            `yaml:"tracing"`
+	Health    health.Config             `yaml:"health"`
+	Audit     audit.Config              `yaml:"audit"`
+	Redaction redact.Config             `yaml:"redaction"`
 }
 
 type configInput struct {
-	Commands  *dispatch.CommandRegistry `yaml:"commands"`
-	Listeners *listener.ListenerConfig  `yaml:"listeners"`
-	Logging   *logging.Config           `yaml:"logging"`
+	Include   []string                   `yaml:"include"`
+	Commands  *dispatch.CommandRegistry  `yaml:"commands"`
+	Workflows *dispatch.WorkflowRegistry `yaml:"workflows"`
+	Listeners *listener.ListenerConfig   `yaml:"listeners"`
+	Logging   *logging.Config            `yaml:"logging"`
+	Queue     *jobs.QueueConfig          `yaml:"queue"`
+	Metrics   *metrics.Config            `yaml:"metrics"`
+	Tracing   *tracing.Config            `yaml:"tracing"`
+	Health    *health.Config             `yaml:"health"`
+	Audit     *audit.Config              `yaml:"audit"`
+	Redaction *redact.Config             `yaml:"redaction"`
 }
 
 // Parse unmarshals raw config bytes into a Config.
+//
+// Included files are not read, see Load.
 func Parse(data []byte) (Config, error) {
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
@@ -33,41 +57,72 @@ func Parse(data []byte) (Config, error) {
 
 // UnmarshalYAML composes command and listener block parsers per docs/configuration/server.md.
 func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	var raw map[string]interface{}
-	if err := unmarshal(&raw); err != nil {
+	in, err := parseConfigInput(unmarshal)
+	if err != nil {
 		return err
 	}
+	return cfg.apply(in)
+}
 
-	if err := rejectLegacyTopLevelAuth(raw); err != nil {
+// apply parses every block of in, applying the defaults of missing blocks.
+func (cfg *Config) apply(in configInput) error {
+	commands, err := parseCommandRegistryOrDefault(in.Commands)
+	if err != nil {
+		return err
+	}
+
+	workflows := parseWorkflowRegistryOrDefault(in.Workflows)
+	if err := commands.AddWorkflows(&workflows); err != nil {
 		return err
 	}
 
-	in, err := decodeConfigInput(raw)
+	listeners, err := parseListenerConfigOrDefault(in.Listeners)
 	if err != nil {
 		return err
 	}
 
-	commands, err := parseCommandRegistryOrDefault(in.Commands)
+	logCfg, err := parseLoggingConfigOrDefault(in.Logging)
 	if err != nil {
 		return err
 	}
 
-	listeners, err := parseListenerConfigOrDefault(in.Listeners)
+	queueCfg, err := parseQueueConfigOrDefault(in.Queue)
 	if err != nil {
 		return err
 	}
 
-	logCfg, err := parseLoggingConfigOrDefault(in.Logging)
+	healthCfg, err := parseHealthConfigOrDefault(in.Health)
 	if err != nil {
 		return err
 	}
 
+	cfg.Include = in.Include
 	cfg.Commands = commands
+	cfg.Workflows = workflows
 	cfg.Listeners = listeners
 	cfg.Logging = logCfg
+	cfg.Queue = queueCfg
+	cfg.Metrics = parseMetricsConfigOrDefault(in.Metrics)
+	cfg.Tracing = parseTracingConfigOrDefault(in.Tracing)
+	cfg.Health = healthCfg
+	cfg.Audit = parseAuditConfigOrDefault(in.Audit)
+	cfg.Redaction = parseRedactionConfigOrDefault(in.Redaction)
 	return nil
 }
 
+// parseConfigInput unmarshals the top-level blocks without parsing defaults.
+func parseConfigInput(unmarshal func(interface{}) error) (configInput, error) {
+	var raw map[string]interface{}
+	if err := unmarshal(&raw); err != nil {
+		return configInput{}, err
+	}
+
+	if err := rejectLegacyTopLevelAuth(raw); err != nil {
+		return configInput{}, err
+	}
+	return decodeConfigInput(raw)
+}
+
 // rejectLegacyTopLevelAuth ensures deprecated top-level auth configuration is not used.
 func rejectLegacyTopLevelAuth(raw map[string]interface{}) error {
 	if _, hasLegacyAuth := raw["auth"]; hasLegacyAuth {
@@ -103,6 +158,14 @@ func parseCommandRegistryOrDefault(input *dispatch.CommandRegistry) (dispatch.Co
 	return empty, nil
 }
 
+// parseWorkflowRegistryOrDefault returns parsed workflows or an empty registry.
+func parseWorkflowRegistryOrDefault(input *dispatch.WorkflowRegistry) dispatch.WorkflowRegistry {
+	if input != nil {
+		return *input
+	}
+	return *dispatch.NewWorkflowRegistry(nil)
+}
+
 // parseListenerConfigOrDefault returns parsed listener config or documented empty default.
 func parseListenerConfigOrDefault(input *listener.ListenerConfig) (listener.ListenerConfig, error) {
 	if input != nil {
@@ -128,3 +191,65 @@ func parseLoggingConfigOrDefault(input *logging.Config) (logging.Config, error)
 	}
 	return defaults, nil
 }
+
+// parseQueueConfigOrDefault returns parsed queue config or the in-memory default.
+func parseQueueConfigOrDefault(input *jobs.QueueConfig) (jobs.QueueConfig, error) {
+	if input != nil {
+		return *input, nil
+	}
+
+	var defaults jobs.QueueConfig
+	if err := yaml.Unmarshal([]byte(`{}`), &defaults); err != nil {
+		return jobs.QueueConfig{}, err
+	}
+	return defaults, nil
+}
+
+// parseMetricsConfigOrDefault returns parsed metrics config; without a `metrics` block
+// the metrics listener is disabled.
+func parseMetricsConfigOrDefault(input *metrics.Config) metrics.Config {
+	if input != nil {
+		return *input
+	}
+	return metrics.Config{}
+}
+
+// parseTracingConfigOrDefault returns parsed tracing config; without a
+// `tracing` block tracing is disabled.
+func parseTracingConfigOrDefault(input *tracing.Config) tracing.Config {
+	if input != nil {
+		return *input
+	}
+	return tracing.Config{}
+}
+
+// parseHealthConfigOrDefault returns parsed health config or the enabled default.
+func parseHealthConfigOrDefault(input *health.Config) (health.Config, error) {
+	if input != nil {
+		return *input, nil
+	}
+
+	var defaults health.Config
+	if err := yaml.Unmarshal([]byte(`{}`), &defaults); err != nil {
+		return health.Config{}, err
+	}
+	return defaults, nil
+}
+
+// parseAuditConfigOrDefault returns parsed audit config; without an `audit`
+// block no audit file is written.
+func parseAuditConfigOrDefault(input *audit.Config) audit.Config {
+	if input != nil {
+		return *input
+	}
+	return audit.Config{}
+}
+
+// parseRedactionConfigOrDefault returns parsed redaction config; without a
+// `redaction` block only known secrets are redacted.
+func parseRedactionConfigOrDefault(input *redact.Config) redact.Config {
+	if input != nil {
+		return *input
+	}
+	return redact.Config{}
+}
@@ -0,0 +1,46 @@
+package dispatch
+
+import (
+	"poke/internal/server/audit"
+	"time"
+)
+
+// auditExecution records the final state of the job in the audit log. Jobs
+// rescheduled for a retry are recorded once they finish, jobs canceled before
+// they started once the dispatcher skips or withdraws them.
+func (d *SyncDispatcher) auditExecution(jobID string) {
+	job, ok := d.jobs.Get(jobID)
+	if !ok || !job.Finished() {
+		return
+	}
+	audit.Write(audit.Record{
+		Event:      audit.EventExecution,
+		Principal:  job.SubmittedBy,
+		CommandID:  job.CommandID,
+		JobID:      job.ID,
+		RequestID:  job.RequestID,
+		State:      string(job.State),
+		Outcome:    string(job.Outcome),
+		ExitCode:   job.ExitCode,
+		Attempts:   len(job.Attempts),
+		Error:      job.Error,
+		CanceledBy: job.CanceledBy,
+		StartedAt:  utc(job.StartedAt),
+		FinishedAt: utc(job.FinishedAt),
+	})
+}
+
+// auditWithdrawn records a job whose request was withdrawn from the queue or
+// a timer by Cancel. Withdraw hooks run while the registry is locked, so the
+// record is written from another goroutine once the cancellation completed.
+func (d *SyncDispatcher) auditWithdrawn(jobID string) {
+	go d.auditExecution(jobID)
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -9,7 +9,8 @@ import (
 )
 
 type CommandRegistry struct {
-	cmds map[string]executor.Command
+	cmds      map[string]executor.Command
+	workflows *WorkflowRegistry
 }
 
 // UnmarshalYAML parses commands config per docs/configuration/command.md.
@@ -65,6 +66,20 @@ func (reg *CommandRegistry) Register(id string, cmd executor.Command) {
 	reg.cmds[id] = cmd
 }
 
+// IDs returns the sorted IDs of the registered commands.
+func (reg *CommandRegistry) IDs() []string {
+	if reg == nil {
+		return nil
+	}
+
+	ids := make([]string, 0, len(reg.cmds))
+	for id := range reg.cmds {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 func (reg *CommandRegistry) Get(id string) (executor.Command, error) {
 	cmd, exists := reg.cmds[id]
 	if exists {
@@ -73,6 +88,45 @@ func (reg *CommandRegistry) Get(id string) (executor.Command, error) {
 	return executor.Command{}, fmt.Errorf("command with ID %s not found", id)
 }
 
+// Coalesces reports whether identical queued requests for id are merged.
+func (reg *CommandRegistry) Coalesces(id string) bool {
+	cmd, exists := reg.cmds[id]
+	return exists && cmd.Coalesce
+}
+
+// CheckPayload reports whether id accepts payload, see executor.ValidatePayload.
+//
+// A workflow's payload is passed to every step, so each step and compensating
+// command must accept it. Unknown IDs pass; the dispatcher reports them.
+func (reg *CommandRegistry) CheckPayload(id string, payload []byte) error {
+	if cmd, exists := reg.cmds[id]; exists {
+		return executor.ValidatePayload(cmd, payload)
+	}
+	wf, ok := reg.Workflow(id)
+	if !ok {
+		return nil
+	}
+	for _, step := range wf.Steps {
+		for _, commandID := range []string{step.Command, step.Compensate} {
+			if cmd, exists := reg.cmds[commandID]; exists {
+				if err := executor.ValidatePayload(cmd, payload); err != nil {
+					return fmt.Errorf("step %s: command %s: %w", step.ID, commandID, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Secrets returns the values of env variables marked secret in any command.
+func (reg *CommandRegistry) Secrets() []string {
+	var out []string
+	for _, cmd := range reg.cmds {
+		out = append(out, cmd.Env.SecretValues()...)
+	}
+	return out
+}
+
 // ExecutorNames returns the unique executor names used by registered commands.
 func (reg *CommandRegistry) ExecutorNames() []string {
 	if reg == nil || len(reg.cmds) == 0 {
@@ -99,6 +153,28 @@ func (reg *CommandRegistry) ExecutorNames() []string {
 	return names
 }
 
+// ProbeSandboxes verifies the kernel supports the isolation every sandboxed
+// command requests, so unavailable isolation fails at startup instead of per request.
+func (reg *CommandRegistry) ProbeSandboxes() error {
+	for _, id := range reg.IDs() {
+		if err := executor.ProbeSandbox(reg.cmds[id]); err != nil {
+			return fmt.Errorf("command %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// CheckExecutables verifies the program of every command can be started, see
+// executor.CheckExecutable.
+func (reg *CommandRegistry) CheckExecutables() error {
+	for _, id := range reg.IDs() {
+		if err := executor.CheckExecutable(reg.cmds[id]); err != nil {
+			return fmt.Errorf("command %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
 // decodeCommandConfig unmarshals a per-command config node into a Command.
 func decodeCommandConfig(rawConfig interface{}) (executor.Command, error) {
 	var cmd executor.Command
@@ -0,0 +1,56 @@
+package dispatch
+
+import (
+	"context"
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+	"poke/internal/server/metrics"
+	"poke/internal/server/request"
+	"strconv"
+	"time"
+)
+
+// recordExecution counts one command attempt and observes its duration.
+func recordExecution(commandID string, startedAt time.Time, finishedAt time.Time, result executor.Result) {
+	metrics.Executions.Inc(commandID, string(result.Outcome), exitStatusLabel(result))
+	metrics.ExecutionDuration.Observe(finishedAt.Sub(startedAt).Seconds(), commandID)
+}
+
+// exitStatusLabel returns the exit code, or "none" when the process did not exit normally.
+func exitStatusLabel(result executor.Result) string {
+	if result.ExitCode < 0 || result.Signal != "" {
+		return metrics.NoExitStatus
+	}
+	return strconv.Itoa(result.ExitCode)
+}
+
+// observeQueueWait records the time job waited for its first start as a
+// metric and as a span under parent. Dispatching a job again for a retry is
+// not a queue wait.
+func observeQueueWait(parent context.Context, job jobs.Job) {
+	if len(job.Attempts) > 0 || len(job.Steps) > 0 {
+		return
+	}
+	recordQueueWait(job)
+	traceQueueWait(parent, job)
+}
+
+// recordQueueWait observes the time job waited between becoming due and starting.
+func recordQueueWait(job jobs.Job) {
+	if job.StartedAt == nil {
+		return
+	}
+	due := job.CreatedAt
+	if job.RunAt != nil && job.RunAt.After(due) {
+		due = *job.RunAt
+	}
+	metrics.QueueWait.Observe(max(job.StartedAt.Sub(due), 0).Seconds())
+}
+
+// publishQueueDepths reports the priority queue depths, including empty priorities.
+func (d *SyncDispatcher) publishQueueDepths() {
+	depths := d.queue.Depths()
+	for priority := request.MinPriority; priority <= request.MaxPriority; priority++ {
+		metrics.DispatchQueueDepth.Set(float64(depths[priority]), strconv.Itoa(priority))
+	}
+}
@@ -0,0 +1,116 @@
+package dispatch
+
+import (
+	"poke/internal/server/request"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PriorityQueue orders requests by priority, FIFO within a priority.
+//
+// Waiting requests age: every `aging` interval (the queue's priority_aging)
+// spent in the queue raises their effective priority by one level, so a
+// steady stream of urgent requests cannot starve routine ones forever.
+type PriorityQueue struct {
+	mu     sync.Mutex
+	levels [request.MaxPriority + 1][]queuedRequest
+	aging  time.Duration
+}
+
+type queuedRequest struct {
+	req        request.CommandRequest
+	enqueuedAt time.Time
+}
+
+// NewPriorityQueue returns an empty queue; aging <= 0 disables starvation protection.
+func NewPriorityQueue(aging time.Duration) *PriorityQueue {
+	return &PriorityQueue{aging: aging}
+}
+
+// Push appends req at priority, clamped to the supported range.
+func (q *PriorityQueue) Push(req request.CommandRequest, priority int, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	priority = min(max(priority, request.MinPriority), request.MaxPriority)
+	q.levels[priority] = append(q.levels[priority], queuedRequest{req: req, enqueuedAt: now})
+}
+
+// Pop removes the request with the highest effective priority at now.
+//
+// Ties go to the request that waited longest.
+func (q *PriorityQueue) Pop(now time.Time) (request.CommandRequest, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	best, bestScore := -1, 0
+	for priority, level := range q.levels {
+		if len(level) == 0 {
+			continue
+		}
+		score := priority + q.aged(level[0].enqueuedAt, now)
+		if best < 0 || score > bestScore || (score == bestScore && level[0].enqueuedAt.Before(q.levels[best][0].enqueuedAt)) {
+			best, bestScore = priority, score
+		}
+	}
+	if best < 0 {
+		return request.CommandRequest{}, false
+	}
+
+	head := q.levels[best][0]
+	q.levels[best][0] = queuedRequest{}
+	q.levels[best] = q.levels[best][1:]
+	return head.req, true
+}
+
+// Remove drops the queued request for jobID, reporting whether one was queued.
+func (q *PriorityQueue) Remove(jobID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if jobID == "" {
+		return false
+	}
+	for priority, level := range q.levels {
+		if i := slices.IndexFunc(level, func(qr queuedRequest) bool { return qr.req.JobID == jobID }); i >= 0 {
+			q.levels[priority] = slices.Delete(level, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// aged returns the priority levels gained by waiting since enqueuedAt.
+func (q *PriorityQueue) aged(enqueuedAt time.Time, now time.Time) int {
+	if q.aging <= 0 {
+		return 0
+	}
+	return int(now.Sub(enqueuedAt) / q.aging)
+}
+
+// Len returns the number of queued requests.
+func (q *PriorityQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := 0
+	for _, level := range q.levels {
+		n += len(level)
+	}
+	return n
+}
+
+// Depths returns the number of queued requests per priority, omitting empty priorities.
+func (q *PriorityQueue) Depths() map[int]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	depths := make(map[int]int)
+	for priority, level := range q.levels {
+		if len(level) > 0 {
+			depths[priority] = len(level)
+		}
+	}
+	return depths
+}
@@ -6,24 +6,35 @@ import (
 	"io"
 	"log/slog"
 	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
 	"poke/internal/server/request"
+	"sync/atomic"
+	"time"
 )
 
 type SyncDispatcher struct {
 	ctx       context.Context                // executor context
 	registry  *CommandRegistry               // command registry
 	reqCh     <-chan request.CommandRequest  // request input stream, executor routes them
+	dueCh     chan request.CommandRequest    // scheduled requests whose run_at has passed
+	queue     *PriorityQueue                 // requests taken from reqCh, ordered by priority
+	closed    bool                           // reqCh was closed, stop once queue is drained
+	stopped   chan struct{}                  // closed when Run returns, releases pending timers
+	running   atomic.Bool                    // Run is consuming requests
 	executors map[string]executor.ExecutorFn // worker input channels
+	jobs      *jobs.Registry                 // job states and cancellation hooks
 	logger    *slog.Logger                   // dispatcher logger
 }
 
 // NewSyncDispatcher constructs a synchronous dispatcher for configured executors.
 //
-// SyncDispatcher executes commands one at a time, taking new requests from
-// reqCh only after the previous one completes.
+// SyncDispatcher executes commands one at a time. Between executions it moves
+// the requests waiting on reqCh into priority queues, up to cap(reqCh) of
+// them, and runs the most urgent one next.
 //
-// Note that SyncDispatcher does not own reqCh.
-func NewSyncDispatcher(ctx context.Context, registry *CommandRegistry, executors []string, reqCh <-chan request.CommandRequest) (*SyncDispatcher, error) {
+// Note that SyncDispatcher does not own reqCh. Jobs are tracked in
+// jobRegistry, or in a new in-memory registry when it is nil.
+func NewSyncDispatcher(ctx context.Context, registry *CommandRegistry, executors []string, reqCh <-chan request.CommandRequest, jobRegistry *jobs.Registry) (*SyncDispatcher, error) {
 	workerChs := make(map[string]executor.ExecutorFn, len(executors))
 
 	for _, e := range executors {
@@ -35,6 +46,10 @@ func NewSyncDispatcher(ctx context.Context, registry *CommandRegistry, executors
 		}
 	}
 
+	if jobRegistry == nil {
+		jobRegistry = jobs.NewRegistry()
+	}
+
 	logger := slog.Default()
 	if logger == nil {
 		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
@@ -44,43 +59,268 @@ func NewSyncDispatcher(ctx context.Context, registry *CommandRegistry, executors
 		ctx:       ctx,
 		registry:  registry,
 		reqCh:     reqCh,
+		dueCh:     make(chan request.CommandRequest),
+		queue:     NewPriorityQueue(jobRegistry.PriorityAging()),
+		stopped:   make(chan struct{}),
 		executors: workerChs,
+		jobs:      jobRegistry,
 		logger:    logger.With("component", "dispatcher"),
 	}, nil
 }
 
+// Jobs returns the registry tracking jobs handled by the dispatcher.
+func (d *SyncDispatcher) Jobs() *jobs.Registry {
+	return d.jobs
+}
+
+// QueueDepths returns the number of requests waiting per priority.
+//
+// Requests still buffered in reqCh are not counted.
+func (d *SyncDispatcher) QueueDepths() map[int]int {
+	return d.queue.Depths()
+}
+
+// Running reports whether Run is consuming requests.
+func (d *SyncDispatcher) Running() bool {
+	return d.running.Load()
+}
+
+// Done returns a channel closed once Run has returned, after the request it
+// was handling finished.
+func (d *SyncDispatcher) Done() <-chan struct{} {
+	return d.stopped
+}
+
 // Run consumes requests and executes commands serially until context or channel closure.
+//
+// Requests already queued when reqCh is closed are still run; scheduled
+// requests and retries waiting for their backoff are not.
 func (d *SyncDispatcher) Run() {
 	d.logger.Info("sync loop started", "event", "loop_started")
+	d.running.Store(true)
+	defer d.running.Store(false)
+	defer close(d.stopped)
 	for {
-		select {
-		case <-d.ctx.Done():
+		if d.ctx.Err() != nil {
 			d.logger.Info("context canceled, stopping", "event", "context_canceled")
 			return
+		}
+		d.drain()
+		if req, ok := d.queue.Pop(time.Now()); ok {
+			d.publishQueueDepths()
+			d.handle(req)
+			continue
+		}
+		if d.closed {
+			d.logger.Info("request channel closed, stopping", "event", "request_channel_closed")
+			return
+		}
+
+		select {
+		case <-d.ctx.Done():
+		case req, ok := <-d.reqCh:
+			d.receive(req, ok)
+		case req := <-d.dueCh:
+			d.enqueue(req)
+		}
+	}
+}
+
+// drain moves requests that are ready on reqCh or dueCh into the priority
+// queue without blocking, until the queue holds cap(reqCh) requests.
+func (d *SyncDispatcher) drain() {
+	limit := max(cap(d.reqCh), 1)
+	for d.queue.Len() < limit {
+		select {
 		case req, ok := <-d.reqCh:
-			if !ok {
-				d.logger.Info("request channel closed, stopping", "event", "request_channel_closed")
-				return
-			}
-			d.logger.Info("request received", "event", "request_received", "command_id", req.CommandID)
-			cmd, err := d.registry.Get(req.CommandID)
-			if err != nil {
-				d.logger.Warn("command lookup failed", "event", "command_lookup_failed", "command_id", req.CommandID, "error", err)
-				continue
-			}
-			cmd.ID = req.CommandID
-			fn, exists := d.executors[cmd.Executor]
-			if !exists {
-				d.logger.Warn("unknown executor", "event", "unknown_executor", "executor", cmd.Executor, "command_id", cmd.ID, "command_name", cmd.Name)
-				continue
-			}
-			d.logger.Info("executing command", "event", "command_execution_started", "executor", cmd.Executor, "command_id", cmd.ID, "command_name", cmd.Name)
-			result := fn(d.ctx, cmd)
-			if result.Error != nil {
-				d.logger.Error("command execution failed", "event", "command_execution_failed", "command_id", cmd.ID, "command_name", cmd.Name, "exit_code", result.ExitCode, "error", result.Error)
-				continue
-			}
-			d.logger.Info("command execution completed", "event", "command_execution_completed", "command_id", cmd.ID, "command_name", cmd.Name, "exit_code", result.ExitCode)
+			d.receive(req, ok)
+		case req := <-d.dueCh:
+			d.enqueue(req)
+		default:
+			return
+		}
+	}
+}
+
+// receive accepts a request read from reqCh, ok is false once reqCh is closed.
+func (d *SyncDispatcher) receive(req request.CommandRequest, ok bool) {
+	if !ok {
+		d.closed = true
+		d.reqCh = nil
+		return
+	}
+	if req.RunAt.After(time.Now()) {
+		d.schedule(req)
+		return
+	}
+	d.enqueue(req)
+}
+
+// enqueue queues req at its override or its command's priority.
+//
+// Canceling the job while it is queued removes req from the queue again.
+func (d *SyncDispatcher) enqueue(req request.CommandRequest) {
+	d.queue.Push(req, d.priority(req), time.Now())
+	d.publishQueueDepths()
+	d.jobs.Hold(req.JobID, func() bool {
+		removed := d.queue.Remove(req.JobID)
+		d.publishQueueDepths()
+		if removed {
+			d.auditWithdrawn(req.JobID)
+		}
+		return removed
+	})
+}
+
+// priority resolves the priority req is queued at.
+//
+// Unknown commands get the lowest priority, handle reports them once dequeued.
+func (d *SyncDispatcher) priority(req request.CommandRequest) int {
+	if req.Priority != nil {
+		return *req.Priority
+	}
+	if wf, ok := d.registry.Workflow(req.CommandID); ok {
+		return wf.Priority
+	}
+	if cmd, err := d.registry.Get(req.CommandID); err == nil {
+		return cmd.Priority
+	}
+	return request.MinPriority
+}
+
+// schedule holds req on a timer until its run_at and hands it back to Run.
+//
+// The timer's stop hook is registered with the job so canceling a scheduled
+// job releases it; a timer that already fired delivers a canceled job, which
+// handle skips. Requests for unknown jobs are dropped.
+func (d *SyncDispatcher) schedule(req request.CommandRequest) {
+	logger := request.Logger(request.ContextWithID(d.ctx, req.RequestID), d.logger)
+	timer := time.AfterFunc(time.Until(req.RunAt), func() {
+		select {
+		case d.dueCh <- req:
+		case <-d.stopped:
 		}
+	})
+	withdraw := func() bool {
+		stopped := timer.Stop()
+		if stopped {
+			d.auditWithdrawn(req.JobID)
+		}
+		return stopped
+	}
+	if !d.jobs.Schedule(req.JobID, req.CommandID, withdraw) {
+		timer.Stop()
+		logger.Info("job canceled before scheduling, skipping", "event", "job_skipped_canceled", "job_id", req.JobID, "command_id", req.CommandID)
+		d.auditExecution(req.JobID)
+		return
+	}
+	logger.Info("job scheduled", "event", "job_scheduled", "job_id", req.JobID, "command_id", req.CommandID, "run_at", req.RunAt)
+}
+
+// handle executes a single request under a per-job context derived from the dispatcher context.
+//
+// A failed attempt that the command's retry policy retries reschedules the
+// job instead of waiting on the dispatcher goroutine, see retryLater.
+func (d *SyncDispatcher) handle(req request.CommandRequest) {
+	parentCtx, spanCtx, span := d.startDispatchSpan(req)
+	defer d.endDispatchSpan(span, req.JobID)
+	logger := request.Logger(parentCtx, d.logger)
+	logger.Info("request received", "event", "request_received", "job_id", req.JobID, "command_id", req.CommandID, "queue_depths", d.queue.Depths())
+
+	jobCtx, cancel := context.WithCancel(spanCtx)
+	defer cancel()
+	job, ok := d.jobs.Start(req.JobID, req.CommandID, cancel)
+	if !ok {
+		if job.ID != "" {
+			logger.Info("job canceled before start, skipping", "event", "job_skipped_canceled", "job_id", job.ID, "command_id", req.CommandID, "canceled_by", job.CanceledBy)
+			d.auditExecution(job.ID)
+		}
+		return
+	}
+	defer d.auditExecution(job.ID)
+	observeQueueWait(parentCtx, job)
+	req.JobID = job.ID
+	if wf, ok := d.registry.Workflow(req.CommandID); ok {
+		d.handleWorkflow(jobCtx, job, wf, req)
+		return
+	}
+
+	cmd, err := d.registry.Get(req.CommandID)
+	if err != nil {
+		logger.Warn("command lookup failed", "event", "command_lookup_failed", "job_id", job.ID, "command_id", req.CommandID, "error", err)
+		d.jobs.Finish(job.ID, executor.Result{ExitCode: -1, Outcome: executor.OutcomeFailed, Error: err})
+		return
+	}
+	cmd.ID = req.CommandID
+	cmd.Payload = req.Payload
+	fn, exists := d.executors[cmd.Executor]
+	if !exists {
+		logger.Warn("unknown executor", "event", "unknown_executor", "executor", cmd.Executor, "job_id", job.ID, "command_id", cmd.ID, "command_name", cmd.Name)
+		d.jobs.Finish(job.ID, executor.Result{ExitCode: -1, Outcome: executor.OutcomeFailed, Error: fmt.Errorf("unknown executor %q", cmd.Executor)})
+		return
+	}
+
+	attempt := len(job.Attempts) + 1
+	result, delay, retry := d.attempt(jobCtx, job, cmd, fn, attempt, func(a jobs.Attempt) { d.jobs.RecordAttempt(job.ID, a) })
+	if retry {
+		if d.retryLater(req, delay) {
+			return
+		}
+		result = retryCanceled(result)
+	}
+	job = d.jobs.Finish(job.ID, result)
+	if result.Error != nil {
+		logger.Error("job failed", "event", "job_failed", "job_id", job.ID, "command_id", cmd.ID, "command_name", cmd.Name, "attempts", len(job.Attempts), "state", job.State, "canceled_by", job.CanceledBy)
+		return
+	}
+	logger.Info("command execution completed", "event", "command_execution_completed", "job_id", job.ID, "command_id", cmd.ID, "command_name", cmd.Name, "exit_code", result.ExitCode, "attempts", len(job.Attempts))
+}
+
+// attempt runs cmd once as attempt number n, passing the attempt to record.
+//
+// When the command failed and its retry policy allows another attempt, retry
+// is true and delay is the backoff before that attempt.
+func (d *SyncDispatcher) attempt(ctx context.Context, job jobs.Job, cmd executor.Command, fn executor.ExecutorFn, n int, record func(jobs.Attempt)) (result executor.Result, delay time.Duration, retry bool) {
+	logger := request.Logger(ctx, d.logger)
+	logger.Info("executing command", "event", "command_execution_started", "executor", cmd.Executor, "job_id", job.ID, "command_id", cmd.ID, "command_name", cmd.Name, "attempt", n)
+	startedAt := time.Now()
+	result = fn(ctx, cmd)
+	finishedAt := time.Now()
+	recordExecution(cmd.ID, startedAt, finishedAt, result)
+	record(jobs.NewAttempt(n, startedAt, finishedAt, result))
+	if result.Error == nil {
+		return result, 0, false
+	}
+
+	maxAttempts := cmd.Retry.Attempts()
+	logger.Error("command execution failed", "event", "command_execution_failed", "job_id", job.ID, "command_id", cmd.ID, "command_name", cmd.Name, "attempt", n, "max_attempts", maxAttempts, "exit_code", result.ExitCode, "outcome", result.Outcome, "error", result.Error)
+	if n >= maxAttempts || !cmd.Retry.ShouldRetry(result) || ctx.Err() != nil {
+		return result, 0, false
+	}
+	delay = cmd.Retry.Delay(n)
+	logger.Info("command retry scheduled", "event", "command_retry_scheduled", "job_id", job.ID, "command_id", cmd.ID, "command_name", cmd.Name, "attempt", n+1, "delay", delay)
+	return result, delay, true
+}
+
+// retryLater reschedules req's job to be dispatched again after delay, held
+// on a timer like requests with a run_at, so other requests run during the
+// backoff. It returns false when the job was canceled meanwhile.
+func (d *SyncDispatcher) retryLater(req request.CommandRequest, delay time.Duration) bool {
+	req.RunAt = time.Now().Add(delay)
+	if !d.jobs.Retry(req.JobID, req.RunAt, req.Payload) {
+		return false
+	}
+	d.schedule(req)
+	return true
+}
+
+// retryCanceled turns the result of a failed attempt into the result of a
+// command canceled before its next attempt.
+func retryCanceled(result executor.Result) executor.Result {
+	return executor.Result{
+		Output:   result.Output,
+		ExitCode: result.ExitCode,
+		Outcome:  executor.OutcomeCanceled,
+		Error:    fmt.Errorf("retry canceled: %w", context.Canceled),
 	}
 }
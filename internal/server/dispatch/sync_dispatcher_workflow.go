@@ -0,0 +1,199 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+	"poke/internal/server/request"
+	"poke/internal/server/tracing"
+	"slices"
+	"time"
+)
+
+// handleWorkflow runs the steps of wf in order as a single job.
+//
+// Steps run one at a time on the dispatcher goroutine. A step is skipped when
+// the workflow was aborted or one of the steps it needs was skipped; failed
+// steps with on_failure continue do not block their dependents. Canceling the
+// job aborts the workflow regardless of on_failure.
+//
+// A step whose command is retried is recorded as retrying and the job is
+// rescheduled for the next attempt. Dispatched again, the workflow resumes
+// with that step, keeping the steps recorded earlier.
+func (d *SyncDispatcher) handleWorkflow(ctx context.Context, job jobs.Job, wf Workflow, req request.CommandRequest) {
+	logger := request.Logger(ctx, d.logger)
+	if len(job.Steps) == 0 {
+		logger.Info("workflow started", "event", "workflow_started", "job_id", job.ID, "workflow_id", wf.ID, "steps", len(wf.Steps))
+	} else {
+		logger.Info("workflow resumed", "event", "workflow_resumed", "job_id", job.ID, "workflow_id", wf.ID, "steps", len(wf.Steps), "recorded", len(job.Steps))
+	}
+
+	failed, failure, rescheduled := d.runWorkflowSteps(ctx, job, wf, req)
+	switch {
+	case rescheduled:
+		return
+	case failed == nil:
+		job = d.jobs.Finish(job.ID, executor.Result{Outcome: executor.OutcomeSucceeded})
+		logger.Info("workflow completed", "event", "workflow_completed", "job_id", job.ID, "workflow_id", wf.ID)
+		return
+	}
+	job = d.jobs.Finish(job.ID, executor.Result{
+		ExitCode: failure.ExitCode,
+		Outcome:  failure.Outcome,
+		Error:    fmt.Errorf("step %s: %w", failed.ID, failure.Error),
+	})
+	logger.Error("workflow failed", "event", "workflow_failed", "job_id", job.ID, "workflow_id", wf.ID, "step", failed.ID, "state", job.State, "canceled_by", job.CanceledBy)
+}
+
+// runWorkflowSteps runs the steps of wf that job has not recorded yet. It
+// returns the step that aborted the workflow with its result, or rescheduled
+// when a step waits for its next attempt.
+func (d *SyncDispatcher) runWorkflowSteps(ctx context.Context, job jobs.Job, wf Workflow, req request.CommandRequest) (*jobs.Step, executor.Result, bool) {
+	states, pending := recordedSteps(job)
+	var failed *jobs.Step
+	var failure executor.Result
+	for _, step := range wf.Steps {
+		if _, recorded := states[step.ID]; recorded {
+			continue
+		}
+		if failed != nil || !stepReady(step, states) {
+			states[step.ID] = jobs.StepSkipped
+			d.jobs.RecordStep(job.ID, jobs.Step{ID: step.ID, CommandID: step.Command, State: jobs.StepSkipped})
+			continue
+		}
+
+		rec, result, delay := d.runWorkflowStep(ctx, job, step, req.Payload, pending)
+		pending = jobs.Step{}
+		d.jobs.RecordStep(job.ID, rec)
+		if rec.Retrying() {
+			if d.retryLater(req, delay) {
+				return nil, executor.Result{}, true
+			}
+			rec, result = rec.Abandoned(), retryCanceled(result)
+			d.jobs.RecordStep(job.ID, rec)
+		}
+		states[step.ID] = rec.State
+		if abortsWorkflow(ctx, step, result) {
+			failed, failure = &rec, result
+		}
+	}
+	return failed, failure, false
+}
+
+// recordedSteps returns the states of the steps job recorded on earlier
+// dispatches and the record of the step waiting for a retry, if any.
+func recordedSteps(job jobs.Job) (map[string]jobs.StepState, jobs.Step) {
+	states := make(map[string]jobs.StepState, len(job.Steps))
+	var pending jobs.Step
+	for _, rec := range job.Steps {
+		if rec.Retrying() {
+			pending = rec
+			continue
+		}
+		states[rec.ID] = rec.State
+	}
+	return states, pending
+}
+
+// abortsWorkflow reports whether a step that ended with result aborts the workflow.
+func abortsWorkflow(ctx context.Context, step WorkflowStep, result executor.Result) bool {
+	return result.Error != nil && (step.OnFailure != OnFailureContinue || ctx.Err() != nil)
+}
+
+// runWorkflowStep runs a step's command and, when it fails with on_failure
+// compensate, its compensating command. When pending is the step's record
+// from an earlier dispatch, the command or compensation that waits for a
+// retry continues with its next attempt.
+//
+// A returned record that is Retrying waits for delay before its next attempt.
+// Compensation is not attempted once the job was canceled.
+func (d *SyncDispatcher) runWorkflowStep(ctx context.Context, job jobs.Job, step WorkflowStep, payload []byte, pending jobs.Step) (jobs.Step, executor.Result, time.Duration) {
+	if pending.ID != step.ID {
+		pending = jobs.Step{}
+	}
+
+	var rec jobs.Step
+	var result executor.Result
+	if pending.State == jobs.StepFailed {
+		// The command failed for good on an earlier dispatch, its compensation is retried.
+		rec, result = pending, stepResult(pending)
+	} else {
+		var delay time.Duration
+		rec, result, delay = d.runStepCommand(ctx, job, step.ID, step.Command, payload, pending.Attempts)
+		if rec.Retrying() || result.Error == nil {
+			return rec, result, delay
+		}
+	}
+	if step.OnFailure != OnFailureCompensate || ctx.Err() != nil {
+		return rec, result, 0
+	}
+
+	var prior []jobs.Attempt
+	if pending.Compensation != nil {
+		prior = pending.Compensation.Attempts
+	} else {
+		request.Logger(ctx, d.logger).Info("workflow step compensating", "event", "workflow_step_compensating", "job_id", job.ID, "step", step.ID, "command_id", step.Compensate)
+	}
+	compensation, _, delay := d.runStepCommand(ctx, job, step.ID, step.Compensate, payload, prior)
+	rec.Compensation = &compensation
+	return rec, result, delay
+}
+
+// runStepCommand resolves and executes commandID, collecting its attempts,
+// after the prior ones of earlier dispatches, into a step record. The record
+// is retrying when the command waits for delay before its next attempt.
+func (d *SyncDispatcher) runStepCommand(ctx context.Context, job jobs.Job, stepID string, commandID string, payload []byte, prior []jobs.Attempt) (jobs.Step, executor.Result, time.Duration) {
+	ctx, span := tracing.Start(ctx, "poke.workflow_step", tracing.WithAttrs("poke.step", stepID, "poke.command_id", commandID))
+	defer span.End()
+	logger := request.Logger(ctx, d.logger)
+
+	cmd, err := d.registry.Get(commandID)
+	if err == nil {
+		cmd.ID = commandID
+		cmd.Payload = payload
+		if _, exists := d.executors[cmd.Executor]; !exists {
+			err = fmt.Errorf("unknown executor %q", cmd.Executor)
+		}
+	}
+	if err != nil {
+		logger.Warn("workflow step could not run", "event", "workflow_step_failed", "job_id", job.ID, "step", stepID, "command_id", commandID, "error", err)
+		result := executor.Result{ExitCode: -1, Outcome: executor.OutcomeFailed, Error: err}
+		span.RecordError(err)
+		return jobs.NewStep(stepID, commandID, prior, result), result, 0
+	}
+
+	attempts := slices.Clip(prior)
+	result, delay, retry := d.attempt(ctx, job, cmd, d.executors[cmd.Executor], len(prior)+1, func(a jobs.Attempt) { attempts = append(attempts, a) })
+	rec := jobs.NewStep(stepID, commandID, attempts, result)
+	if retry {
+		rec.State = jobs.StepRetrying
+	}
+	span.SetAttrs("poke.step_state", string(rec.State))
+	span.RecordError(result.Error)
+	logger.Info("workflow step finished", "event", "workflow_step_finished", "job_id", job.ID, "step", stepID, "command_id", commandID, "state", rec.State, "outcome", result.Outcome)
+	return rec, result, delay
+}
+
+// stepResult rebuilds the result of the command a step record was made from.
+func stepResult(rec jobs.Step) executor.Result {
+	result := executor.Result{ExitCode: -1, Outcome: rec.Outcome}
+	if rec.ExitCode != nil {
+		result.ExitCode = *rec.ExitCode
+	}
+	if rec.Error != "" {
+		result.Error = errors.New(rec.Error)
+	}
+	return result
+}
+
+// stepReady reports whether none of the steps step needs was skipped.
+func stepReady(step WorkflowStep, states map[string]jobs.StepState) bool {
+	for _, need := range step.Needs {
+		if states[need] == jobs.StepSkipped {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"poke/internal/server/jobs"
+	"poke/internal/server/request"
+	"poke/internal/server/tracing"
+)
+
+// startDispatchSpan starts the span covering one job, joining the trace the
+// listener recorded on req. Both contexts carry req's request ID.
+func (d *SyncDispatcher) startDispatchSpan(req request.CommandRequest) (context.Context, context.Context, *tracing.Span) {
+	parent := tracing.ContextWithTraceparent(request.ContextWithID(d.ctx, req.RequestID), req.TraceParent)
+	ctx, span := tracing.Start(parent, "poke.dispatch", tracing.WithAttrs("poke.job_id", req.JobID, "poke.command_id", req.CommandID))
+	return parent, ctx, span
+}
+
+// endDispatchSpan records the job's final state on span and ends it.
+func (d *SyncDispatcher) endDispatchSpan(span *tracing.Span, jobID string) {
+	if job, ok := d.jobs.Get(jobID); ok {
+		span.SetAttrs("poke.job_state", string(job.State), "poke.attempts", len(job.Attempts))
+		if job.State == jobs.StateFailed {
+			span.RecordError(errors.New(job.Error))
+		}
+	}
+	span.End()
+}
+
+// traceQueueWait records the time job waited to start as a span under parent.
+func traceQueueWait(parent context.Context, job jobs.Job) {
+	due := job.CreatedAt
+	if job.RunAt != nil && job.RunAt.After(due) {
+		due = *job.RunAt
+	}
+	_, span := tracing.Start(parent, "poke.queue_wait", tracing.WithStartTime(due), tracing.WithAttrs("poke.job_id", job.ID))
+	span.End()
+}
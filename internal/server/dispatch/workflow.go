@@ -0,0 +1,279 @@
+package dispatch
+
+import (
+	"fmt"
+	"poke/internal/server/request"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// OnFailure selects what a workflow does when a step fails.
+type OnFailure string
+
+const (
+	OnFailureAbort      OnFailure = "abort"      // skip all remaining steps and fail the workflow
+	OnFailureContinue   OnFailure = "continue"   // record the failure and run dependent steps anyway
+	OnFailureCompensate OnFailure = "compensate" // run the step's compensating command, then abort
+)
+
+// Workflow is an ordered or DAG-shaped group of commands run as one job.
+//
+// Steps are stored in execution order: a step only appears after every step
+// it needs.
+type Workflow struct {
+	ID          string         `yaml:"-"`
+	Name        string         `yaml:"name,omitempty"`
+	Description string         `yaml:"description,omitempty"`
+	Priority    int            `yaml:"priority,omitempty"` // Dispatch priority of the workflow job, 0 (default) to 9
+	Steps       []WorkflowStep `yaml:"steps"`
+}
+
+// WorkflowStep runs one command as part of a Workflow.
+type WorkflowStep struct {
+	ID         string    `yaml:"id"`                   // Step identifier, defaults to the command ID
+	Command    string    `yaml:"command"`              // Command ID to run
+	Needs      []string  `yaml:"needs"`                // Steps that must finish first, defaults to the previous step
+	OnFailure  OnFailure `yaml:"on_failure,omitempty"` // Failure handling, default abort
+	Compensate string    `yaml:"compensate,omitempty"` // Command ID run when on_failure is compensate
+}
+
+// WorkflowRegistry holds the workflows defined under top-level `workflows`.
+type WorkflowRegistry struct {
+	workflows map[string]Workflow
+}
+
+// UnmarshalYAML parses workflows config per docs/configuration/workflow.md.
+func (reg *WorkflowRegistry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw yaml.MapSlice
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	reg.workflows = make(map[string]Workflow, len(raw))
+	for _, item := range raw {
+		id, ok := item.Key.(string)
+		if !ok {
+			return fmt.Errorf("workflow id must be string, got %T", item.Key)
+		}
+		if id == "" {
+			return fmt.Errorf("workflow id must not be empty")
+		}
+		if _, exists := reg.workflows[id]; exists {
+			return fmt.Errorf("duplicate workflow id %q", id)
+		}
+
+		wf, err := decodeWorkflowConfig(item.Value)
+		if err != nil {
+			return fmt.Errorf("workflow %s: %w", id, err)
+		}
+		wf.ID = id
+		reg.workflows[id] = wf
+	}
+	return nil
+}
+
+// UnmarshalYAML parses a single workflow and orders its steps.
+func (wf *Workflow) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type stepInput struct {
+		ID         string    `yaml:"id"`
+		Command    string    `yaml:"command"`
+		Needs      *[]string `yaml:"needs"`
+		OnFailure  OnFailure `yaml:"on_failure"`
+		Compensate string    `yaml:"compensate"`
+	}
+	type workflowInput struct {
+		Name        string      `yaml:"name"`
+		Description string      `yaml:"description"`
+		Priority    int         `yaml:"priority"`
+		Steps       []stepInput `yaml:"steps"`
+	}
+
+	var in workflowInput
+	if err := unmarshal(&in); err != nil {
+		return err
+	}
+	if len(in.Steps) == 0 {
+		return fmt.Errorf("steps must not be empty")
+	}
+	if err := request.ValidatePriority(in.Priority); err != nil {
+		return err
+	}
+
+	steps := make([]WorkflowStep, len(in.Steps))
+	for i, s := range in.Steps {
+		step := WorkflowStep{
+			ID:         s.ID,
+			Command:    s.Command,
+			OnFailure:  OnFailure(strings.ToLower(string(s.OnFailure))),
+			Compensate: s.Compensate,
+		}
+		if step.ID == "" {
+			step.ID = step.Command
+		}
+		if step.OnFailure == "" {
+			step.OnFailure = OnFailureAbort
+		}
+		switch {
+		case s.Needs != nil:
+			step.Needs = *s.Needs
+		case i > 0:
+			step.Needs = []string{steps[i-1].ID}
+		}
+		steps[i] = step
+	}
+
+	ordered, err := orderWorkflowSteps(steps)
+	if err != nil {
+		return err
+	}
+	*wf = Workflow{Name: in.Name, Description: in.Description, Priority: in.Priority, Steps: ordered}
+	return nil
+}
+
+// validate checks a single step in isolation.
+func (s WorkflowStep) validate() error {
+	if s.Command == "" {
+		return fmt.Errorf("step %q: command is required", s.ID)
+	}
+	switch s.OnFailure {
+	case OnFailureAbort, OnFailureContinue:
+		if s.Compensate != "" {
+			return fmt.Errorf("step %s: compensate requires on_failure compensate", s.ID)
+		}
+	case OnFailureCompensate:
+		if s.Compensate == "" {
+			return fmt.Errorf("step %s: on_failure compensate requires a compensate command", s.ID)
+		}
+	default:
+		return fmt.Errorf("step %s: invalid on_failure %q (want abort, continue or compensate)", s.ID, s.OnFailure)
+	}
+	return nil
+}
+
+// orderWorkflowSteps validates steps and sorts them topologically.
+//
+// Ties are broken by declaration order, so a plain list of steps keeps its order.
+func orderWorkflowSteps(steps []WorkflowStep) ([]WorkflowStep, error) {
+	if err := checkWorkflowSteps(steps); err != nil {
+		return nil, err
+	}
+
+	ordered := make([]WorkflowStep, 0, len(steps))
+	placed := make(map[string]bool, len(steps))
+	for len(ordered) < len(steps) {
+		next := nextReadyStep(steps, placed)
+		if next < 0 {
+			return nil, fmt.Errorf("steps form a dependency cycle")
+		}
+		placed[steps[next].ID] = true
+		ordered = append(ordered, steps[next])
+	}
+	return ordered, nil
+}
+
+// checkWorkflowSteps validates every step and rejects duplicate or unknown step IDs.
+func checkWorkflowSteps(steps []WorkflowStep) error {
+	ids := make(map[string]struct{}, len(steps))
+	for _, s := range steps {
+		if err := s.validate(); err != nil {
+			return err
+		}
+		if _, exists := ids[s.ID]; exists {
+			return fmt.Errorf("duplicate step id %q", s.ID)
+		}
+		ids[s.ID] = struct{}{}
+	}
+	for _, s := range steps {
+		for _, need := range s.Needs {
+			if _, ok := ids[need]; !ok {
+				return fmt.Errorf("step %s: needs unknown step %q", s.ID, need)
+			}
+		}
+	}
+	return nil
+}
+
+// nextReadyStep returns the first unplaced step whose needs are all placed, or -1.
+func nextReadyStep(steps []WorkflowStep, placed map[string]bool) int {
+	for i, s := range steps {
+		if placed[s.ID] {
+			continue
+		}
+		if !slices.ContainsFunc(s.Needs, func(need string) bool { return !placed[need] }) {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewWorkflowRegistry builds a registry from already ordered workflows.
+func NewWorkflowRegistry(workflows map[string]Workflow) *WorkflowRegistry {
+	if workflows == nil {
+		workflows = make(map[string]Workflow)
+	}
+	return &WorkflowRegistry{workflows: workflows}
+}
+
+// Get returns the workflow registered under id.
+func (reg *WorkflowRegistry) Get(id string) (Workflow, bool) {
+	if reg == nil {
+		return Workflow{}, false
+	}
+	wf, ok := reg.workflows[id]
+	return wf, ok
+}
+
+// AddWorkflows makes workflows callable by ID through the command registry.
+//
+// Workflow IDs share the command ID namespace, and every step and
+// compensating command must reference a registered command.
+func (reg *CommandRegistry) AddWorkflows(workflows *WorkflowRegistry) error {
+	if workflows == nil {
+		return nil
+	}
+
+	ids := make([]string, 0, len(workflows.workflows))
+	for id := range workflows.workflows {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if _, exists := reg.cmds[id]; exists {
+			return fmt.Errorf("workflow %s: id is already used by a command", id)
+		}
+		for _, step := range workflows.workflows[id].Steps {
+			if _, exists := reg.cmds[step.Command]; !exists {
+				return fmt.Errorf("workflow %s: step %s: unknown command %q", id, step.ID, step.Command)
+			}
+			if _, exists := reg.cmds[step.Compensate]; step.Compensate != "" && !exists {
+				return fmt.Errorf("workflow %s: step %s: unknown compensate command %q", id, step.ID, step.Compensate)
+			}
+		}
+	}
+
+	reg.workflows = workflows
+	return nil
+}
+
+// Workflow returns the workflow registered under id.
+func (reg *CommandRegistry) Workflow(id string) (Workflow, bool) {
+	return reg.workflows.Get(id)
+}
+
+// decodeWorkflowConfig unmarshals a per-workflow config node into a Workflow.
+func decodeWorkflowConfig(rawConfig interface{}) (Workflow, error) {
+	data, err := yaml.Marshal(rawConfig)
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	var wf Workflow
+	if err := yaml.Unmarshal(data, &wf); err != nil {
+		return Workflow{}, err
+	}
+	return wf, nil
+}
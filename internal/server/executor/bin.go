@@ -6,11 +6,28 @@ import (
 	"fmt"
 	"log/slog"
 	"os/exec"
+	"poke/internal/server/redact"
+	"poke/internal/server/request"
+	"poke/internal/server/tracing"
 )
 
 // ExecuteBinary runs a configured command using os/exec and returns execution result.
+//
+// The execution is traced as a child of the span in ctx, and the command
+// receives that span's W3C traceparent in TRACEPARENT and the request ID in
+// ctx in POKE_REQUEST_ID.
 func ExecuteBinary(ctx context.Context, cmd Command) Result {
-	logger := slog.Default().With("component", "executor/bin")
+	ctx, span := tracing.Start(ctx, "poke.exec", tracing.WithAttrs("poke.command_id", cmd.ID, "poke.command_name", cmd.Name))
+	defer span.End()
+
+	result := executeBinary(ctx, cmd)
+	span.SetAttrs("process.exit.code", result.ExitCode, "poke.outcome", string(result.Outcome))
+	span.RecordError(result.Error)
+	return result
+}
+
+func executeBinary(ctx context.Context, cmd Command) Result {
+	logger := request.Logger(ctx, slog.Default().With("component", "executor/bin"))
 	logger.Info("binary execution started", "event", "binary_execution_started", "command_id", cmd.ID, "command_name", cmd.Name)
 
 	var cmdCtx context.Context
@@ -27,6 +44,7 @@ func ExecuteBinary(ctx context.Context, cmd Command) Result {
 		logger.Warn("invalid command", "event", "binary_command_invalid", "command_id", cmd.ID, "command_name", cmd.Name, "error", err)
 		return Result{
 			ExitCode: -1,
+			Outcome:  OutcomeFailed,
 			Error:    err,
 		}
 	}
@@ -34,38 +52,94 @@ func ExecuteBinary(ctx context.Context, cmd Command) Result {
 	logger.Debug("invoking command", "event", "binary_command_invoking", "command_id", cmd.ID, "command_name", cmd.Name, "args", cmd.Args)
 	// #nosec G204 -- commands are configured by trusted config after validation.
 	cmdExec := exec.CommandContext(cmdCtx, cmd.Args[0], cmd.Args[1:]...)
-	cmdExec.Env = cmd.Env.Get().ToList()
+	cmdExec.Env = commandEnv(ctx, cmd)
+	cmdExec.Dir = cmd.Workdir
+
+	stdin, closeStdin, err := openStdin(cmd)
+	if err != nil {
+		return startFailure(ctx, cmd, nil, err, logger)
+	}
+	defer closeStdin()
+	cmdExec.Stdin = stdin
+
+	launch, err := prepareLaunch(cmdExec, cmd, logger)
+	if err != nil {
+		return startFailure(ctx, cmd, nil, err, logger)
+	}
+	defer launch.release()
+
+	group := configureProcessGroup(cmdExec, cmd)
 	output, err := cmdExec.CombinedOutput()
+	group.release()
 
 	if cmdExec.ProcessState == nil {
-		execErr := err
-		if execErr == nil {
-			execErr = errors.New("unknown error")
-		}
-		logger.Error("failed to execute command", "event", "binary_execution_failed_to_start", "command_id", cmd.ID, "command_name", cmd.Name, "error", execErr)
-		return Result{
-			Output:   output,
-			ExitCode: -1,
-			Error:    fmt.Errorf("command %s[%s] failed to execute: %w", cmd.ID, cmd.Name, execErr),
+		if err == nil {
+			err = errors.New("unknown error")
 		}
+		return startFailure(ctx, cmd, output, err, logger)
 	}
 
 	exitCode := cmdExec.ProcessState.ExitCode()
+	signal := exitSignal(cmdExec.ProcessState)
+	outcome := OutcomeSucceeded
 	if err != nil {
-		logger.Error("command exited with error", "event", "binary_execution_completed_with_error", "command_id", cmd.ID, "command_name", cmd.Name, "exit_code", exitCode, "error", err)
+		outcome = launch.outcome(failureOutcome(ctx, cmdCtx))
+		logger.Error("command exited with error", "event", "binary_execution_completed_with_error", "command_id", cmd.ID, "command_name", cmd.Name, "exit_code", exitCode, "outcome", outcome, "signal", signal, "error", err)
 	} else {
-		logger.Info("command completed", "event", "binary_execution_completed", "command_id", cmd.ID, "command_name", cmd.Name, "exit_code", exitCode)
+		logger.Info("command completed", "event", "binary_execution_completed", "command_id", cmd.ID, "command_name", cmd.Name, "exit_code", exitCode, "outcome", outcome)
 	}
 	return Result{
-		Output:   output,
+		Output:   redact.Default().Bytes(output),
 		ExitCode: exitCode,
+		Outcome:  outcome,
+		Signal:   signal,
 		Error:    err,
 	}
 }
 
+// startFailure reports a command that could not be started.
+func startFailure(ctx context.Context, cmd Command, output []byte, err error, logger *slog.Logger) Result {
+	outcome := OutcomeFailed
+	if ctx.Err() != nil {
+		outcome = OutcomeCanceled
+	}
+	logger.Error("failed to execute command", "event", "binary_execution_failed_to_start", "command_id", cmd.ID, "command_name", cmd.Name, "outcome", outcome, "error", err)
+	return Result{
+		Output:   redact.Default().Bytes(output),
+		ExitCode: -1,
+		Outcome:  outcome,
+		Error:    fmt.Errorf("command %s[%s] failed to execute: %w", cmd.ID, cmd.Name, err),
+	}
+}
+
+// failureOutcome tells a caller cancellation apart from the command timeout.
+func failureOutcome(ctx context.Context, cmdCtx context.Context) Outcome {
+	switch {
+	case ctx.Err() != nil:
+		return OutcomeCanceled
+	case errors.Is(cmdCtx.Err(), context.DeadlineExceeded):
+		return OutcomeTimedOut
+	default:
+		return OutcomeFailed
+	}
+}
+
 func validateCommand(cmd Command) error {
 	if len(cmd.Args) == 0 {
 		return fmt.Errorf("command %s[%s] has no arguments", cmd.ID, cmd.Name)
 	}
 	return nil
 }
+
+// commandEnv resolves cmd's environment and adds TRACEPARENT for traced
+// requests and POKE_REQUEST_ID for correlated ones.
+func commandEnv(ctx context.Context, cmd Command) []string {
+	env := cmd.Env.Get().ToList()
+	if traceparent := tracing.TraceparentFromContext(ctx); traceparent != "" {
+		env = append(env, tracing.TraceparentEnv+"="+traceparent)
+	}
+	if id := request.IDFromContext(ctx); id != "" {
+		env = append(env, request.IDEnv+"="+id)
+	}
+	return env
+}
@@ -0,0 +1,242 @@
+//go:build linux
+
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	cgroupMountPoint     = "/sys/fs/cgroup"
+	cgroupSupervisorLeaf = "supervisor" // leaf cgroup poke moves itself into
+	cgroupCPUPeriod      = 100000       // cpu.max period in microseconds
+	cgroupRemoveAttempts = 20
+	cgroupRemoveBackoff  = 10 * time.Millisecond
+	accessWriteOK        = 0x2
+)
+
+// cgroupControllers are enabled for command cgroups when available.
+var cgroupControllers = []string{"cpu", "memory", "pids"}
+
+var (
+	cgroupDelegationOnce sync.Once
+	cgroupDelegationRoot string
+	cgroupDelegationErr  error
+	cgroupSeq            atomic.Uint64
+)
+
+// commandCgroup is a per-execution cgroup v2 child of poke's delegated subtree.
+type commandCgroup struct {
+	path string
+	fd   int
+}
+
+// delegatedCgroupRoot returns poke's delegated cgroup v2 directory, set up once.
+func delegatedCgroupRoot() (string, error) {
+	cgroupDelegationOnce.Do(func() {
+		cgroupDelegationRoot, cgroupDelegationErr = setupCgroupDelegation()
+	})
+	return cgroupDelegationRoot, cgroupDelegationErr
+}
+
+// setupCgroupDelegation detects a writable cgroup v2 subtree and prepares it
+// for per-command children.
+//
+// cgroup v2 forbids enabling controllers for children of a cgroup that itself
+// holds processes, so poke moves itself into a `supervisor` leaf first (the
+// layout systemd recommends for `Delegate=yes` services).
+func setupCgroupDelegation() (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupMountPoint, "cgroup.controllers")); err != nil {
+		return "", fmt.Errorf("cgroup v2 is not mounted at %s", cgroupMountPoint)
+	}
+
+	rel, err := ownCgroupPath()
+	if err != nil {
+		return "", err
+	}
+	if rel == "/" {
+		return "", fmt.Errorf("poke runs in the root cgroup, no delegated subtree")
+	}
+
+	root := filepath.Join(cgroupMountPoint, rel)
+	if err := syscall.Access(filepath.Join(root, "cgroup.subtree_control"), accessWriteOK); err != nil {
+		return "", fmt.Errorf("cgroup %s is not delegated: %w", rel, err)
+	}
+	if err := moveSelfToSupervisorLeaf(root); err != nil {
+		return "", err
+	}
+	if err := enableCgroupControllers(root); err != nil {
+		return "", err
+	}
+	return root, nil
+}
+
+// ownCgroupPath returns the cgroup v2 path of the current process.
+func ownCgroupPath() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no cgroup v2 entry in /proc/self/cgroup")
+}
+
+// moveSelfToSupervisorLeaf moves poke out of root when root holds processes.
+func moveSelfToSupervisorLeaf(root string) error {
+	procs, err := os.ReadFile(filepath.Join(root, "cgroup.procs")) // #nosec G304 -- cgroupfs path
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(procs)) == 0 {
+		return nil
+	}
+
+	leaf := filepath.Join(root, cgroupSupervisorLeaf)
+	if err := os.Mkdir(leaf, 0o750); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("create supervisor cgroup: %w", err)
+	}
+	if err := writeCgroupFile(leaf, "cgroup.procs", strconv.Itoa(os.Getpid())); err != nil {
+		return fmt.Errorf("move poke into supervisor cgroup: %w", err)
+	}
+	return nil
+}
+
+// enableCgroupControllers enables available cgroupControllers for children of root.
+func enableCgroupControllers(root string) error {
+	available, err := os.ReadFile(filepath.Join(root, "cgroup.controllers")) // #nosec G304 -- cgroupfs path
+	if err != nil {
+		return err
+	}
+	enabled := strings.Fields(string(available))
+
+	for _, controller := range cgroupControllers {
+		if !slices.Contains(enabled, controller) {
+			continue
+		}
+		if err := writeCgroupFile(root, "cgroup.subtree_control", "+"+controller); err != nil {
+			return fmt.Errorf("enable cgroup controller %s: %w", controller, err)
+		}
+	}
+	return nil
+}
+
+// newCommandCgroup creates a child cgroup for one execution of cmd with its limits applied.
+func newCommandCgroup(root string, cmd Command) (*commandCgroup, error) {
+	name := fmt.Sprintf("cmd-%s-%d", sanitizeCgroupName(cmd.ID), cgroupSeq.Add(1))
+	path := filepath.Join(root, name)
+	if err := os.Mkdir(path, 0o750); err != nil {
+		return nil, fmt.Errorf("create command cgroup: %w", err)
+	}
+
+	cg := &commandCgroup{path: path, fd: -1}
+	if err := cg.applyLimits(cmd.Limits); err != nil {
+		_ = cg.remove()
+		return nil, err
+	}
+
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		_ = cg.remove()
+		return nil, fmt.Errorf("open command cgroup: %w", err)
+	}
+	cg.fd = fd
+	return cg, nil
+}
+
+// applyLimits writes cgroup-backed limits into the cgroup interface files.
+func (cg *commandCgroup) applyLimits(lim *Limits) error {
+	if lim.Memory > 0 {
+		if err := writeCgroupFile(cg.path, "memory.max", strconv.FormatUint(uint64(lim.Memory), 10)); err != nil {
+			return fmt.Errorf("set memory.max: %w", err)
+		}
+	}
+	if lim.CPU > 0 {
+		quota := int64(lim.CPU * cgroupCPUPeriod)
+		if quota < 1000 {
+			quota = 1000
+		}
+		if err := writeCgroupFile(cg.path, "cpu.max", fmt.Sprintf("%d %d", quota, cgroupCPUPeriod)); err != nil {
+			return fmt.Errorf("set cpu.max: %w", err)
+		}
+	}
+	if lim.PIDs > 0 {
+		if err := writeCgroupFile(cg.path, "pids.max", strconv.FormatInt(lim.PIDs, 10)); err != nil {
+			return fmt.Errorf("set pids.max: %w", err)
+		}
+	}
+	return nil
+}
+
+// oomKilled reports whether the OOM killer killed a process in the cgroup.
+func (cg *commandCgroup) oomKilled() bool {
+	data, err := os.ReadFile(filepath.Join(cg.path, "memory.events")) // #nosec G304 -- cgroupfs path
+	if err != nil {
+		return false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "oom_kill "); ok {
+			count, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+			return err == nil && count > 0
+		}
+	}
+	return false
+}
+
+// remove kills leftover processes in the cgroup and deletes it.
+func (cg *commandCgroup) remove() error {
+	if cg.fd >= 0 {
+		_ = syscall.Close(cg.fd)
+		cg.fd = -1
+	}
+
+	// cgroup.kill requires Linux 5.14; older kernels only fail the rmdir below
+	// while processes are left behind.
+	_ = writeCgroupFile(cg.path, "cgroup.kill", "1")
+
+	var err error
+	for range cgroupRemoveAttempts {
+		if err = syscall.Rmdir(cg.path); err == nil || errors.Is(err, syscall.ENOENT) {
+			return nil
+		}
+		time.Sleep(cgroupRemoveBackoff)
+	}
+	return fmt.Errorf("remove command cgroup %s: %w", cg.path, err)
+}
+
+// writeCgroupFile writes value into a cgroup interface file.
+func writeCgroupFile(dir string, name string, value string) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(value), 0)
+}
+
+// sanitizeCgroupName keeps command IDs usable as cgroup directory names.
+func sanitizeCgroupName(id string) string {
+	var b strings.Builder
+	for _, ch := range id {
+		if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '-' || ch == '_' {
+			b.WriteRune(ch)
+			continue
+		}
+		b.WriteByte('_')
+	}
+	return b.String()
+}
@@ -2,19 +2,32 @@ package executor
 
 import (
 	"fmt"
+	"path/filepath"
+	"poke/internal/server/request"
 	"time"
 )
 
 // `Command` struct represents an executable command that is registered with
 // poke server.
 type Command struct {
-	ID          string        `yaml:"-"`                     // Unique identifier for the command, used for loookup
-	Name        string        `yaml:"name,omitempty"`        // Human-readable name of the command, not necessarily unique
-	Description string        `yaml:"description,omitempty"` // Human-readable command description
-	Args        []string      `yaml:"args,omitempty"`        // Command arguments
-	Executor    string        `yaml:"executor,omitempty"`    // Command executor, used to lookup the executor for command
-	Env         Env           `yaml:"env,omitempty"`         // Environmental configuration: vars, merge strategy
-	Timeout     time.Duration `yaml:"timeout,omitempty"`     // Command timeout, 0 = no timeout, use with caution
+	ID          string        `yaml:"-"`                      // Unique identifier for the command, used for loookup
+	Name        string        `yaml:"name,omitempty"`         // Human-readable name of the command, not necessarily unique
+	Description string        `yaml:"description,omitempty"`  // Human-readable command description
+	Args        []string      `yaml:"args,omitempty"`         // Command arguments
+	Executor    string        `yaml:"executor,omitempty"`     // Command executor, used to lookup the executor for command
+	Env         Env           `yaml:"env,omitempty"`          // Environmental configuration: vars, merge strategy
+	Timeout     time.Duration `yaml:"timeout,omitempty"`      // Command timeout, 0 = no timeout, use with caution
+	StopSignal  Signal        `yaml:"stop_signal,omitempty"`  // Signal sent to the process group on timeout/cancel, "" = SIGTERM
+	StopTimeout time.Duration `yaml:"stop_timeout,omitempty"` // Grace period before SIGKILL, 0 = 5s
+	Limits      *Limits       `yaml:"limits,omitempty"`       // Resource limits, nil = inherit from poke
+	Sandbox     *Sandbox      `yaml:"sandbox,omitempty"`      // Filesystem/network isolation, nil = none
+	Workdir     string        `yaml:"workdir,omitempty"`      // Process working directory, "" = poke's working directory
+	Umask       *Umask        `yaml:"umask,omitempty"`        // File mode creation mask, nil = inherit from poke
+	Stdin       *Stdin        `yaml:"stdin,omitempty"`        // Standard input source, nil = no input
+	Retry       *Retry        `yaml:"retry,omitempty"`        // Retry policy applied by the dispatcher, nil = single attempt
+	Priority    int           `yaml:"priority,omitempty"`     // Dispatch priority, 0 (default) to 9, higher runs first
+	Coalesce    bool          `yaml:"coalesce,omitempty"`     // Merge identical requests into one still queued
+	Payload     []byte        `yaml:"-"`                      // Request payload for `stdin.payload`, set by caller
 }
 
 const defaultExecutorName = "bin"
@@ -61,7 +74,13 @@ func (cmd *Command) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 
 	applyCommandOverrides(cmd, inCmd)
-	return validateCommandArgs(cmd.Args)
+	if err := validateCommandArgs(cmd.Args); err != nil {
+		return err
+	}
+	if err := request.ValidatePriority(cmd.Priority); err != nil {
+		return err
+	}
+	return validateCommandWorkdir(cmd.Workdir)
 }
 
 // MarshalYAML renders commands in short or object form depending on fields set.
@@ -73,6 +92,16 @@ func (cmd Command) MarshalYAML() (interface{}, error) {
 	if cmd.Name == "" &&
 		cmd.Description == "" &&
 		cmd.Timeout == 0 &&
+		cmd.StopSignal == "" &&
+		cmd.StopTimeout == 0 &&
+		cmd.Limits.IsZero() &&
+		cmd.Sandbox.IsZero() &&
+		cmd.Workdir == "" &&
+		cmd.Umask == nil &&
+		cmd.Stdin == nil &&
+		cmd.Retry == nil &&
+		cmd.Priority == 0 &&
+		!cmd.Coalesce &&
 		envIsDefault &&
 		executorIsDefault {
 		if len(cmd.Args) == 1 {
@@ -132,11 +161,42 @@ func applyCommandOverrides(cmd *Command, inCmd Command) {
 	}
 
 	cmd.Timeout = inCmd.Timeout
+	cmd.StopSignal = inCmd.StopSignal
+	cmd.StopTimeout = inCmd.StopTimeout
+	if inCmd.Limits != nil {
+		cmd.Limits = inCmd.Limits
+	}
+	if inCmd.Sandbox != nil {
+		cmd.Sandbox = inCmd.Sandbox
+	}
+	if inCmd.Workdir != "" {
+		cmd.Workdir = inCmd.Workdir
+	}
+	if inCmd.Umask != nil {
+		cmd.Umask = inCmd.Umask
+	}
+	if inCmd.Stdin != nil {
+		cmd.Stdin = inCmd.Stdin
+	}
+	if inCmd.Retry != nil {
+		cmd.Retry = inCmd.Retry
+	}
+	cmd.Priority = inCmd.Priority
+	cmd.Coalesce = inCmd.Coalesce
 	if inCmd.Env.Strategy != "" || len(inCmd.Env.Vals) > 0 {
 		cmd.Env = inCmd.Env
 	}
 }
 
+// validateCommandWorkdir requires an absolute working directory so it does not
+// depend on where poke was started.
+func validateCommandWorkdir(workdir string) error {
+	if workdir != "" && !filepath.IsAbs(workdir) {
+		return fmt.Errorf("workdir %q must be an absolute path", workdir)
+	}
+	return nil
+}
+
 // validateCommandArgs ensures commands are always configured with arguments.
 func validateCommandArgs(args []string) error {
 	if len(args) == 0 {
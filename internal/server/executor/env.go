@@ -18,6 +18,8 @@ type Env struct {
 	Strategy EnvStrategy `yaml:"strategy,omitempty"`
 	// Map `key:val` of environmental variables provided with the command
 	Vals EnvMap `yaml:"vals,omitempty"`
+	// Sorted names of `Vals` marked `secret: true`, their values are redacted
+	Secrets []string `yaml:"-"`
 }
 
 const (
@@ -34,14 +36,20 @@ func NewEnvDefault() Env {
 	}
 }
 
+// envEntries holds raw `vals` entries: scalars or `{value, secret}` mappings.
+type envEntries map[string]interface{}
+
 // UnmarshalYAML parses env config per docs/configuration/command.md.
 // Keys and values are always interpreted as strings.
 func (env *Env) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	type envAlias Env
+	type envInput struct {
+		Strategy EnvStrategy `yaml:"strategy"`
+		Vals     envEntries  `yaml:"vals"`
+	}
 
 	*env = NewEnvDefault()
 
-	var in envAlias
+	var in envInput
 	if err := unmarshal(&in); err != nil {
 		return err
 	}
@@ -49,17 +57,76 @@ func (env *Env) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if in.Strategy != "" {
 		env.Strategy = in.Strategy
 	}
-	if in.Vals != nil {
-		env.Vals = in.Vals
+	if in.Vals == nil {
+		return nil
+	}
+
+	vals, secrets, err := in.Vals.parse()
+	if err != nil {
+		return err
 	}
+	env.Vals = vals
+	env.Secrets = secrets
 	return nil
 }
 
+// MarshalYAML renders secret values in `{value, secret}` form so they stay marked.
+func (env Env) MarshalYAML() (interface{}, error) {
+	type envOutput struct {
+		Strategy EnvStrategy            `yaml:"strategy,omitempty"`
+		Vals     map[string]interface{} `yaml:"vals,omitempty"`
+	}
+
+	out := envOutput{Strategy: env.Strategy}
+	if len(env.Vals) > 0 {
+		out.Vals = make(map[string]interface{}, len(env.Vals))
+		for key, val := range env.Vals {
+			out.Vals[key] = val
+		}
+		for _, key := range env.Secrets {
+			if val, ok := env.Vals[key]; ok {
+				out.Vals[key] = map[string]interface{}{"value": val, "secret": true}
+			}
+		}
+	}
+	return out, nil
+}
+
+// SecretValues returns the non-empty values of variables marked secret.
+func (env Env) SecretValues() []string {
+	out := make([]string, 0, len(env.Secrets))
+	for _, key := range env.Secrets {
+		if val := env.Vals[key]; val != "" {
+			out = append(out, val)
+		}
+	}
+	return out
+}
+
 // UnmarshalYAML converts any YAML map into string key/value pairs.
 func (env *EnvMap) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var entries envEntries
+	if err := entries.UnmarshalYAML(unmarshal); err != nil {
+		return err
+	}
+
+	vals, _, err := entries.parse()
+	if err != nil {
+		return err
+	}
+	*env = vals
+	return nil
+}
+
+// UnmarshalYAML reads a YAML map whatever its key types, stringifying keys.
+func (entries *envEntries) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var raw map[interface{}]interface{}
 	if err := unmarshal(&raw); err == nil {
-		*env = mapToEnvMap(raw)
+		out := make(envEntries, len(raw))
+		for key, val := range raw {
+			out[toEnvString(key)] = val
+		}
+		*entries = out
 		return nil
 	}
 
@@ -67,24 +134,72 @@ func (env *EnvMap) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := unmarshal(&rawString); err != nil {
 		return err
 	}
+	*entries = rawString
+	return nil
+}
 
-	out := make(EnvMap, len(rawString))
-	for key, val := range rawString {
-		out[toEnvString(key)] = toEnvString(val)
+// parse stringifies entry values and collects the sorted names of secret ones.
+func (entries envEntries) parse() (EnvMap, []string, error) {
+	if len(entries) == 0 {
+		return nil, nil, nil
 	}
-	*env = out
-	return nil
+
+	vals := make(EnvMap, len(entries))
+	var secrets []string
+	for key, raw := range entries {
+		val, secret, err := parseEnvValue(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("env %s: %w", key, err)
+		}
+		vals[key] = val
+		if secret {
+			secrets = append(secrets, key)
+		}
+	}
+	sort.Strings(secrets)
+	return vals, secrets, nil
 }
 
-func mapToEnvMap(raw map[interface{}]interface{}) EnvMap {
-	if len(raw) == 0 {
-		return nil
+// parseEnvValue reads a scalar value or a `{value, secret}` mapping.
+func parseEnvValue(raw interface{}) (string, bool, error) {
+	spec, ok := envValueSpec(raw)
+	if !ok {
+		return toEnvString(raw), false, nil
 	}
-	out := make(EnvMap, len(raw))
-	for key, val := range raw {
-		out[toEnvString(key)] = toEnvString(val)
+
+	var val string
+	var secret bool
+	for key, field := range spec {
+		switch key {
+		case "value":
+			val = toEnvString(field)
+		case "secret":
+			flag, ok := field.(bool)
+			if !ok {
+				return "", false, fmt.Errorf("secret must be true or false")
+			}
+			secret = flag
+		default:
+			return "", false, fmt.Errorf("unknown field %q, expected value or secret", key)
+		}
+	}
+	return val, secret, nil
+}
+
+// envValueSpec returns raw as a string-keyed map when it is a YAML mapping.
+func envValueSpec(raw interface{}) (map[string]interface{}, bool) {
+	switch spec := raw.(type) {
+	case map[string]interface{}:
+		return spec, true
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(spec))
+		for key, val := range spec {
+			out[toEnvString(key)] = val
+		}
+		return out, true
+	default:
+		return nil, false
 	}
-	return out
 }
 
 func toEnvString(value interface{}) string {
@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// CheckExecutable verifies that cmd's first argument resolves to an executable
+// file the way ExecuteBinary starts it: names without a slash are looked up in
+// poke's PATH, relative paths are taken from Workdir. Commands of other
+// executors are not checked.
+func CheckExecutable(cmd Command) error {
+	if cmd.Executor != defaultExecutorName {
+		return nil
+	}
+	if err := validateCommandArgs(cmd.Args); err != nil {
+		return err
+	}
+
+	program := cmd.Args[0]
+	if !strings.ContainsRune(program, '/') && !strings.ContainsRune(program, filepath.Separator) {
+		if _, err := exec.LookPath(program); err != nil {
+			return fmt.Errorf("executable %q not found in PATH", program)
+		}
+		return nil
+	}
+
+	path := program
+	if !filepath.IsAbs(path) && cmd.Workdir != "" {
+		path = filepath.Join(cmd.Workdir, path)
+	}
+	return checkExecutableFile(path, program)
+}
+
+// checkExecutableFile requires path to be a file with an execute bit set;
+// errors name program as configured.
+func checkExecutableFile(path string, program string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("executable %q: %w", program, err)
+	}
+	if info.IsDir() || info.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("%q is not an executable file", program)
+	}
+	return nil
+}
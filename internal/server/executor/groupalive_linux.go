@@ -0,0 +1,54 @@
+//go:build linux
+
+package executor
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// groupAlive reports whether process group pgid has a member that is not a
+// zombie. Orphaned members are reaped by init, which may take arbitrarily
+// long, so kill(-pgid, 0) alone would keep a group alive until SIGKILL.
+// When /proc cannot be read it falls back to kill(-pgid, 0).
+func groupAlive(pgid int) bool {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return !errors.Is(syscall.Kill(-pgid, 0), syscall.ESRCH)
+	}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		stat, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue // exited while scanning
+		}
+		if state, group, ok := parseProcStat(stat); ok && group == pgid && state != 'Z' && state != 'X' {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProcStat returns the state and process group from a /proc/<pid>/stat
+// line. The command name may contain spaces and parentheses, so fields are
+// read after its closing parenthesis.
+func parseProcStat(stat []byte) (state byte, pgid int, ok bool) {
+	end := bytes.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, 0, false
+	}
+	fields := bytes.Fields(stat[end+1:])
+	if len(fields) < 3 || len(fields[0]) != 1 {
+		return 0, 0, false
+	}
+	pgid, err := strconv.Atoi(string(fields[2]))
+	if err != nil {
+		return 0, 0, false
+	}
+	return fields[0][0], pgid, true
+}
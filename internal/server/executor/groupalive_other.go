@@ -0,0 +1,14 @@
+//go:build unix && !linux
+
+package executor
+
+import (
+	"errors"
+	"syscall"
+)
+
+// groupAlive reports whether process group pgid still has members. Zombies
+// waiting to be reaped count as members.
+func groupAlive(pgid int) bool {
+	return !errors.Is(syscall.Kill(-pgid, 0), syscall.ESRCH)
+}
@@ -0,0 +1,102 @@
+//go:build linux
+
+package executor
+
+import (
+	"log/slog"
+	"os/exec"
+	"syscall"
+)
+
+// launch tracks per-execution resources attached to an exec.Cmd.
+type launch struct {
+	cgroup *commandCgroup
+	logger *slog.Logger
+}
+
+// prepareLaunch applies cmd limits, umask and sandbox to cmdExec before it is started.
+func prepareLaunch(cmdExec *exec.Cmd, cmd Command, logger *slog.Logger) (*launch, error) {
+	l := &launch{logger: logger}
+	if cmd.Limits.IsZero() && cmd.Sandbox.IsZero() && cmd.Umask == nil {
+		return l, nil
+	}
+
+	if cmd.Limits.needsCgroup() {
+		cg, err := l.attachCgroup(cmdExec, cmd)
+		if err != nil {
+			return nil, err
+		}
+		l.cgroup = cg
+	}
+
+	spec := launchSpec{Umask: cmd.Umask}
+	if !cmd.Limits.IsZero() {
+		spec.Rlimits = buildRlimits(cmd.Limits, l.cgroup != nil)
+	}
+	applySandboxAttrs(cmdExec, cmd.Sandbox, &spec)
+	if spec.isZero() {
+		return l, nil
+	}
+	if err := wrapWithShim(cmdExec, spec); err != nil {
+		l.release()
+		return nil, err
+	}
+	return l, nil
+}
+
+// attachCgroup places cmdExec into a fresh command cgroup when poke has a
+// delegated subtree; otherwise cgroup-only limits are skipped with a warning.
+func (l *launch) attachCgroup(cmdExec *exec.Cmd, cmd Command) (*commandCgroup, error) {
+	root, err := delegatedCgroupRoot()
+	if err != nil {
+		l.logger.Warn("cgroup delegation unavailable, falling back to rlimits", "event", "binary_cgroup_unavailable", "command_id", cmd.ID, "command_name", cmd.Name, "error", err)
+		return nil, nil
+	}
+
+	cg, err := newCommandCgroup(root, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if cmdExec.SysProcAttr == nil {
+		cmdExec.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmdExec.SysProcAttr.UseCgroupFD = true
+	cmdExec.SysProcAttr.CgroupFD = cg.fd
+	return cg, nil
+}
+
+// buildRlimits maps limits onto setrlimit calls. Memory is only enforced with
+// RLIMIT_AS when no cgroup enforces memory.max.
+func buildRlimits(lim *Limits, hasCgroup bool) []rlimitSpec {
+	var out []rlimitSpec
+	if lim.OpenFiles > 0 {
+		out = append(out, rlimitSpec{Resource: syscall.RLIMIT_NOFILE, Limit: lim.OpenFiles})
+	}
+	if lim.CoreSize != nil {
+		out = append(out, rlimitSpec{Resource: syscall.RLIMIT_CORE, Limit: uint64(*lim.CoreSize)})
+	}
+	if lim.Memory > 0 && !hasCgroup {
+		out = append(out, rlimitSpec{Resource: syscall.RLIMIT_AS, Limit: uint64(lim.Memory)})
+	}
+	return out
+}
+
+// outcome refines a finished execution's outcome with cgroup OOM events.
+func (l *launch) outcome(base Outcome) Outcome {
+	if l.cgroup != nil && base != OutcomeSucceeded && l.cgroup.oomKilled() {
+		return OutcomeOOMKilled
+	}
+	return base
+}
+
+// release frees per-execution resources once the command has finished.
+func (l *launch) release() {
+	if l.cgroup == nil {
+		return
+	}
+	if err := l.cgroup.remove(); err != nil {
+		l.logger.Warn("failed to remove command cgroup", "event", "binary_cgroup_remove_failed", "error", err)
+	}
+	l.cgroup = nil
+}
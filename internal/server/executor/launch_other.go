@@ -0,0 +1,34 @@
+//go:build !linux
+
+package executor
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+)
+
+// launch tracks per-execution resources attached to an exec.Cmd.
+type launch struct{}
+
+// prepareLaunch rejects limits, sandboxes and umask, which are only supported on Linux.
+func prepareLaunch(_ *exec.Cmd, cmd Command, _ *slog.Logger) (*launch, error) {
+	if !cmd.Limits.IsZero() {
+		return nil, fmt.Errorf("command limits are only supported on linux")
+	}
+	if !cmd.Sandbox.IsZero() {
+		return nil, fmt.Errorf("command sandbox is only supported on linux")
+	}
+	if cmd.Umask != nil {
+		return nil, fmt.Errorf("command umask is only supported on linux")
+	}
+	return &launch{}, nil
+}
+
+// outcome returns base unchanged; OOM detection requires cgroups.
+func (l *launch) outcome(base Outcome) Outcome {
+	return base
+}
+
+// release is a no-op without per-execution resources.
+func (l *launch) release() {}
@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"poke/internal/server/bytesize"
+)
+
+// Limits defines per-command resource limits.
+//
+// `open_files` and `core_size` are enforced with setrlimit in the command
+// process. `memory`, `cpu` and `pids` are enforced through a per-execution
+// cgroup v2 child when poke runs inside a delegated cgroup subtree; without
+// delegation `memory` falls back to RLIMIT_AS and `cpu`/`pids` are not enforced.
+type Limits struct {
+	Memory    bytesize.Size  `yaml:"memory,omitempty"`     // Max memory in bytes, 0 = unlimited
+	CPU       float64        `yaml:"cpu,omitempty"`        // CPU quota in CPUs (0.5 = half a CPU), 0 = unlimited
+	PIDs      int64          `yaml:"pids,omitempty"`       // Max number of processes/threads, 0 = unlimited
+	OpenFiles uint64         `yaml:"open_files,omitempty"` // Max open file descriptors, 0 = inherited
+	CoreSize  *bytesize.Size `yaml:"core_size,omitempty"`  // Max core dump size in bytes, nil = inherited
+}
+
+// UnmarshalYAML parses limits config per docs/configuration/command.md.
+func (lim *Limits) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type limitsAlias Limits
+
+	var in limitsAlias
+	if err := unmarshal(&in); err != nil {
+		return err
+	}
+
+	*lim = Limits(in)
+	return lim.validate()
+}
+
+// IsZero reports whether no limit is configured.
+func (lim *Limits) IsZero() bool {
+	return lim == nil || (lim.Memory == 0 && lim.CPU == 0 && lim.PIDs == 0 && lim.OpenFiles == 0 && lim.CoreSize == nil)
+}
+
+// needsCgroup reports whether any limit is enforced through cgroups.
+func (lim *Limits) needsCgroup() bool {
+	return lim != nil && (lim.Memory > 0 || lim.CPU > 0 || lim.PIDs > 0)
+}
+
+// validate rejects negative or non-finite limit values.
+func (lim *Limits) validate() error {
+	if lim.CPU < 0 || math.IsNaN(lim.CPU) || math.IsInf(lim.CPU, 0) {
+		return fmt.Errorf("limits cpu must be a positive number of CPUs")
+	}
+	if lim.PIDs < 0 {
+		return fmt.Errorf("limits pids must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+//go:build !unix
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+var signalsByName = map[string]syscall.Signal{
+	"SIGINT":  syscall.SIGINT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// processGroup is a no-op where process groups are unavailable; os/exec
+// kills the direct child on cancellation.
+type processGroup struct{}
+
+// configureProcessGroup keeps the os/exec default of killing the direct child.
+func configureProcessGroup(_ *exec.Cmd, _ Command) *processGroup {
+	return &processGroup{}
+}
+
+// release is a no-op without process group escalation.
+func (group *processGroup) release() {}
+
+// exitSignal is not reported without POSIX wait statuses.
+func exitSignal(_ *os.ProcessState) Signal {
+	return ""
+}
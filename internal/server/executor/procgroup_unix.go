@@ -0,0 +1,112 @@
+//go:build unix
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// waitDelayMargin lets the SIGKILL escalation land before os/exec gives up
+// waiting for output pipes held open by surviving processes.
+const waitDelayMargin = 500 * time.Millisecond
+
+// groupPollInterval is how often a stopped process group is checked for
+// surviving members during the stop timeout.
+const groupPollInterval = 50 * time.Millisecond
+
+var signalsByName = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGALRM": syscall.SIGALRM,
+	"SIGPIPE": syscall.SIGPIPE,
+	"SIGABRT": syscall.SIGABRT,
+	"SIGSEGV": syscall.SIGSEGV,
+	"SIGXCPU": syscall.SIGXCPU,
+	"SIGXFSZ": syscall.SIGXFSZ,
+}
+
+// processGroup stops a command's whole process group on context cancellation.
+type processGroup struct {
+	mu      sync.Mutex
+	stopped chan struct{} // closed once the group is gone or was killed, nil until stopping
+}
+
+// configureProcessGroup starts cmdExec in its own process group. When the
+// command context ends, the group receives cmd's stop signal and, if it is
+// still alive after the stop timeout, SIGKILL.
+func configureProcessGroup(cmdExec *exec.Cmd, cmd Command) *processGroup {
+	group := &processGroup{}
+	if cmdExec.SysProcAttr == nil {
+		cmdExec.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmdExec.SysProcAttr.Setpgid = true
+
+	sig := signalsByName[string(cmd.stopSignal())]
+	grace := cmd.stopTimeout()
+	cmdExec.Cancel = func() error {
+		pgid := cmdExec.Process.Pid
+		stopped := make(chan struct{})
+		group.mu.Lock()
+		group.stopped = stopped
+		group.mu.Unlock()
+		go escalate(pgid, grace, stopped)
+		return syscall.Kill(-pgid, sig)
+	}
+	cmdExec.WaitDelay = grace + waitDelayMargin
+	return group
+}
+
+// escalate sends SIGKILL to process group pgid once grace has passed, unless
+// the group is gone by then. Members that ignore the stop signal and do not
+// hold the output pipes outlive the leader, so the group is polled rather
+// than the leader waited for. A group ID cannot be reused while any member
+// exists, and polling stops as soon as none but zombies remain.
+func escalate(pgid int, grace time.Duration, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	deadline := time.Now().Add(grace)
+	ticker := time.NewTicker(groupPollInterval)
+	defer ticker.Stop()
+	for time.Now().Before(deadline) {
+		if !groupAlive(pgid) {
+			return
+		}
+		<-ticker.C
+	}
+	_ = syscall.Kill(-pgid, syscall.SIGKILL)
+}
+
+// release waits until a stopped command's process group is gone or was
+// killed, at most the stop timeout. It returns at once if the command was
+// never stopped.
+func (group *processGroup) release() {
+	group.mu.Lock()
+	stopped := group.stopped
+	group.mu.Unlock()
+	if stopped != nil {
+		<-stopped
+	}
+}
+
+// exitSignal returns the name of the signal that terminated the process, if any.
+func exitSignal(state *os.ProcessState) Signal {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+	for name, sig := range signalsByName {
+		if sig == status.Signal() {
+			return Signal(name)
+		}
+	}
+	return Signal(status.Signal().String())
+}
@@ -1,8 +1,22 @@
 package executor
 
+// Outcome classifies how a command execution ended.
+type Outcome string
+
+const (
+	OutcomeSucceeded   Outcome = "succeeded"   // process exited with code 0
+	OutcomeFailed      Outcome = "failed"      // process exited non-zero, was killed, or failed to start
+	OutcomeOOMKilled   Outcome = "oom_killed"  // process was killed by the cgroup OOM killer
+	OutcomeTimedOut    Outcome = "timed_out"   // process was stopped after the command timeout
+	OutcomeCanceled    Outcome = "canceled"    // process was stopped because the caller's context ended
+	OutcomeInterrupted Outcome = "interrupted" // poke stopped while the process was running
+)
+
 // The result of command execution
 type Result struct {
 	Output   []byte
 	ExitCode int
+	Outcome  Outcome
+	Signal   Signal // Signal that terminated the process, "" when it exited
 	Error    error
 }
@@ -0,0 +1,152 @@
+package executor
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts = 3           // Attempts including the first one
+	defaultRetryBackoff     = time.Second // Delay before the second attempt
+	defaultRetryMaxBackoff  = time.Minute // Upper bound for exponential backoff
+	maxRetryAttempts        = 100         // Guards against configs that retry forever
+	minRetryExitCode        = 1           // Exit code 0 is success and never retried
+	maxRetryExitCode        = 255         // Highest exit status a process can report
+)
+
+// Retry defines how the dispatcher re-runs a failed command.
+//
+// Delays grow exponentially from `backoff` up to `max_backoff`; `jitter`
+// spreads each delay by up to that fraction in either direction. Without
+// `on`, failed and timed-out executions are retried; cancellations never are.
+type Retry struct {
+	MaxAttempts int           `yaml:"max_attempts,omitempty"` // Total attempts including the first, default 3
+	Backoff     time.Duration `yaml:"backoff,omitempty"`      // Delay before the second attempt, default 1s
+	MaxBackoff  time.Duration `yaml:"max_backoff,omitempty"`  // Delay cap, default 1m
+	Jitter      float64       `yaml:"jitter,omitempty"`       // Random spread as a fraction of the delay, 0..1
+	On          *RetryOn      `yaml:"on,omitempty"`           // Failures to retry, nil = any failure or timeout
+}
+
+// RetryOn restricts retries to specific failures.
+type RetryOn struct {
+	ExitCodes []int `yaml:"exit_codes,omitempty"` // Retry when the process exits with one of these codes
+	Timeout   bool  `yaml:"timeout,omitempty"`    // Retry when the command timed out
+}
+
+// UnmarshalYAML parses retry config per docs/configuration/command.md.
+func (r *Retry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type retryInput struct {
+		MaxAttempts *int           `yaml:"max_attempts"`
+		Backoff     *time.Duration `yaml:"backoff"`
+		MaxBackoff  *time.Duration `yaml:"max_backoff"`
+		Jitter      float64        `yaml:"jitter"`
+		On          *RetryOn       `yaml:"on"`
+	}
+
+	*r = Retry{
+		MaxAttempts: defaultRetryMaxAttempts,
+		Backoff:     defaultRetryBackoff,
+		MaxBackoff:  defaultRetryMaxBackoff,
+	}
+
+	var in retryInput
+	if err := unmarshal(&in); err != nil {
+		return err
+	}
+
+	if in.MaxAttempts != nil {
+		r.MaxAttempts = *in.MaxAttempts
+	}
+	if in.Backoff != nil {
+		r.Backoff = *in.Backoff
+	}
+	if in.MaxBackoff != nil {
+		r.MaxBackoff = *in.MaxBackoff
+	} else if r.MaxBackoff < r.Backoff {
+		r.MaxBackoff = r.Backoff
+	}
+	r.Jitter = in.Jitter
+	r.On = in.On
+	return r.validate()
+}
+
+// validate enforces attempt, delay and exit code bounds.
+func (r *Retry) validate() error {
+	if r.MaxAttempts < 1 || r.MaxAttempts > maxRetryAttempts {
+		return fmt.Errorf("retry max_attempts must be between 1 and %d", maxRetryAttempts)
+	}
+	if r.Backoff < 0 {
+		return fmt.Errorf("retry backoff must not be negative")
+	}
+	if r.MaxBackoff < r.Backoff {
+		return fmt.Errorf("retry max_backoff must not be less than backoff")
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+	if r.On == nil {
+		return nil
+	}
+	if len(r.On.ExitCodes) == 0 && !r.On.Timeout {
+		return fmt.Errorf("retry on requires exit_codes or timeout")
+	}
+	for _, code := range r.On.ExitCodes {
+		if code < minRetryExitCode || code > maxRetryExitCode {
+			return fmt.Errorf("retry exit code %d must be between %d and %d", code, minRetryExitCode, maxRetryExitCode)
+		}
+	}
+	return nil
+}
+
+// Attempts returns the total number of attempts allowed, 1 without a policy.
+func (r *Retry) Attempts() int {
+	if r == nil || r.MaxAttempts < 1 {
+		return 1
+	}
+	return r.MaxAttempts
+}
+
+// ShouldRetry reports whether result is a failure the policy retries.
+func (r *Retry) ShouldRetry(result Result) bool {
+	if r == nil || result.Error == nil {
+		return false
+	}
+
+	switch result.Outcome {
+	case OutcomeTimedOut:
+		return r.On == nil || r.On.Timeout
+	case OutcomeFailed:
+		if r.On == nil {
+			return true
+		}
+		if result.Signal != "" {
+			return false
+		}
+		for _, code := range r.On.ExitCodes {
+			if result.ExitCode == code {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// Delay returns the wait before the attempt following attempt (1-based).
+func (r *Retry) Delay(attempt int) time.Duration {
+	if r == nil {
+		return 0
+	}
+
+	delay := r.Backoff
+	for i := 1; i < attempt && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if r.Jitter > 0 {
+		spread := float64(delay) * r.Jitter
+		delay += time.Duration(spread * (2*rand.Float64() - 1)) // #nosec G404 -- jitter does not need a CSPRNG
+	}
+	return min(delay, r.MaxBackoff)
+}
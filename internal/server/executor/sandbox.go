@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type SandboxNetwork string
+
+const (
+	SandboxNetworkHost SandboxNetwork = "host" // share poke's network namespace
+	SandboxNetworkNone SandboxNetwork = "none" // private network namespace with loopback only
+)
+
+// Sandbox defines per-command filesystem and network isolation.
+type Sandbox struct {
+	// Paths bind-mounted read-only over themselves in a private mount namespace
+	ReadOnly []string `yaml:"read_only,omitempty"`
+	// Network namespace mode, `host` (default) or `none`
+	Network SandboxNetwork `yaml:"network,omitempty"`
+	// Landlock path rules; when set, all paths not listed are inaccessible
+	Landlock *LandlockRules `yaml:"landlock,omitempty"`
+}
+
+// LandlockRules lists path hierarchies the command may access.
+type LandlockRules struct {
+	Read      []string `yaml:"read,omitempty"`       // read and execute access
+	ReadWrite []string `yaml:"read_write,omitempty"` // full filesystem access
+}
+
+// UnmarshalYAML parses sandbox config per docs/configuration/command.md.
+func (sb *Sandbox) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type sandboxAlias Sandbox
+
+	var in sandboxAlias
+	if err := unmarshal(&in); err != nil {
+		return err
+	}
+
+	*sb = Sandbox(in)
+	if sb.Network == "" {
+		sb.Network = SandboxNetworkHost
+	}
+	return sb.validate()
+}
+
+// IsZero reports whether no isolation is requested.
+func (sb *Sandbox) IsZero() bool {
+	return sb == nil || (len(sb.ReadOnly) == 0 && !sb.privateNetwork() && sb.Landlock == nil)
+}
+
+// privateMounts reports whether a private mount namespace is required.
+func (sb *Sandbox) privateMounts() bool {
+	return sb != nil && len(sb.ReadOnly) > 0
+}
+
+// privateNetwork reports whether a private network namespace is required.
+func (sb *Sandbox) privateNetwork() bool {
+	return sb != nil && sb.Network == SandboxNetworkNone
+}
+
+// validate rejects unknown network modes and relative or unclean paths.
+func (sb *Sandbox) validate() error {
+	switch sb.Network {
+	case SandboxNetworkHost, SandboxNetworkNone:
+	default:
+		return fmt.Errorf("sandbox network must be one of host or none")
+	}
+
+	if err := validateSandboxPaths("read_only", sb.ReadOnly); err != nil {
+		return err
+	}
+	if sb.Landlock == nil {
+		return nil
+	}
+	if err := validateSandboxPaths("landlock read", sb.Landlock.Read); err != nil {
+		return err
+	}
+	return validateSandboxPaths("landlock read_write", sb.Landlock.ReadWrite)
+}
+
+// validateSandboxPaths requires absolute, clean paths so rules are unambiguous.
+func validateSandboxPaths(field string, paths []string) error {
+	for _, path := range paths {
+		if strings.TrimSpace(path) == "" {
+			return fmt.Errorf("sandbox %s path must not be empty", field)
+		}
+		if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+			return fmt.Errorf("sandbox %s path %q must be absolute and clean", field, path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+//go:build linux
+
+package executor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Landlock syscalls share these numbers on all Linux architectures.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1 << 0
+	landlockRulePathBeneath      = 1
+	prSetNoNewPrivs              = 38
+	oPath                        = 0x200000 // O_PATH, missing from package syscall
+)
+
+// Landlock filesystem access rights (see linux/landlock.h).
+const (
+	landlockAccessFSExecute    = 1 << 0
+	landlockAccessFSWriteFile  = 1 << 1
+	landlockAccessFSReadFile   = 1 << 2
+	landlockAccessFSReadDir    = 1 << 3
+	landlockAccessFSRemoveDir  = 1 << 4
+	landlockAccessFSRemoveFile = 1 << 5
+	landlockAccessFSMakeChar   = 1 << 6
+	landlockAccessFSMakeDir    = 1 << 7
+	landlockAccessFSMakeReg    = 1 << 8
+	landlockAccessFSMakeSock   = 1 << 9
+	landlockAccessFSMakeFifo   = 1 << 10
+	landlockAccessFSMakeBlock  = 1 << 11
+	landlockAccessFSMakeSym    = 1 << 12
+	landlockAccessFSRefer      = 1 << 13 // ABI 2
+	landlockAccessFSTruncate   = 1 << 14 // ABI 3
+	landlockAccessFSIoctlDev   = 1 << 15 // ABI 5
+
+	landlockAccessFSv1 = landlockAccessFSExecute | landlockAccessFSWriteFile | landlockAccessFSReadFile |
+		landlockAccessFSReadDir | landlockAccessFSRemoveDir | landlockAccessFSRemoveFile |
+		landlockAccessFSMakeChar | landlockAccessFSMakeDir | landlockAccessFSMakeReg |
+		landlockAccessFSMakeSock | landlockAccessFSMakeFifo | landlockAccessFSMakeBlock |
+		landlockAccessFSMakeSym
+	landlockAccessFSRead = landlockAccessFSExecute | landlockAccessFSReadFile | landlockAccessFSReadDir
+	landlockAccessFile   = landlockAccessFSExecute | landlockAccessFSWriteFile | landlockAccessFSReadFile |
+		landlockAccessFSTruncate | landlockAccessFSIoctlDev
+)
+
+// statfs flags that must be preserved when remounting inside a user namespace.
+var lockedMountFlags = map[int64]uintptr{
+	0x2:    syscall.MS_NOSUID,     // ST_NOSUID
+	0x4:    syscall.MS_NODEV,      // ST_NODEV
+	0x8:    syscall.MS_NOEXEC,     // ST_NOEXEC
+	0x400:  syscall.MS_NOATIME,    // ST_NOATIME
+	0x800:  syscall.MS_NODIRATIME, // ST_NODIRATIME
+	0x1000: syscall.MS_RELATIME,   // ST_RELATIME
+}
+
+// ProbeSandbox verifies the running kernel supports the isolation cmd requests
+// by launching the exec shim with the command's sandbox in probe mode.
+func ProbeSandbox(cmd Command) error {
+	if cmd.Sandbox.IsZero() {
+		return nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("resolve poke executable: %w", err)
+	}
+
+	// #nosec G204 -- re-executes poke itself.
+	probe := exec.Command(self, "poke-sandbox-probe")
+	spec := launchSpec{Probe: true}
+	applySandboxAttrs(probe, cmd.Sandbox, &spec)
+	if err := wrapWithShim(probe, spec); err != nil {
+		return err
+	}
+
+	output, err := probe.CombinedOutput()
+	if err != nil {
+		detail := strings.TrimSpace(string(output))
+		if detail == "" {
+			detail = err.Error()
+		}
+		return fmt.Errorf("sandbox unavailable: %s", detail)
+	}
+	return nil
+}
+
+// applySandboxAttrs configures namespaces on cmdExec and shim steps in spec.
+//
+// Unprivileged poke additionally creates a user namespace that maps its own
+// uid/gid, which grants the capabilities needed for mount and network
+// namespaces without granting anything outside them.
+func applySandboxAttrs(cmdExec *exec.Cmd, sb *Sandbox, spec *launchSpec) {
+	if sb.IsZero() {
+		return
+	}
+
+	var flags uintptr
+	if sb.privateMounts() {
+		flags |= syscall.CLONE_NEWNS
+		spec.ReadOnly = sb.ReadOnly
+	}
+	if sb.privateNetwork() {
+		flags |= syscall.CLONE_NEWNET
+		spec.Loopback = true
+	}
+	spec.Landlock = sb.Landlock
+	if flags == 0 {
+		return
+	}
+
+	if cmdExec.SysProcAttr == nil {
+		cmdExec.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if uid := os.Geteuid(); uid != 0 {
+		gid := os.Getegid()
+		flags |= syscall.CLONE_NEWUSER
+		cmdExec.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+		cmdExec.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+	}
+	cmdExec.SysProcAttr.Cloneflags |= flags
+}
+
+// applyReadOnlyMounts makes mount propagation private and re-binds each path read-only.
+func applyReadOnlyMounts(paths []string) error {
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+
+	for _, path := range paths {
+		if err := syscall.Mount(path, path, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("bind mount %s: %w", path, err)
+		}
+		flags := syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY | preservedMountFlags(path)
+		if err := syscall.Mount("", path, "", flags, ""); err != nil {
+			return fmt.Errorf("remount %s read-only: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// preservedMountFlags returns locked flags of the mount containing path.
+func preservedMountFlags(path string) uintptr {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0
+	}
+
+	var flags uintptr
+	for stFlag, msFlag := range lockedMountFlags {
+		if st.Flags&stFlag != 0 {
+			flags |= msFlag
+		}
+	}
+	return flags
+}
+
+// bringLoopbackUp enables `lo` in a fresh network namespace.
+func bringLoopbackUp() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("loopback socket: %w", err)
+	}
+	defer syscall.Close(fd) //nolint:errcheck // Best-effort close of a probe socket.
+
+	// struct ifreq: 16-byte interface name followed by the flags union member.
+	var req [40]byte
+	copy(req[:], "lo")
+	binary.NativeEndian.PutUint16(req[16:], uint16(syscall.IFF_UP|syscall.IFF_LOOPBACK|syscall.IFF_RUNNING))
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCSIFFLAGS, uintptr(unsafe.Pointer(&req[0]))); errno != 0 {
+		return fmt.Errorf("bring up loopback: %w", errno)
+	}
+	return nil
+}
+
+// applyLandlock restricts the calling thread (and the program it execs) to rules.
+func applyLandlock(rules *LandlockRules) error {
+	abi, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return fmt.Errorf("landlock is not supported: %w", errno)
+	}
+	handled := landlockHandledAccess(int(abi))
+
+	attr := struct{ handledAccessFS uint64 }{handledAccessFS: handled}
+	rulesetFD, _, errno := syscall.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("create landlock ruleset: %w", errno)
+	}
+	defer syscall.Close(int(rulesetFD)) //nolint:errcheck // Closed before exec either way.
+
+	for _, path := range rules.Read {
+		if err := addLandlockPathRule(int(rulesetFD), path, landlockAccessFSRead&handled); err != nil {
+			return err
+		}
+	}
+	for _, path := range rules.ReadWrite {
+		if err := addLandlockPathRule(int(rulesetFD), path, handled); err != nil {
+			return err
+		}
+	}
+
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return fmt.Errorf("set no_new_privs: %w", errno)
+	}
+	if _, _, errno := syscall.Syscall(sysLandlockRestrictSelf, rulesetFD, 0, 0); errno != 0 {
+		return fmt.Errorf("landlock restrict self: %w", errno)
+	}
+	return nil
+}
+
+// landlockHandledAccess returns the access rights supported by a Landlock ABI version.
+func landlockHandledAccess(abi int) uint64 {
+	access := uint64(landlockAccessFSv1)
+	if abi >= 2 {
+		access |= landlockAccessFSRefer
+	}
+	if abi >= 3 {
+		access |= landlockAccessFSTruncate
+	}
+	if abi >= 5 {
+		access |= landlockAccessFSIoctlDev
+	}
+	return access
+}
+
+// addLandlockPathRule allows access beneath path; files only accept file rights.
+func addLandlockPathRule(rulesetFD int, path string, access uint64) error {
+	pathFD, err := syscall.Open(path, oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("landlock path %s: %w", path, err)
+	}
+	defer syscall.Close(pathFD) //nolint:errcheck // O_PATH descriptor, nothing to flush.
+
+	var st syscall.Stat_t
+	if err := syscall.Fstat(pathFD, &st); err != nil {
+		return fmt.Errorf("landlock path %s: %w", path, err)
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		access &= landlockAccessFile
+	}
+
+	// struct landlock_path_beneath_attr is packed: u64 allowed_access, s32 parent_fd.
+	var attr [12]byte
+	binary.NativeEndian.PutUint64(attr[0:8], access)
+	binary.NativeEndian.PutUint32(attr[8:12], uint32(pathFD)) // #nosec G115 -- fds are non-negative
+	if _, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(rulesetFD), landlockRulePathBeneath, uintptr(unsafe.Pointer(&attr[0])), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("landlock rule %s: %w", path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package executor
+
+import "fmt"
+
+// ProbeSandbox rejects sandboxed commands, which require Linux namespaces and Landlock.
+func ProbeSandbox(cmd Command) error {
+	if cmd.Sandbox.IsZero() {
+		return nil
+	}
+	return fmt.Errorf("sandbox unavailable: only supported on linux")
+}
@@ -0,0 +1,132 @@
+//go:build linux
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+// shimEnvVar carries the JSON-encoded launchSpec to a re-executed poke binary.
+//
+// Some process attributes (rlimits, umask, mounts, landlock) must be applied by the command
+// process itself before exec. os/exec has no hook for that, so ExecuteBinary
+// re-executes the current binary with this variable set; the init function
+// below applies the spec and then execs the real command in place.
+const shimEnvVar = "POKE_EXEC_SHIM"
+
+// shimFailureExitCode is reported when the shim cannot prepare the command.
+const shimFailureExitCode = 126
+
+// launchSpec describes setup the exec shim performs before exec'ing Path.
+type launchSpec struct {
+	Path     string         `json:"path"`
+	Rlimits  []rlimitSpec   `json:"rlimits,omitempty"`
+	Umask    *Umask         `json:"umask,omitempty"`
+	ReadOnly []string       `json:"read_only,omitempty"` // requires a private mount namespace
+	Loopback bool           `json:"loopback,omitempty"`  // requires a private network namespace
+	Landlock *LandlockRules `json:"landlock,omitempty"`
+	Probe    bool           `json:"probe,omitempty"` // exit after setup instead of exec
+}
+
+// isZero reports whether the spec needs no shim at all.
+func (spec launchSpec) isZero() bool {
+	return len(spec.Rlimits) == 0 && spec.Umask == nil && len(spec.ReadOnly) == 0 && !spec.Loopback && spec.Landlock == nil
+}
+
+// rlimitSpec is one setrlimit call; soft and hard limits are set to Limit.
+type rlimitSpec struct {
+	Resource int    `json:"resource"`
+	Limit    uint64 `json:"limit"`
+}
+
+func init() {
+	raw, ok := os.LookupEnv(shimEnvVar)
+	if !ok {
+		return
+	}
+	if err := runShim(raw); err != nil {
+		fmt.Fprintf(os.Stderr, "poke exec shim: %v\n", err)
+		os.Exit(shimFailureExitCode)
+	}
+}
+
+// runShim applies the spec to the current process and execs the command.
+// It only returns on failure.
+func runShim(raw string) error {
+	if err := os.Unsetenv(shimEnvVar); err != nil {
+		return err
+	}
+
+	var spec launchSpec
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		return fmt.Errorf("decode launch spec: %w", err)
+	}
+
+	// Landlock and no_new_privs are per-thread; they must be set on the thread that execs.
+	runtime.LockOSThread()
+
+	if err := applyLaunchSpec(spec); err != nil {
+		return err
+	}
+	if spec.Probe {
+		os.Exit(0)
+	}
+
+	// #nosec G204 -- path was resolved by the parent from trusted config.
+	return syscall.Exec(spec.Path, os.Args, os.Environ())
+}
+
+// applyLaunchSpec performs shim setup in order; landlock comes last since a
+// landlocked process may no longer mount.
+func applyLaunchSpec(spec launchSpec) error {
+	if len(spec.ReadOnly) > 0 {
+		if err := applyReadOnlyMounts(spec.ReadOnly); err != nil {
+			return err
+		}
+	}
+	if spec.Loopback {
+		if err := bringLoopbackUp(); err != nil {
+			return err
+		}
+	}
+	for _, lim := range spec.Rlimits {
+		rlim := syscall.Rlimit{Cur: lim.Limit, Max: lim.Limit}
+		if err := syscall.Setrlimit(lim.Resource, &rlim); err != nil {
+			return fmt.Errorf("setrlimit %d: %w", lim.Resource, err)
+		}
+	}
+	if spec.Umask != nil {
+		syscall.Umask(int(*spec.Umask))
+	}
+	if spec.Landlock != nil {
+		return applyLandlock(spec.Landlock)
+	}
+	return nil
+}
+
+// wrapWithShim rewrites cmdExec to run through the exec shim with spec.
+func wrapWithShim(cmdExec *exec.Cmd, spec launchSpec) error {
+	if cmdExec.Err != nil {
+		return cmdExec.Err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("resolve poke executable: %w", err)
+	}
+
+	spec.Path = cmdExec.Path
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	cmdExec.Path = self
+	cmdExec.Env = append(cmdExec.Env, shimEnvVar+"="+string(encoded))
+	return nil
+}
@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStopSignal  Signal = "SIGTERM"       // Signal sent to the process group on timeout/cancel
+	defaultStopTimeout        = 5 * time.Second // Grace period before escalating to SIGKILL
+)
+
+// Signal is a POSIX signal name such as `SIGTERM`.
+type Signal string
+
+// UnmarshalYAML parses signal names with or without the `SIG` prefix.
+func (sig *Signal) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw string
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	parsed, err := ParseSignal(raw)
+	if err != nil {
+		return err
+	}
+	*sig = parsed
+	return nil
+}
+
+// ParseSignal normalizes a signal name (`term`, `SIGTERM`) to its `SIG` form.
+func ParseSignal(raw string) (Signal, error) {
+	name := strings.ToUpper(strings.TrimSpace(raw))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if _, ok := signalsByName[name]; !ok {
+		return "", fmt.Errorf("unsupported signal %q", raw)
+	}
+	return Signal(name), nil
+}
+
+// stopSignal returns the configured stop signal or the default.
+func (cmd Command) stopSignal() Signal {
+	if cmd.StopSignal == "" {
+		return defaultStopSignal
+	}
+	return cmd.StopSignal
+}
+
+// stopTimeout returns the configured grace period or the default.
+func (cmd Command) stopTimeout() time.Duration {
+	if cmd.StopTimeout <= 0 {
+		return defaultStopTimeout
+	}
+	return cmd.StopTimeout
+}
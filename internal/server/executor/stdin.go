@@ -0,0 +1,129 @@
+package executor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"poke/internal/server/bytesize"
+	"strings"
+)
+
+const defaultStdinPayloadMaxSize bytesize.Size = 64 << 10 // Default request payload limit.
+
+var (
+	ErrPayloadNotAccepted = errors.New("command does not accept a payload")
+	ErrPayloadTooLarge    = errors.New("payload exceeds the command's max_size")
+)
+
+// Stdin configures the standard input of a command.
+//
+// Exactly one source must be configured:
+// - text: static text from config
+// - file: file opened on every execution
+// - payload: the request's `payload` field, limited to `max_size` bytes
+type Stdin struct {
+	Text    string        `yaml:"text,omitempty"`
+	File    string        `yaml:"file,omitempty"`
+	Payload bool          `yaml:"payload,omitempty"`
+	MaxSize bytesize.Size `yaml:"max_size,omitempty"` // Payload size limit, only valid with payload
+}
+
+// UnmarshalYAML parses stdin config per docs/configuration/command.md.
+func (in *Stdin) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type stdinInput struct {
+		Text    *string        `yaml:"text"`
+		File    *string        `yaml:"file"`
+		Payload *bool          `yaml:"payload"`
+		MaxSize *bytesize.Size `yaml:"max_size"`
+	}
+
+	*in = Stdin{}
+
+	var raw stdinInput
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	sources := 0
+	if raw.Text != nil {
+		sources++
+		in.Text = *raw.Text
+	}
+	if raw.File != nil {
+		sources++
+		in.File = strings.TrimSpace(*raw.File)
+		if in.File == "" {
+			return fmt.Errorf("stdin file must not be empty")
+		}
+	}
+	if raw.Payload != nil && *raw.Payload {
+		sources++
+		in.Payload = true
+		in.MaxSize = defaultStdinPayloadMaxSize
+	}
+	if sources != 1 {
+		return fmt.Errorf("stdin requires exactly one of text, file, or payload")
+	}
+
+	if raw.MaxSize != nil {
+		if !in.Payload {
+			return fmt.Errorf("stdin max_size is only valid with payload")
+		}
+		in.MaxSize = *raw.MaxSize
+	}
+	return nil
+}
+
+// MarshalYAML renders only the configured source.
+func (in Stdin) MarshalYAML() (interface{}, error) {
+	switch {
+	case in.Payload:
+		return map[string]interface{}{"payload": true, "max_size": in.MaxSize}, nil
+	case in.File != "":
+		return map[string]string{"file": in.File}, nil
+	default:
+		return map[string]string{"text": in.Text}, nil
+	}
+}
+
+// openStdin returns the reader for cmd's standard input and a function
+// releasing it after execution.
+func openStdin(cmd Command) (io.Reader, func(), error) {
+	noop := func() {}
+	if err := ValidatePayload(cmd, cmd.Payload); err != nil {
+		return nil, noop, fmt.Errorf("command %s[%s]: %w", cmd.ID, cmd.Name, err)
+	}
+
+	switch {
+	case cmd.Stdin == nil:
+		return nil, noop, nil
+	case cmd.Stdin.Payload:
+		return bytes.NewReader(cmd.Payload), noop, nil
+	case cmd.Stdin.File != "":
+		file, err := os.Open(cmd.Stdin.File) // #nosec G304 -- by design, comes from config
+		if err != nil {
+			return nil, noop, fmt.Errorf("stdin file: %w", err)
+		}
+		return file, func() { _ = file.Close() }, nil
+	default:
+		return strings.NewReader(cmd.Stdin.Text), noop, nil
+	}
+}
+
+// ValidatePayload rejects payload when cmd does not read it from stdin or it
+// exceeds the configured limit. Listeners call it before enqueueing, the
+// executor again before every execution.
+func ValidatePayload(cmd Command, payload []byte) error {
+	if len(payload) == 0 {
+		return nil
+	}
+	if cmd.Stdin == nil || !cmd.Stdin.Payload {
+		return ErrPayloadNotAccepted
+	}
+	if uint64(len(payload)) > uint64(cmd.Stdin.MaxSize) {
+		return fmt.Errorf("%w (%d bytes)", ErrPayloadTooLarge, cmd.Stdin.MaxSize)
+	}
+	return nil
+}
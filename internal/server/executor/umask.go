@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Umask is a file mode creation mask configured as a quoted octal string, e.g. "0027".
+type Umask uint32
+
+// UnmarshalYAML parses umask strings; unquoted numbers are rejected because
+// YAML versions disagree on whether a leading zero means octal.
+func (mask *Umask) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	value, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("umask must be a quoted octal string such as \"0022\"")
+	}
+
+	parsed, err := ParseUmask(value)
+	if err != nil {
+		return err
+	}
+	*mask = parsed
+	return nil
+}
+
+// MarshalYAML renders the umask as a 4-digit octal string.
+func (mask Umask) MarshalYAML() (interface{}, error) {
+	return mask.String(), nil
+}
+
+// String returns the umask in 4-digit octal notation.
+func (mask Umask) String() string {
+	return fmt.Sprintf("%04o", uint32(mask))
+}
+
+// ParseUmask parses an octal umask between 0000 and 0777.
+func ParseUmask(raw string) (Umask, error) {
+	value := strings.TrimSpace(raw)
+	parsed, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || value == "" {
+		return 0, fmt.Errorf("invalid umask %q: must be octal", raw)
+	}
+	if parsed > 0o777 {
+		return 0, fmt.Errorf("invalid umask %q: must be between 0000 and 0777", raw)
+	}
+	return Umask(parsed), nil
+}
@@ -0,0 +1,31 @@
+package health
+
+import "poke/internal/server/sidelistener"
+
+const defaultHost = "127.0.0.1"
+
+// Config controls the health endpoints, see docs/configuration/health.md.
+//
+// Endpoints are enabled by default and served on every HTTP listener; a
+// non-zero Port serves them on a separate listener instead.
+type Config sidelistener.Config
+
+// UnmarshalYAML parses health config and applies the documented defaults.
+func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	parsed, err := sidelistener.Decode(unmarshal, sidelistener.Config{Enabled: true, Host: defaultHost})
+	if err != nil {
+		return err
+	}
+	*cfg = Config(parsed)
+	return sidelistener.Config(*cfg).Validate("health", "use the HTTP listeners")
+}
+
+// Separate reports whether the endpoints get their own listener.
+func (cfg Config) Separate() bool {
+	return cfg.Enabled && cfg.Port != 0
+}
+
+// Address returns the host:port of the separate listener.
+func (cfg Config) Address() string {
+	return sidelistener.Config(cfg).Address()
+}
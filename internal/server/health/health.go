@@ -0,0 +1,102 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// Check reports why a component is not ready, or nil, plus a short detail
+// shown in either case.
+type Check func() (detail string, err error)
+
+// CheckResult is the JSON form of one readiness check.
+type CheckResult struct {
+	Status string `json:"status"` // "ok" or "failing"
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the JSON body of /healthz and /readyz.
+type Report struct {
+	Status string                 `json:"status"` // "ok", "ready" or "not_ready"
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker runs named readiness checks.
+type Checker struct {
+	mu     sync.Mutex
+	checks map[string]Check
+}
+
+// NewChecker constructs a checker without checks, which is always ready.
+func NewChecker() *Checker {
+	return &Checker{checks: make(map[string]Check)}
+}
+
+// Register adds or replaces the check called name.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+}
+
+// Ready runs every check and reports whether all passed.
+func (c *Checker) Ready() Report {
+	c.mu.Lock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	checks := make([]Check, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.Unlock()
+
+	report := Report{Status: "ready", Checks: make(map[string]CheckResult, len(names))}
+	for i, name := range names {
+		detail, err := checks[i]()
+		result := CheckResult{Status: "ok", Detail: detail}
+		if err != nil {
+			result.Status = "failing"
+			result.Error = err.Error()
+			report.Status = "not_ready"
+		}
+		report.Checks[name] = result
+	}
+	return report
+}
+
+// RegisterRoutes adds the liveness and readiness routes to mux.
+//
+// Liveness only shows the process is serving HTTP; readiness runs the checks
+// and answers 503 Service Unavailable when any fails.
+func (c *Checker) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+LivenessPath, func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: "ok"})
+	})
+	mux.HandleFunc("GET "+ReadinessPath, func(w http.ResponseWriter, _ *http.Request) {
+		report := c.Ready()
+		status := http.StatusOK
+		if report.Status != "ready" {
+			status = http.StatusServiceUnavailable
+		}
+		writeReport(w, status, report)
+	})
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,15 @@
+package health
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"poke/internal/server/sidelistener"
+)
+
+// Listen binds cfg's address and serves the health endpoints of c until ctx is done.
+func Listen(ctx context.Context, cfg Config, c *Checker) (net.Addr, error) {
+	mux := http.NewServeMux()
+	c.RegisterRoutes(mux)
+	return sidelistener.Listen(ctx, "health", cfg.Address(), mux)
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"poke/internal/server/dispatch"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// defaultIncludeDir holds config fragments always included from the directory
+// of the main config file.
+const defaultIncludeDir = "conf.d"
+
+// Load reads the config file at path like Parse and merges the commands of
+// its included files into Commands.
+//
+// Included are the files matching each `include` entry, a file or glob
+// relative to the config's directory, then conf.d/*.yml next to it. An
+// included file may only set `commands`, and command IDs must be unique across
+// all files. Errors in included files name the file.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- by design, comes as CLI arg
+	if err != nil {
+		return Config{}, err
+	}
+	return load(data, path)
+}
+
+// load parses data, read from the config file at path, with its includes.
+func load(data []byte, path string) (Config, error) {
+	in, err := parseConfigInput(func(v interface{}) error { return yaml.Unmarshal(data, v) })
+	if err != nil {
+		return Config{}, err
+	}
+
+	files, err := includedFiles(path, in.Include)
+	if err != nil {
+		return Config{}, err
+	}
+	commands, err := parseCommandRegistryOrDefault(in.Commands)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := mergeIncludedCommands(&commands, path, files); err != nil {
+		return Config{}, err
+	}
+	in.Commands = &commands
+
+	var cfg Config
+	if err := cfg.apply(in); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// includedFiles resolves the include patterns of the config at path and adds
+// conf.d/*.yml. Each file is listed once, in include order, and the config
+// itself is never included.
+func includedFiles(path string, patterns []string) ([]string, error) {
+	dir := filepath.Dir(path)
+	self, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	seen := map[string]bool{self: true}
+	for _, pattern := range append(slices.Clone(patterns), filepath.Join(defaultIncludeDir, "*.yml")) {
+		matches, err := includeMatches(dir, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range matches {
+			abs, err := filepath.Abs(file)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[abs] {
+				seen[abs] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// includeMatches returns the sorted files matching pattern, relative to dir
+// unless absolute. A pattern without wildcards must name an existing file.
+func includeMatches(dir string, pattern string) ([]string, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("include entries must not be empty")
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("include %q: %w", pattern, err)
+	}
+	if len(matches) == 0 && !strings.ContainsAny(pattern, `*?[\`) {
+		return nil, fmt.Errorf("include %q: file not found", pattern)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// mergeIncludedCommands adds the commands of each included file to commands,
+// rejecting IDs already defined by the config at path or an earlier file.
+func mergeIncludedCommands(commands *dispatch.CommandRegistry, path string, files []string) error {
+	sources := make(map[string]string)
+	for _, id := range commands.IDs() {
+		sources[id] = path
+	}
+
+	for _, file := range files {
+		included, err := loadIncludedCommands(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for _, id := range included.IDs() {
+			if source, exists := sources[id]; exists {
+				return fmt.Errorf("%s: duplicate command id %q, already defined in %s", file, id, source)
+			}
+			sources[id] = file
+			cmd, _ := included.Get(id)
+			commands.Register(id, cmd)
+		}
+	}
+	return nil
+}
+
+// loadIncludedCommands parses the `commands` block of an included file.
+func loadIncludedCommands(file string) (dispatch.CommandRegistry, error) {
+	data, err := os.ReadFile(file) // #nosec G304 -- by design, comes from config
+	if err != nil {
+		return dispatch.CommandRegistry{}, err
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return dispatch.CommandRegistry{}, err
+	}
+	if err := checkIncludedKeys(raw); err != nil {
+		return dispatch.CommandRegistry{}, err
+	}
+
+	var in struct {
+		Commands *dispatch.CommandRegistry `yaml:"commands"`
+	}
+	if err := yaml.Unmarshal(data, &in); err != nil {
+		return dispatch.CommandRegistry{}, err
+	}
+	return parseCommandRegistryOrDefault(in.Commands)
+}
+
+// checkIncludedKeys rejects top-level keys other than `commands`, which
+// included files cannot set.
+func checkIncludedKeys(raw map[string]interface{}) error {
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		if key != "commands" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	return fmt.Errorf("included files may only set commands, found %q", keys[0])
+}
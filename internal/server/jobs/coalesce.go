@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Admission tells how Enqueue handled a submission.
+type Admission int
+
+const (
+	Admitted  Admission = iota // a new job was registered and must be enqueued
+	Replayed                   // the idempotency key matched an earlier job
+	Coalesced                  // the submission was merged into an identical queued job
+)
+
+// SetCoalescePolicy selects the commands whose identical queued submissions
+// are merged; nil disables coalescing. Jobs already enqueued are indexed
+// under the new policy.
+func (r *Registry) SetCoalescePolicy(coalesce func(commandID string) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.coalesce = coalesce
+	r.coalescable = make(map[string]string)
+	for _, e := range r.jobs {
+		r.indexCoalescable(e)
+	}
+}
+
+// MarkEnqueued records that the request of job id reached the dispatcher's
+// request channel. Only such jobs accept coalesced submissions: a job whose
+// request could not be sent is discarded, and must not take callers with it.
+func (r *Registry) MarkEnqueued(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.jobs[id]; ok {
+		e.enqueued = true
+		r.indexCoalescable(e)
+	}
+}
+
+// coalesceKey identifies submissions that merge: same command, priority
+// override and payload, the latter by digest.
+func coalesceKey(commandID string, priority *int, payload []byte) string {
+	digest := sha256.Sum256(payload)
+	override := "-"
+	if priority != nil {
+		override = strconv.Itoa(*priority)
+	}
+	return commandID + "\x00" + override + "\x00" + hex.EncodeToString(digest[:])
+}
+
+// coalescableInto reports whether submissions may be merged into e.
+func (r *Registry) coalescableInto(e *entry) bool {
+	return e.enqueued && e.job.State == StateQueued && r.coalesce != nil && r.coalesce(e.job.CommandID)
+}
+
+// indexCoalescable makes e the job identical submissions merge into, unless
+// another enqueued job already is.
+func (r *Registry) indexCoalescable(e *entry) {
+	if !r.coalescableInto(e) {
+		return
+	}
+	key := coalesceKey(e.job.CommandID, e.job.Priority, e.payload)
+	if _, ok := r.lookupIndexed(key); !ok {
+		r.coalescable[key] = e.job.ID
+	}
+}
+
+// unindexCoalescable drops e from the index once it stops being queued.
+func (r *Registry) unindexCoalescable(e *entry) {
+	key := coalesceKey(e.job.CommandID, e.job.Priority, e.payload)
+	if r.coalescable[key] == e.job.ID {
+		delete(r.coalescable, key)
+	}
+}
+
+// lookupIndexed returns the job indexed under key if it still accepts merges.
+func (r *Registry) lookupIndexed(key string) (*entry, bool) {
+	id, ok := r.coalescable[key]
+	if !ok {
+		return nil, false
+	}
+	e, ok := r.jobs[id]
+	if !ok || !r.coalescableInto(e) {
+		delete(r.coalescable, key)
+		return nil, false
+	}
+	return e, true
+}
+
+// lookupCoalescable returns a queued job sub can be merged into.
+//
+// Only submissions to run right away coalesce, and only with enqueued jobs of
+// the same command, payload and priority override that have not started yet.
+func (r *Registry) lookupCoalescable(sub Submission, now time.Time) (*entry, bool) {
+	if r.coalesce == nil || sub.RunAt.After(now) || !r.coalesce(sub.CommandID) {
+		return nil, false
+	}
+	e, ok := r.lookupIndexed(coalesceKey(sub.CommandID, sub.Priority, sub.Payload))
+	if !ok || !bytes.Equal(e.payload, sub.Payload) {
+		return nil, false
+	}
+	return e, true
+}
+
+// merge counts sub against the queued job in e and returns the updated job.
+func (r *Registry) merge(e *entry, sub Submission) (Job, error) {
+	job := e.job
+	job.Coalesced++
+	rec := journalRecord{Job: job, Payload: e.payload, Key: newJournalKey(sub.idempotencyKey(), r.now(), sub.Window)}
+	if r.journal != nil {
+		if err := r.journal.append(rec); err != nil {
+			return Job{}, err
+		}
+	}
+	e.job = job
+	r.rememberKey(e, rec.Key)
+	r.maybeCompact()
+	return job, nil
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	QueueTypeMemory = "memory" // jobs are lost on restart
+	QueueTypeFile   = "file"   // jobs are journaled to a local file
+
+	defaultQueueType = QueueTypeMemory
+	defaultMaxDelay  = 24 * time.Hour // Furthest a request may be scheduled ahead.
+
+	// DefaultPriorityAging is how long a waiting request takes to gain one
+	// priority level: a priority 0 request overtakes fresh priority 9 ones
+	// after about 5 minutes, long enough to keep priorities meaningful in
+	// bursts and short enough that routine jobs never wait for hours.
+	DefaultPriorityAging = 30 * time.Second
+)
+
+// QueueConfig selects where queued jobs are kept, see docs/configuration/server.md.
+type QueueConfig struct {
+	Type string `yaml:"type,omitempty"` // `memory` (default) or `file`
+	Path string `yaml:"path,omitempty"` // journal file for `file` queues
+	// Furthest `run_at`/`delay` a request may schedule ahead, default 24h
+	MaxDelay time.Duration `yaml:"max_delay,omitempty"`
+	// Wait after which a queued request gains one priority level, default 30s
+	PriorityAging time.Duration `yaml:"priority_aging,omitempty"`
+}
+
+// UnmarshalYAML parses queue config and applies the memory, max_delay and
+// priority_aging defaults.
+func (cfg *QueueConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type queueInput struct {
+		Type          string         `yaml:"type"`
+		Path          string         `yaml:"path"`
+		MaxDelay      *time.Duration `yaml:"max_delay"`
+		PriorityAging *time.Duration `yaml:"priority_aging"`
+	}
+
+	var in queueInput
+	if err := unmarshal(&in); err != nil {
+		return err
+	}
+
+	*cfg = QueueConfig{
+		Type:          strings.ToLower(strings.TrimSpace(in.Type)),
+		Path:          in.Path,
+		MaxDelay:      defaultMaxDelay,
+		PriorityAging: DefaultPriorityAging,
+	}
+	if cfg.Type == "" {
+		cfg.Type = defaultQueueType
+	}
+	if in.MaxDelay != nil {
+		cfg.MaxDelay = *in.MaxDelay
+	}
+	if in.PriorityAging != nil {
+		cfg.PriorityAging = *in.PriorityAging
+	}
+	return cfg.validate()
+}
+
+// validate requires a positive max_delay and priority_aging and an absolute
+// journal path for file queues only.
+func (cfg QueueConfig) validate() error {
+	if cfg.MaxDelay <= 0 {
+		return fmt.Errorf("queue max_delay must be positive")
+	}
+	if cfg.PriorityAging <= 0 {
+		return fmt.Errorf("queue priority_aging must be positive")
+	}
+	switch cfg.Type {
+	case QueueTypeMemory:
+		if cfg.Path != "" {
+			return fmt.Errorf("queue path is only valid with type file")
+		}
+	case QueueTypeFile:
+		if strings.TrimSpace(cfg.Path) == "" {
+			return fmt.Errorf("queue type file requires path")
+		}
+		if !filepath.IsAbs(cfg.Path) {
+			return fmt.Errorf("queue path %q must be absolute", cfg.Path)
+		}
+	default:
+		return fmt.Errorf("queue type must be one of memory or file")
+	}
+	return nil
+}
+
+// Durable reports whether jobs survive a restart.
+func (cfg QueueConfig) Durable() bool {
+	return cfg.Type == QueueTypeFile
+}
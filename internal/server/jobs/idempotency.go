@@ -0,0 +1,147 @@
+package jobs
+
+import "time"
+
+const keySweepInterval = time.Minute // How often expired idempotency keys are dropped.
+
+// keyEntry maps an idempotency key to the job it admitted until the key's
+// window ends, independent of how long the job itself is retained.
+type keyEntry struct {
+	id      string
+	expires time.Time
+	job     *Job // final snapshot, set once the job left retention
+}
+
+// newJournalKey returns the key entry for indexKey admitted at now, nil when
+// the submission has no key or no window.
+func newJournalKey(indexKey string, now time.Time, window time.Duration) *journalKey {
+	if indexKey == "" || window <= 0 {
+		return nil
+	}
+	return &journalKey{Index: indexKey, Expires: now.Add(window)}
+}
+
+// rememberKey maps key to the job in e until the key expires.
+func (r *Registry) rememberKey(e *entry, key *journalKey) {
+	if key == nil {
+		return
+	}
+	r.keys[key.Index] = &keyEntry{id: e.job.ID, expires: key.Expires}
+	e.keys = append(e.keys, key.Index)
+}
+
+// lookupKey returns the job registered under indexKey if it is within window
+// and the key has not expired.
+func (r *Registry) lookupKey(indexKey string, now time.Time, window time.Duration) (Job, bool) {
+	if indexKey == "" || window <= 0 {
+		return Job{}, false
+	}
+	k, ok := r.keys[indexKey]
+	if !ok {
+		return Job{}, false
+	}
+	job, ok := r.keyedJob(k)
+	if !ok || !now.Before(k.expires) || now.Sub(job.CreatedAt) >= window {
+		delete(r.keys, indexKey)
+		return Job{}, false
+	}
+	return job, true
+}
+
+// keyedJob returns the job k points at, from retention or its snapshot.
+func (r *Registry) keyedJob(k *keyEntry) (Job, bool) {
+	if e, ok := r.jobs[k.id]; ok {
+		return e.job, true
+	}
+	if k.job != nil {
+		return *k.job, true
+	}
+	return Job{}, false
+}
+
+// releaseKeys detaches the keys of e, a job leaving retention: keys still
+// within their window keep a snapshot of the job, the others are dropped.
+func (r *Registry) releaseKeys(e *entry, now time.Time) {
+	for _, indexKey := range e.keys {
+		k, ok := r.keys[indexKey]
+		if !ok || k.id != e.job.ID {
+			continue
+		}
+		if now.Before(k.expires) {
+			job := e.job
+			k.job = &job
+			continue
+		}
+		delete(r.keys, indexKey)
+	}
+}
+
+// forgetKeys drops the keys of e, a job that was never accepted.
+func (r *Registry) forgetKeys(e *entry) {
+	for _, indexKey := range e.keys {
+		if k, ok := r.keys[indexKey]; ok && k.id == e.job.ID {
+			delete(r.keys, indexKey)
+		}
+	}
+}
+
+// maybeSweepKeys drops expired keys once per keySweepInterval, so keys of
+// jobs that left retention do not accumulate.
+func (r *Registry) maybeSweepKeys(now time.Time) {
+	if now.Sub(r.keysSwept) < keySweepInterval {
+		return
+	}
+	r.keysSwept = now
+	for indexKey, k := range r.keys {
+		if !now.Before(k.expires) {
+			delete(r.keys, indexKey)
+		}
+	}
+}
+
+// keyRecords returns one journal record per unexpired key. Keys of jobs that
+// left retention carry the job's snapshot, the others only its ID.
+func (r *Registry) keyRecords(now time.Time) []journalRecord {
+	records := make([]journalRecord, 0, len(r.keys))
+	for indexKey, k := range r.keys {
+		if !now.Before(k.expires) {
+			continue
+		}
+		job := Job{ID: k.id}
+		if _, retained := r.jobs[k.id]; !retained && k.job != nil {
+			job = *k.job
+		}
+		records = append(records, journalRecord{Job: job, Key: &journalKey{Index: indexKey, Expires: k.expires}, KeyOnly: true})
+	}
+	return records
+}
+
+// restoreKeys rebuilds the key index from the key records of a journal, in
+// journal order, and returns the keys per job ID for replay to attach.
+func (r *Registry) restoreKeys(records []journalRecord) map[string][]string {
+	now := r.now()
+	byJob := make(map[string][]string)
+	for _, rec := range records {
+		if rec.Key == nil || !now.Before(rec.Key.Expires) {
+			continue
+		}
+		k := &keyEntry{id: rec.Job.ID, expires: rec.Key.Expires}
+		if rec.KeyOnly && !rec.Job.CreatedAt.IsZero() {
+			job := rec.Job
+			k.job = &job
+		}
+		r.keys[rec.Key.Index] = k
+		byJob[rec.Job.ID] = append(byJob[rec.Job.ID], rec.Key.Index)
+	}
+	return byJob
+}
+
+// dropDanglingKeys removes keys whose job is neither retained nor kept as a
+// snapshot, such as keys of discarded jobs.
+func (r *Registry) dropDanglingKeys() {
+	for indexKey, k := range r.keys {
+		if _, ok := r.keyedJob(k); !ok {
+			delete(r.keys, indexKey)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const journalCompactEvery = 4096 // Appended records that trigger a journal rewrite.
+
+// journalRecord is one line of the journal: the latest snapshot of a job.
+//
+// Replaying keeps the last record per job ID; queued jobs carry their payload
+// so they can be re-enqueued after a restart.
+//
+// Records carrying Key map an idempotency key to the job until the key
+// expires. KeyOnly records hold nothing else: a compacted journal keeps the
+// keys of jobs that left retention that way, with the job's final snapshot.
+type journalRecord struct {
+	Job     Job         `json:"job"`
+	Payload []byte      `json:"payload,omitempty"`
+	Deleted bool        `json:"deleted,omitempty"` // job was discarded before it was accepted
+	Key     *journalKey `json:"key,omitempty"`
+	KeyOnly bool        `json:"key_only,omitempty"`
+}
+
+// journalKey is an idempotency index entry and the time it expires.
+type journalKey struct {
+	Index   string    `json:"index"`
+	Expires time.Time `json:"expires"`
+}
+
+// journal is an append-only JSON lines file with fsync per record.
+type journal struct {
+	path    string
+	file    *os.File
+	records int // records appended since the last rewrite
+}
+
+// openJournal reads all intact records from path and opens it for appending.
+//
+// A torn last line left by a crash is ignored.
+func openJournal(path string) (*journal, []journalRecord, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, nil, fmt.Errorf("create queue directory: %w", err)
+	}
+
+	records, err := readJournal(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600) // #nosec G304 -- by design, comes from config
+	if err != nil {
+		return nil, nil, fmt.Errorf("open queue journal: %w", err)
+	}
+	return &journal{path: path, file: file}, records, nil
+}
+
+// readJournal decodes every complete line of the journal at path.
+func readJournal(path string) ([]journalRecord, error) {
+	file, err := os.Open(path) // #nosec G304 -- by design, comes from config
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open queue journal: %w", err)
+	}
+	defer file.Close() //nolint:errcheck // Read-only handle.
+
+	var records []journalRecord
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return records, nil // an unterminated last line is a torn write
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read queue journal: %w", err)
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+
+		var rec journalRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("queue journal %s line %d: %w", path, line, err)
+		}
+		records = append(records, rec)
+	}
+}
+
+// append durably writes rec before returning.
+func (j *journal) append(rec journalRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write queue journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("sync queue journal: %w", err)
+	}
+	j.records++
+	return nil
+}
+
+// rewrite atomically replaces the journal with records.
+func (j *journal) rewrite(records []journalRecord) error {
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("compact queue journal: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // Already renamed on success.
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, rec := range records {
+		if err := encoder.Encode(rec); err != nil {
+			_ = tmp.Close()
+			return fmt.Errorf("compact queue journal: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("compact queue journal: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("compact queue journal: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("compact queue journal: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return fmt.Errorf("compact queue journal: %w", err)
+	}
+
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0o600) // #nosec G304 -- by design, comes from config
+	if err != nil {
+		return fmt.Errorf("reopen queue journal: %w", err)
+	}
+	_ = j.file.Close()
+	j.file = file
+	j.records = 0
+	return nil
+}
+
+// close releases the journal file.
+func (j *journal) close() error {
+	return j.file.Close()
+}
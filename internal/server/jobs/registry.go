@@ -0,0 +1,619 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"poke/internal/server/executor"
+	"poke/internal/server/redact"
+	"poke/internal/server/request"
+	"slices"
+	"sync"
+	"time"
+)
+
+// State is the lifecycle state of a job.
+type State string
+
+const (
+	StateQueued    State = "queued"    // accepted by a listener, waiting for the dispatcher
+	StateScheduled State = "scheduled" // held by the dispatcher until run_at or its next attempt
+	StateRunning   State = "running"   // picked up by the dispatcher
+	StateSucceeded State = "succeeded" // command exited with code 0
+	StateFailed    State = "failed"    // command failed, timed out or could not be started
+	StateCanceled  State = "canceled"  // removed from the queue or stopped while running
+)
+
+const defaultRetainedJobs = 1000 // Finished jobs kept for lookups before the oldest are evicted.
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobFinished    = errors.New("job already finished")
+	ErrBeyondMaxDelay = errors.New("run_at is beyond the queue max_delay")
+)
+
+// Job is a snapshot of a single command request as it moves through poke.
+type Job struct {
+	ID             string           `json:"id"`
+	CommandID      string           `json:"command_id"`
+	RequestID      string           `json:"request_id,omitempty"` // correlation ID of the submitting request
+	State          State            `json:"state"`
+	Outcome        executor.Outcome `json:"outcome,omitempty"`
+	ExitCode       *int             `json:"exit_code,omitempty"`
+	Error          string           `json:"error,omitempty"`
+	SubmittedBy    string           `json:"submitted_by,omitempty"`    // principal that submitted the request
+	IdempotencyKey string           `json:"idempotency_key,omitempty"` // client key deduplicating submissions
+	CanceledBy     string           `json:"canceled_by,omitempty"`     // principal that requested cancellation
+	CreatedAt      time.Time        `json:"created_at"`
+	RunAt          *time.Time       `json:"run_at,omitempty"`    // requested start, nil = as soon as possible
+	Priority       *int             `json:"priority,omitempty"`  // requested priority override, nil = the command's priority
+	Coalesced      int              `json:"coalesced,omitempty"` // later submissions merged into this job while queued
+	StartedAt      *time.Time       `json:"started_at,omitempty"`
+	FinishedAt     *time.Time       `json:"finished_at,omitempty"`
+	Attempts       []Attempt        `json:"attempts,omitempty"` // one entry per execution, oldest first
+	Steps          []Step           `json:"steps,omitempty"`    // workflow step results in execution order
+}
+
+// Submission describes a request a listener registers as a job.
+type Submission struct {
+	CommandID      string
+	Payload        []byte
+	Principal      string        // authenticated submitter
+	IdempotencyKey string        // optional, deduplicates submissions per principal and command
+	Window         time.Duration // how long IdempotencyKey maps to the original job
+	RunAt          time.Time     // earliest execution time, zero = as soon as possible
+	Priority       *int          // optional priority override, already authorized
+	RequestID      string        // correlation ID of the submitting request
+}
+
+// idempotencyKey scopes a client key to the submitting principal and command.
+func (s Submission) idempotencyKey() string {
+	return idempotencyIndexKey(s.Principal, s.CommandID, s.IdempotencyKey)
+}
+
+func idempotencyIndexKey(principal, commandID, key string) string {
+	if key == "" {
+		return ""
+	}
+	return principal + "\x00" + commandID + "\x00" + key
+}
+
+// Attempt records a single execution of a job's command.
+type Attempt struct {
+	Attempt    int              `json:"attempt"` // 1-based attempt number
+	Outcome    executor.Outcome `json:"outcome"`
+	ExitCode   int              `json:"exit_code"`
+	Error      string           `json:"error,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+}
+
+// NewAttempt builds an attempt record from an execution result.
+func NewAttempt(attempt int, startedAt time.Time, finishedAt time.Time, result executor.Result) Attempt {
+	a := Attempt{
+		Attempt:    attempt,
+		Outcome:    result.Outcome,
+		ExitCode:   result.ExitCode,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+	}
+	if result.Error != nil {
+		a.Error = errorText(result.Error)
+	}
+	return a
+}
+
+// errorText renders err for a job record, which is exposed by the API, with
+// secrets redacted.
+func errorText(err error) string {
+	return redact.Default().String(err.Error())
+}
+
+// Finished reports whether the job reached a terminal state.
+func (j Job) Finished() bool {
+	switch j.State {
+	case StateSucceeded, StateFailed, StateCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+type entry struct {
+	job       Job
+	payload   []byte             // request payload, kept while queued
+	cancel    context.CancelFunc // stops the job while running
+	withdraw  func() bool        // removes a waiting job's request from the dispatcher, reports success
+	unclaimed bool               // canceled while its request was still on its way to the dispatcher
+	keys      []string           // idempotency index keys that mapped to the job
+	enqueued  bool               // request reached the request channel, merges may join it
+}
+
+// waiting reports whether the job has not been picked up for execution yet.
+func (e *entry) waiting() bool {
+	return e.job.State == StateQueued || e.job.State == StateScheduled
+}
+
+// Registry tracks jobs from enqueue to completion and cancels them on request.
+//
+// Listeners register jobs when they enqueue requests, the dispatcher moves
+// them through running to a terminal state. Canceling a waiting job withdraws
+// its request from the dispatcher's queue or timer. A request still on the
+// request channel cannot be withdrawn: the canceled job is then kept, exempt
+// from retention, until the dispatcher reaches the request and skips it.
+//
+// With a durable queue every transition is appended to a journal, so queued
+// jobs survive restarts (see Open).
+type Registry struct {
+	mu        sync.Mutex
+	jobs      map[string]*entry
+	keys      map[string]*keyEntry // idempotency index key -> job, until the key expires
+	keysSwept time.Time            // last time expired keys were dropped
+	finished  []string             // terminal job IDs, oldest first
+	retain    int
+	now       func() time.Time
+	maxDelay  time.Duration
+	aging     time.Duration               // wait after which a queued request gains one priority level
+	coalesce  func(commandID string) bool // commands whose identical queued submissions merge
+	// coalesce key -> enqueued queued job identical submissions merge into
+	coalescable map[string]string
+	journal     *journal // nil for in-memory queues
+	logger      *slog.Logger
+}
+
+// NewRegistry constructs an empty in-memory job registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		jobs:        make(map[string]*entry),
+		keys:        make(map[string]*keyEntry),
+		coalescable: make(map[string]string),
+		retain:      defaultRetainedJobs,
+		maxDelay:    defaultMaxDelay,
+		aging:       DefaultPriorityAging,
+		now:         time.Now,
+		logger:      slog.Default().With("component", "jobs"),
+	}
+}
+
+// Open constructs a registry for cfg and returns the requests to re-enqueue.
+//
+// For file queues the journal is replayed: queued jobs are returned in their
+// original order (at-least-once delivery), jobs that were running when poke
+// stopped are recorded as failed with outcome `interrupted`.
+func Open(cfg QueueConfig) (*Registry, []request.CommandRequest, error) {
+	r := NewRegistry()
+	if cfg.MaxDelay > 0 {
+		r.maxDelay = cfg.MaxDelay
+	}
+	if cfg.PriorityAging > 0 {
+		r.aging = cfg.PriorityAging
+	}
+	if !cfg.Durable() {
+		return r, nil, nil
+	}
+
+	j, records, err := openJournal(cfg.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	r.journal = j
+
+	pending := r.replay(records)
+	if err := r.compact(); err != nil {
+		_ = j.close()
+		return nil, nil, err
+	}
+	r.logger.Info("queue journal recovered", "event", "queue_recovered", "path", cfg.Path, "jobs", len(r.jobs), "requeued", len(pending))
+	return r, pending, nil
+}
+
+// PriorityAging returns the queue's priority_aging, for the dispatcher's
+// priority queue.
+func (r *Registry) PriorityAging() time.Duration {
+	return r.aging
+}
+
+// Close releases the journal of a durable registry.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.journal == nil {
+		return nil
+	}
+	err := r.journal.close()
+	r.journal = nil
+	return err
+}
+
+// Enqueue registers a new queued job for sub.
+//
+// When sub carries an idempotency key already used by the same principal for
+// the same command within sub.Window, the original job is returned as
+// Replayed. When the command coalesces and an identical job is still queued
+// and marked enqueued (see MarkEnqueued), that job is returned as Coalesced. In both cases nothing must be enqueued.
+// Durable registries return once the job is on disk; an error means the
+// request must not be acknowledged.
+func (r *Registry) Enqueue(sub Submission) (Job, Admission, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	indexKey := sub.idempotencyKey()
+	if original, ok := r.lookupKey(indexKey, now, sub.Window); ok {
+		return original, Replayed, nil
+	}
+	if sub.RunAt.Sub(now) > r.maxDelay {
+		return Job{}, Admitted, ErrBeyondMaxDelay
+	}
+	if e, ok := r.lookupCoalescable(sub, now); ok {
+		job, err := r.merge(e, sub)
+		return job, Coalesced, err
+	}
+
+	job := Job{
+		ID:             newJobID(),
+		CommandID:      sub.CommandID,
+		RequestID:      sub.RequestID,
+		State:          StateQueued,
+		SubmittedBy:    sub.Principal,
+		IdempotencyKey: sub.IdempotencyKey,
+		CreatedAt:      now,
+		Priority:       sub.Priority,
+	}
+	if sub.RunAt.After(now) {
+		runAt := sub.RunAt
+		job.State = StateScheduled
+		job.RunAt = &runAt
+	}
+	e := &entry{job: job, payload: sub.Payload}
+	rec := journalRecord{Job: job, Payload: sub.Payload, Key: newJournalKey(indexKey, now, sub.Window)}
+	if r.journal != nil {
+		if err := r.journal.append(rec); err != nil {
+			return Job{}, Admitted, err
+		}
+	}
+	r.jobs[job.ID] = e
+	r.rememberKey(e, rec.Key)
+	r.maybeSweepKeys(now)
+	r.maybeCompact()
+	return job, Admitted, nil
+}
+
+// Discard forgets a queued job that never reached the request channel.
+func (r *Registry) Discard(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.jobs[id]; ok && e.waiting() {
+		delete(r.jobs, id)
+		r.forgetKeys(e)
+		r.unindexCoalescable(e)
+		r.persist(journalRecord{Job: e.job, Deleted: true})
+	}
+}
+
+// Schedule attaches withdraw as the hook that stops a scheduled job's timer,
+// reporting whether the request will no longer be delivered.
+//
+// It returns false when the job was canceled before it could be scheduled or
+// is unknown. Requests without a job ID are not tracked until they start.
+func (r *Registry) Schedule(id string, commandID string, withdraw func() bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id == "" {
+		return true
+	}
+	e, ok := r.jobs[id]
+	if !ok {
+		r.logger.Warn("unknown job scheduled, skipping", "event", "job_unknown", "job_id", id, "command_id", commandID)
+		return false
+	}
+	if e.job.State == StateCanceled {
+		r.claim(id, e)
+		return false
+	}
+	e.withdraw = withdraw
+	return true
+}
+
+// Hold attaches withdraw as the hook that removes a queued job's request from
+// the dispatcher's queue, reporting whether it was still queued.
+//
+// Canceled and unknown jobs are left alone; Start skips their request.
+func (r *Registry) Hold(id string, withdraw func() bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.jobs[id]; ok && e.waiting() {
+		e.withdraw = withdraw
+	}
+}
+
+// Start marks a job running with cancel as its stop hook.
+//
+// Requests without a job ID are registered on the fly so requests that bypass
+// a listener are tracked as well. It returns false when the job was canceled
+// while waiting or its ID is unknown; the zero Job is returned for the latter.
+func (r *Registry) Start(id string, commandID string, cancel context.CancelFunc) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	e, ok := r.jobs[id]
+	switch {
+	case !ok && id != "":
+		r.logger.Warn("unknown job dequeued, skipping", "event", "job_unknown", "job_id", id, "command_id", commandID)
+		return Job{}, false
+	case !ok:
+		id = newJobID()
+		e = &entry{job: Job{ID: id, CommandID: commandID, CreatedAt: now}}
+		r.jobs[id] = e
+	case e.job.State == StateCanceled:
+		r.claim(id, e)
+		return e.job, false
+	}
+
+	r.unindexCoalescable(e)
+	e.job.State = StateRunning
+	if e.job.StartedAt == nil {
+		e.job.StartedAt = &now
+	}
+	e.payload = nil
+	e.withdraw = nil
+	e.cancel = cancel
+	r.persist(journalRecord{Job: e.job})
+	return e.job, true
+}
+
+// claim retires a job canceled while waiting once the dispatcher reached its
+// request, which it will skip.
+func (r *Registry) claim(id string, e *entry) {
+	if e.unclaimed {
+		e.unclaimed = false
+		r.retire(id)
+	}
+}
+
+// Retry moves a running job back to scheduled until runAt, when the
+// dispatcher runs its next attempt. payload is kept for that attempt, also
+// across restarts with a durable queue.
+//
+// It returns false when the job was canceled during the failed attempt.
+func (r *Registry) Retry(id string, runAt time.Time, payload []byte) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.jobs[id]
+	if !ok || e.job.State != StateRunning || e.job.CanceledBy != "" {
+		return false
+	}
+	e.job.State = StateScheduled
+	e.job.RunAt = &runAt
+	e.payload = payload
+	e.cancel = nil
+	r.persist(journalRecord{Job: e.job, Payload: payload})
+	return true
+}
+
+// Finish records the execution result and moves the job to a terminal state.
+func (r *Registry) Finish(id string, result executor.Result) Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.jobs[id]
+	if !ok || e.job.Finished() {
+		return Job{}
+	}
+
+	now := r.now()
+	exitCode := result.ExitCode
+	e.job.Outcome = result.Outcome
+	e.job.ExitCode = &exitCode
+	e.job.FinishedAt = &now
+	e.cancel = nil
+	if result.Error != nil {
+		e.job.Error = errorText(result.Error)
+	}
+
+	switch {
+	case result.Outcome == executor.OutcomeCanceled:
+		e.job.State = StateCanceled
+	case result.Error == nil:
+		e.job.State = StateSucceeded
+	default:
+		e.job.State = StateFailed
+	}
+	r.retire(id)
+	r.persist(journalRecord{Job: e.job})
+	return e.job
+}
+
+// RecordAttempt appends an execution attempt to a running job's history.
+func (r *Registry) RecordAttempt(id string, attempt Attempt) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.jobs[id]
+	if !ok || e.job.Finished() {
+		return
+	}
+	// Clip so snapshots handed out earlier never share the appended element.
+	e.job.Attempts = append(slices.Clip(e.job.Attempts), attempt)
+	r.persist(journalRecord{Job: e.job})
+}
+
+// Cancel removes a queued job or stops a running one on behalf of principal.
+//
+// Running jobs keep the running state until the executor returns and Finish
+// records the outcome.
+func (r *Registry) Cancel(id string, principal string) (Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	if e.job.Finished() {
+		return e.job, ErrJobFinished
+	}
+
+	e.job.CanceledBy = principal
+	switch e.job.State {
+	case StateQueued, StateScheduled:
+		now := r.now()
+		r.unindexCoalescable(e)
+		e.job.State = StateCanceled
+		e.job.FinishedAt = &now
+		e.payload = nil
+		if n := len(e.job.Steps); n > 0 && e.job.Steps[n-1].Retrying() {
+			e.job.Steps = slices.Clone(e.job.Steps)
+			e.job.Steps[n-1] = e.job.Steps[n-1].Abandoned()
+		}
+		if e.withdraw != nil && e.withdraw() {
+			r.retire(id)
+		} else {
+			e.unclaimed = true
+		}
+		e.withdraw = nil
+	case StateRunning:
+		if e.cancel != nil {
+			e.cancel()
+		}
+	}
+	r.persist(journalRecord{Job: e.job})
+	return e.job, nil
+}
+
+// Get returns a snapshot of the job with id.
+func (r *Registry) Get(id string) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// retire records a terminal job and evicts the oldest beyond the retention limit.
+func (r *Registry) retire(id string) {
+	r.finished = append(r.finished, id)
+	for len(r.finished) > r.retain {
+		if e, ok := r.jobs[r.finished[0]]; ok {
+			r.releaseKeys(e, r.now())
+		}
+		delete(r.jobs, r.finished[0])
+		r.finished = r.finished[1:]
+	}
+}
+
+// replay rebuilds jobs from journal records and returns the requests to re-enqueue.
+func (r *Registry) replay(records []journalRecord) []request.CommandRequest {
+	keys := r.restoreKeys(records)
+	latest := make(map[string]journalRecord, len(records))
+	order := make([]string, 0, len(records))
+	for _, rec := range records {
+		if rec.KeyOnly {
+			continue
+		}
+		if _, seen := latest[rec.Job.ID]; !seen {
+			order = append(order, rec.Job.ID)
+		}
+		latest[rec.Job.ID] = rec
+	}
+
+	var pending []request.CommandRequest
+	for _, id := range order {
+		rec := latest[id]
+		if rec.Deleted {
+			continue
+		}
+		e := &entry{job: rec.Job, keys: keys[id]}
+		r.jobs[id] = e
+
+		switch rec.Job.State {
+		case StateQueued, StateScheduled:
+			e.payload = rec.Payload
+			e.enqueued = true // Start sends recovered requests before listeners accept new ones
+			req := request.CommandRequest{JobID: id, CommandID: rec.Job.CommandID, Payload: rec.Payload, Priority: rec.Job.Priority, RequestID: rec.Job.RequestID}
+			if rec.Job.RunAt != nil {
+				req.RunAt = *rec.Job.RunAt
+			}
+			pending = append(pending, req)
+		case StateRunning:
+			now := r.now()
+			e.job.State = StateFailed
+			e.job.Outcome = executor.OutcomeInterrupted
+			e.job.Error = "poke stopped while the job was running"
+			e.job.FinishedAt = &now
+			r.logger.Warn("running job interrupted by restart", "event", "job_interrupted", "job_id", id, "command_id", rec.Job.CommandID)
+			r.retire(id)
+		default:
+			r.retire(id)
+		}
+	}
+	r.dropDanglingKeys()
+	return pending
+}
+
+// persist appends rec to the journal of a durable registry.
+//
+// Failures after enqueue are logged rather than returned: the job already
+// ran or changed state, the worst case is a stale record on recovery.
+func (r *Registry) persist(rec journalRecord) {
+	if r.journal == nil {
+		return
+	}
+	if err := r.journal.append(rec); err != nil {
+		r.logger.Error("queue journal write failed", "event", "queue_journal_write_failed", "job_id", rec.Job.ID, "state", rec.Job.State, "error", err)
+		return
+	}
+	r.maybeCompact()
+}
+
+// maybeCompact rewrites the journal once enough records have accumulated.
+func (r *Registry) maybeCompact() {
+	if r.journal == nil || r.journal.records < journalCompactEvery {
+		return
+	}
+	if err := r.compact(); err != nil {
+		r.logger.Error("queue journal compaction failed", "event", "queue_journal_compact_failed", "error", err)
+	}
+}
+
+// compact rewrites the journal with one record per retained job, retired
+// jobs first, followed by one record per unexpired idempotency key.
+func (r *Registry) compact() error {
+	records := make([]journalRecord, 0, len(r.jobs))
+	for _, id := range r.finished {
+		records = append(records, journalRecord{Job: r.jobs[id].job})
+	}
+	for _, e := range r.jobs {
+		if !e.job.Finished() || e.unclaimed {
+			records = append(records, journalRecord{Job: e.job, Payload: e.payload})
+		}
+	}
+	slices.SortStableFunc(records[len(r.finished):], func(a, b journalRecord) int {
+		return a.Job.CreatedAt.Compare(b.Job.CreatedAt)
+	})
+	records = append(records, r.keyRecords(r.now())...)
+
+	if err := r.journal.rewrite(records); err != nil {
+		return fmt.Errorf("queue journal %s: %w", r.journal.path, err)
+	}
+	return nil
+}
+
+// newJobID returns a random 128-bit hex identifier.
+func newJobID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:]) // crypto/rand.Read never returns an error
+	return hex.EncodeToString(b[:])
+}
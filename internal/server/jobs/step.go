@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"poke/internal/server/executor"
+	"slices"
+)
+
+// StepState is the result of a single workflow step.
+type StepState string
+
+const (
+	StepSucceeded StepState = "succeeded" // the step's command succeeded
+	StepFailed    StepState = "failed"    // the step's command failed, was canceled or could not run
+	StepSkipped   StepState = "skipped"   // the workflow aborted or a needed step was skipped
+	StepRetrying  StepState = "retrying"  // the step's command failed and waits for its next attempt
+)
+
+// Step records how one step of a workflow job ended.
+type Step struct {
+	ID           string           `json:"id"`
+	CommandID    string           `json:"command_id"`
+	State        StepState        `json:"state"`
+	Outcome      executor.Outcome `json:"outcome,omitempty"`
+	ExitCode     *int             `json:"exit_code,omitempty"`
+	Error        string           `json:"error,omitempty"`
+	Attempts     []Attempt        `json:"attempts,omitempty"`     // one entry per execution, oldest first
+	Compensation *Step            `json:"compensation,omitempty"` // compensating command run after a failure
+}
+
+// NewStep builds a step record from the attempts and final result of its command.
+func NewStep(id string, commandID string, attempts []Attempt, result executor.Result) Step {
+	exitCode := result.ExitCode
+	s := Step{
+		ID:        id,
+		CommandID: commandID,
+		State:     StepSucceeded,
+		Outcome:   result.Outcome,
+		ExitCode:  &exitCode,
+		Attempts:  attempts,
+	}
+	if result.Error != nil {
+		s.State = StepFailed
+		s.Error = errorText(result.Error)
+	}
+	return s
+}
+
+// Retrying reports whether the step, or its compensating command, waits for
+// its next attempt.
+func (s Step) Retrying() bool {
+	return s.State == StepRetrying || (s.Compensation != nil && s.Compensation.State == StepRetrying)
+}
+
+// Abandoned returns the step, which waits for its next attempt, as canceled.
+func (s Step) Abandoned() Step {
+	if s.Compensation != nil && s.Compensation.State == StepRetrying {
+		compensation := s.Compensation.Abandoned()
+		s.Compensation = &compensation
+		return s
+	}
+	s.State = StepFailed
+	s.Outcome = executor.OutcomeCanceled
+	return s
+}
+
+// RecordStep appends a workflow step to a job's results. A record for the
+// step that ends the results, left while it was retrying, is replaced.
+func (r *Registry) RecordStep(id string, step Step) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.jobs[id]
+	if !ok || e.job.Finished() {
+		return
+	}
+	// Clip so snapshots handed out earlier never share the appended element.
+	steps := slices.Clip(e.job.Steps)
+	if n := len(steps); n > 0 && steps[n-1].ID == step.ID {
+		steps = steps[:n-1]
+	}
+	e.job.Steps = append(steps, step)
+	r.persist(journalRecord{Job: e.job})
+}
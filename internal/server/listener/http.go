@@ -10,18 +10,42 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"poke/internal/server/audit"
 	"poke/internal/server/auth"
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+	"poke/internal/server/metrics"
 	"poke/internal/server/request"
+	"poke/internal/server/tracing"
+	"slices"
 	"strings"
 	"time"
 )
 
 type HTTPListener struct {
-	srv *http.Server
+	srv    *http.Server
+	jobs   *jobs.Registry
+	queue  QueueStats   // serves GET /queue, nil = not exposed
+	health HealthRoutes // serves /healthz and /readyz, nil = not exposed
+	// rejects payloads before enqueueing, nil = checked at execution only
+	payloads PayloadPolicy
+}
+
+// NewHTTPListener constructs an HTTP listener that registers jobs in registry.
+func NewHTTPListener(registry *jobs.Registry) *HTTPListener {
+	return &HTTPListener{jobs: registry}
 }
 
 type httpCommandRequest struct {
 	CommandID string `json:"command_id"`
+	Payload   string `json:"payload,omitempty"`
+	// Alternative to the Idempotency-Key header
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// Delayed execution, at most one of both
+	RunAt *time.Time `json:"run_at,omitempty"`
+	Delay string     `json:"delay,omitempty"`
+	// Overrides the command's priority within the caller's allowed range
+	Priority *int `json:"priority,omitempty"`
 }
 
 // HTTPListenerTLSConfig defines TLS settings for the HTTP listener.
@@ -126,13 +150,16 @@ func ensureReadableFile(path string) error {
 
 // Config for HTTP listener.
 type HTTPListenerConfig struct {
-	Host         string                 `yaml:"host,omitempty"`
-	Port         int                    `yaml:"port,omitempty"`
-	ReadTimeout  time.Duration          `yaml:"read_timeout,omitempty"`
-	WriteTimeout time.Duration          `yaml:"write_timeout,omitempty"`
-	IdleTimeout  time.Duration          `yaml:"idle_timeout,omitempty"`
-	TLS          *HTTPListenerTLSConfig `yaml:"tls,omitempty"`
-	Auth         *auth.Auth             `yaml:"auth,omitempty"`
+	Host         string        `yaml:"host,omitempty"`
+	Port         int           `yaml:"port,omitempty"`
+	ReadTimeout  time.Duration `yaml:"read_timeout,omitempty"`
+	WriteTimeout time.Duration `yaml:"write_timeout,omitempty"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout,omitempty"`
+	// How long an idempotency key maps to its original job, 0 = keys are ignored
+	IdempotencyWindow time.Duration          `yaml:"idempotency_window,omitempty"`
+	TLS               *HTTPListenerTLSConfig `yaml:"tls,omitempty"`
+	Auth              *auth.Auth             `yaml:"auth,omitempty"`
+	AdminAuth         *auth.Auth             `yaml:"admin_auth,omitempty"` // credentials for /admin routes, nil = disabled
 }
 
 const (
@@ -144,30 +171,68 @@ const (
 	httpListenerType        = "http"             // Listener type identifier used in auth contexts.
 	httpAPITokenHeader      = "X-Poke-API-Token" // #nosec G101 -- Header key identifier, not a secret.
 	httpAuthMethodHeader    = "X-Poke-Auth-Method"
+	httpMaxRequestBodySize  = 1 << 20 // Upper bound for request bodies, payload limits are per command.
+	httpIdempotencyHeader   = "Idempotency-Key"
+	httpReplayedHeader      = "Idempotent-Replayed"
+	httpCoalescedHeader     = "X-Poke-Coalesced"
+	httpRequestIDHeader     = "X-Request-ID"
+	httpMaxRequestID        = 128            // Longer X-Request-ID values are replaced by a generated ID.
+	httpMaxIdempotencyKey   = 255            // Maximum idempotency key length in bytes.
+	defaultIdempotencyWin   = 24 * time.Hour // Default window for idempotency keys.
+	httpRunAtClockSkew      = time.Minute    // run_at this far in the past still runs immediately.
 )
 
-// validateHTTPCommandAuth validates request-scoped auth when listener auth validators are configured.
-func validateHTTPCommandAuth(cfg HTTPListenerConfig, headers http.Header) error {
+// validateHTTPCommandAuth validates request-scoped auth when listener auth validators are configured
+// and returns the authenticated principal. Failures are counted per auth method.
+func validateHTTPCommandAuth(cfg HTTPListenerConfig, headers http.Header) (string, error) {
 	if cfg.Auth == nil || len(cfg.Auth.Validators) == 0 {
-		return nil
+		return auth.AnonymousPrincipal(httpListenerType), nil
+	}
+	authCtx, err := authenticateHTTPHeaders(cfg.Auth, headers)
+	if err != nil {
+		metrics.AuthFailures.Inc(httpListenerType, httpAuthFailureMethod(cfg.Auth, headers))
+		return "", err
 	}
+	return authCtx.Principal(), nil
+}
 
+// validateHTTPAdminAuth validates admin requests against admin_auth only, so
+// command credentials never reach the admin routes, and returns the admin
+// principal. It returns errHTTPAdminDisabled without admin_auth.
+func validateHTTPAdminAuth(cfg HTTPListenerConfig, headers http.Header) (string, error) {
+	if cfg.AdminAuth == nil || len(cfg.AdminAuth.Validators) == 0 {
+		return "", errHTTPAdminDisabled
+	}
+	authCtx, err := authenticateHTTPHeaders(cfg.AdminAuth, headers)
+	if err != nil {
+		metrics.AuthFailures.Inc(httpListenerType, httpAuthFailureMethod(cfg.AdminAuth, headers))
+		return "", err
+	}
+	return authCtx.AdminPrincipal(), nil
+}
+
+// authenticateHTTPHeaders runs the validator of validators selected by the
+// auth method header.
+func authenticateHTTPHeaders(validators *auth.Auth, headers http.Header) (auth.AuthContext, error) {
 	method := strings.TrimSpace(headers.Get(httpAuthMethodHeader))
 	if method == "" {
-		return fmt.Errorf("auth method header %q is required", httpAuthMethodHeader)
+		return auth.AuthContext{}, fmt.Errorf("auth method header %q is required", httpAuthMethodHeader)
 	}
 
-	validator, exists := cfg.Auth.Validators[method]
+	validator, exists := validators.Validators[method]
 	if !exists {
-		return fmt.Errorf("auth method %q is not configured", method)
+		return auth.AuthContext{}, fmt.Errorf("auth method %q is not configured", method)
 	}
 
 	authCtx, err := buildHTTPAuthContext(method, headers)
 	if err != nil {
-		return err
+		return auth.AuthContext{}, err
+	}
+	if err := validator.Validate(&authCtx); err != nil {
+		return auth.AuthContext{}, err
 	}
 
-	return validator.Validate(&authCtx)
+	return authCtx, nil
 }
 
 // buildHTTPAuthContext maps a request auth method to its auth context.
@@ -184,18 +249,21 @@ func buildHTTPAuthContext(method string, headers http.Header) (auth.AuthContext,
 // UnmarshalYAML parses HTTP listener config per docs/configuration/listener.md.
 func (cfg *HTTPListenerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type httpListenerConfigInput struct {
-		Host         *string                `yaml:"host"`
-		Port         *int                   `yaml:"port"`
-		ReadTimeout  *time.Duration         `yaml:"read_timeout"`
-		WriteTimeout *time.Duration         `yaml:"write_timeout"`
-		IdleTimeout  *time.Duration         `yaml:"idle_timeout"`
-		TLS          *HTTPListenerTLSConfig `yaml:"tls"`
-		Auth         *auth.Auth             `yaml:"auth"`
+		Host              *string                `yaml:"host"`
+		Port              *int                   `yaml:"port"`
+		ReadTimeout       *time.Duration         `yaml:"read_timeout"`
+		WriteTimeout      *time.Duration         `yaml:"write_timeout"`
+		IdleTimeout       *time.Duration         `yaml:"idle_timeout"`
+		IdempotencyWindow *time.Duration         `yaml:"idempotency_window"`
+		TLS               *HTTPListenerTLSConfig `yaml:"tls"`
+		Auth              *auth.Auth             `yaml:"auth"`
+		AdminAuth         *auth.Auth             `yaml:"admin_auth"`
 	}
 
 	*cfg = HTTPListenerConfig{
-		Host: defaultHTTPListenerHost,
-		Port: defaultHTTPListenerPort,
+		Host:              defaultHTTPListenerHost,
+		Port:              defaultHTTPListenerPort,
+		IdempotencyWindow: defaultIdempotencyWin,
 	}
 
 	var in httpListenerConfigInput
@@ -218,12 +286,12 @@ func (cfg *HTTPListenerConfig) UnmarshalYAML(unmarshal func(interface{}) error)
 	if in.IdleTimeout != nil {
 		cfg.IdleTimeout = *in.IdleTimeout
 	}
-	if in.TLS != nil {
-		cfg.TLS = in.TLS
-	}
-	if in.Auth != nil {
-		cfg.Auth = in.Auth
+	if in.IdempotencyWindow != nil {
+		cfg.IdempotencyWindow = *in.IdempotencyWindow
 	}
+	cfg.TLS = in.TLS
+	cfg.Auth = in.Auth
+	cfg.AdminAuth = in.AdminAuth
 
 	return cfg.validate()
 }
@@ -236,6 +304,9 @@ func (cfg HTTPListenerConfig) validate() error {
 	if cfg.Port < minHTTPListenerPort || cfg.Port > maxHTTPListenerPort {
 		return fmt.Errorf("port must be between %d and %d", minHTTPListenerPort, maxHTTPListenerPort)
 	}
+	if cfg.IdempotencyWindow < 0 {
+		return fmt.Errorf("idempotency_window must not be negative")
+	}
 	if cfg.TLS != nil {
 		if err := cfg.TLS.validate(); err != nil {
 			return err
@@ -247,6 +318,20 @@ func (cfg HTTPListenerConfig) validate() error {
 	if len(cfg.Auth.Validators) == 0 {
 		return fmt.Errorf("auth must configure at least one method")
 	}
+	return cfg.validateAdminAuth()
+}
+
+// validateAdminAuth rejects admin credentials shared with command requests.
+func (cfg HTTPListenerConfig) validateAdminAuth() error {
+	if cfg.AdminAuth == nil {
+		return nil
+	}
+	commandSecrets := cfg.Auth.Secrets()
+	for _, secret := range cfg.AdminAuth.Secrets() {
+		if slices.Contains(commandSecrets, secret) {
+			return fmt.Errorf("admin_auth must not reuse a credential of auth")
+		}
+	}
 	return nil
 }
 
@@ -264,8 +349,12 @@ func (l *HTTPListener) Listen(ctx context.Context, cfg HTTPListenerConfig, ch ch
 		IdleTimeout:  cfg.IdleTimeout,
 	}
 
+	if l.jobs == nil {
+		l.jobs = jobs.NewRegistry()
+	}
+
 	logHTTPListenerStart(logger, cfg)
-	l.srv.Handler = newHTTPHandler(ctx, cfg, ch)
+	l.srv.Handler = newHTTPHandler(ctx, cfg, ch, l.jobs, l.queue, l.health, l.payloads)
 
 	srvListener, err := buildHTTPServerListener(cfg)
 	if err != nil {
@@ -278,6 +367,15 @@ func (l *HTTPListener) Listen(ctx context.Context, cfg HTTPListenerConfig, ch ch
 	return nil
 }
 
+// Shutdown gracefully stops the server started by Listen; it does nothing
+// when Listen was not called.
+func (l *HTTPListener) Shutdown(ctx context.Context) error {
+	if l.srv == nil {
+		return nil
+	}
+	return l.srv.Shutdown(ctx)
+}
+
 func logHTTPListenerStart(logger *slog.Logger, cfg HTTPListenerConfig) {
 	if cfg.TLS != nil {
 		logger.Info("listener starting with tls", "event", "listener_starting_tls", "listener", "http", "address", cfg.address())
@@ -286,61 +384,370 @@ func logHTTPListenerStart(logger *slog.Logger, cfg HTTPListenerConfig) {
 	logger.Info("listener starting without tls", "event", "listener_starting_plain", "listener", "http", "address", cfg.address())
 }
 
-func newHTTPHandler(ctx context.Context, cfg HTTPListenerConfig, ch chan<- request.CommandRequest) http.Handler {
+func newHTTPHandler(ctx context.Context, cfg HTTPListenerConfig, ch chan<- request.CommandRequest, registry *jobs.Registry, queue QueueStats, health HealthRoutes, payloads PayloadPolicy) http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		handleHTTPCommandRequest(ctx, cfg, ch, w, r)
+		rec := &httpStatusRecorder{ResponseWriter: w}
+		r = withHTTPRequestID(rec, r)
+		entry := newHTTPAuditRecord(r)
+		r, span := startHTTPRequestSpan(r)
+		handleHTTPCommandRequest(ctx, cfg, ch, registry, payloads, rec, r, entry)
+		endHTTPRequestSpan(span, rec.status)
+		metrics.Requests.Inc(httpListenerType, httpRequestOutcome(rec.status))
+		auditHTTPRequest(entry, rec.status)
+	})
+	mux.HandleFunc("GET /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleHTTPJobGet(cfg, registry, w, r)
 	})
+	mux.HandleFunc("DELETE /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		rec := &httpStatusRecorder{ResponseWriter: w}
+		r = withHTTPRequestID(rec, r)
+		entry := newHTTPAuditRecord(r)
+		entry.Event = audit.EventCancel
+		handleHTTPJobCancel(cfg, registry, rec, r, entry)
+		auditHTTPRequest(entry, rec.status)
+	})
+	if queue != nil {
+		mux.HandleFunc("GET /queue", func(w http.ResponseWriter, r *http.Request) {
+			handleHTTPQueueGet(cfg, ch, queue, w, r)
+		})
+	}
+	registerHTTPAdminRoutes(mux, cfg)
+	if health != nil {
+		health.RegisterRoutes(mux)
+	}
 	return mux
 }
 
-func handleHTTPCommandRequest(ctx context.Context, cfg HTTPListenerConfig, ch chan<- request.CommandRequest, w http.ResponseWriter, r *http.Request) {
-	logger := slog.Default().With("component", "listener/http")
+// handleHTTPCommandRequest validates, authenticates and submits a command
+// request, recording what it learns about the request in entry.
+func handleHTTPCommandRequest(ctx context.Context, cfg HTTPListenerConfig, ch chan<- request.CommandRequest, registry *jobs.Registry, payloads PayloadPolicy, w http.ResponseWriter, r *http.Request, entry *audit.Record) {
+	logger := request.Logger(r.Context(), slog.Default().With("component", "listener/http"))
 	logger.Info("request received", "event", "request_received", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr)
 	if r.Method != http.MethodPut {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	req, err := decodeHTTPCommandRequest(r)
+	req, err := decodeHTTPCommandRequest(w, r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		logger.Warn("request body too large", "event", "request_body_too_large", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "limit", tooLarge.Limit)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
 	if err != nil {
 		logger.Warn("invalid json", "event", "request_invalid_json", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	entry.CommandID = req.CommandID
 	if req.CommandID == "" {
 		logger.Warn("missing command id", "event", "request_missing_command_id", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	if err := validateHTTPCommandAuth(cfg, r.Header); err != nil {
+	idempotencyKey, err := httpIdempotencyKey(r.Header, req)
+	if err != nil {
+		logger.Warn("invalid idempotency key", "event", "request_invalid_idempotency_key", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "command_id", req.CommandID, "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	runAt, err := httpRunAt(req, time.Now())
+	if err != nil {
+		logger.Warn("invalid schedule", "event", "request_invalid_schedule", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "command_id", req.CommandID, "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	_, authSpan := tracing.Start(r.Context(), "poke.auth")
+	principal, err := validateHTTPCommandAuth(cfg, r.Header)
+	authSpan.SetAttrs("poke.principal", principal)
+	authSpan.RecordError(err)
+	authSpan.End()
+	if err != nil {
 		logger.Warn("auth failed", "event", "request_auth_failed", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "command_id", req.CommandID, "error", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
+	entry.Principal = principal
+	if !checkHTTPSubmission(cfg, payloads, w, r, req, principal, logger) {
+		return
+	}
+
+	sub := jobs.Submission{
+		CommandID:      req.CommandID,
+		Principal:      principal,
+		IdempotencyKey: idempotencyKey,
+		Window:         cfg.IdempotencyWindow,
+		RunAt:          runAt,
+		Priority:       req.Priority,
+		RequestID:      request.IDFromContext(r.Context()),
+	}
+	if req.Payload != "" {
+		sub.Payload = []byte(req.Payload)
+	}
+	entry.Parameters = audit.NewParameters(sub.Payload, sub.Priority, sub.RunAt, sub.IdempotencyKey)
+	enqueueCtx, enqueueSpan := tracing.Start(r.Context(), "poke.enqueue", tracing.WithAttrs("poke.command_id", req.CommandID))
+	defer enqueueSpan.End()
+	submitHTTPCommandRequest(ctx, ch, registry, sub, tracing.TraceparentFromContext(enqueueCtx), w, logger, entry)
+}
+
+// submitHTTPCommandRequest registers sub as a job and enqueues it unless it
+// repeats an earlier submission with the same idempotency key. The dispatcher
+// continues the trace identified by traceparent. The job ID and admission
+// are recorded in entry.
+func submitHTTPCommandRequest(ctx context.Context, ch chan<- request.CommandRequest, registry *jobs.Registry, sub jobs.Submission, traceparent string, w http.ResponseWriter, logger *slog.Logger, entry *audit.Record) {
+	job, admission, err := registry.Enqueue(sub)
+	if errors.Is(err, jobs.ErrBeyondMaxDelay) {
+		logger.Warn("schedule beyond max delay", "event", "request_beyond_max_delay", "listener", "http", "command_id", sub.CommandID, "run_at", sub.RunAt)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logger.Error("job registration failed", "event", "request_job_failed", "listener", "http", "command_id", sub.CommandID, "error", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	entry.JobID = job.ID
+	switch admission {
+	case jobs.Replayed:
+		logger.Info("idempotent request replayed", "event", "request_replayed", "listener", "http", "job_id", job.ID, "command_id", sub.CommandID, "principal", sub.Principal, "idempotency_key", sub.IdempotencyKey)
+		entry.Admission = audit.AdmissionReplayed
+		w.Header().Set(httpReplayedHeader, "true")
+		writeHTTPJob(w, http.StatusOK, job, logger)
+		return
+	case jobs.Coalesced:
+		logger.Info("request coalesced into queued job", "event", "request_coalesced", "listener", "http", "job_id", job.ID, "command_id", sub.CommandID, "principal", sub.Principal, "coalesced", job.Coalesced)
+		entry.Admission = audit.AdmissionCoalesced
+		w.Header().Set(httpCoalescedHeader, "true")
+		writeHTTPJob(w, http.StatusAccepted, job, logger)
+		return
+	}
 
-	if !enqueueHTTPCommandRequest(ctx, ch, req.CommandID, logger) {
+	cmdReq := request.CommandRequest{JobID: job.ID, CommandID: sub.CommandID, Payload: sub.Payload, RunAt: sub.RunAt, Priority: sub.Priority, TraceParent: traceparent, RequestID: sub.RequestID}
+	if !enqueueHTTPCommandRequest(ctx, ch, cmdReq, logger) {
+		registry.Discard(job.ID)
 		w.WriteHeader(http.StatusServiceUnavailable)
 		return
 	}
-	w.WriteHeader(http.StatusAccepted)
+	registry.MarkEnqueued(job.ID)
+	writeHTTPJob(w, http.StatusAccepted, job, logger)
+}
+
+// checkHTTPSubmission checks an authenticated request's priority override and
+// payload against what principal and the command allow, answering 403, 400
+// or 413 when it must not be enqueued.
+func checkHTTPSubmission(cfg HTTPListenerConfig, payloads PayloadPolicy, w http.ResponseWriter, r *http.Request, req httpCommandRequest, principal string, logger *slog.Logger) bool {
+	if err := httpPriorityAllowed(cfg, r.Header, req); err != nil {
+		logger.Warn("priority not allowed", "event", "request_priority_forbidden", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "command_id", req.CommandID, "principal", principal, "error", err)
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	if payloads == nil {
+		return true
+	}
+	err := payloads.CheckPayload(req.CommandID, []byte(req.Payload))
+	switch {
+	case errors.Is(err, executor.ErrPayloadTooLarge):
+		logger.Warn("payload too large", "event", "request_payload_too_large", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "command_id", req.CommandID, "principal", principal, "error", err)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return false
+	case err != nil:
+		logger.Warn("payload not accepted", "event", "request_payload_rejected", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "command_id", req.CommandID, "principal", principal, "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// httpPriorityAllowed checks a priority override against the range configured
+// for the request's auth method; without auth no override is allowed.
+func httpPriorityAllowed(cfg HTTPListenerConfig, headers http.Header, req httpCommandRequest) error {
+	if req.Priority == nil {
+		return nil
+	}
+	method := strings.TrimSpace(headers.Get(httpAuthMethodHeader))
+	if !cfg.Auth.AllowsPriority(method, *req.Priority) {
+		return fmt.Errorf("priority %d is outside the range allowed for auth method %q", *req.Priority, method)
+	}
+	return nil
+}
+
+// httpRunAt resolves `run_at` or `delay` to an absolute time, zero when neither is set.
+func httpRunAt(req httpCommandRequest, now time.Time) (time.Time, error) {
+	switch {
+	case req.RunAt != nil && req.Delay != "":
+		return time.Time{}, fmt.Errorf("run_at and delay are mutually exclusive")
+	case req.RunAt != nil:
+		if req.RunAt.Before(now.Add(-httpRunAtClockSkew)) {
+			return time.Time{}, fmt.Errorf("run_at %s is in the past", req.RunAt.Format(time.RFC3339))
+		}
+		return *req.RunAt, nil
+	case req.Delay != "":
+		delay, err := time.ParseDuration(req.Delay)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("delay: %w", err)
+		}
+		if delay < 0 {
+			return time.Time{}, fmt.Errorf("delay must not be negative")
+		}
+		return now.Add(delay), nil
+	default:
+		return time.Time{}, nil
+	}
+}
+
+// httpIdempotencyKey returns the request's idempotency key from the header or body.
+func httpIdempotencyKey(headers http.Header, req httpCommandRequest) (string, error) {
+	header := strings.TrimSpace(headers.Get(httpIdempotencyHeader))
+	body := strings.TrimSpace(req.IdempotencyKey)
+	if header != "" && body != "" && header != body {
+		return "", fmt.Errorf("%s header and idempotency_key differ", httpIdempotencyHeader)
+	}
+
+	key := header
+	if key == "" {
+		key = body
+	}
+	if len(key) > httpMaxIdempotencyKey {
+		return "", fmt.Errorf("idempotency key exceeds %d bytes", httpMaxIdempotencyKey)
+	}
+	for _, c := range key {
+		if c < 0x20 || c > 0x7e {
+			return "", fmt.Errorf("idempotency key must be printable ASCII")
+		}
+	}
+	return key, nil
+}
+
+// withHTTPRequestID returns r carrying its correlation ID, taken from the
+// X-Request-ID header or generated when the header is missing or invalid, and
+// echoes the ID in the response header.
+func withHTTPRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	id := strings.TrimSpace(r.Header.Get(httpRequestIDHeader))
+	if !validHTTPRequestID(id) {
+		id = request.NewID()
+	}
+	w.Header().Set(httpRequestIDHeader, id)
+	return r.WithContext(request.ContextWithID(r.Context(), id))
+}
+
+// validHTTPRequestID reports whether a client request ID is non-empty,
+// printable ASCII and at most httpMaxRequestID bytes.
+func validHTTPRequestID(id string) bool {
+	if id == "" || len(id) > httpMaxRequestID {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// handleHTTPJobGet reports the current state of a job.
+func handleHTTPJobGet(cfg HTTPListenerConfig, registry *jobs.Registry, w http.ResponseWriter, r *http.Request) {
+	logger := slog.Default().With("component", "listener/http")
+	id := r.PathValue("id")
+	if _, err := validateHTTPCommandAuth(cfg, r.Header); err != nil {
+		logger.Warn("auth failed", "event", "request_auth_failed", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "job_id", id, "error", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	job, ok := registry.Get(id)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writeHTTPJob(w, http.StatusOK, job, logger)
+}
+
+// handleHTTPJobCancel removes a queued job or stops a running one.
+//
+// Queued jobs are canceled immediately (200), running jobs are signaled and
+// finish asynchronously (202). The caller and resulting state are recorded in
+// entry.
+func handleHTTPJobCancel(cfg HTTPListenerConfig, registry *jobs.Registry, w http.ResponseWriter, r *http.Request, entry *audit.Record) {
+	logger := slog.Default().With("component", "listener/http")
+	id := r.PathValue("id")
+	entry.JobID = id
+	logger.Info("job cancel received", "event", "job_cancel_received", "listener", "http", "job_id", id, "remote_addr", r.RemoteAddr)
+
+	principal, err := validateHTTPCommandAuth(cfg, r.Header)
+	if err != nil {
+		logger.Warn("auth failed", "event", "request_auth_failed", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "job_id", id, "error", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	entry.Principal = principal
+
+	job, err := registry.Cancel(id, principal)
+	entry.CommandID = job.CommandID
+	entry.State = string(job.State)
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, jobs.ErrJobFinished):
+		writeHTTPJob(w, http.StatusConflict, job, logger)
+	case job.State == jobs.StateCanceled:
+		logger.Info("queued job canceled", "event", "job_canceled", "listener", "http", "job_id", id, "command_id", job.CommandID, "canceled_by", principal)
+		writeHTTPJob(w, http.StatusOK, job, logger)
+	default:
+		logger.Info("running job cancellation requested", "event", "job_cancel_requested", "listener", "http", "job_id", id, "command_id", job.CommandID, "canceled_by", principal)
+		writeHTTPJob(w, http.StatusAccepted, job, logger)
+	}
+}
+
+// httpQueueResponse is the body of GET /queue.
+type httpQueueResponse struct {
+	Depths  map[int]int `json:"depths"`  // requests waiting in the dispatcher per priority
+	Backlog int         `json:"backlog"` // requests buffered before the dispatcher sorted them
+}
+
+// handleHTTPQueueGet reports how many requests wait for execution per priority.
+func handleHTTPQueueGet(cfg HTTPListenerConfig, ch chan<- request.CommandRequest, queue QueueStats, w http.ResponseWriter, r *http.Request) {
+	logger := slog.Default().With("component", "listener/http")
+	if _, err := validateHTTPCommandAuth(cfg, r.Header); err != nil {
+		logger.Warn("auth failed", "event", "request_auth_failed", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "error", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	body := httpQueueResponse{Depths: queue.QueueDepths(), Backlog: len(ch)}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Warn("queue response write failed", "event", "response_write_failed", "listener", "http", "error", err)
+	}
+}
+
+// writeHTTPJob writes a job snapshot as the JSON response body.
+func writeHTTPJob(w http.ResponseWriter, status int, job jobs.Job, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logger.Warn("response write failed", "event", "response_write_failed", "listener", "http", "job_id", job.ID, "error", err)
+	}
 }
 
-func decodeHTTPCommandRequest(r *http.Request) (httpCommandRequest, error) {
+func decodeHTTPCommandRequest(w http.ResponseWriter, r *http.Request) (httpCommandRequest, error) {
 	var req httpCommandRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	body := http.MaxBytesReader(w, r.Body, httpMaxRequestBodySize)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
 		return httpCommandRequest{}, err
 	}
 	return req, nil
 }
 
-func enqueueHTTPCommandRequest(ctx context.Context, ch chan<- request.CommandRequest, commandID string, logger *slog.Logger) bool {
+func enqueueHTTPCommandRequest(ctx context.Context, ch chan<- request.CommandRequest, req request.CommandRequest, logger *slog.Logger) bool {
 	select {
 	case <-ctx.Done():
-		logger.Warn("context canceled before enqueue", "event", "request_enqueue_canceled", "listener", "http", "command_id", commandID)
+		logger.Warn("context canceled before enqueue", "event", "request_enqueue_canceled", "listener", "http", "command_id", req.CommandID)
 		return false
-	case ch <- request.CommandRequest{CommandID: commandID}:
-		logger.Info("request enqueued", "event", "request_enqueued", "listener", "http", "command_id", commandID)
+	case ch <- req:
+		logger.Info("request enqueued", "event", "request_enqueued", "listener", "http", "job_id", req.JobID, "command_id", req.CommandID, "payload_bytes", len(req.Payload))
 		return true
 	}
 }
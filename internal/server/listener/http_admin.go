@@ -0,0 +1,123 @@
+package listener
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"poke/internal/server/logging"
+	"strings"
+	"time"
+)
+
+// errHTTPAdminDisabled rejects admin requests on listeners without admin_auth.
+var errHTTPAdminDisabled = errors.New("admin routes require admin_auth")
+
+// httpLogLevelRequest is the body of PUT /admin/log-level.
+type httpLogLevelRequest struct {
+	Level     string `json:"level"`
+	Component string `json:"component,omitempty"` // empty for all sinks
+	Duration  string `json:"duration,omitempty"`  // Go duration, default level_revert_after
+}
+
+// registerHTTPAdminRoutes adds the runtime administration routes.
+func registerHTTPAdminRoutes(mux *http.ServeMux, cfg HTTPListenerConfig) {
+	mux.HandleFunc("GET /admin/log-level", func(w http.ResponseWriter, r *http.Request) {
+		handleHTTPLogLevelGet(cfg, w, r)
+	})
+	mux.HandleFunc("PUT /admin/log-level", func(w http.ResponseWriter, r *http.Request) {
+		handleHTTPLogLevelSet(cfg, w, r)
+	})
+	mux.HandleFunc("DELETE /admin/log-level", func(w http.ResponseWriter, r *http.Request) {
+		handleHTTPLogLevelReset(cfg, w, r)
+	})
+}
+
+// authorizeHTTPAdmin authenticates r against admin_auth and returns the admin
+// principal. It answers 403 without admin_auth and 401 for bad credentials.
+func authorizeHTTPAdmin(cfg HTTPListenerConfig, w http.ResponseWriter, r *http.Request, logger *slog.Logger) (string, bool) {
+	principal, err := validateHTTPAdminAuth(cfg, r.Header)
+	if err == nil {
+		return principal, true
+	}
+	logger.Warn("auth failed", "event", "request_auth_failed", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "error", err)
+	if errors.Is(err, errHTTPAdminDisabled) {
+		w.WriteHeader(http.StatusForbidden)
+	} else {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+	return "", false
+}
+
+// handleHTTPLogLevelGet reports the configured sink levels and active overrides.
+func handleHTTPLogLevelGet(cfg HTTPListenerConfig, w http.ResponseWriter, r *http.Request) {
+	logger := slog.Default().With("component", "listener/http")
+	if _, ok := authorizeHTTPAdmin(cfg, w, r, logger); !ok {
+		return
+	}
+	writeHTTPAdminJSON(w, logging.Levels(), logger)
+}
+
+// handleHTTPLogLevelSet overrides the level of all sinks or of one component
+// until the requested duration has passed.
+func handleHTTPLogLevelSet(cfg HTTPListenerConfig, w http.ResponseWriter, r *http.Request) {
+	logger := slog.Default().With("component", "listener/http")
+	principal, ok := authorizeHTTPAdmin(cfg, w, r, logger)
+	if !ok {
+		return
+	}
+
+	override, err := setHTTPLogLevel(w, r)
+	if err != nil {
+		logger.Warn("invalid log level request", "event", "request_invalid_log_level", "listener", "http", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	logger.Info("log level changed", "event", "log_level_changed", "listener", "http", "target_component", override.Component, "level", override.Level, "until", override.Until, "changed_by", principal)
+	writeHTTPAdminJSON(w, override, logger)
+}
+
+// handleHTTPLogLevelReset drops the override of the component query
+// parameter, or all overrides without one.
+func handleHTTPLogLevelReset(cfg HTTPListenerConfig, w http.ResponseWriter, r *http.Request) {
+	logger := slog.Default().With("component", "listener/http")
+	principal, ok := authorizeHTTPAdmin(cfg, w, r, logger)
+	if !ok {
+		return
+	}
+
+	component := strings.TrimSpace(r.URL.Query().Get("component"))
+	logging.ResetLevel(component)
+	logger.Info("log level reset", "event", "log_level_reset", "listener", "http", "target_component", component, "changed_by", principal)
+	writeHTTPAdminJSON(w, logging.Levels(), logger)
+}
+
+// setHTTPLogLevel decodes the request body and applies its override.
+func setHTTPLogLevel(w http.ResponseWriter, r *http.Request) (logging.LevelOverride, error) {
+	var req httpLogLevelRequest
+	body := http.MaxBytesReader(w, r.Body, httpMaxRequestBodySize)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return logging.LevelOverride{}, err
+	}
+
+	var d time.Duration
+	if req.Duration != "" {
+		parsed, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			return logging.LevelOverride{}, err
+		}
+		if parsed <= 0 {
+			return logging.LevelOverride{}, errors.New("duration must be positive")
+		}
+		d = parsed
+	}
+	return logging.SetLevel(req.Component, req.Level, d)
+}
+
+// writeHTTPAdminJSON writes body as the JSON response.
+func writeHTTPAdminJSON(w http.ResponseWriter, body any, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Warn("response write failed", "event", "response_write_failed", "listener", "http", "error", err)
+	}
+}
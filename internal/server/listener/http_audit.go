@@ -0,0 +1,38 @@
+package listener
+
+import (
+	"net"
+	"net/http"
+	"poke/internal/server/audit"
+	"poke/internal/server/request"
+)
+
+// newHTTPAuditRecord starts the audit record of a command request; the
+// handler fills in what it learns about the request.
+func newHTTPAuditRecord(r *http.Request) *audit.Record {
+	return &audit.Record{Event: audit.EventRequest, Listener: httpListenerType, SourceIP: httpSourceIP(r), RequestID: request.IDFromContext(r.Context())}
+}
+
+// auditHTTPRequest records the decision the response status stands for.
+func auditHTTPRequest(rec *audit.Record, status int) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	rec.Status = status
+	rec.Decision = audit.DecisionRejected
+	if status == http.StatusOK || status == http.StatusAccepted {
+		rec.Decision = audit.DecisionAccepted
+	}
+	audit.Write(*rec)
+}
+
+// httpSourceIP returns the peer address of r without its port.
+//
+// Forwarding headers are not trusted, behind a proxy this is the proxy.
+func httpSourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
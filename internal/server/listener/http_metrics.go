@@ -0,0 +1,54 @@
+package listener
+
+import (
+	"net/http"
+	"poke/internal/server/auth"
+	"poke/internal/server/metrics"
+	"strconv"
+	"strings"
+)
+
+// httpStatusRecorder remembers the status code written by a handler.
+type httpStatusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *httpStatusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *httpStatusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// httpRequestOutcome maps a response status to the poke_requests_total outcome label.
+func httpRequestOutcome(status int) string {
+	switch status {
+	case 0:
+		return strconv.Itoa(http.StatusOK)
+	case http.StatusAccepted:
+		return metrics.OutcomeAccepted
+	default:
+		return strconv.Itoa(status)
+	}
+}
+
+// httpAuthFailureMethod returns the auth method label for a failed authentication:
+// the configured method, "none" without a method header or "unknown" otherwise.
+func httpAuthFailureMethod(validators *auth.Auth, headers http.Header) string {
+	method := strings.TrimSpace(headers.Get(httpAuthMethodHeader))
+	if method == "" {
+		return "none"
+	}
+	if _, ok := validators.Validators[method]; !ok {
+		return "unknown"
+	}
+	return method
+}
@@ -0,0 +1,30 @@
+package listener
+
+import (
+	"fmt"
+	"net/http"
+	"poke/internal/server/tracing"
+)
+
+// startHTTPRequestSpan starts the server span for a command request, joining
+// the caller's trace when it sent a valid traceparent header.
+func startHTTPRequestSpan(r *http.Request) (*http.Request, *tracing.Span) {
+	parent := tracing.ContextWithTraceparent(r.Context(), r.Header.Get(tracing.TraceparentHeader))
+	ctx, span := tracing.Start(parent, "poke.http.request",
+		tracing.WithKind(tracing.SpanKindServer),
+		tracing.WithAttrs("poke.listener", httpListenerType, "http.request.method", r.Method, "url.path", r.URL.Path, "client.address", r.RemoteAddr),
+	)
+	return r.WithContext(ctx), span
+}
+
+// endHTTPRequestSpan records the response status on span and ends it.
+func endHTTPRequestSpan(span *tracing.Span, status int) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	span.SetAttrs("http.response.status_code", status)
+	if status >= http.StatusBadRequest {
+		span.RecordError(fmt.Errorf("%d %s", status, http.StatusText(status)))
+	}
+	span.End()
+}
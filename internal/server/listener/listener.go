@@ -3,6 +3,8 @@ package listener
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"poke/internal/server/jobs"
 	"poke/internal/server/request"
 	"sort"
 
@@ -13,6 +15,22 @@ type RequestSource[T any] interface {
 	Listen(ctx context.Context, cfg T, ch chan<- request.CommandRequest) error
 }
 
+// QueueStats reports the requests waiting for the dispatcher per priority.
+type QueueStats interface {
+	QueueDepths() map[int]int
+}
+
+// PayloadPolicy checks a request payload against the command it targets
+// before the request is enqueued.
+type PayloadPolicy interface {
+	CheckPayload(commandID string, payload []byte) error
+}
+
+// HealthRoutes adds unauthenticated health endpoints to a listener's routes.
+type HealthRoutes interface {
+	RegisterRoutes(mux *http.ServeMux)
+}
+
 type Listener struct {
 	listener interface{}
 	config   interface{}
@@ -57,7 +75,11 @@ func (lc *ListenerConfig) UnmarshalYAML(unmarshall func(interface{}) error) erro
 }
 
 // StartAll starts all configured listeners and returns the started instances.
-func (lc ListenerConfig) StartAll(ctx context.Context, ch chan<- request.CommandRequest) ([]Listener, error) {
+//
+// Listeners register accepted requests as jobs in registry, report queue
+// depths from queue, serve health endpoints from health and reject payloads
+// payloads does not accept; queue, health and payloads may be nil.
+func (lc ListenerConfig) StartAll(ctx context.Context, ch chan<- request.CommandRequest, registry *jobs.Registry, queue QueueStats, health HealthRoutes, payloads PayloadPolicy) ([]Listener, error) {
 	if len(lc.listeners) == 0 {
 		return nil, nil
 	}
@@ -81,6 +103,10 @@ func (lc ListenerConfig) StartAll(ctx context.Context, ch chan<- request.Command
 			if !ok {
 				return nil, fmt.Errorf("listener http: invalid config type %T", entry.config)
 			}
+			httpListener.jobs = registry
+			httpListener.queue = queue
+			httpListener.health = health
+			httpListener.payloads = payloads
 			if err := httpListener.Listen(ctx, cfg, ch); err != nil {
 				return nil, fmt.Errorf("listener http: %w", err)
 			}
@@ -93,6 +119,47 @@ func (lc ListenerConfig) StartAll(ctx context.Context, ch chan<- request.Command
 	return started, nil
 }
 
+// Shutdown stops l from accepting requests and waits for the requests it is
+// handling until ctx is done.
+func (l Listener) Shutdown(ctx context.Context) error {
+	switch listener := l.listener.(type) {
+	case *HTTPListener:
+		return listener.Shutdown(ctx)
+	default:
+		return fmt.Errorf("unsupported listener %T", l.listener)
+	}
+}
+
+// Len returns the number of configured listeners.
+func (lc ListenerConfig) Len() int {
+	return len(lc.listeners)
+}
+
+// Secrets returns the auth and admin_auth credentials of all configured listeners.
+func (lc ListenerConfig) Secrets() []string {
+	var out []string
+	for _, l := range lc.listeners {
+		if cfg, ok := l.config.(HTTPListenerConfig); ok {
+			out = append(out, cfg.Auth.Secrets()...)
+			if cfg.AdminAuth != nil {
+				out = append(out, cfg.AdminAuth.Secrets()...)
+			}
+		}
+	}
+	return out
+}
+
+// Addresses returns the address each configured listener binds, by listener type.
+func (lc ListenerConfig) Addresses() map[string]string {
+	out := make(map[string]string, len(lc.listeners))
+	for listenerType, l := range lc.listeners {
+		if cfg, ok := l.config.(HTTPListenerConfig); ok {
+			out[listenerType] = cfg.address()
+		}
+	}
+	return out
+}
+
 // decodeListenerConfig unmarshals a per-listener config node into a target struct.
 func decodeListenerConfig(rawConfig interface{}, target interface{}) error {
 	if rawConfig == nil {
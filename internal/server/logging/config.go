@@ -2,7 +2,12 @@ package logging
 
 import (
 	"fmt"
+	"net"
+	"path"
+	"path/filepath"
+	"poke/internal/server/bytesize"
 	"strings"
+	"time"
 )
 
 const (
@@ -10,6 +15,14 @@ const (
 	defaultFormat         = "text"
 	defaultSinkType       = "stdout"
 	defaultJournaldFallbk = "stdout"
+	defaultSyslogNetwork  = "unix"
+	defaultSyslogAddress  = "/dev/log"
+	defaultSyslogFacility = "daemon"
+	defaultSyslogAppName  = "poke"
+	defaultSyslogFallback = "stdout"
+
+	defaultLevelRevertAfter = 15 * time.Minute
+	defaultLevelOverrideMax = 24 * time.Hour
 )
 
 var (
@@ -26,10 +39,18 @@ var (
 	allowedSinkTypes = map[string]struct{}{
 		"stdout":   {},
 		"journald": {},
+		"file":     {},
+		"syslog":   {},
 	}
 	allowedJournaldFallbacks = map[string]struct{}{
 		"stdout": {},
 	}
+	allowedSyslogNetworks = map[string]struct{}{
+		"unix": {},
+		"udp":  {},
+		"tcp":  {},
+		"tls":  {},
+	}
 )
 
 // Config defines server logging settings from docs/configuration/logging.md.
@@ -38,13 +59,26 @@ type Config struct {
 	Format       string            `yaml:"format,omitempty"`
 	AddSource    bool              `yaml:"add_source,omitempty"`
 	StaticFields map[string]string `yaml:"static_fields,omitempty"`
-	Sink         SinkConfig        `yaml:"sink,omitempty"`
+	Sink         SinkList          `yaml:"sink,omitempty"`
+	// How long a runtime level change lasts unless it sets its own duration
+	LevelRevertAfter time.Duration `yaml:"level_revert_after,omitempty"`
+	// Longest duration a runtime level change may ask for
+	LevelOverrideMax time.Duration `yaml:"level_override_max,omitempty"`
 }
 
-// SinkConfig defines the active log sink and sink-specific options.
+// SinkList holds the active log sinks. In YAML it is a single sink mapping or
+// a list of them.
+type SinkList []SinkConfig
+
+// SinkConfig defines one log sink and sink-specific options.
 type SinkConfig struct {
 	Type     string              `yaml:"type,omitempty"`
+	Level    string              `yaml:"level,omitempty"`  // default logging.level
+	Format   string              `yaml:"format,omitempty"` // default logging.format
+	Events   []string            `yaml:"events,omitempty"` // event name patterns, empty = all records
 	Journald *JournaldSinkConfig `yaml:"journald,omitempty"`
+	File     *FileSinkConfig     `yaml:"file,omitempty"`
+	Syslog   *SyslogSinkConfig   `yaml:"syslog,omitempty"`
 }
 
 // JournaldSinkConfig defines journald-only sink options.
@@ -53,6 +87,34 @@ type JournaldSinkConfig struct {
 	Fallback   string `yaml:"fallback,omitempty"`
 }
 
+// FileSinkConfig defines file-only sink options.
+type FileSinkConfig struct {
+	Path     string        `yaml:"path,omitempty"`
+	MaxSize  bytesize.Size `yaml:"max_size,omitempty"`  // rotate before the file exceeds this size, 0 = never
+	Daily    bool          `yaml:"daily,omitempty"`     // rotate at local midnight
+	MaxFiles int           `yaml:"max_files,omitempty"` // rotated files kept, 0 = all
+	Compress bool          `yaml:"compress,omitempty"`  // gzip rotated files
+}
+
+// SyslogSinkConfig defines syslog-only sink options.
+type SyslogSinkConfig struct {
+	Network  string           `yaml:"network,omitempty"`  // unix (default), udp, tcp or tls
+	Address  string           `yaml:"address,omitempty"`  // socket path for unix, host:port otherwise
+	Facility string           `yaml:"facility,omitempty"` // syslog facility name, default daemon
+	AppName  string           `yaml:"app_name,omitempty"` // APP-NAME header field, default poke
+	Hostname string           `yaml:"hostname,omitempty"` // HOSTNAME header field, default os.Hostname()
+	TLS      *SyslogTLSConfig `yaml:"tls,omitempty"`      // only with network tls
+	Fallback string           `yaml:"fallback,omitempty"`
+}
+
+// SyslogTLSConfig defines how the syslog sink verifies and authenticates to a TLS relay.
+type SyslogTLSConfig struct {
+	CAFile     string `yaml:"ca_file,omitempty"`     // relay CA bundle, default system roots
+	CertFile   string `yaml:"cert_file,omitempty"`   // optional client certificate
+	KeyFile    string `yaml:"key_file,omitempty"`    // key of cert_file
+	ServerName string `yaml:"server_name,omitempty"` // default host of address
+}
+
 // UnmarshalYAML parses and validates logging config, applying documented defaults.
 func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type configInput struct {
@@ -60,15 +122,19 @@ func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		Format       *string           `yaml:"format"`
 		AddSource    *bool             `yaml:"add_source"`
 		StaticFields map[string]string `yaml:"static_fields"`
-		Sink         *SinkConfig       `yaml:"sink"`
+		Sink         *SinkList         `yaml:"sink"`
+		RevertAfter  *time.Duration    `yaml:"level_revert_after"`
+		OverrideMax  *time.Duration    `yaml:"level_override_max"`
 	}
 
 	*cfg = Config{
 		Level:  defaultLevel,
 		Format: defaultFormat,
-		Sink: SinkConfig{
+		Sink: SinkList{{
 			Type: defaultSinkType,
-		},
+		}},
+		LevelRevertAfter: defaultLevelRevertAfter,
+		LevelOverrideMax: defaultLevelOverrideMax,
 	}
 
 	var in configInput
@@ -91,15 +157,50 @@ func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if in.Sink != nil {
 		cfg.Sink = *in.Sink
 	}
+	if in.RevertAfter != nil {
+		cfg.LevelRevertAfter = *in.RevertAfter
+	}
+	if in.OverrideMax != nil {
+		cfg.LevelOverrideMax = *in.OverrideMax
+	}
 
 	return cfg.validate()
 }
 
+// UnmarshalYAML parses a single sink mapping or a list of sinks.
+func (list *SinkList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	if _, ok := raw.([]interface{}); !ok {
+		var sink SinkConfig
+		if err := unmarshal(&sink); err != nil {
+			return err
+		}
+		*list = SinkList{sink}
+		return nil
+	}
+
+	var sinks []SinkConfig
+	if err := unmarshal(&sinks); err != nil {
+		return err
+	}
+	*list = sinks
+	return list.validate()
+}
+
 // UnmarshalYAML parses sink options and applies sink-level defaults.
 func (cfg *SinkConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type sinkInput struct {
 		Type     *string             `yaml:"type"`
+		Level    *string             `yaml:"level"`
+		Format   *string             `yaml:"format"`
+		Events   []string            `yaml:"events"`
 		Journald *JournaldSinkConfig `yaml:"journald"`
+		File     *FileSinkConfig     `yaml:"file"`
+		Syslog   *SyslogSinkConfig   `yaml:"syslog"`
 	}
 
 	*cfg = SinkConfig{
@@ -114,9 +215,24 @@ func (cfg *SinkConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if in.Type != nil {
 		cfg.Type = normalizeToken(*in.Type)
 	}
+	if in.Level != nil {
+		cfg.Level = normalizeToken(*in.Level)
+	}
+	if in.Format != nil {
+		cfg.Format = normalizeToken(*in.Format)
+	}
+	for _, event := range in.Events {
+		cfg.Events = append(cfg.Events, strings.TrimSpace(event))
+	}
 	if in.Journald != nil {
 		cfg.Journald = in.Journald
 	}
+	if in.File != nil {
+		cfg.File = in.File
+	}
+	if in.Syslog != nil {
+		cfg.Syslog = in.Syslog
+	}
 
 	return cfg.validate()
 }
@@ -147,6 +263,47 @@ func (cfg *JournaldSinkConfig) UnmarshalYAML(unmarshal func(interface{}) error)
 	return nil
 }
 
+// UnmarshalYAML parses syslog sink options and applies syslog defaults.
+func (cfg *SyslogSinkConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type syslogAlias SyslogSinkConfig
+
+	var in syslogAlias
+	if err := unmarshal(&in); err != nil {
+		return err
+	}
+
+	*cfg = SyslogSinkConfig(in).withDefaults()
+	return nil
+}
+
+// withDefaults normalizes tokens and fills unset syslog options.
+func (cfg SyslogSinkConfig) withDefaults() SyslogSinkConfig {
+	out := cfg
+	out.Network = normalizeToken(out.Network)
+	out.Facility = normalizeToken(out.Facility)
+	out.Fallback = normalizeToken(out.Fallback)
+	out.Address = strings.TrimSpace(out.Address)
+	out.AppName = strings.TrimSpace(out.AppName)
+	out.Hostname = strings.TrimSpace(out.Hostname)
+
+	if out.Network == "" {
+		out.Network = defaultSyslogNetwork
+	}
+	if out.Network == "unix" && out.Address == "" {
+		out.Address = defaultSyslogAddress
+	}
+	if out.Facility == "" {
+		out.Facility = defaultSyslogFacility
+	}
+	if out.AppName == "" {
+		out.AppName = defaultSyslogAppName
+	}
+	if out.Fallback == "" {
+		out.Fallback = defaultSyslogFallback
+	}
+	return out
+}
+
 func (cfg Config) validate() error {
 	if _, ok := allowedLevels[cfg.Level]; !ok {
 		return fmt.Errorf("logging level must be one of debug, info, warn, error")
@@ -154,19 +311,85 @@ func (cfg Config) validate() error {
 	if _, ok := allowedFormats[cfg.Format]; !ok {
 		return fmt.Errorf("logging format must be one of json or text")
 	}
+	if cfg.LevelRevertAfter <= 0 {
+		return fmt.Errorf("logging level_revert_after must be positive")
+	}
+	if cfg.LevelRevertAfter > cfg.LevelOverrideMax {
+		return fmt.Errorf("logging level_revert_after must not exceed level_override_max")
+	}
 	if err := cfg.Sink.validate(); err != nil {
 		return err
 	}
 	return nil
 }
 
+func (list SinkList) validate() error {
+	if len(list) == 0 {
+		return fmt.Errorf("logging sink list must not be empty")
+	}
+
+	paths := make(map[string]struct{})
+	for i, sink := range list {
+		if err := sink.validate(); err != nil {
+			if len(list) == 1 {
+				return err
+			}
+			return fmt.Errorf("logging sink %d: %w", i+1, err)
+		}
+		if sink.Type != "file" {
+			continue
+		}
+		path := filepath.Clean(sink.File.Path)
+		if _, dup := paths[path]; dup {
+			return fmt.Errorf("logging sink %d: file path %q is used by another sink", i+1, sink.File.Path)
+		}
+		paths[path] = struct{}{}
+	}
+	return nil
+}
+
 func (cfg SinkConfig) validate() error {
 	if _, ok := allowedSinkTypes[cfg.Type]; !ok {
-		return fmt.Errorf("logging sink type must be one of stdout or journald")
+		return fmt.Errorf("logging sink type must be one of stdout, journald, file or syslog")
+	}
+	if err := cfg.validateFilters(); err != nil {
+		return err
 	}
-	if cfg.Type != "journald" {
+	switch cfg.Type {
+	case "journald":
+		return cfg.validateJournald()
+	case "file":
+		return cfg.validateFile()
+	case "syslog":
+		if cfg.Syslog == nil {
+			return fmt.Errorf("logging sink syslog config is required when sink type is syslog")
+		}
+		return cfg.Syslog.validate()
+	default:
 		return nil
 	}
+}
+
+// validateFilters checks the optional per-sink level, format and events.
+func (cfg SinkConfig) validateFilters() error {
+	if _, ok := allowedLevels[cfg.Level]; cfg.Level != "" && !ok {
+		return fmt.Errorf("logging sink level must be one of debug, info, warn, error")
+	}
+	if _, ok := allowedFormats[cfg.Format]; cfg.Format != "" && !ok {
+		return fmt.Errorf("logging sink format must be one of json or text")
+	}
+	for _, event := range cfg.Events {
+		if event == "" {
+			return fmt.Errorf("logging sink events must not contain empty names")
+		}
+		if _, err := path.Match(event, ""); err != nil {
+			return fmt.Errorf("logging sink event pattern %q is invalid: %w", event, err)
+		}
+	}
+	return nil
+}
+
+func (cfg SinkConfig) validateJournald() error {
 	if cfg.Journald == nil {
 		return fmt.Errorf("logging sink journald config is required when sink type is journald")
 	}
@@ -180,6 +403,64 @@ func (cfg SinkConfig) validate() error {
 	return nil
 }
 
+func (cfg SinkConfig) validateFile() error {
+	if cfg.File == nil {
+		return fmt.Errorf("logging sink file config is required when sink type is file")
+	}
+	path := strings.TrimSpace(cfg.File.Path)
+	if path == "" {
+		return fmt.Errorf("logging sink file path is required")
+	}
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("logging sink file path %q must be absolute", path)
+	}
+	if cfg.File.MaxFiles < 0 {
+		return fmt.Errorf("logging sink file max_files must not be negative")
+	}
+	rotates := cfg.File.MaxSize > 0 || cfg.File.Daily
+	if !rotates && (cfg.File.MaxFiles > 0 || cfg.File.Compress) {
+		return fmt.Errorf("logging sink file max_files and compress require max_size or daily")
+	}
+	return nil
+}
+
+func (cfg SyslogSinkConfig) validate() error {
+	if _, ok := allowedSyslogNetworks[cfg.Network]; !ok {
+		return fmt.Errorf("logging sink syslog network must be one of unix, udp, tcp or tls")
+	}
+	if cfg.Network != "unix" {
+		if _, _, err := net.SplitHostPort(cfg.Address); err != nil {
+			return fmt.Errorf("logging sink syslog address must be host:port for network %s", cfg.Network)
+		}
+	}
+	if _, ok := syslogFacilities[cfg.Facility]; !ok {
+		return fmt.Errorf("logging sink syslog facility %q is unknown", cfg.Facility)
+	}
+	if !isSyslogHeaderToken(cfg.AppName, syslogAppNameMaxLen) {
+		return fmt.Errorf("logging sink syslog app_name must be 1 to %d printable ASCII characters", syslogAppNameMaxLen)
+	}
+	if cfg.Hostname != "" && !isSyslogHeaderToken(cfg.Hostname, syslogHostnameMaxLen) {
+		return fmt.Errorf("logging sink syslog hostname must be 1 to %d printable ASCII characters", syslogHostnameMaxLen)
+	}
+	if cfg.Fallback != "stdout" {
+		return fmt.Errorf("logging sink syslog fallback must be stdout")
+	}
+	return cfg.validateTLS()
+}
+
+func (cfg SyslogSinkConfig) validateTLS() error {
+	if cfg.TLS == nil {
+		return nil
+	}
+	if cfg.Network != "tls" {
+		return fmt.Errorf("logging sink syslog tls is only valid with network tls")
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return fmt.Errorf("logging sink syslog tls cert_file and key_file must be set together")
+	}
+	return nil
+}
+
 func normalizeToken(value string) string {
 	return strings.ToLower(strings.TrimSpace(value))
 }
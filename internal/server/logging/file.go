@@ -0,0 +1,364 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotatedTimeLayout = "20060102-150405.000" // suffix of rotated files, sorts by age
+
+// openFiles tracks the file sinks opened by New so Reopen can reach them.
+var openFiles struct {
+	mu    sync.Mutex
+	files []*rotatingFile
+}
+
+// rotatingFile is an io.Writer appending to a log file that is rotated by
+// size and/or at local midnight.
+//
+// Rotated files are renamed to `<path>.<timestamp>`, then compressed and
+// pruned in the background. When reopening the file fails, Write retries on
+// every call until it succeeds.
+type rotatingFile struct {
+	mu      sync.Mutex
+	cfg     FileSinkConfig
+	file    *os.File
+	size    int64
+	opened  time.Time // day the current file was started, for daily rotation
+	closed  bool      // set by Close, Write fails from then on
+	failing bool      // a rotation or open failed and was reported on stderr
+
+	post sync.Mutex     // serializes compression and pruning
+	wg   sync.WaitGroup // running post-rotation work
+}
+
+// openFileSink opens the log file configured in cfg, creating missing parent
+// directories, and registers it for Reopen and Close.
+func openFileSink(cfg FileSinkConfig) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o750); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+	f := &rotatingFile{cfg: cfg}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	openFiles.mu.Lock()
+	openFiles.files = append(openFiles.files, f)
+	openFiles.mu.Unlock()
+	return f, nil
+}
+
+// Reopen closes and reopens every file sink, picking up a log file moved
+// away by an external tool such as logrotate.
+func Reopen() error {
+	openFiles.mu.Lock()
+	files := append([]*rotatingFile(nil), openFiles.files...)
+	openFiles.mu.Unlock()
+
+	var errs []error
+	for _, f := range files {
+		errs = append(errs, f.Reopen())
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every file sink opened by New and waits for pending
+// compression; loggers writing to them fail afterwards.
+func Close() error {
+	openFiles.mu.Lock()
+	files := openFiles.files
+	openFiles.files = nil
+	openFiles.mu.Unlock()
+
+	var errs []error
+	for _, f := range files {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// open opens cfg.Path for appending; an existing file keeps its size and
+// counts as started on the day it was last written.
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640) // #nosec G302 G304 -- by design, comes from config
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.opened = info.ModTime()
+	if f.size == 0 {
+		f.opened = time.Now()
+	}
+	return nil
+}
+
+// Write appends p, rotating first when p would exceed max_size or the day changed.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file == nil {
+		if err := f.reopen(); err != nil {
+			return 0, err
+		}
+	}
+	if now := time.Now(); f.due(len(p), now) {
+		err := f.rotate(now)
+		f.report(err)
+		if f.file == nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// due reports whether the current file must be rotated before writing n bytes.
+func (f *rotatingFile) due(n int, now time.Time) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.cfg.MaxSize > 0 && f.size+int64(n) > int64(f.cfg.MaxSize) {
+		return true
+	}
+	return f.cfg.Daily && !sameDay(f.opened, now)
+}
+
+// reopen opens the file for Reopen or after a failed rotation or Reopen,
+// reporting the first failure and the recovery on stderr; callers hold f.mu.
+func (f *rotatingFile) reopen() error {
+	err := f.open()
+	f.report(err)
+	return err
+}
+
+// report writes err to stderr, since the logger itself is the one failing.
+// Repeated failures are reported once, until a rotation or open succeeds.
+func (f *rotatingFile) report(err error) {
+	switch {
+	case err == nil && f.failing:
+		f.failing = false
+		fmt.Fprintf(os.Stderr, "poke: log file %s: recovered\n", f.cfg.Path)
+	case err != nil && !f.failing:
+		f.failing = true
+		fmt.Fprintf(os.Stderr, "poke: log file %s: %v\n", f.cfg.Path, err)
+	}
+}
+
+// rotate moves the current file aside and starts a new one; callers hold f.mu.
+// If the file cannot be reopened, f.file stays nil and Write retries.
+func (f *rotatingFile) rotate(now time.Time) error {
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return fmt.Errorf("rotate log file: %w", err)
+	}
+
+	rotated := rotatedName(f.cfg.Path, now)
+	if err := os.Rename(f.cfg.Path, rotated); err != nil {
+		return errors.Join(fmt.Errorf("rotate log file: %w", err), f.open())
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.afterRotate(rotated)
+	}()
+	return nil
+}
+
+// afterRotate compresses the rotated file and prunes old ones. Errors are
+// reported on stderr since the logger itself is the one failing.
+func (f *rotatingFile) afterRotate(rotated string) {
+	f.post.Lock()
+	defer f.post.Unlock()
+
+	if f.cfg.Compress {
+		// A faster rotation may already have pruned it.
+		if err := compressFile(rotated); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "poke: compress rotated log %s: %v\n", rotated, err)
+		}
+	}
+	if err := pruneRotated(f.cfg.Path, f.cfg.MaxFiles); err != nil {
+		fmt.Fprintf(os.Stderr, "poke: prune rotated logs of %s: %v\n", f.cfg.Path, err)
+	}
+}
+
+// Reopen closes the current file and opens cfg.Path again. If that fails,
+// Write retries the open.
+func (f *rotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.file != nil {
+		_ = f.file.Close()
+		f.file = nil
+	}
+	return f.reopen()
+}
+
+// Close closes the file and waits for pending compression and pruning.
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	f.closed = true
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.wg.Wait()
+	return err
+}
+
+// rotatedName returns a name for path rotated at now that does not exist yet.
+func rotatedName(path string, now time.Time) string {
+	base := path + "." + now.Format(rotatedTimeLayout)
+	name := base
+	for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
+
+// compressFile replaces path with path.gz.
+func compressFile(path string) error {
+	src, err := os.Open(path) // #nosec G304 -- rotated log file
+	if err != nil {
+		return err
+	}
+	defer src.Close() //nolint:errcheck // Read-only handle.
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640) // #nosec G302 G304 -- rotated log file
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// pruneRotated removes the oldest rotated files of path beyond keep; keep 0
+// keeps all of them.
+func pruneRotated(path string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	rotated, err := rotatedFiles(path)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for len(rotated) > keep {
+		errs = append(errs, os.Remove(rotated[0]))
+		rotated = rotated[1:]
+	}
+	return errors.Join(errs...)
+}
+
+// rotatedFile is a rotated file of a log path with its parsed suffix.
+type rotatedFile struct {
+	name    string
+	rotated time.Time
+	counter int // `-N` suffix of files rotated within the same millisecond
+}
+
+// rotatedFiles lists the rotated files of path, oldest first: by rotation
+// time, then by counter, whether compressed or not.
+func rotatedFiles(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var files []rotatedFile
+	for _, match := range matches {
+		if file, ok := parseRotated(match, strings.TrimPrefix(match, path+".")); ok {
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].rotated.Equal(files[j].rotated) {
+			return files[i].rotated.Before(files[j].rotated)
+		}
+		return files[i].counter < files[j].counter
+	})
+
+	out := make([]string, len(files))
+	for i, file := range files {
+		out[i] = file.name
+	}
+	return out, nil
+}
+
+// parseRotated parses suffix as a rotation timestamp, optionally followed by
+// a `-N` counter and `.gz`.
+func parseRotated(name string, suffix string) (rotatedFile, bool) {
+	suffix = strings.TrimSuffix(suffix, ".gz")
+	if len(suffix) < len(rotatedTimeLayout) {
+		return rotatedFile{}, false
+	}
+	rotated, err := time.Parse(rotatedTimeLayout, suffix[:len(rotatedTimeLayout)])
+	if err != nil {
+		return rotatedFile{}, false
+	}
+	file := rotatedFile{name: name, rotated: rotated}
+	counter := suffix[len(rotatedTimeLayout):]
+	if counter == "" {
+		return file, true
+	}
+	if !strings.HasPrefix(counter, "-") {
+		return rotatedFile{}, false
+	}
+	file.counter, err = strconv.Atoi(counter[1:])
+	return file, err == nil && file.counter > 0
+}
+
+func sameDay(a time.Time, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
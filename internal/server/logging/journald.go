@@ -63,7 +63,7 @@ func (h *journaldHandler) Enabled(_ context.Context, level slog.Level) bool {
 // Handle emits one slog record to journald, falling back when delivery fails.
 func (h *journaldHandler) Handle(ctx context.Context, rec slog.Record) error {
 	fields := map[string]string{
-		"MESSAGE":           rec.Message,
+		"MESSAGE":           replaceMessage(h.opts, rec.Message),
 		"PRIORITY":          journaldPriority(rec.Level),
 		"SYSLOG_IDENTIFIER": h.identifier,
 		"POKE_LEVEL":        rec.Level.String(),
@@ -173,11 +173,21 @@ func addSourceFields(fields map[string]string, pc uintptr) {
 
 // appendAttr flattens an attr into journald fields.
 func appendAttr(fields map[string]string, opts *slog.HandlerOptions, groups []string, attr slog.Attr) {
-	if opts.ReplaceAttr != nil {
-		attr = opts.ReplaceAttr(groups, attr)
-	}
+	walkAttr(opts, groups, attr, func(groups []string, key string, value slog.Value) {
+		fields[buildCustomFieldKey(groups, key)] = attrValueString(value)
+	})
+}
 
+// walkAttr resolves attr and calls emit for every non-group leaf with the
+// groups enclosing it.
+func walkAttr(opts *slog.HandlerOptions, groups []string, attr slog.Attr, emit func(groups []string, key string, value slog.Value)) {
+	// Like the built-in handlers, values are resolved first and ReplaceAttr
+	// is not called for groups.
 	attr.Value = attr.Value.Resolve()
+	if opts.ReplaceAttr != nil && attr.Value.Kind() != slog.KindGroup {
+		attr = opts.ReplaceAttr(groups, attr)
+		attr.Value = attr.Value.Resolve()
+	}
 	if attr.Equal(slog.Attr{}) {
 		return
 	}
@@ -189,7 +199,7 @@ func appendAttr(fields map[string]string, opts *slog.HandlerOptions, groups []st
 		}
 
 		for _, child := range attr.Value.Group() {
-			appendAttr(fields, opts, nextGroups, child)
+			walkAttr(opts, nextGroups, child, emit)
 		}
 		return
 	}
@@ -198,8 +208,7 @@ func appendAttr(fields map[string]string, opts *slog.HandlerOptions, groups []st
 		return
 	}
 
-	fieldKey := buildCustomFieldKey(groups, attr.Key)
-	fields[fieldKey] = attrValueString(attr.Value)
+	emit(groups, attr.Key, attr.Value)
 }
 
 // appendCopy returns a new slice containing src values and tail value.
@@ -0,0 +1,286 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LevelOverride is a runtime level change made with SetLevel.
+type LevelOverride struct {
+	Component string    `json:"component,omitempty"` // empty for all sinks
+	Level     string    `json:"level"`
+	Until     time.Time `json:"until"` // when the configured level is restored
+}
+
+// LevelStatus lists the configured sink levels and the active overrides.
+type LevelStatus struct {
+	Sinks       []string        `json:"sinks"` // configured level per sink, in config order
+	Overrides   []LevelOverride `json:"overrides"`
+	RevertAfter string          `json:"revert_after"` // default override duration
+	MaxDuration string          `json:"max_duration"` // longest override duration SetLevel accepts
+}
+
+// levelControl applies runtime level overrides to the sinks of the last
+// logger built by New.
+type levelControl struct {
+	mu          sync.Mutex
+	sinks       []*slog.LevelVar
+	configured  []slog.Level
+	revertAfter time.Duration
+	maxDuration time.Duration
+	global      *activeOverride
+	components  map[string]*activeOverride
+
+	// componentLevels is a read-only snapshot of components for Enabled.
+	componentLevels atomic.Pointer[map[string]slog.Level]
+}
+
+// activeOverride is an override waiting for its revert timer.
+type activeOverride struct {
+	level slog.Level
+	until time.Time
+	timer *time.Timer
+}
+
+var levels = &levelControl{
+	revertAfter: defaultLevelRevertAfter,
+	maxDuration: defaultLevelOverrideMax,
+	components:  map[string]*activeOverride{},
+}
+
+// SetLevel overrides the level of all sinks, or of the loggers of component
+// when it is not empty, until d has passed. A non-positive d uses the
+// configured level_revert_after; a d above level_override_max is rejected.
+func SetLevel(component string, level string, d time.Duration) (LevelOverride, error) {
+	parsed, err := parseLevel(normalizeToken(level))
+	if err != nil {
+		return LevelOverride{}, err
+	}
+	component = strings.TrimSpace(component)
+	return levels.set(component, parsed, d)
+}
+
+// ResetLevel drops the override of component, or every override when
+// component is empty.
+func ResetLevel(component string) {
+	levels.reset(strings.TrimSpace(component))
+}
+
+// ToggleDebug switches all sinks to debug for level_revert_after, or back to
+// their configured levels when they are already overridden.
+func ToggleDebug() (LevelOverride, bool) {
+	return levels.toggleDebug()
+}
+
+// Levels reports the configured sink levels and the active overrides.
+func Levels() LevelStatus {
+	return levels.status()
+}
+
+// register makes sinks the ones overridden from now on, keeping an active
+// global override.
+func (c *levelControl) register(sinks []*slog.LevelVar, revertAfter time.Duration, maxDuration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sinks = sinks
+	c.configured = make([]slog.Level, len(sinks))
+	for i, sink := range sinks {
+		c.configured[i] = sink.Level()
+	}
+	c.revertAfter = revertAfter
+	c.maxDuration = maxDuration
+	if c.global != nil {
+		c.applyGlobal(c.global.level)
+	}
+}
+
+func (c *levelControl) set(component string, level slog.Level, d time.Duration) (LevelOverride, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if d > c.maxDuration {
+		return LevelOverride{}, fmt.Errorf("duration %s exceeds level_override_max %s", d, c.maxDuration)
+	}
+	return c.setLocked(component, level, d), nil
+}
+
+func (c *levelControl) setLocked(component string, level slog.Level, d time.Duration) LevelOverride {
+	if d <= 0 {
+		d = c.revertAfter
+	}
+	override := &activeOverride{level: level, until: time.Now().Add(d)}
+	override.timer = time.AfterFunc(d, func() { c.expire(component, override) })
+
+	if component == "" {
+		c.global.stop()
+		c.global = override
+		c.applyGlobal(level)
+	} else {
+		c.components[component].stop()
+		c.components[component] = override
+		c.publishComponents()
+	}
+	return LevelOverride{Component: component, Level: levelName(level), Until: override.until}
+}
+
+func (c *levelControl) reset(component string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if component == "" {
+		c.resetGlobal()
+		for _, override := range c.components {
+			override.stop()
+		}
+		clear(c.components)
+		c.publishComponents()
+		return
+	}
+	c.components[component].stop()
+	delete(c.components, component)
+	c.publishComponents()
+}
+
+func (c *levelControl) toggleDebug() (LevelOverride, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.global != nil {
+		c.resetGlobal()
+		return LevelOverride{}, false
+	}
+	return c.setLocked("", slog.LevelDebug, 0), true
+}
+
+// expire reverts override unless it was replaced or reset meanwhile.
+func (c *levelControl) expire(component string, override *activeOverride) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case component == "" && c.global == override:
+		c.resetGlobal()
+	case component != "" && c.components[component] == override:
+		delete(c.components, component)
+		c.publishComponents()
+	default:
+		return
+	}
+	slog.Default().With("component", "logging").Info("log level reverted", "event", "log_level_reverted", "target_component", component)
+}
+
+func (c *levelControl) resetGlobal() {
+	c.global.stop()
+	c.global = nil
+	for i, sink := range c.sinks {
+		sink.Set(c.configured[i])
+	}
+}
+
+func (c *levelControl) applyGlobal(level slog.Level) {
+	for _, sink := range c.sinks {
+		sink.Set(level)
+	}
+}
+
+func (c *levelControl) publishComponents() {
+	snapshot := make(map[string]slog.Level, len(c.components))
+	for component, override := range c.components {
+		snapshot[component] = override.level
+	}
+	c.componentLevels.Store(&snapshot)
+}
+
+// componentLevel returns the override of component, if any.
+func (c *levelControl) componentLevel(component string) (slog.Level, bool) {
+	snapshot := c.componentLevels.Load()
+	if snapshot == nil || component == "" {
+		return 0, false
+	}
+	level, ok := (*snapshot)[component]
+	return level, ok
+}
+
+func (c *levelControl) status() LevelStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := LevelStatus{Overrides: []LevelOverride{}, RevertAfter: c.revertAfter.String(), MaxDuration: c.maxDuration.String()}
+	for _, level := range c.configured {
+		status.Sinks = append(status.Sinks, levelName(level))
+	}
+	if c.global != nil {
+		status.Overrides = append(status.Overrides, LevelOverride{Level: levelName(c.global.level), Until: c.global.until})
+	}
+	for _, component := range slices.Sorted(maps.Keys(c.components)) {
+		override := c.components[component]
+		status.Overrides = append(status.Overrides, LevelOverride{Component: component, Level: levelName(override.level), Until: override.until})
+	}
+	return status
+}
+
+func (o *activeOverride) stop() {
+	if o != nil {
+		o.timer.Stop()
+	}
+}
+
+// levelName renders level as a config token.
+func levelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// levelHandler applies runtime overrides on top of a sink's level; the
+// component attr added with WithAttrs selects a component override.
+type levelHandler struct {
+	next      slog.Handler
+	level     *slog.LevelVar
+	component string
+	grouped   bool // a group was opened, later attrs are not top-level
+}
+
+// Enabled checks level against the component override or the sink level.
+func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if override, ok := levels.componentLevel(h.component); ok {
+		return level >= override
+	}
+	return level >= h.level.Level()
+}
+
+// Handle passes rec to the sink.
+func (h *levelHandler) Handle(ctx context.Context, rec slog.Record) error {
+	return h.next.Handle(ctx, rec)
+}
+
+// WithAttrs returns a handler with additional attributes.
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.next = h.next.WithAttrs(attrs)
+	if !h.grouped {
+		for _, attr := range attrs {
+			if attr.Key == "component" {
+				next.component = attr.Value.String()
+			}
+		}
+	}
+	return &next
+}
+
+// WithGroup returns a handler that nests future attributes under group.
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.next = h.next.WithGroup(name)
+	next.grouped = true
+	return &next
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package logging
+
+import "context"
+
+// ToggleDebugOnSignal is a no-op where SIGUSR2 is unavailable; use the admin
+// endpoint or SetLevel instead.
+func ToggleDebugOnSignal(_ context.Context) {}
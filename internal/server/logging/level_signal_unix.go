@@ -0,0 +1,35 @@
+//go:build unix
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ToggleDebugOnSignal switches all sinks to debug on SIGUSR2, and back on the
+// next one, until ctx is done. See ToggleDebug.
+func ToggleDebugOnSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR2)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				logger := slog.Default().With("component", "logging")
+				if override, on := ToggleDebug(); on {
+					logger.Info("log level changed", "event", "log_level_changed", "level", override.Level, "until", override.Until, "source", "signal")
+					continue
+				}
+				logger.Info("log level reverted", "event", "log_level_reverted", "source", "signal")
+			}
+		}
+	}()
+}
@@ -5,29 +5,35 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"poke/internal/server/redact"
 	"sort"
 	"strings"
 )
 
 // New builds a structured logger from config.
+//
+// Each sink gets its own level and format; records are fanned out to every
+// sink that accepts them. Sink levels of the returned logger can be changed
+// at runtime with SetLevel.
 func New(cfg Config) (*slog.Logger, error) {
 	normalized := withRuntimeDefaults(cfg)
 	if err := normalized.validate(); err != nil {
 		return nil, err
 	}
 
-	level, err := parseLevel(normalized.Level)
-	if err != nil {
-		return nil, err
-	}
-
-	opts := &slog.HandlerOptions{
-		AddSource: normalized.AddSource,
-		Level:     level,
+	handlers := make([]slog.Handler, 0, len(normalized.Sink))
+	sinkLevels := make([]*slog.LevelVar, 0, len(normalized.Sink))
+	for _, sink := range normalized.Sink {
+		level := new(slog.LevelVar)
+		handler, err := newSinkHandler(sink, normalized.AddSource, level)
+		if err != nil {
+			return nil, err
+		}
+		handlers = append(handlers, handler)
+		sinkLevels = append(sinkLevels, level)
 	}
-
-	handler := newHandler(normalized, opts)
-	logger := slog.New(handler)
+	levels.register(sinkLevels, normalized.LevelRevertAfter, normalized.LevelOverrideMax)
+	logger := slog.New(newMultiHandler(handlers))
 
 	staticAttrs := staticFieldAttrs(normalized.StaticFields)
 	if len(staticAttrs) > 0 {
@@ -53,28 +59,62 @@ func withRuntimeDefaults(cfg Config) Config {
 		out.Format = normalizeToken(out.Format)
 	}
 
-	if strings.TrimSpace(out.Sink.Type) == "" {
-		out.Sink.Type = defaultSinkType
-	} else {
-		out.Sink.Type = normalizeToken(out.Sink.Type)
+	if out.LevelRevertAfter == 0 {
+		out.LevelRevertAfter = defaultLevelRevertAfter
+	}
+	if out.LevelOverrideMax == 0 {
+		out.LevelOverrideMax = max(defaultLevelOverrideMax, out.LevelRevertAfter)
+	}
+
+	if len(out.Sink) == 0 {
+		out.Sink = SinkList{{}}
 	}
+	sinks := make(SinkList, 0, len(out.Sink))
+	for _, sink := range out.Sink {
+		sinks = append(sinks, sinkWithRuntimeDefaults(sink, out.Level, out.Format))
+	}
+	out.Sink = sinks
+
+	return out
+}
 
-	if out.Sink.Type == "journald" {
-		if out.Sink.Journald == nil {
-			out.Sink.Journald = &JournaldSinkConfig{
-				Fallback: defaultJournaldFallbk,
-			}
+// sinkWithRuntimeDefaults fills unset sink options, taking level and format
+// from the top-level logging settings.
+func sinkWithRuntimeDefaults(sink SinkConfig, level, format string) SinkConfig {
+	out := sink
+	out.Type = tokenOrDefault(out.Type, defaultSinkType)
+	out.Level = tokenOrDefault(out.Level, level)
+	out.Format = tokenOrDefault(out.Format, format)
+
+	if out.Type == "journald" {
+		journald := JournaldSinkConfig{}
+		if out.Journald != nil {
+			journald = *out.Journald
 		}
-		if strings.TrimSpace(out.Sink.Journald.Fallback) == "" {
-			out.Sink.Journald.Fallback = defaultJournaldFallbk
-		} else {
-			out.Sink.Journald.Fallback = normalizeToken(out.Sink.Journald.Fallback)
+		journald.Fallback = tokenOrDefault(journald.Fallback, defaultJournaldFallbk)
+		out.Journald = &journald
+	}
+
+	if out.Type == "syslog" {
+		syslog := SyslogSinkConfig{}
+		if out.Syslog != nil {
+			syslog = *out.Syslog
 		}
+		syslog = syslog.withDefaults()
+		out.Syslog = &syslog
 	}
 
 	return out
 }
 
+// tokenOrDefault normalizes value, returning fallback when it is blank.
+func tokenOrDefault(value, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return normalizeToken(value)
+}
+
 // parseLevel maps a config level token to slog level.
 func parseLevel(level string) (slog.Level, error) {
 	switch level {
@@ -91,18 +131,73 @@ func parseLevel(level string) (slog.Level, error) {
 	}
 }
 
+// newSinkHandler builds the handler of one sink, applying its level and
+// event filter. level is set to the sink level.
+func newSinkHandler(sink SinkConfig, addSource bool, level *slog.LevelVar) (slog.Handler, error) {
+	configured, err := parseLevel(sink.Level)
+	if err != nil {
+		return nil, err
+	}
+	level.Set(configured)
+
+	opts := &slog.HandlerOptions{
+		AddSource:   addSource,
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}
+
+	handler, err := newHandler(sink, opts)
+	if err != nil {
+		return nil, err
+	}
+	handler = &levelHandler{next: handler, level: level}
+	if len(sink.Events) > 0 {
+		handler = newEventFilterHandler(handler, sink.Events)
+	}
+	return handler, nil
+}
+
+// redactAttr masks secrets with the redactor set by redact.SetDefault.
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	return redact.Default().ReplaceAttr(groups, attr)
+}
+
+// replaceMessage applies opts.ReplaceAttr to a record message like the
+// built-in handlers do, for handlers that render the message themselves.
+func replaceMessage(opts *slog.HandlerOptions, msg string) string {
+	if opts == nil || opts.ReplaceAttr == nil {
+		return msg
+	}
+	return opts.ReplaceAttr(nil, slog.String(slog.MessageKey, msg)).Value.String()
+}
+
 // newHandler builds a handler for the configured format and sink.
-func newHandler(cfg Config, opts *slog.HandlerOptions) slog.Handler {
-	fallback := newOutputHandler(cfg.Format, resolveFallbackOutput(cfg.Sink), opts)
+//
+// A journald or syslog sink that cannot connect falls back to stdout; a file
+// sink that cannot be opened or unreadable syslog TLS files are an error.
+func newHandler(sink SinkConfig, opts *slog.HandlerOptions) (slog.Handler, error) {
+	if sink.Type == "file" && sink.File != nil {
+		file, err := openFileSink(*sink.File)
+		if err != nil {
+			return nil, err
+		}
+		return newOutputHandler(sink.Format, file, opts), nil
+	}
+
+	fallback := newOutputHandler(sink.Format, resolveFallbackOutput(sink), opts)
+
+	if sink.Type == "syslog" && sink.Syslog != nil {
+		return newSyslogHandler(*sink.Syslog, opts, fallback)
+	}
 
-	if cfg.Sink.Type == "journald" && cfg.Sink.Journald != nil {
-		handler, err := newJournaldHandler(cfg.Sink.Journald.Identifier, opts, fallback)
+	if sink.Type == "journald" && sink.Journald != nil {
+		handler, err := newJournaldHandler(sink.Journald.Identifier, opts, fallback)
 		if err == nil {
-			return handler
+			return handler, nil
 		}
 	}
 
-	return fallback
+	return fallback, nil
 }
 
 // newOutputHandler builds a text or JSON handler for a plain output writer.
@@ -116,7 +211,7 @@ func newOutputHandler(format string, output io.Writer, opts *slog.HandlerOptions
 // resolveFallbackOutput resolves the configured fallback output writer.
 func resolveFallbackOutput(sink SinkConfig) *os.File {
 	switch sink.Type {
-	case "stdout", "journald":
+	case "stdout", "journald", "syslog":
 		return os.Stdout
 	default:
 		return os.Stdout
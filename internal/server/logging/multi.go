@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path"
+)
+
+// multiHandler fans records out to every sink handler that accepts them.
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+// newMultiHandler combines handlers, returning a lone handler unwrapped.
+func newMultiHandler(handlers []slog.Handler) slog.Handler {
+	if len(handlers) == 1 {
+		return handlers[0]
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+// Enabled reports whether any sink accepts records at level.
+func (h *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes rec to each sink enabled for its level; a failing sink does
+// not keep rec from the others.
+func (h *multiHandler) Handle(ctx context.Context, rec slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, rec.Level) {
+			continue
+		}
+		errs = append(errs, handler.Handle(ctx, rec.Clone()))
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a handler with additional attributes on every sink.
+func (h *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.each(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+// WithGroup returns a handler that nests future attributes under group on every sink.
+func (h *multiHandler) WithGroup(name string) slog.Handler {
+	return h.each(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *multiHandler) each(derive func(slog.Handler) slog.Handler) slog.Handler {
+	next := make([]slog.Handler, 0, len(h.handlers))
+	for _, handler := range h.handlers {
+		next = append(next, derive(handler))
+	}
+	return &multiHandler{handlers: next}
+}
+
+// eventFilterHandler passes on records whose top-level event attr matches
+// one of its patterns; records without an event are dropped.
+type eventFilterHandler struct {
+	next     slog.Handler
+	patterns []string
+	event    string // event attr added with WithAttrs
+	grouped  bool   // a group was opened, later attrs are not top-level
+}
+
+// newEventFilterHandler wraps next so only records matching patterns reach it.
+func newEventFilterHandler(next slog.Handler, patterns []string) slog.Handler {
+	return &eventFilterHandler{next: next, patterns: patterns}
+}
+
+// Enabled defers to the wrapped handler; events are checked in Handle.
+func (h *eventFilterHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle passes rec on when its event matches.
+func (h *eventFilterHandler) Handle(ctx context.Context, rec slog.Record) error {
+	event := h.event
+	if !h.grouped {
+		rec.Attrs(func(attr slog.Attr) bool {
+			if attr.Key == "event" {
+				event = attr.Value.String()
+			}
+			return true
+		})
+	}
+	if !h.matches(event) {
+		return nil
+	}
+	return h.next.Handle(ctx, rec)
+}
+
+func (h *eventFilterHandler) matches(event string) bool {
+	if event == "" {
+		return false
+	}
+	for _, pattern := range h.patterns {
+		if ok, _ := path.Match(pattern, event); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAttrs returns a handler with additional attributes.
+func (h *eventFilterHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.next = h.next.WithAttrs(attrs)
+	if !h.grouped {
+		for _, attr := range attrs {
+			if attr.Key == "event" {
+				next.event = attr.Value.String()
+			}
+		}
+	}
+	return &next
+}
+
+// WithGroup returns a handler that nests future attributes under group.
+func (h *eventFilterHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.next = h.next.WithGroup(name)
+	next.grouped = true
+	return &next
+}
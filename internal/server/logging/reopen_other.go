@@ -0,0 +1,8 @@
+//go:build !unix
+
+package logging
+
+import "context"
+
+// ReopenOnSignal is a no-op where SIGUSR1 is unavailable; call Reopen instead.
+func ReopenOnSignal(_ context.Context) {}
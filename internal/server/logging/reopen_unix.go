@@ -0,0 +1,33 @@
+//go:build unix
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReopenOnSignal reopens file sinks on every SIGUSR1 until ctx is done.
+func ReopenOnSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				if err := Reopen(); err != nil {
+					slog.Default().With("component", "logging").Error("log file reopen failed", "event", "log_reopen_failed", "error", err)
+					continue
+				}
+				slog.Default().With("component", "logging").Info("log files reopened", "event", "log_reopened")
+			}
+		}
+	}()
+}
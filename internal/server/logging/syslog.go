@@ -0,0 +1,273 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// syslogSDID names the structured data element carrying record attrs;
+	// 32473 is the enterprise number RFC 5612 reserves for documentation.
+	syslogSDID           = "poke@32473"
+	syslogTimeLayout     = "2006-01-02T15:04:05.000000Z07:00"
+	syslogNil            = "-"
+	syslogAppNameMaxLen  = 48
+	syslogHostnameMaxLen = 255
+	syslogMsgIDMaxLen    = 32
+	syslogParamMaxLen    = 32
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogHandler writes slog records as RFC 5424 messages.
+//
+// Attrs are flattened like for journald, with groups joined by ".", into
+// one structured data element; a top-level `event` attr also becomes MSGID.
+type syslogHandler struct {
+	header   syslogHeader
+	opts     *slog.HandlerOptions
+	writer   *syslogWriter
+	fallback slog.Handler
+	attrs    syslogData
+	groups   []string
+}
+
+// syslogHeader holds the per-process RFC 5424 header fields.
+type syslogHeader struct {
+	facility int
+	hostname string
+	appName  string
+	procID   string
+}
+
+// syslogParam is one SD-PARAM of the structured data element.
+type syslogParam struct {
+	name  string
+	value string
+}
+
+// newSyslogHandler connects to the configured relay, returning fallback when
+// it cannot be reached. Invalid TLS files are reported as errors.
+func newSyslogHandler(cfg SyslogSinkConfig, opts *slog.HandlerOptions, fallback slog.Handler) (slog.Handler, error) {
+	writer, err := newSyslogWriter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.connect(); err != nil {
+		return fallback, nil
+	}
+
+	hostname := cfg.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	if !isSyslogHeaderToken(hostname, syslogHostnameMaxLen) {
+		hostname = syslogNil
+	}
+
+	return &syslogHandler{
+		header: syslogHeader{
+			facility: syslogFacilities[cfg.Facility],
+			hostname: hostname,
+			appName:  cfg.AppName,
+			procID:   strconv.Itoa(os.Getpid()),
+		},
+		opts:     opts,
+		writer:   writer,
+		fallback: fallback,
+	}, nil
+}
+
+// Enabled checks whether the configured level allows the record.
+func (h *syslogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	leveler := h.opts.Level
+	if leveler == nil {
+		return level >= slog.LevelInfo
+	}
+	return level >= leveler.Level()
+}
+
+// syslogData collects the SD-PARAMs and MSGID of a message.
+type syslogData struct {
+	msgID  string
+	params []syslogParam
+}
+
+// add flattens attr under groups into SD-PARAMs; a top-level event attr
+// also becomes the MSGID.
+func (sd *syslogData) add(opts *slog.HandlerOptions, groups []string, attr slog.Attr) {
+	walkAttr(opts, groups, attr, func(groups []string, key string, value slog.Value) {
+		sd.params = append(sd.params, syslogParam{name: syslogParamName(groups, key), value: attrValueString(value)})
+		if len(groups) == 0 && key == "event" && isSyslogHeaderToken(value.String(), syslogMsgIDMaxLen) {
+			sd.msgID = value.String()
+		}
+	})
+}
+
+// Handle sends one slog record to the relay, falling back when delivery fails.
+func (h *syslogHandler) Handle(ctx context.Context, rec slog.Record) error {
+	sd := syslogData{msgID: h.attrs.msgID}
+	if h.opts.AddSource && rec.PC != 0 {
+		sd.params = appendSourceParams(sd.params, rec.PC)
+	}
+	sd.params = append(sd.params, h.attrs.params...)
+	rec.Attrs(func(attr slog.Attr) bool {
+		sd.add(h.opts, h.groups, attr)
+		return true
+	})
+
+	msg := rec // the fallback replaces the message itself
+	msg.Message = replaceMessage(h.opts, rec.Message)
+	if err := h.writer.Write(formatSyslogMessage(h.header, msg, sd.msgID, sd.params)); err != nil {
+		if h.fallback != nil {
+			return h.fallback.Handle(ctx, rec)
+		}
+		return err
+	}
+	return nil
+}
+
+// WithAttrs returns a handler with additional attributes.
+//
+// Attrs are rendered right away so they keep the groups opened so far.
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = syslogData{msgID: h.attrs.msgID, params: slices.Clone(h.attrs.params)}
+	for _, attr := range attrs {
+		next.attrs.add(h.opts, h.groups, attr)
+	}
+	if h.fallback != nil {
+		next.fallback = h.fallback.WithAttrs(attrs)
+	}
+	return &next
+}
+
+// WithGroup returns a handler that nests future attributes under group.
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	if strings.TrimSpace(name) == "" {
+		return h
+	}
+
+	next := *h
+	next.groups = appendCopy(h.groups, name)
+	if h.fallback != nil {
+		next.fallback = h.fallback.WithGroup(name)
+	}
+	return &next
+}
+
+// formatSyslogMessage renders rec as an RFC 5424 message without transport framing.
+func formatSyslogMessage(header syslogHeader, rec slog.Record, msgID string, params []syslogParam) []byte {
+	timestamp := syslogNil
+	if msgID == "" {
+		msgID = syslogNil
+	}
+	if !rec.Time.IsZero() {
+		timestamp = rec.Time.Format(syslogTimeLayout)
+	}
+
+	var b strings.Builder
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(header.facility*8 + syslogSeverity(rec.Level)))
+	b.WriteString(">1 ")
+	for _, field := range []string{timestamp, header.hostname, header.appName, header.procID, msgID} {
+		b.WriteString(field)
+		b.WriteByte(' ')
+	}
+
+	if len(params) == 0 {
+		b.WriteString(syslogNil)
+	} else {
+		b.WriteString("[" + syslogSDID)
+		for _, param := range params {
+			b.WriteString(" " + param.name + `="` + escapeSyslogParamValue(param.value) + `"`)
+		}
+		b.WriteByte(']')
+	}
+
+	if rec.Message != "" {
+		b.WriteByte(' ')
+		b.WriteString(rec.Message)
+	}
+	return []byte(b.String())
+}
+
+// syslogSeverity maps slog levels to syslog severities.
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3
+	case level >= slog.LevelWarn:
+		return 4
+	case level >= slog.LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// appendSourceParams appends source metadata from program counter.
+func appendSourceParams(params []syslogParam, pc uintptr) []syslogParam {
+	frame, ok := runtime.CallersFrames([]uintptr{pc}).Next()
+	if !ok {
+		return params
+	}
+	return append(params,
+		syslogParam{name: "code_file", value: frame.File},
+		syslogParam{name: "code_line", value: strconv.Itoa(frame.Line)},
+		syslogParam{name: "code_func", value: frame.Function},
+	)
+}
+
+// syslogParamName joins groups and key with "." into a valid SD-PARAM name:
+// at most 32 printable ASCII characters other than '=', ' ', ']' and '"'.
+func syslogParamName(groups []string, key string) string {
+	parts := make([]string, 0, len(groups)+1)
+	parts = append(parts, groups...)
+	name := strings.Join(append(parts, key), ".")
+
+	var b strings.Builder
+	for _, ch := range name {
+		if ch <= ' ' || ch > '~' || ch == '=' || ch == ']' || ch == '"' {
+			ch = '_'
+		}
+		b.WriteRune(ch)
+	}
+	out := b.String()
+	if len(out) > syslogParamMaxLen {
+		out = out[:syslogParamMaxLen]
+	}
+	if out == "" {
+		return "field"
+	}
+	return out
+}
+
+// escapeSyslogParamValue escapes '"', '\' and ']' as RFC 5424 requires.
+func escapeSyslogParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+// isSyslogHeaderToken reports whether value is 1 to maxLen printable ASCII
+// characters, as required for HOSTNAME, APP-NAME and MSGID.
+func isSyslogHeaderToken(value string, maxLen int) bool {
+	if value == "" || len(value) > maxLen {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < '!' || value[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
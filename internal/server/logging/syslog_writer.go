@@ -0,0 +1,171 @@
+package logging
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	syslogDialTimeout    = 5 * time.Second
+	syslogWriteTimeout   = 5 * time.Second
+	syslogRedialInterval = 5 * time.Second // records go to the fallback in between
+)
+
+var errSyslogDisconnected = errors.New("syslog relay disconnected")
+
+// syslogWriter delivers formatted messages to a syslog relay.
+//
+// Datagram transports send one message per datagram. TCP and TLS use octet
+// counting framing (RFC 6587, RFC 5425) and a local stream socket gets
+// newline-terminated messages. A failed write reconnects once and retries;
+// while the relay stays down, reconnects are attempted every
+// syslogRedialInterval.
+type syslogWriter struct {
+	mu        sync.Mutex
+	network   string // unix, udp, tcp or tls
+	address   string
+	tlsConfig *tls.Config
+	conn      net.Conn
+	stream    bool // conn is connection-oriented
+	lastDial  time.Time
+}
+
+// newSyslogWriter prepares a writer for cfg without connecting.
+func newSyslogWriter(cfg SyslogSinkConfig) (*syslogWriter, error) {
+	w := &syslogWriter{network: cfg.Network, address: cfg.Address}
+	if cfg.Network == "tls" {
+		tlsConfig, err := newSyslogTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		w.tlsConfig = tlsConfig
+	}
+	return w, nil
+}
+
+// newSyslogTLSConfig loads the CA bundle and client certificate of cfg.
+func newSyslogTLSConfig(cfg SyslogSinkConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if host, _, err := net.SplitHostPort(cfg.Address); err == nil {
+		tlsConfig.ServerName = host
+	}
+	if cfg.TLS == nil {
+		return tlsConfig, nil
+	}
+	if cfg.TLS.ServerName != "" {
+		tlsConfig.ServerName = cfg.TLS.ServerName
+	}
+	if cfg.TLS.CAFile != "" {
+		pem, err := os.ReadFile(cfg.TLS.CAFile) // #nosec G304 -- by design, comes from config
+		if err != nil {
+			return nil, fmt.Errorf("logging sink syslog tls ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("logging sink syslog tls ca_file %s contains no certificates", cfg.TLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("logging sink syslog tls key pair: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// connect dials the relay.
+func (w *syslogWriter) connect() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.dial()
+}
+
+// dial opens a new connection; callers hold w.mu.
+func (w *syslogWriter) dial() error {
+	w.lastDial = time.Now()
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+
+	var conn net.Conn
+	var err error
+	switch w.network {
+	case "unix":
+		// Local syslog daemons listen on a datagram or a stream socket.
+		w.stream = false
+		conn, err = dialer.Dial("unixgram", w.address)
+		if err != nil {
+			w.stream = true
+			conn, err = dialer.Dial("unix", w.address)
+		}
+	case "tls":
+		w.stream = true
+		conn, err = tls.DialWithDialer(dialer, "tcp", w.address, w.tlsConfig)
+	default:
+		w.stream = w.network == "tcp"
+		conn, err = dialer.Dial(w.network, w.address)
+	}
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write sends one message, reconnecting once if the connection failed.
+func (w *syslogWriter) Write(msg []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		if time.Since(w.lastDial) < syslogRedialInterval {
+			return errSyslogDisconnected
+		}
+		if err := w.dial(); err != nil {
+			return err
+		}
+	}
+	if err := w.send(msg); err == nil {
+		return nil
+	}
+
+	_ = w.conn.Close()
+	w.conn = nil
+	if err := w.dial(); err != nil {
+		return err
+	}
+	if err := w.send(msg); err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
+
+// send frames msg for the transport and writes it; callers hold w.mu.
+func (w *syslogWriter) send(msg []byte) error {
+	frame := msg
+	switch {
+	case w.network == "unix" && w.stream:
+		frame = append(append([]byte(nil), msg...), '\n')
+	case w.stream:
+		frame = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	if w.network != "unix" {
+		if err := w.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout)); err != nil {
+			return err
+		}
+	}
+	_, err := w.conn.Write(frame)
+	return err
+}
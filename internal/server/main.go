@@ -2,9 +2,18 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"poke/internal/server/audit"
 	"poke/internal/server/dispatch"
+	"poke/internal/server/health"
+	"poke/internal/server/jobs"
 	"poke/internal/server/listener"
+	"poke/internal/server/metrics"
+	"poke/internal/server/redact"
 	"poke/internal/server/request"
+	"poke/internal/server/tracing"
+	"sync"
 )
 
 const defaultRequestBuffer = 16 // Default buffer for inbound command requests.
@@ -13,30 +22,187 @@ const defaultRequestBuffer = 16 // Default buffer for inbound command requests.
 type Runtime struct {
 	RequestChannel chan request.CommandRequest
 	Dispatcher     *dispatch.SyncDispatcher
+	Jobs           *jobs.Registry
 	Listeners      []listener.Listener
+	Tracer         *tracing.Tracer // nil when tracing is disabled
+	Health         *health.Checker // readiness checks behind /readyz
+	Audit          *audit.Log      // nil when auditing is disabled
+
+	cancel    context.CancelFunc // stops the dispatcher and side listeners
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // Start wires configuration into listeners and the dispatcher, then starts them.
+//
+// Components stop when ctx is done; Close stops them in order instead.
 func Start(ctx context.Context, cfg Config) (*Runtime, error) {
-	reqCh := make(chan request.CommandRequest, defaultRequestBuffer)
+	ctx, cancel := context.WithCancel(ctx)
+	runtime, err := start(ctx, cfg)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	runtime.cancel = cancel
+	return runtime, nil
+}
+
+// start wires the runtime's components under ctx.
+func start(ctx context.Context, cfg Config) (*Runtime, error) {
 	registry := &cfg.Commands
+	if err := registry.ProbeSandboxes(); err != nil {
+		return nil, err
+	}
 
-	startedListeners, err := cfg.Listeners.StartAll(ctx, reqCh)
+	redactor, err := newRedactor(cfg)
 	if err != nil {
 		return nil, err
 	}
+	redact.SetDefault(redactor)
 
-	executors := registry.ExecutorNames()
-	dispatcher, err := dispatch.NewSyncDispatcher(ctx, registry, executors, reqCh)
+	tracer, err := tracing.New(cfg.Tracing)
 	if err != nil {
 		return nil, err
 	}
+	tracing.SetDefault(tracer)
+
+	auditLog, err := audit.Open(cfg.Audit)
+	if err != nil {
+		return nil, abortStart(nil, tracer, nil, err)
+	}
+	audit.SetDefault(auditLog)
+
+	jobRegistry, recovered, err := jobs.Open(cfg.Queue)
+	if err != nil {
+		return nil, abortStart(nil, tracer, auditLog, err)
+	}
+	jobRegistry.SetCoalescePolicy(registry.Coalesces)
+
+	// Recovered jobs go first and must fit the buffer, listeners are not started yet.
+	reqCh := make(chan request.CommandRequest, max(defaultRequestBuffer, len(recovered)))
+	for _, req := range recovered {
+		reqCh <- req
+	}
+
+	executors := registry.ExecutorNames()
+	dispatcher, err := dispatch.NewSyncDispatcher(ctx, registry, executors, reqCh, jobRegistry)
+	if err != nil {
+		return nil, abortStart(jobRegistry, tracer, auditLog, err)
+	}
+
+	metrics.RequestQueueDepth.SetFunc(func() float64 { return float64(len(reqCh)) })
+	readiness := newReadiness(dispatcher, reqCh, cfg.Listeners.Len())
+	if err := startSideListeners(ctx, cfg, readiness.checker); err != nil {
+		return nil, abortStart(jobRegistry, tracer, auditLog, err)
+	}
+
+	startedListeners, err := cfg.Listeners.StartAll(ctx, reqCh, jobRegistry, dispatcher, listenerHealthRoutes(cfg.Health, readiness.checker), registry)
+	if err != nil {
+		return nil, abortStart(jobRegistry, tracer, auditLog, err)
+	}
+	readiness.listenersStarted(len(startedListeners))
 
 	go dispatcher.Run()
 
 	return &Runtime{
 		RequestChannel: reqCh,
 		Dispatcher:     dispatcher,
+		Jobs:           jobRegistry,
 		Listeners:      startedListeners,
+		Tracer:         tracer,
+		Health:         readiness.checker,
+		Audit:          auditLog,
 	}, nil
 }
+
+// Close shuts the runtime down in order: listeners stop accepting requests
+// and finish the ones in flight, the dispatcher runs the requests already
+// queued, then the job registry, audit log and tracer are closed.
+//
+// When ctx is done before the dispatcher drained its queue, the dispatcher is
+// canceled, which stops the running execution, and Close waits for it to
+// return. Scheduled requests and retries stay in the journal for the next
+// start. Close is safe to call more than once.
+func (r *Runtime) Close(ctx context.Context) error {
+	r.closeOnce.Do(func() {
+		r.closeErr = r.close(ctx)
+	})
+	return r.closeErr
+}
+
+func (r *Runtime) close(ctx context.Context) error {
+	var errs []error
+	for _, l := range r.Listeners {
+		if err := l.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("listener shutdown: %w", err))
+		}
+	}
+	if err := r.stopDispatcher(ctx, len(errs) == 0); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := r.Jobs.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("job registry close: %w", err))
+	}
+	audit.SetDefault(nil)
+	if err := r.Audit.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("audit log close: %w", err))
+	}
+	tracing.SetDefault(nil)
+	if err := r.Tracer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("trace flush: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// stopDispatcher lets the dispatcher drain its queue until ctx is done, then
+// cancels it and waits for Run to return. The request channel is only closed
+// when drain is set: a listener that failed to shut down may still send on it.
+func (r *Runtime) stopDispatcher(ctx context.Context, drain bool) error {
+	var err error
+	if drain {
+		close(r.RequestChannel)
+		select {
+		case <-r.Dispatcher.Done():
+		case <-ctx.Done():
+			err = fmt.Errorf("dispatcher drain: %w", ctx.Err())
+		}
+	}
+	r.cancel()
+	<-r.Dispatcher.Done()
+	return err
+}
+
+// newRedactor builds the redactor masking cfg's patterns and the secrets cfg
+// knows about: listener API and admin tokens and command env values marked secret.
+func newRedactor(cfg Config) (*redact.Redactor, error) {
+	secrets := append(cfg.Listeners.Secrets(), cfg.Commands.Secrets()...)
+	return redact.New(cfg.Redaction, secrets)
+}
+
+// startSideListeners starts the metrics and separate health listeners when configured.
+func startSideListeners(ctx context.Context, cfg Config, checker *health.Checker) error {
+	if cfg.Metrics.Enabled {
+		if _, err := metrics.Listen(ctx, cfg.Metrics, metrics.Default); err != nil {
+			return err
+		}
+	}
+	if cfg.Health.Separate() {
+		if _, err := health.Listen(ctx, cfg.Health, checker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// abortStart releases what Start set up before failing with err.
+func abortStart(jobRegistry *jobs.Registry, tracer *tracing.Tracer, auditLog *audit.Log, err error) error {
+	if jobRegistry != nil {
+		_ = jobRegistry.Close()
+	}
+	audit.SetDefault(nil)
+	_ = auditLog.Close()
+	tracing.SetDefault(nil)
+	_ = tracer.Shutdown(context.Background())
+	return err
+}
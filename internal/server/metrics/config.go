@@ -0,0 +1,30 @@
+package metrics
+
+import "poke/internal/server/sidelistener"
+
+const (
+	defaultHost = "127.0.0.1"
+	defaultPort = 9464
+	metricsPath = "/metrics"
+)
+
+// Config selects the metrics listener, see docs/configuration/metrics.md.
+//
+// The zero value is disabled; a `metrics` block enables the listener unless it
+// sets `enabled: false`.
+type Config sidelistener.Config
+
+// UnmarshalYAML parses metrics config and applies the documented defaults.
+func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	parsed, err := sidelistener.Decode(unmarshal, sidelistener.Config{Enabled: true, Host: defaultHost, Port: defaultPort})
+	if err != nil {
+		return err
+	}
+	*cfg = Config(parsed)
+	return sidelistener.Config(*cfg).Validate("metrics", "")
+}
+
+// Address returns the host:port the metrics listener binds to.
+func (cfg Config) Address() string {
+	return sidelistener.Config(cfg).Address()
+}
@@ -0,0 +1,265 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+)
+
+// family stores one value set per combination of label values.
+type family[T any] struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	labels []string
+	series map[string]*series[T]
+}
+
+type series[T any] struct {
+	values []string
+	data   T
+}
+
+func newFamily[T any](name string, help string, labels []string) family[T] {
+	return family[T]{name: name, help: help, labels: labels, series: make(map[string]*series[T])}
+}
+
+// with returns the series for values, creating it with init; callers hold f.mu.
+func (f *family[T]) with(values []string, init func() T) *series[T] {
+	checkLabels(f.name, f.labels, values)
+	key := labelKey(values)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series[T]{values: append([]string(nil), values...), data: init()}
+		f.series[key] = s
+	}
+	return s
+}
+
+// sorted returns all series ordered by label values; callers hold f.mu.
+func (f *family[T]) sorted() []*series[T] {
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]*series[T], len(keys))
+	for i, key := range keys {
+		out[i] = f.series[key]
+	}
+	return out
+}
+
+// CounterVec is a monotonically increasing value per label combination.
+type CounterVec struct {
+	family[float64]
+}
+
+// NewCounterVec registers a counter family in r.
+func (r *Registry) NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	c := &CounterVec{family: newFamily[float64](name, help, labels)}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the counter for values.
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds delta, which must not be negative, to the counter for values.
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.with(values, func() float64 { return 0 }).data += delta
+}
+
+// Value returns the current counter for values, zero if it was never incremented.
+func (c *CounterVec) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, ok := c.series[labelKey(values)]; ok {
+		return s.data
+	}
+	return 0
+}
+
+func (c *CounterVec) describe() (string, string, string) { return c.name, c.help, "counter" }
+
+func (c *CounterVec) samples() []sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var out []sample
+	for _, s := range c.sorted() {
+		out = append(out, sample{labels: pairs(c.labels, s.values), value: s.data})
+	}
+	return out
+}
+
+// GaugeVec is a value per label combination that can go up and down.
+type GaugeVec struct {
+	family[float64]
+}
+
+// NewGaugeVec registers a gauge family in r.
+func (r *Registry) NewGaugeVec(name string, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{family: newFamily[float64](name, help, labels)}
+	r.register(g)
+	return g
+}
+
+// Set sets the gauge for values.
+func (g *GaugeVec) Set(v float64, values ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.with(values, func() float64 { return 0 }).data = v
+}
+
+// Value returns the current gauge for values.
+func (g *GaugeVec) Value(values ...string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if s, ok := g.series[labelKey(values)]; ok {
+		return s.data
+	}
+	return 0
+}
+
+func (g *GaugeVec) describe() (string, string, string) { return g.name, g.help, "gauge" }
+
+func (g *GaugeVec) samples() []sample {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var out []sample
+	for _, s := range g.sorted() {
+		out = append(out, sample{labels: pairs(g.labels, s.values), value: s.data})
+	}
+	return out
+}
+
+// GaugeFunc is an unlabelled gauge whose value is read when metrics are written.
+type GaugeFunc struct {
+	mu   sync.Mutex
+	name string
+	help string
+	fn   func() float64
+}
+
+// NewGaugeFunc registers a gauge in r that reports 0 until SetFunc is called.
+func (r *Registry) NewGaugeFunc(name string, help string) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help}
+	r.register(g)
+	return g
+}
+
+// SetFunc replaces the function read on every scrape.
+func (g *GaugeFunc) SetFunc(fn func() float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.fn = fn
+}
+
+// Value returns the gauge's current value.
+func (g *GaugeFunc) Value() float64 {
+	g.mu.Lock()
+	fn := g.fn
+	g.mu.Unlock()
+
+	if fn == nil {
+		return 0
+	}
+	return fn()
+}
+
+func (g *GaugeFunc) describe() (string, string, string) { return g.name, g.help, "gauge" }
+
+func (g *GaugeFunc) samples() []sample {
+	return []sample{{value: g.Value()}}
+}
+
+// HistogramVec counts observations into cumulative buckets per label combination.
+type HistogramVec struct {
+	family[*histogram]
+	buckets []float64
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec registers a histogram family in r with ascending bucket upper bounds.
+func (r *Registry) NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{family: newFamily[*histogram](name, help, labels), buckets: append([]float64(nil), buckets...)}
+	sort.Float64s(h.buckets)
+	r.register(h)
+	return h
+}
+
+// Observe records v for values.
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.with(values, func() *histogram { return &histogram{counts: make([]uint64, len(h.buckets))} })
+	i := sort.SearchFloat64s(h.buckets, v)
+	if i < len(h.buckets) {
+		s.data.counts[i]++
+	}
+	s.data.count++
+	s.data.sum += v
+}
+
+// Count returns the number of observations for values.
+func (h *HistogramVec) Count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s, ok := h.series[labelKey(values)]; ok {
+		return s.data.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) describe() (string, string, string) { return h.name, h.help, "histogram" }
+
+func (h *HistogramVec) samples() []sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var out []sample
+	for _, s := range h.sorted() {
+		labels := pairs(h.labels, s.values)
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.data.counts[i]
+			out = append(out, sample{suffix: "_bucket", labels: withLabel(labels, "le", formatValue(bound)), value: float64(cumulative)})
+		}
+		out = append(out,
+			sample{suffix: "_bucket", labels: withLabel(labels, "le", "+Inf"), value: float64(s.data.count)},
+			sample{suffix: "_sum", labels: labels, value: s.data.sum},
+			sample{suffix: "_count", labels: labels, value: float64(s.data.count)},
+		)
+	}
+	return out
+}
+
+func withLabel(labels []labelPair, name string, value string) []labelPair {
+	out := make([]labelPair, len(labels), len(labels)+1)
+	copy(out, labels)
+	return append(out, labelPair{name: name, value: value})
+}
+
+// DurationBuckets are histogram bounds in seconds for queue waits and command runs.
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
@@ -0,0 +1,25 @@
+package metrics
+
+// Metrics poke exposes on the metrics listener, see docs/configuration/metrics.md.
+var (
+	// Requests counts command requests by listener and outcome: "accepted" or the HTTP status code.
+	Requests = Default.NewCounterVec("poke_requests_total", "Command requests by listener and outcome.", "listener", "outcome")
+	// AuthFailures counts rejected credentials by listener and requested auth method.
+	AuthFailures = Default.NewCounterVec("poke_auth_failures_total", "Failed authentications by listener and auth method.", "listener", "method")
+	// Executions counts command attempts by command, outcome and exit status.
+	Executions = Default.NewCounterVec("poke_executions_total", "Command executions by command, outcome and exit status.", "command_id", "outcome", "exit_status")
+	// ExecutionDuration observes how long each command attempt ran.
+	ExecutionDuration = Default.NewHistogramVec("poke_execution_duration_seconds", "Command execution duration in seconds.", DurationBuckets, "command_id")
+	// QueueWait observes how long jobs waited between becoming due and starting.
+	QueueWait = Default.NewHistogramVec("poke_queue_wait_seconds", "Time jobs waited in the queue before starting, in seconds.", DurationBuckets)
+	// RequestQueueDepth reports requests buffered between listeners and the dispatcher.
+	RequestQueueDepth = Default.NewGaugeFunc("poke_request_queue_depth", "Requests buffered for the dispatcher.")
+	// DispatchQueueDepth reports requests drained by the dispatcher and waiting per priority.
+	DispatchQueueDepth = Default.NewGaugeVec("poke_dispatch_queue_depth", "Requests waiting in the dispatcher priority queue by priority.", "priority")
+)
+
+// Outcome label values not tied to an HTTP status.
+const (
+	OutcomeAccepted = "accepted"
+	NoExitStatus    = "none" // the command did not exit, e.g. it failed to start
+)
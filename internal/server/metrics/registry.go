@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds metrics and renders them in the Prometheus text exposition format.
+//
+// Only what poke needs is implemented: counters, gauges and histograms with
+// string labels. Samples are written sorted by label values, so the output is
+// stable and can be compared in tests.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+	names      map[string]struct{}
+}
+
+// collector is a metric family that can write itself in text format.
+type collector interface {
+	describe() (name string, help string, kind string)
+	samples() []sample
+}
+
+// sample is one line of a metric family.
+type sample struct {
+	suffix string // appended to the family name, e.g. "_bucket"
+	labels []labelPair
+	value  float64
+}
+
+type labelPair struct {
+	name  string
+	value string
+}
+
+// Default is the registry poke's own metrics are registered in.
+var Default = NewRegistry()
+
+// NewRegistry constructs an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]struct{})}
+}
+
+// register adds c, metric names must be unique within a registry.
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name, _, _ := c.describe()
+	if _, exists := r.names[name]; exists {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", name))
+	}
+	r.names[name] = struct{}{}
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+	sort.Slice(collectors, func(i, j int) bool {
+		a, _, _ := collectors[i].describe()
+		b, _, _ := collectors[j].describe()
+		return a < b
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		name, help, kind := c.describe()
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+		for _, s := range c.samples() {
+			bw.WriteString(name + s.suffix)
+			writeLabels(bw, s.labels)
+			bw.WriteString(" " + formatValue(s.value) + "\n")
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry at any path.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+func writeLabels(w *bufio.Writer, labels []labelPair) {
+	if len(labels) == 0 {
+		return
+	}
+	w.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString(l.name + `="` + escapeLabelValue(l.value) + `"`)
+	}
+	w.WriteByte('}')
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// labelKey joins label values into a map key; NUL bytes are dropped from
+// values so the separator is unambiguous.
+func labelKey(values []string) string {
+	cleaned := make([]string, len(values))
+	for i, v := range values {
+		cleaned[i] = strings.ReplaceAll(v, "\x00", "")
+	}
+	return strings.Join(cleaned, "\x00")
+}
+
+// pairs zips label names with values.
+func pairs(names []string, values []string) []labelPair {
+	out := make([]labelPair, len(names))
+	for i, name := range names {
+		out[i] = labelPair{name: name, value: values[i]}
+	}
+	return out
+}
+
+// checkLabels panics on a label count mismatch, which is always a programming error.
+func checkLabels(name string, names []string, values []string) {
+	if len(names) != len(values) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", name, len(names), len(values)))
+	}
+}
@@ -0,0 +1,15 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"poke/internal/server/sidelistener"
+)
+
+// Listen binds cfg's address and serves reg at /metrics until ctx is done.
+func Listen(ctx context.Context, cfg Config, reg *Registry) (net.Addr, error) {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, reg.Handler())
+	return sidelistener.Listen(ctx, "metrics", cfg.Address(), mux)
+}
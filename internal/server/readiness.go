@@ -0,0 +1,59 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"poke/internal/server/dispatch"
+	"poke/internal/server/health"
+	"poke/internal/server/listener"
+	"poke/internal/server/request"
+	"sync/atomic"
+)
+
+// readiness backs /readyz with the runtime's dispatcher, request buffer and listeners.
+type readiness struct {
+	checker    *health.Checker
+	configured int
+	started    atomic.Int64
+}
+
+// newReadiness registers the dispatcher, queue and listeners checks.
+func newReadiness(dispatcher *dispatch.SyncDispatcher, reqCh chan request.CommandRequest, listeners int) *readiness {
+	r := &readiness{checker: health.NewChecker(), configured: listeners}
+	r.checker.Register("dispatcher", func() (string, error) {
+		if !dispatcher.Running() {
+			return "stopped", errors.New("dispatcher is not running")
+		}
+		return "running", nil
+	})
+	r.checker.Register("queue", func() (string, error) {
+		detail := fmt.Sprintf("%d/%d buffered", len(reqCh), cap(reqCh))
+		if len(reqCh) >= cap(reqCh) {
+			return detail, errors.New("request buffer is full")
+		}
+		return detail, nil
+	})
+	r.checker.Register("listeners", func() (string, error) {
+		started := int(r.started.Load())
+		detail := fmt.Sprintf("%d/%d started", started, r.configured)
+		if started < r.configured {
+			return detail, errors.New("not all listeners started")
+		}
+		return detail, nil
+	})
+	return r
+}
+
+// listenersStarted records how many listeners StartAll started.
+func (r *readiness) listenersStarted(n int) {
+	r.started.Store(int64(n))
+}
+
+// listenerHealthRoutes returns the routes HTTP listeners serve, nil when the
+// endpoints are disabled or have their own listener.
+func listenerHealthRoutes(cfg health.Config, checker *health.Checker) listener.HealthRoutes {
+	if !cfg.Enabled || cfg.Separate() {
+		return nil
+	}
+	return checker
+}
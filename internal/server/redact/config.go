@@ -0,0 +1,51 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultReplacement is what a redacted secret is replaced with.
+const DefaultReplacement = "[REDACTED]"
+
+// Config lists extra patterns to redact, see docs/configuration/redaction.md.
+//
+// Known secrets are always redacted; the zero value adds no patterns.
+type Config struct {
+	Patterns    []string `yaml:"patterns,omitempty"`    // regular expressions, capture groups limit what is replaced
+	Replacement string   `yaml:"replacement,omitempty"` // default DefaultReplacement
+}
+
+// UnmarshalYAML parses redaction config and compiles its patterns to validate them.
+func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type configAlias Config
+
+	var in configAlias
+	if err := unmarshal(&in); err != nil {
+		return err
+	}
+
+	*cfg = Config(in)
+	_, err := cfg.compile()
+	return err
+}
+
+// compile parses Patterns, rejecting empty ones and those matching the empty string.
+func (cfg Config) compile() ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(cfg.Patterns))
+	for _, pattern := range cfg.Patterns {
+		if strings.TrimSpace(pattern) == "" {
+			return nil, fmt.Errorf("redaction patterns must not be empty")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %q: %w", pattern, err)
+		}
+		if re.MatchString("") {
+			return nil, fmt.Errorf("redaction pattern %q must not match the empty string", pattern)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
@@ -0,0 +1,140 @@
+package redact
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Redactor masks known secrets and configured patterns in text.
+//
+// A nil *Redactor, and the default redactor until SetDefault is called,
+// returns text unchanged.
+type Redactor struct {
+	secrets     *strings.Replacer
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+var defaultRedactor atomic.Pointer[Redactor]
+
+// Default returns the redactor set with SetDefault, nil when none is set.
+func Default() *Redactor {
+	return defaultRedactor.Load()
+}
+
+// SetDefault makes r the redactor used by logging, jobs and executors.
+func SetDefault(r *Redactor) {
+	defaultRedactor.Store(r)
+}
+
+// New builds a redactor masking secrets, taken literally, and cfg's patterns.
+// Empty secrets are ignored.
+func New(cfg Config, secrets []string) (*Redactor, error) {
+	patterns, err := cfg.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Redactor{patterns: patterns, replacement: cmp.Or(cfg.Replacement, DefaultReplacement)}
+
+	// Longer secrets go first so one containing another is masked whole.
+	known := slices.DeleteFunc(slices.Clone(secrets), func(s string) bool { return s == "" })
+	slices.SortFunc(known, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	known = slices.Compact(known)
+	if len(known) > 0 {
+		pairs := make([]string, 0, len(known)*2)
+		for _, secret := range known {
+			pairs = append(pairs, secret, r.replacement)
+		}
+		r.secrets = strings.NewReplacer(pairs...)
+	}
+	return r, nil
+}
+
+// String returns s with secrets and pattern matches replaced.
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	if r.secrets != nil {
+		s = r.secrets.Replace(s)
+	}
+	for _, re := range r.patterns {
+		s = r.replacePattern(re, s)
+	}
+	return s
+}
+
+// Bytes returns b redacted like String; b itself is not modified.
+func (r *Redactor) Bytes(b []byte) []byte {
+	if r == nil || len(b) == 0 {
+		return b
+	}
+	return []byte(r.String(string(b)))
+}
+
+// replacePattern replaces every match of re in s, or only its capture groups
+// when it has any.
+func (r *Redactor) replacePattern(re *regexp.Regexp, s string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllLiteralString(s, r.replacement)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+		for i := 2; i < len(match); i += 2 {
+			start, end := match[i], match[i+1]
+			if start < last || start == end {
+				continue // unmatched, empty or nested group
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(r.replacement)
+			last = end
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// ReplaceAttr redacts string values and the text of other values, for use as
+// slog.HandlerOptions.ReplaceAttr. Time, level and source are left alone.
+func (r *Redactor) ReplaceAttr(groups []string, attr slog.Attr) slog.Attr {
+	if r == nil {
+		return attr
+	}
+	if len(groups) == 0 && (attr.Key == slog.TimeKey || attr.Key == slog.LevelKey || attr.Key == slog.SourceKey) {
+		return attr
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(r.String(attr.Value.String()))
+	case slog.KindAny:
+		attr.Value = r.anyValue(attr.Value.Any())
+	}
+	return attr
+}
+
+// anyValue redacts each element of a string slice, and the formatted text of
+// other values when it contains a secret.
+func (r *Redactor) anyValue(value any) slog.Value {
+	if values, ok := value.([]string); ok {
+		out := make([]string, len(values))
+		for i, s := range values {
+			out[i] = r.String(s)
+		}
+		return slog.AnyValue(out)
+	}
+
+	text := fmt.Sprint(value)
+	if redacted := r.String(text); redacted != text {
+		return slog.StringValue(redacted)
+	}
+	return slog.AnyValue(value)
+}
@@ -0,0 +1,43 @@
+package request
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// IDEnv is the environment variable a command receives its request ID in.
+const IDEnv = "POKE_REQUEST_ID"
+
+type idContextKey struct{}
+
+// NewID returns a random request correlation ID.
+func NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:]) // crypto/rand.Read never returns an error
+	return hex.EncodeToString(b[:])
+}
+
+// ContextWithID returns ctx carrying the correlation ID of the request being served.
+func ContextWithID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idContextKey{}, id)
+}
+
+// IDFromContext returns the request ID carried by ctx, "" when there is none.
+func IDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(idContextKey{}).(string)
+	return id
+}
+
+// Logger returns logger with a request_id attribute for the request ID in
+// ctx, or logger itself when ctx carries none.
+func Logger(ctx context.Context, logger *slog.Logger) *slog.Logger {
+	if id := IDFromContext(ctx); id != "" {
+		return logger.With("request_id", id)
+	}
+	return logger
+}
@@ -1,6 +1,31 @@
 package request
 
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	MinPriority = 0 // Lowest request priority, the default
+	MaxPriority = 9 // Highest request priority
+)
+
 // CommandRequest identifies a pre-registered command to execute.
 type CommandRequest struct {
+	JobID     string // Job tracking this request, assigned by the listener
 	CommandID string
+	Payload   []byte    // Optional input fed to commands configured with `stdin.payload`
+	RunAt     time.Time // Earliest execution time, zero = as soon as possible
+	Priority  *int      // Per-request priority override, nil = the command's priority
+	// W3C traceparent of the span that accepted the request, "" when untraced
+	TraceParent string
+	RequestID   string // Correlation ID logged with every record about the request
+}
+
+// ValidatePriority checks priority is within MinPriority and MaxPriority.
+func ValidatePriority(priority int) error {
+	if priority < MinPriority || priority > MaxPriority {
+		return fmt.Errorf("priority %d must be between %d and %d", priority, MinPriority, MaxPriority)
+	}
+	return nil
 }
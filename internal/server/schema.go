@@ -0,0 +1,17 @@
+package server
+
+import (
+	_ "embed"
+	"slices"
+)
+
+//go:embed schema.json
+var schema []byte
+
+// Schema returns a JSON Schema (draft 2020-12) of the config format Parse
+// reads, for editor completion and CI checks of config files.
+//
+// It is stricter than Parse in one way: keys Parse ignores are rejected.
+func Schema() []byte {
+	return slices.Clone(schema)
+}
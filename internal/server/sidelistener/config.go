@@ -0,0 +1,70 @@
+// Package sidelistener serves operational endpoints, such as /metrics and
+// /readyz, on a listener of their own next to the command listeners.
+package sidelistener
+
+import (
+	"fmt"
+	"strings"
+)
+
+const maxPort = 65535
+
+// Config is the address of a side listener. Packages serving an endpoint
+// define their config as a named type of Config with their own defaults.
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	Host    string `yaml:"host,omitempty"`
+	Port    int    `yaml:"port,omitempty"`
+}
+
+// Decode parses the enabled, host and port keys over defaults.
+func Decode(unmarshal func(interface{}) error, defaults Config) (Config, error) {
+	type configInput struct {
+		Enabled *bool   `yaml:"enabled"`
+		Host    *string `yaml:"host"`
+		Port    *int    `yaml:"port"`
+	}
+
+	var in configInput
+	if err := unmarshal(&in); err != nil {
+		return Config{}, err
+	}
+
+	cfg := defaults
+	if in.Enabled != nil {
+		cfg.Enabled = *in.Enabled
+	}
+	if in.Host != nil {
+		cfg.Host = strings.TrimSpace(*in.Host)
+	}
+	if in.Port != nil {
+		cfg.Port = *in.Port
+	}
+	return cfg, nil
+}
+
+// Validate checks the address of an enabled config, naming the endpoint in
+// errors. A zero port is only accepted when zeroPort describes its meaning.
+func (cfg Config) Validate(name string, zeroPort string) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Host == "" {
+		return fmt.Errorf("%s host must not be empty", name)
+	}
+	if cfg.Port == 0 && zeroPort != "" {
+		return nil
+	}
+	if cfg.Port < 1 || cfg.Port > maxPort {
+		if zeroPort != "" {
+			return fmt.Errorf("%s port must be between 1 and %d, or 0 to %s", name, maxPort, zeroPort)
+		}
+		return fmt.Errorf("%s port must be between 1 and %d", name, maxPort)
+	}
+	return nil
+}
+
+// Address returns the host:port the listener binds to.
+func (cfg Config) Address() string {
+	return fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+}
@@ -0,0 +1,48 @@
+package sidelistener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Listen binds address and serves handler until ctx is done. name is the
+// endpoint's log component and event prefix, such as "metrics".
+//
+// Binding happens before Listen returns, so a port conflict is reported to the
+// caller instead of being logged from the serve goroutine.
+func Listen(ctx context.Context, name string, address string, handler http.Handler) (net.Addr, error) {
+	logger := slog.Default().With("component", name)
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("start %s listener on %s: %w", name, address, err)
+	}
+
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: shutdownTimeout}
+
+	logger.Info(name+" listener starting", "event", name+"_listener_starting", "address", ln.Addr().String())
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error(name+" listener shutdown failed", "event", name+"_listener_shutdown_failed", "error", err)
+		}
+	}()
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(name+" listener serve failed", "event", name+"_listener_serve_failed", "error", err)
+		}
+	}()
+
+	return ln.Addr(), nil
+}
@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	ExporterOTLP = "otlp" // OTLP/HTTP JSON to a collector
+	ExporterFile = "file" // OTLP/JSON lines appended to a local file
+
+	defaultExporter    = ExporterOTLP
+	defaultEndpoint    = "http://127.0.0.1:4318/v1/traces"
+	defaultServiceName = "poke"
+	defaultTimeout     = 10 * time.Second
+)
+
+// Config selects where spans are exported, see docs/configuration/tracing.md.
+//
+// The zero value is disabled; a `tracing` block enables tracing unless it sets
+// `enabled: false`.
+type Config struct {
+	Enabled     bool              `yaml:"enabled"`
+	Exporter    string            `yaml:"exporter,omitempty"`     // `otlp` (default) or `file`
+	Endpoint    string            `yaml:"endpoint,omitempty"`     // OTLP/HTTP traces URL
+	Headers     map[string]string `yaml:"headers,omitempty"`      // extra OTLP request headers
+	Timeout     time.Duration     `yaml:"timeout,omitempty"`      // OTLP request timeout
+	Path        string            `yaml:"path,omitempty"`         // trace file for `file`
+	ServiceName string            `yaml:"service_name,omitempty"` // resource service.name
+}
+
+// UnmarshalYAML parses tracing config and applies the documented defaults.
+func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type configInput struct {
+		Enabled     *bool             `yaml:"enabled"`
+		Exporter    string            `yaml:"exporter"`
+		Endpoint    string            `yaml:"endpoint"`
+		Headers     map[string]string `yaml:"headers"`
+		Timeout     time.Duration     `yaml:"timeout"`
+		Path        string            `yaml:"path"`
+		ServiceName string            `yaml:"service_name"`
+	}
+
+	var in configInput
+	if err := unmarshal(&in); err != nil {
+		return err
+	}
+
+	*cfg = Config{
+		Enabled:     in.Enabled == nil || *in.Enabled,
+		Exporter:    in.Exporter,
+		Endpoint:    in.Endpoint,
+		Headers:     in.Headers,
+		Timeout:     in.Timeout,
+		Path:        in.Path,
+		ServiceName: in.ServiceName,
+	}
+	cfg.Exporter = strings.ToLower(strings.TrimSpace(cfg.Exporter))
+	if cfg.Exporter == "" {
+		cfg.Exporter = defaultExporter
+	}
+	if cfg.Exporter == ExporterOTLP && cfg.Endpoint == "" {
+		cfg.Endpoint = defaultEndpoint
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = defaultServiceName
+	}
+	return cfg.validate()
+}
+
+// validate checks the exporter settings of an enabled config.
+func (cfg Config) validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("tracing timeout must not be negative")
+	}
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		if cfg.Path != "" {
+			return fmt.Errorf("tracing path is only valid with exporter file")
+		}
+		u, err := url.Parse(cfg.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tracing endpoint %q must be an http or https URL", cfg.Endpoint)
+		}
+	case ExporterFile:
+		if cfg.Endpoint != "" || len(cfg.Headers) > 0 {
+			return fmt.Errorf("tracing endpoint and headers are only valid with exporter otlp")
+		}
+		if !filepath.IsAbs(cfg.Path) {
+			return fmt.Errorf("tracing exporter file requires an absolute path")
+		}
+	default:
+		return fmt.Errorf("tracing exporter must be one of otlp or file")
+	}
+	return nil
+}
+
+// New builds a tracer for an enabled config; a disabled config yields nil.
+func New(cfg Config) (*Tracer, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	var exporter Exporter
+	switch cfg.Exporter {
+	case ExporterFile:
+		fileExporter, err := NewFileExporter(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		exporter = fileExporter
+	default:
+		exporter = NewOTLPExporter(cfg.Endpoint, cfg.Headers, cfg.Timeout)
+	}
+	return NewTracer(exporter, Resource{ServiceName: cfg.ServiceName}), nil
+}
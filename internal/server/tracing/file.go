@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileExporter appends each batch as one line of OTLP/JSON, the format the
+// OpenTelemetry Collector's file exporter writes and its otlpjsonfile
+// receiver reads.
+type FileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileExporter opens path for appending, creating missing parent directories.
+func NewFileExporter(path string) (*FileExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("create trace directory: %w", err)
+	}
+	// #nosec G304 -- path comes from trusted server config.
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("open trace file: %w", err)
+	}
+	return &FileExporter{file: file}, nil
+}
+
+// Export writes spans as a single JSON line.
+func (e *FileExporter) Export(_ context.Context, resource Resource, spans []SpanData) error {
+	line, err := json.Marshal(EncodeOTLP(resource, spans))
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err = e.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the trace file.
+func (e *FileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.file.Close()
+}
@@ -0,0 +1,168 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const otlpScopeName = "poke"
+
+// OTLPExporter posts spans as OTLP/HTTP JSON to a collector's /v1/traces endpoint.
+type OTLPExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewOTLPExporter constructs an exporter for endpoint, sending headers with every request.
+func NewOTLPExporter(endpoint string, headers map[string]string, timeout time.Duration) *OTLPExporter {
+	return &OTLPExporter{endpoint: endpoint, headers: headers, client: &http.Client{Timeout: timeout}}
+}
+
+// Export sends one batch; any non-2xx response is an error.
+func (e *OTLPExporter) Export(ctx context.Context, resource Resource, spans []SpanData) error {
+	body, err := json.Marshal(EncodeOTLP(resource, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("export spans to %s: %w", e.endpoint, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("export spans to %s: status %d", e.endpoint, resp.StatusCode)
+	}
+	return nil
+}
+
+// Close releases idle connections.
+func (e *OTLPExporter) Close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// OTLP/JSON request body, see opentelemetry-proto's trace service.
+type (
+	OTLPTraces struct {
+		ResourceSpans []OTLPResourceSpans `json:"resourceSpans"`
+	}
+	OTLPResourceSpans struct {
+		Resource   OTLPResource     `json:"resource"`
+		ScopeSpans []OTLPScopeSpans `json:"scopeSpans"`
+	}
+	OTLPResource struct {
+		Attributes []OTLPAttribute `json:"attributes"`
+	}
+	OTLPScopeSpans struct {
+		Scope OTLPScope  `json:"scope"`
+		Spans []OTLPSpan `json:"spans"`
+	}
+	OTLPScope struct {
+		Name string `json:"name"`
+	}
+	OTLPSpan struct {
+		TraceID                string          `json:"traceId"`
+		SpanID                 string          `json:"spanId"`
+		ParentSpanID           string          `json:"parentSpanId,omitempty"`
+		Name                   string          `json:"name"`
+		Kind                   int             `json:"kind"`
+		StartTimeUnixNano      string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano        string          `json:"endTimeUnixNano"`
+		Attributes             []OTLPAttribute `json:"attributes,omitempty"`
+		DroppedAttributesCount int             `json:"droppedAttributesCount,omitempty"`
+		Status                 OTLPStatus      `json:"status"`
+	}
+	OTLPStatus struct {
+		Code    int    `json:"code,omitempty"` // 0 unset, 2 error
+		Message string `json:"message,omitempty"`
+	}
+	OTLPAttribute struct {
+		Key   string    `json:"key"`
+		Value OTLPValue `json:"value"`
+	}
+	OTLPValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"` // int64 is a string in OTLP/JSON
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+const otlpStatusError = 2
+
+// EncodeOTLP converts spans into an OTLP/JSON export request.
+func EncodeOTLP(resource Resource, spans []SpanData) OTLPTraces {
+	out := make([]OTLPSpan, len(spans))
+	for i, s := range spans {
+		span := OTLPSpan{
+			TraceID:                s.Context.TraceID.String(),
+			SpanID:                 s.Context.SpanID.String(),
+			Name:                   s.Name,
+			Kind:                   int(s.Kind),
+			StartTimeUnixNano:      strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:        strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:             encodeAttrs(s.Attrs),
+			DroppedAttributesCount: s.DroppedAttrs,
+		}
+		if s.Parent != (SpanID{}) {
+			span.ParentSpanID = s.Parent.String()
+		}
+		if s.Failed {
+			span.Status = OTLPStatus{Code: otlpStatusError, Message: s.Error}
+		}
+		out[i] = span
+	}
+
+	return OTLPTraces{ResourceSpans: []OTLPResourceSpans{{
+		Resource:   OTLPResource{Attributes: encodeAttrs([]Attr{{Key: "service.name", Value: resource.ServiceName}})},
+		ScopeSpans: []OTLPScopeSpans{{Scope: OTLPScope{Name: otlpScopeName}, Spans: out}},
+	}}}
+}
+
+func encodeAttrs(attrs []Attr) []OTLPAttribute {
+	out := make([]OTLPAttribute, 0, len(attrs))
+	for _, a := range attrs {
+		out = append(out, OTLPAttribute{Key: a.Key, Value: encodeValue(a.Value)})
+	}
+	return out
+}
+
+func encodeValue(v any) OTLPValue {
+	switch v := v.(type) {
+	case string:
+		return OTLPValue{StringValue: &v}
+	case bool:
+		return OTLPValue{BoolValue: &v}
+	case int:
+		s := strconv.Itoa(v)
+		return OTLPValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return OTLPValue{IntValue: &s}
+	case float64:
+		return OTLPValue{DoubleValue: &v}
+	case time.Duration:
+		f := v.Seconds()
+		return OTLPValue{DoubleValue: &f}
+	default:
+		s := fmt.Sprint(v)
+		return OTLPValue{StringValue: &s}
+	}
+}
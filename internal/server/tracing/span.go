@@ -0,0 +1,197 @@
+package tracing
+
+import (
+	"context"
+	"poke/internal/server/redact"
+	"sync"
+	"time"
+)
+
+// SpanKind mirrors the OTLP span kinds poke emits.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+)
+
+// Attr is a span attribute; values are strings, bools, ints, floats or durations.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// SpanData is a finished span as handed to exporters.
+type SpanData struct {
+	Name         string
+	Kind         SpanKind
+	Context      SpanContext
+	Parent       SpanID // zero for root spans
+	Start        time.Time
+	End          time.Time
+	Attrs        []Attr
+	DroppedAttrs int    // attributes over the per-span limit
+	Failed       bool   // the span has an error status
+	Error        string // error status message
+}
+
+// Span is an operation in a trace, created with Start.
+//
+// Spans of a disabled tracer or of unsampled traces do not record anything
+// but still carry their parent's context, so trace headers pass through.
+type Span struct {
+	mu        sync.Mutex
+	tracer    *Tracer
+	data      SpanData
+	recording bool
+	ended     bool
+}
+
+const maxSpanAttrs = 64
+
+type spanKey struct{}
+
+// StartOption customizes a span created by Start.
+type StartOption func(*SpanData)
+
+// WithKind sets the span kind, the default is SpanKindInternal.
+func WithKind(kind SpanKind) StartOption {
+	return func(d *SpanData) { d.Kind = kind }
+}
+
+// WithStartTime backdates the span, e.g. for time spent waiting in a queue.
+func WithStartTime(start time.Time) StartOption {
+	return func(d *SpanData) { d.Start = start }
+}
+
+// WithAttrs sets initial attributes as key/value pairs like slog.
+func WithAttrs(args ...any) StartOption {
+	return func(d *SpanData) { d.Attrs = appendAttrs(d.Attrs, args) }
+}
+
+// Start begins a span named name as a child of the span or remote context in
+// ctx, using the default tracer, and returns a context carrying it.
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	return Default().Start(ctx, name, opts...)
+}
+
+// Start begins a span named name as a child of the span or remote context in ctx.
+func (t *Tracer) Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	span := &Span{tracer: t, data: SpanData{Name: name, Kind: SpanKindInternal, Start: time.Now()}}
+	for _, opt := range opts {
+		opt(&span.data)
+	}
+
+	if !t.enabled() || (parent.IsValid() && !parent.Sampled) {
+		span.data.Context = parent
+		return context.WithValue(ctx, spanKey{}, span), span
+	}
+
+	span.recording = true
+	span.data.Context = SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: true}
+	if parent.IsValid() {
+		span.data.Parent = parent.SpanID
+	} else {
+		span.data.Context.TraceID = newTraceID()
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// ContextWithRemote returns ctx carrying sc received from a caller, so the
+// next span started from it joins the caller's trace.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, &Span{data: SpanData{Context: sc}})
+}
+
+// ContextWithTraceparent is ContextWithRemote for a traceparent value;
+// invalid or empty values leave ctx unchanged.
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx
+	}
+	return ContextWithRemote(ctx, sc)
+}
+
+// SpanContextFromContext returns the context of the span in ctx, if any.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok {
+		return span.Context()
+	}
+	return SpanContext{}
+}
+
+// TraceparentFromContext returns the traceparent for the span in ctx, or "".
+func TraceparentFromContext(ctx context.Context) string {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.Traceparent()
+	}
+	return ""
+}
+
+// Context returns the span's own context.
+func (s *Span) Context() SpanContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.Context
+}
+
+// SetAttrs adds attributes as key/value pairs like slog.
+func (s *Span) SetAttrs(args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.recording && !s.ended {
+		s.data.Attrs = appendAttrs(s.data.Attrs, args)
+	}
+}
+
+// RecordError marks the span failed with err's message; nil is ignored.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.recording && !s.ended {
+		s.data.Failed = true
+		s.data.Error = redact.Default().String(err.Error())
+	}
+}
+
+// End finishes the span and hands it to the tracer's exporter. Only the first call has an effect.
+func (s *Span) End() {
+	s.mu.Lock()
+	if !s.recording || s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	if len(s.data.Attrs) > maxSpanAttrs {
+		s.data.DroppedAttrs = len(s.data.Attrs) - maxSpanAttrs
+		s.data.Attrs = s.data.Attrs[:maxSpanAttrs]
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.enqueue(data)
+}
+
+// appendAttrs converts alternating key/value arguments; a trailing key without value is dropped.
+func appendAttrs(attrs []Attr, args []any) []Attr {
+	for i := 0; i+1 < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			continue
+		}
+		attrs = append(attrs, Attr{Key: key, Value: args[i+1]})
+	}
+	return attrs
+}
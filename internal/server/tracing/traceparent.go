@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header and, upper-cased, the
+// environment variable commands receive it in.
+const (
+	TraceparentHeader = "traceparent"
+	TraceparentEnv    = "TRACEPARENT"
+)
+
+const (
+	traceparentVersion = "00"
+	traceparentLen     = 55 // version-traceid-spanid-flags for version 00
+	flagSampled        = 0x01
+)
+
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	Remote  bool // received from a caller rather than started in this process
+}
+
+// IsValid reports whether both IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats sc as a version 00 W3C traceparent value.
+func (sc SpanContext) Traceparent() string {
+	flags := byte(0)
+	if sc.Sampled {
+		flags |= flagSampled
+	}
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent parses a W3C traceparent value.
+//
+// Versions above 00 are accepted as long as their prefix has the 00 layout,
+// as the specification requires.
+func ParseTraceparent(value string) (SpanContext, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < traceparentLen {
+		return SpanContext{}, fmt.Errorf("traceparent %q is too short", value)
+	}
+	version := value[:2]
+	switch {
+	case !isLowerHex(version) || version == "ff":
+		return SpanContext{}, fmt.Errorf("traceparent version %q is invalid", version)
+	case version == traceparentVersion && len(value) != traceparentLen:
+		return SpanContext{}, fmt.Errorf("traceparent %q has trailing data", value)
+	case len(value) > traceparentLen && value[traceparentLen] != '-':
+		return SpanContext{}, fmt.Errorf("traceparent %q is malformed", value)
+	}
+
+	parts := strings.Split(value[:traceparentLen], "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, fmt.Errorf("traceparent %q is malformed", value)
+	}
+	var sc SpanContext
+	if err := decodeHexID(parts[1], sc.TraceID[:]); err != nil {
+		return SpanContext{}, fmt.Errorf("traceparent trace id: %w", err)
+	}
+	if err := decodeHexID(parts[2], sc.SpanID[:]); err != nil {
+		return SpanContext{}, fmt.Errorf("traceparent parent id: %w", err)
+	}
+	if !isLowerHex(parts[3]) {
+		return SpanContext{}, fmt.Errorf("traceparent flags %q are invalid", parts[3])
+	}
+	flags, _ := hex.DecodeString(parts[3])
+	sc.Sampled = flags[0]&flagSampled != 0
+	sc.Remote = true
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("traceparent %q has an all-zero id", value)
+	}
+	return sc, nil
+}
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+func decodeHexID(s string, dst []byte) error {
+	if !isLowerHex(s) {
+		return fmt.Errorf("%q is not lowercase hex", s)
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
@@ -0,0 +1,159 @@
+package tracing
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBatchSize     = 256
+	defaultMaxQueue      = 4096
+	defaultFlushInterval = 2 * time.Second
+)
+
+// Exporter sends finished spans to a trace backend.
+type Exporter interface {
+	Export(ctx context.Context, resource Resource, spans []SpanData) error
+	Close() error
+}
+
+// Resource describes the process emitting spans.
+type Resource struct {
+	ServiceName string
+}
+
+// Tracer batches finished spans and exports them in the background.
+//
+// A nil *Tracer, and the default tracer until SetDefault is called, is
+// disabled: spans only propagate their parent's context.
+type Tracer struct {
+	exporter Exporter
+	resource Resource
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending []SpanData
+	closed  bool
+	dropped int
+
+	flushCh chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+var defaultTracer atomic.Pointer[Tracer]
+
+// Default returns the tracer set with SetDefault, nil when tracing is disabled.
+func Default() *Tracer {
+	return defaultTracer.Load()
+}
+
+// SetDefault makes t the tracer used by Start; nil disables tracing.
+func SetDefault(t *Tracer) {
+	defaultTracer.Store(t)
+}
+
+// NewTracer starts a tracer exporting batches to exporter.
+func NewTracer(exporter Exporter, resource Resource) *Tracer {
+	t := &Tracer{
+		exporter: exporter,
+		resource: resource,
+		logger:   slog.Default().With("component", "tracing"),
+		flushCh:  make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.loop()
+	return t
+}
+
+func (t *Tracer) enabled() bool {
+	return t != nil && t.exporter != nil
+}
+
+// enqueue buffers a finished span, dropping it when the buffer is full.
+func (t *Tracer) enqueue(span SpanData) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return
+	}
+	if len(t.pending) >= defaultMaxQueue {
+		t.dropped++
+		return
+	}
+	t.pending = append(t.pending, span)
+	if len(t.pending) >= defaultBatchSize {
+		select {
+		case t.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *Tracer) loop() {
+	defer close(t.done)
+	ticker := time.NewTicker(defaultFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.flushCh:
+		case <-t.stop:
+			return
+		}
+		if err := t.ForceFlush(context.Background()); err != nil {
+			t.logger.Warn("span export failed", "event", "span_export_failed", "error", err)
+		}
+	}
+}
+
+// ForceFlush exports all buffered spans now.
+func (t *Tracer) ForceFlush(ctx context.Context) error {
+	if !t.enabled() {
+		return nil
+	}
+	t.mu.Lock()
+	spans, dropped := t.pending, t.dropped
+	t.pending, t.dropped = nil, 0
+	t.mu.Unlock()
+
+	if dropped > 0 {
+		t.logger.Warn("spans dropped, export buffer full", "event", "spans_dropped", "dropped", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	return t.exporter.Export(ctx, t.resource, spans)
+}
+
+// Shutdown stops the background exporter, exports buffered spans and closes the exporter.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if !t.enabled() {
+		return nil
+	}
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	close(t.stop)
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	flushErr := t.ForceFlush(ctx)
+	if err := t.exporter.Close(); err != nil && flushErr == nil {
+		return err
+	}
+	return flushErr
+}
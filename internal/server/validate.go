@@ -0,0 +1,373 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"poke/internal/server/dispatch"
+	"poke/internal/server/health"
+	"poke/internal/server/listener"
+	"poke/internal/server/metrics"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// Problem is a configuration error found by Validate.
+type Problem struct {
+	File    string // included file the problem is in, empty for the validated config
+	Line    int    // 1-based line of the offending key, 0 when unknown
+	Column  int    // 1-based column of the offending key, 0 when unknown
+	Message string
+}
+
+// String formats p as "line:column: message".
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", p.Line, p.Column, p.Message)
+}
+
+// ValidateOptions selects the host checks Validate runs.
+type ValidateOptions struct {
+	SkipPorts bool   // do not require listener ports to be free, e.g. while poke runs
+	Path      string // file the config was read from, resolves includes; "" = includes are not read
+}
+
+// Validate parses data like Parse and checks the host can run it: command
+// executables exist and listener, metrics and health ports are free. Unknown
+// top-level keys, which Parse ignores, are reported too.
+//
+// Unlike Parse it does not stop at the first error. Each top-level block, and
+// each entry of commands, workflows and listeners, is decoded on its own, and
+// problems are reported at the key of the block that failed, in source order.
+// With opts.Path set, the commands of included files are checked like Load
+// merges them, and their problems carry the file.
+func Validate(data []byte, opts ValidateOptions) []Problem {
+	// Duplicate keys are reported per block rather than failing the whole file.
+	file, err := parser.ParseBytes(data, 0, parser.AllowDuplicateMapKey())
+	if err != nil {
+		return []Problem{syntaxProblem(err)}
+	}
+
+	v := &configValidator{
+		opts:    opts,
+		dec:     yaml.NewDecoder(&bytes.Buffer{}),
+		blocks:  map[string]*ast.MappingValueNode{},
+		failed:  map[string]bool{},
+		sources: map[string]map[string]string{},
+		files:   map[string]int{},
+	}
+	for _, doc := range file.Docs {
+		v.document(doc.Body)
+	}
+	if opts.Path != "" {
+		v.includes()
+	}
+	v.checkWorkflows()
+	if len(v.problems) == 0 {
+		// Safety net for checks spanning blocks that Validate does not repeat.
+		if err := parseForValidate(data, opts.Path); err != nil {
+			v.problems = append(v.problems, Problem{Message: problemMessage(err)})
+		}
+	}
+
+	// Problems of included files follow those of the config, in include order.
+	slices.SortStableFunc(v.problems, func(a, b Problem) int {
+		return cmp.Or(cmp.Compare(v.files[a.File], v.files[b.File]), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return v.problems
+}
+
+// configBlocks maps each top-level key to its block type, taken from
+// configInput so both stay in sync.
+var configBlocks = func() map[string]reflect.Type {
+	t := reflect.TypeFor[configInput]()
+	blocks := make(map[string]reflect.Type, t.NumField())
+	for i := range t.NumField() {
+		field := t.Field(i)
+		typ := field.Type
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		blocks[field.Tag.Get("yaml")] = typ
+	}
+	return blocks
+}()
+
+// entryBlocks are decoded entry by entry so one bad entry does not hide
+// problems in the others.
+var entryBlocks = map[string]bool{"commands": true, "workflows": true, "listeners": true}
+
+// configValidator collects the problems of a config and its included files.
+type configValidator struct {
+	opts     ValidateOptions
+	dec      *yaml.Decoder // shared so aliases resolve to anchors of other blocks
+	blocks   map[string]*ast.MappingValueNode
+	failed   map[string]bool              // top-level keys with problems
+	sources  map[string]map[string]string // file of each entry of entry blocks
+	file     string                       // included file being checked, "" for the config
+	files    map[string]int               // include order of files with problems
+	include  []string
+	commands dispatch.CommandRegistry // commands of all files, for the workflow check
+	problems []Problem
+}
+
+// document checks every top-level block of body.
+func (v *configValidator) document(body ast.Node) {
+	entries := v.mapping(body, "")
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		key := entry.Key.GetToken().Value
+		if seen[key] {
+			v.add(entry.Key, key, fmt.Errorf("duplicate top-level key %q", key))
+			continue
+		}
+		seen[key] = true
+		v.block(key, entry)
+	}
+}
+
+// mapping registers the anchors of a document body and returns its entries,
+// reporting a body that is not a mapping as a problem of key.
+func (v *configValidator) mapping(body ast.Node, key string) []*ast.MappingValueNode {
+	if body == nil {
+		return nil
+	}
+	// Only registers anchors; the errors are reported per block.
+	var anchors any
+	_ = v.dec.DecodeFromNode(body, &anchors)
+
+	entries, ok := mappingEntries(body)
+	if !ok {
+		v.add(body, key, errors.New("config must be a mapping"))
+	}
+	return entries
+}
+
+// block checks the top-level block of key.
+func (v *configValidator) block(key string, entry *ast.MappingValueNode) {
+	typ, known := configBlocks[key]
+	switch {
+	case key == "auth":
+		v.add(entry.Key, key, rejectLegacyTopLevelAuth(map[string]interface{}{key: nil}))
+	case !known:
+		v.add(entry.Key, key, fmt.Errorf("unknown top-level key %q", key))
+	case key == "include":
+		v.blocks[key] = entry
+		v.decode(key, entry.Key, entry.Value, &v.include)
+	case entryBlocks[key]:
+		v.blocks[key] = entry
+		v.entries(key, entry, typ)
+	default:
+		v.decode(key, entry.Key, entry.Value, reflect.New(typ).Interface())
+	}
+}
+
+// entries decodes each entry of a keyed block into its own value of typ.
+func (v *configValidator) entries(key string, block *ast.MappingValueNode, typ reflect.Type) {
+	values, ok := mappingEntries(block.Value)
+	if !ok {
+		v.decode(key, block.Key, block.Value, reflect.New(typ).Interface())
+		return
+	}
+
+	if v.sources[key] == nil {
+		v.sources[key] = make(map[string]string, len(values))
+	}
+	seen := v.sources[key]
+	for _, entry := range values {
+		id := entry.Key.GetToken().Value
+		if source, exists := seen[id]; exists {
+			err := fmt.Errorf("%s: duplicate key %q", key, id)
+			if source != v.file {
+				err = fmt.Errorf("%s: duplicate key %q, already defined in %s", key, id, cmp.Or(source, v.opts.Path))
+			}
+			v.add(entry.Key, key, err)
+			continue
+		}
+		seen[id] = v.file
+		v.decode(key, entry.Key, ast.Mapping(entry.GetToken(), false, entry), reflect.New(typ).Interface())
+	}
+}
+
+// decode decodes node into target and runs the host checks for its type,
+// reporting problems at pos as problems of the top-level block key.
+func (v *configValidator) decode(key string, pos ast.Node, node ast.Node, target any) {
+	if err := v.dec.DecodeFromNode(node, target); err != nil {
+		v.add(pos, key, err)
+		return
+	}
+	if commands, ok := target.(*dispatch.CommandRegistry); ok {
+		for _, id := range commands.IDs() {
+			cmd, _ := commands.Get(id)
+			v.commands.Register(id, cmd)
+		}
+	}
+	for _, err := range v.checkHost(target) {
+		v.add(pos, key, err)
+	}
+}
+
+// checkHost runs the host checks that apply to a decoded block.
+func (v *configValidator) checkHost(target any) []error {
+	var errs []error
+	switch cfg := target.(type) {
+	case *dispatch.CommandRegistry:
+		errs = append(errs, cfg.CheckExecutables())
+	case *listener.ListenerConfig:
+		addresses := cfg.Addresses()
+		for _, listenerType := range slices.Sorted(maps.Keys(addresses)) {
+			errs = append(errs, v.checkAddress("listener "+listenerType, addresses[listenerType]))
+		}
+	case *metrics.Config:
+		if cfg.Enabled {
+			errs = append(errs, v.checkAddress("metrics", cfg.Address()))
+		}
+	case *health.Config:
+		if cfg.Enabled && cfg.Port != 0 {
+			errs = append(errs, v.checkAddress("health", cfg.Address()))
+		}
+	}
+	return slices.DeleteFunc(errs, func(err error) bool { return err == nil })
+}
+
+// checkAddress verifies a TCP listener can be bound on addr.
+func (v *configValidator) checkAddress(name string, addr string) error {
+	if v.opts.SkipPorts {
+		return nil
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("%s address %s is not available: %w", name, addr, err)
+	}
+	return ln.Close()
+}
+
+// checkWorkflows checks that workflows only use configured commands, once
+// both blocks decoded without problems in every file.
+func (v *configValidator) checkWorkflows() {
+	workflowsBlock := v.blocks["workflows"]
+	if workflowsBlock == nil || v.failed["commands"] || v.failed["workflows"] {
+		return
+	}
+
+	var workflows dispatch.WorkflowRegistry
+	if err := v.dec.DecodeFromNode(workflowsBlock.Value, &workflows); err != nil {
+		v.add(workflowsBlock.Key, "workflows", err)
+		return
+	}
+	if err := v.commands.AddWorkflows(&workflows); err != nil {
+		v.add(workflowsBlock.Key, "workflows", err)
+	}
+}
+
+// add records err at the position of node as a problem of the top-level
+// block key.
+func (v *configValidator) add(node ast.Node, key string, err error) {
+	pos := node.GetToken().Position
+	v.failed[key] = true
+	v.problems = append(v.problems, Problem{File: v.file, Line: pos.Line, Column: pos.Column, Message: problemMessage(err)})
+}
+
+// includes checks the commands of the files the config includes, see Load.
+func (v *configValidator) includes() {
+	files, err := includedFiles(v.opts.Path, v.include)
+	if err != nil {
+		if entry := v.blocks["include"]; entry != nil {
+			v.add(entry.Key, "include", err)
+		} else {
+			v.problems = append(v.problems, Problem{Message: err.Error()})
+		}
+		return
+	}
+
+	for i, file := range files {
+		v.file = file
+		v.files[file] = i + 1
+		v.includedFile(file)
+	}
+	v.file = ""
+}
+
+// includedFile checks the `commands` block of an included file, the only
+// block it may set.
+func (v *configValidator) includedFile(file string) {
+	data, err := os.ReadFile(file) // #nosec G304 -- by design, comes from config
+	if err != nil {
+		v.failed["commands"] = true
+		v.problems = append(v.problems, Problem{File: file, Message: err.Error()})
+		return
+	}
+	parsed, err := parser.ParseBytes(data, 0, parser.AllowDuplicateMapKey())
+	if err != nil {
+		problem := syntaxProblem(err)
+		problem.File = file
+		v.failed["commands"] = true
+		v.problems = append(v.problems, problem)
+		return
+	}
+
+	for _, doc := range parsed.Docs {
+		for _, entry := range v.mapping(doc.Body, "commands") {
+			key := entry.Key.GetToken().Value
+			if key != "commands" {
+				v.add(entry.Key, "commands", fmt.Errorf("included files may only set commands, found %q", key))
+				continue
+			}
+			v.entries(key, entry, configBlocks[key])
+		}
+	}
+}
+
+// parseForValidate parses data like Load when path is set, like Parse otherwise.
+func parseForValidate(data []byte, path string) error {
+	var err error
+	if path != "" {
+		_, err = load(data, path)
+	} else {
+		_, err = Parse(data)
+	}
+	return err
+}
+
+// syntaxProblem converts a YAML parse error to a Problem.
+func syntaxProblem(err error) Problem {
+	var yamlErr yaml.Error
+	if errors.As(err, &yamlErr) && yamlErr.GetToken() != nil {
+		pos := yamlErr.GetToken().Position
+		return Problem{Line: pos.Line, Column: pos.Column, Message: yamlErr.GetMessage()}
+	}
+	return Problem{Message: err.Error()}
+}
+
+// problemMessage returns err's message without the source excerpt YAML
+// errors carry; their positions refer to re-encoded blocks, not the file.
+func problemMessage(err error) string {
+	var yamlErr yaml.Error
+	if errors.As(err, &yamlErr) {
+		return strings.Replace(err.Error(), yamlErr.Error(), yamlErr.GetMessage(), 1)
+	}
+	return err.Error()
+}
+
+// mappingEntries returns the key/value pairs of a mapping node.
+func mappingEntries(node ast.Node) ([]*ast.MappingValueNode, bool) {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		return n.Values, true
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{n}, true
+	default:
+		return nil, false
+	}
+}
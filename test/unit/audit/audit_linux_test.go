@@ -0,0 +1,57 @@
+//go:build linux
+
+package audit_test
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+
+	"poke/internal/server/audit"
+)
+
+func TestLogRemovesPartialRecordWhenWriteFails(t *testing.T) {
+	path := writeAuditFile(t, 2)
+	log, err := audit.Open(audit.Config{Enabled: true, Path: path})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer log.Close() //nolint:errcheck // Test cleanup.
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+
+	// A file size limit a few bytes past the end makes the write stop
+	// mid-record with EFBIG instead of SIGXFSZ.
+	signal.Ignore(syscall.SIGXFSZ)
+	defer signal.Reset(syscall.SIGXFSZ)
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_FSIZE, &limit); err != nil {
+		t.Fatalf("getrlimit: %v", err)
+	}
+	short := syscall.Rlimit{Cur: uint64(info.Size()) + 16, Max: limit.Max}
+	if err := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &short); err != nil {
+		t.Skipf("setrlimit: %v", err)
+	}
+	err = log.Append(audit.Record{Event: audit.EventExecution, JobID: "job-3", Error: strings.Repeat("x", 256)})
+	if restoreErr := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &limit); restoreErr != nil {
+		t.Fatalf("restore rlimit: %v", restoreErr)
+	}
+	if err == nil {
+		t.Fatalf("append: expected the write to fail")
+	}
+
+	if err := log.Append(audit.Record{Event: audit.EventExecution, JobID: "job-4"}); err != nil {
+		t.Fatalf("append after failure: %v", err)
+	}
+	summary, err := audit.Verify(path, nil)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if summary.Records != 3 {
+		t.Fatalf("records: got %d want 3", summary.Records)
+	}
+}
@@ -0,0 +1,309 @@
+package audit_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-yaml"
+
+	"poke/internal/server/audit"
+)
+
+func TestConfigRequiresAbsolutePath(t *testing.T) {
+	var cfg audit.Config
+	if err := yaml.Unmarshal([]byte("path: /var/log/poke/audit.jsonl"), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !cfg.Enabled || cfg.Path != "/var/log/poke/audit.jsonl" {
+		t.Fatalf("config: got %#v", cfg)
+	}
+
+	if err := yaml.Unmarshal([]byte("enabled: false"), &cfg); err != nil || cfg.Enabled {
+		t.Fatalf("disabled config: got %#v, %v", cfg, err)
+	}
+
+	for input, want := range map[string]string{
+		"{}":                 "audit requires path",
+		"path: audit.jsonl":  "must be absolute",
+		"path: ./audit.json": "must be absolute",
+		"{path: /var/log/poke/audit.jsonl, key_file: audit.key}": "key_file \"audit.key\" must be absolute",
+	} {
+		err := yaml.Unmarshal([]byte(input), &cfg)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%q: got %v want %q", input, err, want)
+		}
+	}
+}
+
+func TestLogChainsRecordsAcrossReopen(t *testing.T) {
+	path := writeAuditFile(t, 2)
+
+	log, err := audit.Open(audit.Config{Enabled: true, Path: path})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if err := log.Append(audit.Record{Event: audit.EventExecution, JobID: "job-3", State: "succeeded"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	_ = log.Close()
+	if err := log.Append(audit.Record{Event: audit.EventExecution}); !errors.Is(err, audit.ErrClosed) {
+		t.Fatalf("append after close: got %v want ErrClosed", err)
+	}
+
+	summary, err := audit.Verify(path, nil)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	records := readRecords(t, path)
+	if summary.Records != 3 || summary.LastHash != records[2].Hash {
+		t.Fatalf("summary: got %#v", summary)
+	}
+	if records[0].PrevHash != audit.GenesisHash || records[2].PrevHash != records[1].Hash || records[2].Seq != 3 {
+		t.Fatalf("chain: got %#v", records)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	cases := []struct {
+		name   string
+		tamper func(lines [][]byte) [][]byte
+		want   string
+	}{
+		{
+			name: "modified field",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1] = bytes.Replace(lines[1], []byte(`"principal":"http/api_token"`), []byte(`"principal":"http/other"`), 1)
+				return lines
+			},
+			want: "line 2: seq 2: hash mismatch",
+		},
+		{
+			name:   "removed record",
+			tamper: func(lines [][]byte) [][]byte { return append(lines[:1], lines[2:]...) },
+			want:   "line 2: seq 3, want 2",
+		},
+		{
+			name:   "reordered records",
+			tamper: func(lines [][]byte) [][]byte { lines[1], lines[2] = lines[2], lines[1]; return lines },
+			want:   "line 2: seq 3, want 2",
+		},
+		{
+			name:   "truncated file",
+			tamper: func(lines [][]byte) [][]byte { return lines[:2] },
+			want:   "is truncated: head is at seq 3, the file ends at seq 2",
+		},
+		{
+			name:   "torn record",
+			tamper: func(lines [][]byte) [][]byte { lines[2] = lines[2][:10]; return lines },
+			want:   "line 3: incomplete record",
+		},
+		{
+			name: "unknown field",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[0] = bytes.Replace(lines[0], []byte(`{`), []byte(`{"note":"x",`), 1)
+				return lines
+			},
+			want: "line 1: invalid record",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeAuditFile(t, 3)
+			lines := splitLines(t, path)
+			if err := os.WriteFile(path, bytes.Join(tc.tamper(lines), nil), 0o600); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			_, err := audit.Verify(path, nil)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("verify: got %v want %q", err, tc.want)
+			}
+			if _, err := audit.Open(audit.Config{Enabled: true, Path: path}); err == nil {
+				t.Fatalf("open must refuse a tampered audit file")
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsMissingFileOrHead(t *testing.T) {
+	path := writeAuditFile(t, 1)
+	if err := os.Remove(audit.HeadPath(path)); err != nil {
+		t.Fatalf("remove head: %v", err)
+	}
+	if _, err := audit.Verify(path, nil); err == nil || !strings.Contains(err.Error(), "is missing") {
+		t.Fatalf("missing head: got %v", err)
+	}
+
+	path = writeAuditFile(t, 1)
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove file: %v", err)
+	}
+	if _, err := audit.Verify(path, nil); err == nil || !strings.Contains(err.Error(), "is truncated") {
+		t.Fatalf("missing file: got %v", err)
+	}
+
+	summary, err := audit.Verify(filepath.Join(t.TempDir(), "audit.jsonl"), nil)
+	if err != nil || summary.Records != 0 || summary.LastHash != audit.GenesisHash {
+		t.Fatalf("new file: got %#v, %v", summary, err)
+	}
+}
+
+func TestOpenRepairsHeadLeftBehindByCrash(t *testing.T) {
+	path := writeAuditFile(t, 2)
+	records := readRecords(t, path)
+	head, err := json.Marshal(map[string]any{"seq": 1, "hash": records[0].Hash})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := os.WriteFile(audit.HeadPath(path), head, 0o600); err != nil {
+		t.Fatalf("write head: %v", err)
+	}
+
+	if _, err := audit.Verify(path, nil); !errors.Is(err, audit.ErrHeadBehind) {
+		t.Fatalf("verify: got %v want ErrHeadBehind", err)
+	}
+	log, err := audit.Open(audit.Config{Enabled: true, Path: path})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = log.Close()
+	if _, err := audit.Verify(path, nil); err != nil {
+		t.Fatalf("verify after open: %v", err)
+	}
+}
+
+func TestKeyedLogVerifiesOnlyWithItsKey(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "audit.key")
+	if err := os.WriteFile(keyFile, []byte(strings.Repeat("k", 32)+"\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	path := filepath.Join(dir, "audit.jsonl")
+	log, err := audit.Open(audit.Config{Enabled: true, Path: path, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := log.Append(audit.Record{Event: audit.EventCancel, JobID: "job-1", Principal: "http/api_token"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	_ = log.Close()
+
+	key, err := audit.LoadKey(keyFile)
+	if err != nil {
+		t.Fatalf("load key: %v", err)
+	}
+	if summary, err := audit.Verify(path, key); err != nil || summary.Records != 1 {
+		t.Fatalf("verify with key: got %#v, %v", summary, err)
+	}
+	if _, err := audit.Verify(path, nil); err == nil || !strings.Contains(err.Error(), "hash mismatch") {
+		t.Fatalf("verify without key: got %v want hash mismatch", err)
+	}
+	if _, err := audit.Verify(path, []byte(strings.Repeat("x", 32))); err == nil {
+		t.Fatal("verify with another key: got nil error")
+	}
+}
+
+func TestLoadKeyRejectsShortKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "audit.key")
+	if err := os.WriteFile(keyFile, []byte("short"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	if _, err := audit.LoadKey(keyFile); err == nil || !strings.Contains(err.Error(), "at least 32 bytes") {
+		t.Fatalf("short key: got %v", err)
+	}
+}
+
+func TestNewParametersDigestsPayload(t *testing.T) {
+	priority := 7
+	runAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	params := audit.NewParameters([]byte("secret"), &priority, runAt, "key-1")
+
+	if params.PayloadBytes != 6 || params.PayloadSHA256 != "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b" {
+		t.Fatalf("payload digest: got %#v", params)
+	}
+	if *params.Priority != 7 || params.IdempotencyKey != "key-1" || !params.RunAt.Equal(runAt) || params.RunAt.Location() != time.UTC {
+		t.Fatalf("parameters: got %#v", params)
+	}
+	if got := audit.NewParameters(nil, nil, time.Time{}, ""); got.RunAt != nil || got.PayloadSHA256 != "" {
+		t.Fatalf("empty parameters: got %#v", got)
+	}
+}
+
+func TestDisabledLogIsNoop(t *testing.T) {
+	log, err := audit.Open(audit.Config{})
+	if err != nil || log != nil {
+		t.Fatalf("open disabled: got %v, %v", log, err)
+	}
+	if err := log.Append(audit.Record{Event: audit.EventRequest}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
+
+// writeAuditFile appends n request records to a new audit file.
+func writeAuditFile(t *testing.T, n int) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	log, err := audit.Open(audit.Config{Enabled: true, Path: path})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer log.Close() //nolint:errcheck // Test cleanup.
+
+	for i := range n {
+		rec := audit.Record{
+			Event:     audit.EventRequest,
+			Decision:  audit.DecisionAccepted,
+			Principal: "http/api_token",
+			CommandID: "hello",
+			JobID:     "job-" + string(rune('1'+i)),
+		}
+		if err := log.Append(rec); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	return path
+}
+
+func splitLines(t *testing.T, path string) [][]byte {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	return lines[:len(lines)-1] // drop the empty remainder after the last newline
+}
+
+func readRecords(t *testing.T, path string) []audit.Record {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer file.Close() //nolint:errcheck // Read-only handle.
+
+	var records []audit.Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var rec audit.Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
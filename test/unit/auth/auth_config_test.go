@@ -50,3 +50,48 @@ func TestAuthUnmarshalAPITokenNullConfig(t *testing.T) {
 		t.Fatalf("expected error for null api_token config")
 	}
 }
+
+func TestAuthUnmarshalPriorityRange(t *testing.T) {
+	input := []byte(`
+api_token:
+  token: "secret"
+  priority:
+    max: 5
+`)
+
+	var cfg auth.Auth
+	if err := yaml.Unmarshal(input, &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got := cfg.Priorities[auth.AuthTypeAPIToken]; got != (auth.PriorityRange{Min: 0, Max: 5}) {
+		t.Fatalf("priority range: got %#v", got)
+	}
+	if !cfg.AllowsPriority(auth.AuthTypeAPIToken, 5) || cfg.AllowsPriority(auth.AuthTypeAPIToken, 6) {
+		t.Fatalf("expected priorities up to 5 to be allowed")
+	}
+	if cfg.AllowsPriority("other", 0) {
+		t.Fatalf("expected auth kinds without a range to be denied")
+	}
+
+	ctx := auth.NewAPITokenContext("http", "secret")
+	if err := cfg.Validate(&ctx); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+}
+
+func TestAuthUnmarshalRejectsInvalidPriorityRange(t *testing.T) {
+	cases := map[string]string{
+		"above max":    `{api_token: {token: secret, priority: {max: 10}}}`,
+		"below min":    `{api_token: {token: secret, priority: {min: -1}}}`,
+		"min over max": `{api_token: {token: secret, priority: {min: 6, max: 2}}}`,
+	}
+
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			var cfg auth.Auth
+			if err := yaml.Unmarshal([]byte(input), &cfg); err == nil {
+				t.Fatalf("expected error for %s", input)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package bytesize_test
+
+import (
+	"testing"
+
+	"poke/internal/server/bytesize"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]bytesize.Size{
+		"0":      0,
+		"1024":   1024,
+		"4k":     4 << 10,
+		"512M":   512 << 20,
+		"2GiB":   2 << 30,
+		"1 T":    1 << 40,
+		" 16KB ": 16 << 10,
+	}
+
+	for raw, want := range cases {
+		got, err := bytesize.Parse(raw)
+		if err != nil {
+			t.Fatalf("%q: %v", raw, err)
+		}
+		if got != want {
+			t.Fatalf("%q: got %d want %d", raw, got, want)
+		}
+	}
+}
+
+func TestParseRejectsInvalid(t *testing.T) {
+	for _, raw := range []string{"", "M", "1.5G", "-1", "12X", "99999999999T"} {
+		if _, err := bytesize.Parse(raw); err == nil {
+			t.Fatalf("%q: expected error", raw)
+		}
+	}
+}
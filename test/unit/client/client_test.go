@@ -0,0 +1,62 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"poke/internal/client"
+	"poke/internal/server/jobs"
+)
+
+func TestClientCancelJobSendsAuthenticatedDelete(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/jobs/abc" {
+			t.Errorf("request: got %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("X-Poke-Auth-Method") != "api_token" || r.Header.Get("X-Poke-API-Token") != "secret" {
+			t.Errorf("auth headers: got %v", r.Header)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(jobs.Job{ID: "abc", State: jobs.StateRunning, CanceledBy: "http/api_token"})
+	}))
+	defer srv.Close()
+
+	c := &client.Client{BaseURL: srv.URL + "/", Token: "secret"}
+	job, err := c.CancelJob(context.Background(), "abc")
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if job.ID != "abc" || job.CanceledBy != "http/api_token" {
+		t.Fatalf("job: got %#v", job)
+	}
+}
+
+func TestClientCancelJobReportsStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(jobs.Job{ID: "abc", State: jobs.StateSucceeded})
+	}))
+	defer srv.Close()
+
+	c := &client.Client{BaseURL: srv.URL}
+	_, err := c.CancelJob(context.Background(), "abc")
+
+	var statusErr *client.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusConflict {
+		t.Fatalf("expected conflict status error, got %v", err)
+	}
+	if statusErr.Job == nil || statusErr.Job.State != jobs.StateSucceeded {
+		t.Fatalf("expected decoded job in error, got %#v", statusErr.Job)
+	}
+}
+
+func TestClientCancelJobRejectsEmptyID(t *testing.T) {
+	c := &client.Client{BaseURL: "http://127.0.0.1:1"}
+	if _, err := c.CancelJob(context.Background(), " "); err == nil {
+		t.Fatalf("expected empty id error")
+	}
+}
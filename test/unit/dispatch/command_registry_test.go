@@ -1,6 +1,7 @@
 package dispatch_test
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -182,3 +183,48 @@ func getCommand(t *testing.T, reg *dispatch.CommandRegistry, id string) executor
 	}
 	return cmd
 }
+
+// TestCommandRegistryCoalesces verifies the coalesce policy lookup by command ID.
+func TestCommandRegistryCoalesces(t *testing.T) {
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"reindex": {Args: []string{"reindex"}, Coalesce: true},
+		"backup":  {Args: []string{"backup"}},
+	})
+
+	if !reg.Coalesces("reindex") || reg.Coalesces("backup") || reg.Coalesces("missing") {
+		t.Fatalf("unexpected coalesce policy")
+	}
+}
+
+// TestCommandRegistryCheckPayload verifies payloads are checked against the
+// command, or every command of a workflow, before enqueueing.
+func TestCommandRegistryCheckPayload(t *testing.T) {
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"import": {Args: []string{"import"}, Stdin: &executor.Stdin{Payload: true, MaxSize: 4}},
+		"report": {Args: []string{"report"}},
+	})
+	workflows := dispatch.NewWorkflowRegistry(map[string]dispatch.Workflow{
+		"nightly": {Steps: []dispatch.WorkflowStep{{ID: "import", Command: "import"}, {ID: "report", Command: "report"}}},
+	})
+	if err := reg.AddWorkflows(workflows); err != nil {
+		t.Fatalf("add workflows: %v", err)
+	}
+
+	cases := []struct {
+		id      string
+		payload string
+		want    error
+	}{
+		{"import", "data", nil},
+		{"import", "large", executor.ErrPayloadTooLarge},
+		{"report", "", nil},
+		{"report", "data", executor.ErrPayloadNotAccepted},
+		{"nightly", "data", executor.ErrPayloadNotAccepted},
+		{"missing", "data", nil},
+	}
+	for _, tc := range cases {
+		if err := reg.CheckPayload(tc.id, []byte(tc.payload)); !errors.Is(err, tc.want) {
+			t.Fatalf("%s %q: got %v want %v", tc.id, tc.payload, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package dispatch_test
+
+import (
+	"testing"
+	"time"
+
+	"poke/internal/server/dispatch"
+	"poke/internal/server/request"
+)
+
+func TestPriorityQueuePopsHighestPriorityFirst(t *testing.T) {
+	q := dispatch.NewPriorityQueue(time.Minute)
+	now := time.Now()
+	q.Push(request.CommandRequest{JobID: "low-1"}, 0, now)
+	q.Push(request.CommandRequest{JobID: "high"}, 9, now)
+	q.Push(request.CommandRequest{JobID: "low-2"}, 0, now.Add(time.Millisecond))
+	q.Push(request.CommandRequest{JobID: "mid"}, 5, now)
+
+	if got := q.Depths(); got[0] != 2 || got[5] != 1 || got[9] != 1 || len(got) != 3 {
+		t.Fatalf("depths: got %v", got)
+	}
+	for _, want := range []string{"high", "mid", "low-1", "low-2"} {
+		req, ok := q.Pop(now)
+		if !ok || req.JobID != want {
+			t.Fatalf("pop: got %q, %v want %q", req.JobID, ok, want)
+		}
+	}
+	if _, ok := q.Pop(now); ok || q.Len() != 0 {
+		t.Fatalf("expected empty queue")
+	}
+}
+
+func TestPriorityQueueAgesWaitingRequests(t *testing.T) {
+	q := dispatch.NewPriorityQueue(time.Second)
+	start := time.Now()
+	q.Push(request.CommandRequest{JobID: "old-low"}, 0, start)
+	q.Push(request.CommandRequest{JobID: "new-high"}, 5, start.Add(6*time.Second))
+
+	// After 6s the low request has gained 6 levels and outranks the fresh one.
+	req, _ := q.Pop(start.Add(6 * time.Second))
+	if req.JobID != "old-low" {
+		t.Fatalf("pop: got %q want old-low", req.JobID)
+	}
+}
+
+func TestPriorityQueueWithoutAgingNeverPromotes(t *testing.T) {
+	q := dispatch.NewPriorityQueue(0)
+	start := time.Now()
+	q.Push(request.CommandRequest{JobID: "old-low"}, 0, start)
+	q.Push(request.CommandRequest{JobID: "new-high"}, 1, start.Add(time.Hour))
+
+	req, _ := q.Pop(start.Add(time.Hour))
+	if req.JobID != "new-high" {
+		t.Fatalf("pop: got %q want new-high", req.JobID)
+	}
+}
+
+func TestPriorityQueueRemovesRequestByJobID(t *testing.T) {
+	q := dispatch.NewPriorityQueue(time.Minute)
+	now := time.Now()
+	q.Push(request.CommandRequest{JobID: "a"}, 3, now)
+	q.Push(request.CommandRequest{JobID: "b"}, 3, now)
+	q.Push(request.CommandRequest{JobID: "c"}, 7, now)
+
+	if !q.Remove("b") || q.Remove("b") || q.Remove("") {
+		t.Fatalf("remove must succeed once for a queued job")
+	}
+	for _, want := range []string{"c", "a"} {
+		if req, ok := q.Pop(now); !ok || req.JobID != want {
+			t.Fatalf("pop: got %q, %v want %q", req.JobID, ok, want)
+		}
+	}
+	if q.Len() != 0 {
+		t.Fatalf("expected empty queue")
+	}
+}
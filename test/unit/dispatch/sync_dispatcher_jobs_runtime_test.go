@@ -0,0 +1,142 @@
+package dispatch_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"poke/internal/server/dispatch"
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+	"poke/internal/server/request"
+)
+
+func TestSyncDispatcherSkipsJobCanceledWhileQueued(t *testing.T) {
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"ok": {Args: []string{"true"}, Env: executor.NewEnvDefault(), Executor: "bin"},
+	})
+	reqCh := make(chan request.CommandRequest, 1)
+
+	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	job := mustEnqueue(t, d.Jobs(), "ok")
+	if _, err := d.Jobs().Cancel(job.ID, "http/api_token"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	logs := captureLogs(t, func() {
+		done := make(chan struct{})
+		go func() {
+			d.Run()
+			close(done)
+		}()
+
+		reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "ok"}
+		close(reqCh)
+		waitDone(t, done)
+	})
+
+	if !strings.Contains(logs, "event=job_skipped_canceled") || strings.Contains(logs, "event=command_execution_started") {
+		t.Fatalf("expected canceled job to be skipped, got %q", logs)
+	}
+}
+
+func TestSyncDispatcherRemovesJobCanceledInPriorityQueue(t *testing.T) {
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"sleep": {Args: []string{"sleep", "5"}, Env: executor.NewEnvDefault(), Executor: "bin"},
+		"ok":    {Args: []string{"true"}, Env: executor.NewEnvDefault(), Executor: "bin"},
+	})
+	reqCh := make(chan request.CommandRequest, 2)
+
+	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	running := mustEnqueue(t, d.Jobs(), "sleep")
+	queued := mustEnqueue(t, d.Jobs(), "ok")
+	reqCh <- request.CommandRequest{JobID: running.ID, CommandID: "sleep"}
+	reqCh <- request.CommandRequest{JobID: queued.ID, CommandID: "ok"}
+
+	done := make(chan struct{})
+	go func() {
+		d.Run()
+		close(done)
+	}()
+	waitJobState(t, d.Jobs(), running.ID, jobs.StateRunning)
+	if depths := d.QueueDepths(); depths[0] != 1 {
+		t.Fatalf("depths while running: got %v", depths)
+	}
+
+	if _, err := d.Jobs().Cancel(queued.ID, "http/api_token"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if depths := d.QueueDepths(); len(depths) != 0 {
+		t.Fatalf("canceled job must leave the queue, depths %v", depths)
+	}
+
+	if _, err := d.Jobs().Cancel(running.ID, "http/api_token"); err != nil {
+		t.Fatalf("cancel running: %v", err)
+	}
+	close(reqCh)
+	waitDone(t, done)
+}
+
+func TestSyncDispatcherCancelsRunningJob(t *testing.T) {
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"sleep": {Args: []string{"sleep", "5"}, Env: executor.NewEnvDefault(), Executor: "bin"},
+	})
+	reqCh := make(chan request.CommandRequest, 1)
+
+	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	job := mustEnqueue(t, d.Jobs(), "sleep")
+
+	done := make(chan struct{})
+	go func() {
+		d.Run()
+		close(done)
+	}()
+	reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "sleep"}
+
+	waitJobState(t, d.Jobs(), job.ID, jobs.StateRunning)
+	if _, err := d.Jobs().Cancel(job.ID, "http/api_token"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	got := waitJobState(t, d.Jobs(), job.ID, jobs.StateCanceled)
+	if got.CanceledBy != "http/api_token" || got.Outcome != executor.OutcomeCanceled {
+		t.Fatalf("canceled job: got %#v", got)
+	}
+
+	close(reqCh)
+	waitDone(t, done)
+}
+
+func waitJobState(t *testing.T, reg *jobs.Registry, id string, want jobs.State) jobs.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, ok := reg.Get(id); ok && job.State == want {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	job, _ := reg.Get(id)
+	t.Fatalf("job %s: got state %q want %q", id, job.State, want)
+	return jobs.Job{}
+}
+
+func mustEnqueue(t *testing.T, reg *jobs.Registry, commandID string) jobs.Job {
+	t.Helper()
+
+	job, _, err := reg.Enqueue(jobs.Submission{CommandID: commandID})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	return job
+}
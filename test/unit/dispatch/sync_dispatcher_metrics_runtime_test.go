@@ -0,0 +1,56 @@
+package dispatch_test
+
+import (
+	"context"
+	"testing"
+
+	"poke/internal/server/dispatch"
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+	"poke/internal/server/metrics"
+	"poke/internal/server/request"
+)
+
+func TestSyncDispatcherRecordsExecutionMetrics(t *testing.T) {
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"metrics-ok":   {Args: []string{"true"}, Env: executor.NewEnvDefault(), Executor: "bin"},
+		"metrics-fail": {Args: []string{"sh", "-c", "exit 3"}, Env: executor.NewEnvDefault(), Executor: "bin"},
+	})
+	reqCh := make(chan request.CommandRequest, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, err := dispatch.NewSyncDispatcher(ctx, reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+
+	waits := metrics.QueueWait.Count()
+	succeeded := metrics.Executions.Value("metrics-ok", string(executor.OutcomeSucceeded), "0")
+	failedRuns := metrics.Executions.Value("metrics-fail", string(executor.OutcomeFailed), "3")
+	durations := metrics.ExecutionDuration.Count("metrics-ok")
+	ok := mustEnqueue(t, d.Jobs(), "metrics-ok")
+	failed := mustEnqueue(t, d.Jobs(), "metrics-fail")
+	reqCh <- request.CommandRequest{JobID: ok.ID, CommandID: "metrics-ok"}
+	reqCh <- request.CommandRequest{JobID: failed.ID, CommandID: "metrics-fail"}
+
+	go d.Run()
+	waitJobState(t, d.Jobs(), ok.ID, jobs.StateSucceeded)
+	waitJobState(t, d.Jobs(), failed.ID, jobs.StateFailed)
+
+	if got := metrics.Executions.Value("metrics-ok", string(executor.OutcomeSucceeded), "0") - succeeded; got != 1 {
+		t.Fatalf("succeeded executions: got +%v want +1", got)
+	}
+	if got := metrics.Executions.Value("metrics-fail", string(executor.OutcomeFailed), "3") - failedRuns; got != 1 {
+		t.Fatalf("failed executions: got +%v want +1", got)
+	}
+	if got := metrics.ExecutionDuration.Count("metrics-ok") - durations; got != 1 {
+		t.Fatalf("duration observations: got +%d want +1", got)
+	}
+	if got := metrics.QueueWait.Count() - waits; got != 2 {
+		t.Fatalf("queue wait observations: got +%d want +2", got)
+	}
+	if got := metrics.DispatchQueueDepth.Value("0"); got != 0 {
+		t.Fatalf("dispatch queue depth after drain: got %v want 0", got)
+	}
+}
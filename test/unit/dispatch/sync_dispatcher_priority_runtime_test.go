@@ -0,0 +1,56 @@
+package dispatch_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"poke/internal/server/dispatch"
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+	"poke/internal/server/request"
+)
+
+func TestSyncDispatcherRunsHigherPriorityFirst(t *testing.T) {
+	dir := t.TempDir()
+	trace := filepath.Join(dir, "trace")
+	traced := func(name string, priority int) executor.Command {
+		return executor.Command{Args: []string{"sh", "-c", "echo " + name + " >> " + trace}, Env: executor.NewEnvDefault(), Executor: "bin", Priority: priority}
+	}
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"routine":   traced("routine", 0),
+		"emergency": traced("emergency", 9),
+	})
+	reqCh := make(chan request.CommandRequest, 4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, err := dispatch.NewSyncDispatcher(ctx, reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+
+	// All requests are buffered before Run starts, so they are ordered together.
+	routine := mustEnqueue(t, d.Jobs(), "routine")
+	emergency := mustEnqueue(t, d.Jobs(), "emergency")
+	boosted := mustEnqueue(t, d.Jobs(), "routine")
+	urgent := 9
+	reqCh <- request.CommandRequest{JobID: routine.ID, CommandID: "routine"}
+	reqCh <- request.CommandRequest{JobID: emergency.ID, CommandID: "emergency"}
+	reqCh <- request.CommandRequest{JobID: boosted.ID, CommandID: "routine", Priority: &urgent}
+
+	go d.Run()
+	waitJobState(t, d.Jobs(), routine.ID, jobs.StateSucceeded)
+
+	if got := readTrace(t, dir); got != "emergency\nroutine\nroutine\n" {
+		t.Fatalf("trace: got %q", got)
+	}
+	first, _ := d.Jobs().Get(boosted.ID)
+	last, _ := d.Jobs().Get(routine.ID)
+	if first.StartedAt == nil || !first.StartedAt.Before(*last.StartedAt) {
+		t.Fatalf("expected boosted routine request to run before the default one")
+	}
+	if depths := d.QueueDepths(); len(depths) != 0 {
+		t.Fatalf("depths after drain: got %v", depths)
+	}
+}
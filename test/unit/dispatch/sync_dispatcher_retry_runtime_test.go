@@ -0,0 +1,170 @@
+package dispatch_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"poke/internal/server/dispatch"
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+	"poke/internal/server/request"
+)
+
+func TestSyncDispatcherRetriesUntilSuccess(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "attempts")
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"flaky": {
+			Args:     []string{"sh", "-c", `n=$(cat "$0" 2>/dev/null || echo 0); n=$((n+1)); echo $n > "$0"; [ $n -ge 3 ] || exit 75`, counter},
+			Env:      executor.NewEnvDefault(),
+			Executor: "bin",
+			Retry:    &executor.Retry{MaxAttempts: 5, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, On: &executor.RetryOn{ExitCodes: []int{75}}},
+		},
+	})
+	reqCh := make(chan request.CommandRequest, 1)
+
+	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	job := mustEnqueue(t, d.Jobs(), "flaky")
+
+	logs := captureLogs(t, func() {
+		done := make(chan struct{})
+		go func() {
+			d.Run()
+			close(done)
+		}()
+
+		reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "flaky"}
+		waitJobState(t, d.Jobs(), job.ID, jobs.StateSucceeded)
+		close(reqCh)
+		waitDone(t, done)
+	})
+
+	got, _ := d.Jobs().Get(job.ID)
+	if got.State != jobs.StateSucceeded || len(got.Attempts) != 3 {
+		t.Fatalf("job: got state %q with %d attempts", got.State, len(got.Attempts))
+	}
+	for i, attempt := range got.Attempts[:2] {
+		if attempt.Attempt != i+1 || attempt.ExitCode != 75 || attempt.Outcome != executor.OutcomeFailed {
+			t.Fatalf("attempt %d: got %#v", i+1, attempt)
+		}
+	}
+	if !strings.Contains(logs, "event=command_execution_failed") || !strings.Contains(logs, "attempt=2") {
+		t.Fatalf("expected failed attempts to be logged with attempt field, got %q", logs)
+	}
+}
+
+func TestSyncDispatcherStopsRetryingUnlistedFailure(t *testing.T) {
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"broken": {
+			Args:     []string{"false"},
+			Env:      executor.NewEnvDefault(),
+			Executor: "bin",
+			Retry:    &executor.Retry{MaxAttempts: 5, Backoff: time.Millisecond, MaxBackoff: time.Millisecond, On: &executor.RetryOn{ExitCodes: []int{75}}},
+		},
+	})
+	reqCh := make(chan request.CommandRequest, 1)
+
+	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	job := mustEnqueue(t, d.Jobs(), "broken")
+
+	captureLogs(t, func() {
+		done := make(chan struct{})
+		go func() {
+			d.Run()
+			close(done)
+		}()
+
+		reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "broken"}
+		close(reqCh)
+		waitDone(t, done)
+	})
+
+	got, _ := d.Jobs().Get(job.ID)
+	if got.State != jobs.StateFailed || len(got.Attempts) != 1 {
+		t.Fatalf("job: got state %q with %d attempts", got.State, len(got.Attempts))
+	}
+}
+
+func TestSyncDispatcherRunsOtherJobsDuringBackoff(t *testing.T) {
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"broken": {
+			Args:     []string{"false"},
+			Env:      executor.NewEnvDefault(),
+			Executor: "bin",
+			Retry:    &executor.Retry{MaxAttempts: 3, Backoff: time.Hour, MaxBackoff: time.Hour},
+		},
+		"ok": {Args: []string{"true"}, Env: executor.NewEnvDefault(), Executor: "bin"},
+	})
+	reqCh := make(chan request.CommandRequest, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, err := dispatch.NewSyncDispatcher(ctx, reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	go d.Run()
+
+	broken := mustEnqueue(t, d.Jobs(), "broken")
+	reqCh <- request.CommandRequest{JobID: broken.ID, CommandID: "broken"}
+	waiting := waitJobState(t, d.Jobs(), broken.ID, jobs.StateScheduled)
+	if len(waiting.Attempts) != 1 || waiting.RunAt == nil || time.Until(*waiting.RunAt) < 59*time.Minute {
+		t.Fatalf("job waiting for retry: got %#v", waiting)
+	}
+
+	ok := mustEnqueue(t, d.Jobs(), "ok")
+	reqCh <- request.CommandRequest{JobID: ok.ID, CommandID: "ok"}
+	waitJobState(t, d.Jobs(), ok.ID, jobs.StateSucceeded)
+}
+
+func TestSyncDispatcherCancelDuringBackoff(t *testing.T) {
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"broken": {
+			Args:     []string{"false"},
+			Env:      executor.NewEnvDefault(),
+			Executor: "bin",
+			Retry:    &executor.Retry{MaxAttempts: 3, Backoff: time.Hour, MaxBackoff: time.Hour},
+		},
+	})
+	reqCh := make(chan request.CommandRequest, 1)
+
+	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	job := mustEnqueue(t, d.Jobs(), "broken")
+
+	done := make(chan struct{})
+	go func() {
+		d.Run()
+		close(done)
+	}()
+	reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "broken"}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ := d.Jobs().Get(job.ID); len(got.Attempts) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := d.Jobs().Cancel(job.ID, "http/api_token"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	got := waitJobState(t, d.Jobs(), job.ID, jobs.StateCanceled)
+	if len(got.Attempts) != 1 {
+		t.Fatalf("attempts: got %d want 1", len(got.Attempts))
+	}
+
+	close(reqCh)
+	waitDone(t, done)
+}
@@ -17,7 +17,7 @@ func TestNewSyncDispatcherRejectsUnknownExecutor(t *testing.T) {
 	d := dispatch.NewCommandRegistry(map[string]executor.Command{})
 	reqCh := make(chan request.CommandRequest)
 
-	if _, err := dispatch.NewSyncDispatcher(context.Background(), d, []string{"unknown"}, reqCh); err == nil {
+	if _, err := dispatch.NewSyncDispatcher(context.Background(), d, []string{"unknown"}, reqCh, nil); err == nil {
 		t.Fatalf("expected error for unknown executor")
 	}
 }
@@ -27,7 +27,7 @@ func TestSyncDispatcherRunStopsWhenContextIsCanceled(t *testing.T) {
 	reqCh := make(chan request.CommandRequest)
 	ctx, cancel := context.WithCancel(context.Background())
 
-	d, err := dispatch.NewSyncDispatcher(ctx, reg, nil, reqCh)
+	d, err := dispatch.NewSyncDispatcher(ctx, reg, nil, reqCh, nil)
 	if err != nil {
 		t.Fatalf("new dispatcher: %v", err)
 	}
@@ -52,7 +52,7 @@ func TestSyncDispatcherRunStopsWhenRequestChannelCloses(t *testing.T) {
 	reg := dispatch.NewCommandRegistry(map[string]executor.Command{})
 	reqCh := make(chan request.CommandRequest)
 
-	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, nil, reqCh)
+	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, nil, reqCh, nil)
 	if err != nil {
 		t.Fatalf("new dispatcher: %v", err)
 	}
@@ -77,7 +77,7 @@ func TestSyncDispatcherRunSkipsUnknownCommand(t *testing.T) {
 	reg := dispatch.NewCommandRegistry(map[string]executor.Command{})
 	reqCh := make(chan request.CommandRequest, 1)
 
-	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, nil, reqCh)
+	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, nil, reqCh, nil)
 	if err != nil {
 		t.Fatalf("new dispatcher: %v", err)
 	}
@@ -108,7 +108,7 @@ func TestSyncDispatcherRunHandlesUnknownExecutor(t *testing.T) {
 	})
 	reqCh := make(chan request.CommandRequest, 1)
 
-	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, reg.ExecutorNames(), reqCh)
+	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, reg.ExecutorNames(), reqCh, nil)
 	if err != nil {
 		t.Fatalf("new dispatcher: %v", err)
 	}
@@ -141,7 +141,7 @@ func TestSyncDispatcherRunExecutesCommand(t *testing.T) {
 	})
 	reqCh := make(chan request.CommandRequest, 1)
 
-	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, reg.ExecutorNames(), reqCh)
+	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, reg.ExecutorNames(), reqCh, nil)
 	if err != nil {
 		t.Fatalf("new dispatcher: %v", err)
 	}
@@ -0,0 +1,81 @@
+package dispatch_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"poke/internal/server/dispatch"
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+	"poke/internal/server/request"
+)
+
+func TestSyncDispatcherRunsScheduledJobWhenDue(t *testing.T) {
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"ok": {Args: []string{"true"}, Env: executor.NewEnvDefault(), Executor: "bin"},
+	})
+	reqCh := make(chan request.CommandRequest, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, err := dispatch.NewSyncDispatcher(ctx, reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	runAt := time.Now().Add(100 * time.Millisecond)
+	job, _, err := d.Jobs().Enqueue(jobs.Submission{CommandID: "ok", RunAt: runAt})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	go d.Run()
+	reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "ok", RunAt: runAt}
+
+	got := waitJobState(t, d.Jobs(), job.ID, jobs.StateSucceeded)
+	if got.StartedAt == nil || got.StartedAt.Before(runAt) {
+		t.Fatalf("expected job to start after run_at, got %#v", got)
+	}
+}
+
+func TestSyncDispatcherSkipsCanceledScheduledJob(t *testing.T) {
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"ok": {Args: []string{"true"}, Env: executor.NewEnvDefault(), Executor: "bin"},
+	})
+	reqCh := make(chan request.CommandRequest, 1)
+
+	d, err := dispatch.NewSyncDispatcher(context.Background(), reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	runAt := time.Now().Add(200 * time.Millisecond)
+	job, _, err := d.Jobs().Enqueue(jobs.Submission{CommandID: "ok", RunAt: runAt})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	logs := captureLogs(t, func() {
+		done := make(chan struct{})
+		go func() {
+			d.Run()
+			close(done)
+		}()
+
+		reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "ok", RunAt: runAt}
+		time.Sleep(50 * time.Millisecond)
+		if _, err := d.Jobs().Cancel(job.ID, "http/api_token"); err != nil {
+			t.Fatalf("cancel: %v", err)
+		}
+		time.Sleep(400 * time.Millisecond)
+		close(reqCh)
+		waitDone(t, done)
+	})
+
+	if strings.Contains(logs, "event=command_execution_started") {
+		t.Fatalf("expected canceled scheduled job not to run, got %q", logs)
+	}
+	if got, _ := d.Jobs().Get(job.ID); got.State != jobs.StateCanceled {
+		t.Fatalf("state: got %q want %q", got.State, jobs.StateCanceled)
+	}
+}
@@ -0,0 +1,180 @@
+package dispatch_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"poke/internal/server/dispatch"
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+	"poke/internal/server/request"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestSyncDispatcherRunsWorkflowSteps(t *testing.T) {
+	dir := t.TempDir()
+	d, reqCh := newWorkflowDispatcher(t, dir, `
+deploy:
+  steps:
+    - command: stop
+    - command: migrate
+    - command: start
+`)
+	job := mustEnqueue(t, d.Jobs(), "deploy")
+	reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "deploy"}
+
+	got := waitJobState(t, d.Jobs(), job.ID, jobs.StateSucceeded)
+	if got.Error != "" || stepStates(got) != "stop=succeeded,migrate=succeeded,start=succeeded" {
+		t.Fatalf("job: got %#v", got)
+	}
+	if trace := readTrace(t, dir); trace != "stop\nmigrate\nstart\n" {
+		t.Fatalf("trace: got %q", trace)
+	}
+}
+
+func TestSyncDispatcherAbortsWorkflowOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	d, reqCh := newWorkflowDispatcher(t, dir, `
+deploy:
+  steps:
+    - command: stop
+    - command: fail
+    - command: start
+`)
+	job := mustEnqueue(t, d.Jobs(), "deploy")
+	reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "deploy"}
+
+	got := waitJobState(t, d.Jobs(), job.ID, jobs.StateFailed)
+	if !strings.HasPrefix(got.Error, "step fail:") || stepStates(got) != "stop=succeeded,fail=failed,start=skipped" {
+		t.Fatalf("job: got %#v", got)
+	}
+	if trace := readTrace(t, dir); trace != "stop\n" {
+		t.Fatalf("trace: got %q", trace)
+	}
+}
+
+func TestSyncDispatcherContinuesWorkflowAfterFailure(t *testing.T) {
+	dir := t.TempDir()
+	d, reqCh := newWorkflowDispatcher(t, dir, `
+deploy:
+  steps:
+    - {command: fail, on_failure: continue}
+    - command: start
+`)
+	job := mustEnqueue(t, d.Jobs(), "deploy")
+	reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "deploy"}
+
+	got := waitJobState(t, d.Jobs(), job.ID, jobs.StateSucceeded)
+	if stepStates(got) != "fail=failed,start=succeeded" {
+		t.Fatalf("job: got %#v", got)
+	}
+}
+
+func TestSyncDispatcherCompensatesFailedWorkflowStep(t *testing.T) {
+	dir := t.TempDir()
+	d, reqCh := newWorkflowDispatcher(t, dir, `
+deploy:
+  steps:
+    - command: stop
+    - {command: fail, on_failure: compensate, compensate: start}
+    - command: migrate
+`)
+	job := mustEnqueue(t, d.Jobs(), "deploy")
+	reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "deploy"}
+
+	got := waitJobState(t, d.Jobs(), job.ID, jobs.StateFailed)
+	if stepStates(got) != "stop=succeeded,fail=failed,migrate=skipped" {
+		t.Fatalf("job: got %#v", got)
+	}
+	compensation := got.Steps[1].Compensation
+	if compensation == nil || compensation.CommandID != "start" || compensation.State != jobs.StepSucceeded {
+		t.Fatalf("compensation: got %#v", compensation)
+	}
+	if trace := readTrace(t, dir); trace != "stop\nstart\n" {
+		t.Fatalf("trace: got %q", trace)
+	}
+}
+
+func TestSyncDispatcherResumesWorkflowAfterStepRetry(t *testing.T) {
+	dir := t.TempDir()
+	d, reqCh := newWorkflowDispatcher(t, dir, `
+deploy:
+  steps:
+    - command: stop
+    - command: flaky
+    - command: start
+`)
+	job := mustEnqueue(t, d.Jobs(), "deploy")
+	reqCh <- request.CommandRequest{JobID: job.ID, CommandID: "deploy"}
+
+	got := waitJobState(t, d.Jobs(), job.ID, jobs.StateSucceeded)
+	if stepStates(got) != "stop=succeeded,flaky=succeeded,start=succeeded" || len(got.Steps[1].Attempts) != 2 {
+		t.Fatalf("job: got %#v", got)
+	}
+	if trace := readTrace(t, dir); trace != "stop\nflaky\nstart\n" {
+		t.Fatalf("steps recorded before the retry must not run again, trace: got %q", trace)
+	}
+}
+
+// newWorkflowDispatcher runs a dispatcher whose commands append their name to dir/trace.
+func newWorkflowDispatcher(t *testing.T, dir string, workflows string) (*dispatch.SyncDispatcher, chan<- request.CommandRequest) {
+	t.Helper()
+
+	trace := filepath.Join(dir, "trace")
+	step := func(name string) executor.Command {
+		return executor.Command{Args: []string{"sh", "-c", "echo " + name + " >> " + trace}, Env: executor.NewEnvDefault(), Executor: "bin"}
+	}
+	reg := dispatch.NewCommandRegistry(map[string]executor.Command{
+		"stop":    step("stop"),
+		"migrate": step("migrate"),
+		"start":   step("start"),
+		"fail":    {Args: []string{"false"}, Env: executor.NewEnvDefault(), Executor: "bin"},
+		// fails on its first run only
+		"flaky": {
+			Args:     []string{"sh", "-c", `[ -e "$0" ] || { touch "$0"; exit 1; }; echo flaky >> ` + trace, filepath.Join(dir, "flaky")},
+			Env:      executor.NewEnvDefault(),
+			Executor: "bin",
+			Retry:    &executor.Retry{MaxAttempts: 2, Backoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		},
+	})
+	var wfs dispatch.WorkflowRegistry
+	if err := yaml.Unmarshal([]byte(workflows), &wfs); err != nil {
+		t.Fatalf("unmarshal workflows: %v", err)
+	}
+	if err := reg.AddWorkflows(&wfs); err != nil {
+		t.Fatalf("add workflows: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	reqCh := make(chan request.CommandRequest, 1)
+	d, err := dispatch.NewSyncDispatcher(ctx, reg, reg.ExecutorNames(), reqCh, nil)
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	go d.Run()
+	return d, reqCh
+}
+
+func stepStates(job jobs.Job) string {
+	states := make([]string, 0, len(job.Steps))
+	for _, step := range job.Steps {
+		states = append(states, step.ID+"="+string(step.State))
+	}
+	return strings.Join(states, ",")
+}
+
+func readTrace(t *testing.T, dir string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dir, "trace"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("read trace: %v", err)
+	}
+	return string(data)
+}
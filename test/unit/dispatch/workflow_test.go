@@ -0,0 +1,118 @@
+package dispatch_test
+
+import (
+	"strings"
+	"testing"
+
+	"poke/internal/server/dispatch"
+	"poke/internal/server/executor"
+
+	"github.com/goccy/go-yaml"
+)
+
+// TestWorkflowRegistryUnmarshalOrderedSteps verifies list order, step ID and on_failure defaults.
+func TestWorkflowRegistryUnmarshalOrderedSteps(t *testing.T) {
+	wf := mustWorkflow(t, `
+deploy:
+  name: Deploy
+  steps:
+    - command: stop
+    - id: migrate
+      command: migrate
+      on_failure: compensate
+      compensate: rollback
+    - command: start
+`, "deploy")
+
+	if wf.ID != "deploy" || wf.Name != "Deploy" || len(wf.Steps) != 3 {
+		t.Fatalf("workflow: got %#v", wf)
+	}
+	stop, migrate, start := wf.Steps[0], wf.Steps[1], wf.Steps[2]
+	if stop.ID != "stop" || len(stop.Needs) != 0 || stop.OnFailure != dispatch.OnFailureAbort {
+		t.Fatalf("stop step: got %#v", stop)
+	}
+	if migrate.OnFailure != dispatch.OnFailureCompensate || migrate.Compensate != "rollback" || strings.Join(migrate.Needs, ",") != "stop" {
+		t.Fatalf("migrate step: got %#v", migrate)
+	}
+	if start.ID != "start" || strings.Join(start.Needs, ",") != "migrate" {
+		t.Fatalf("start step: got %#v", start)
+	}
+}
+
+// TestWorkflowRegistryUnmarshalOrdersDAG verifies explicit needs reorder steps topologically.
+func TestWorkflowRegistryUnmarshalOrdersDAG(t *testing.T) {
+	wf := mustWorkflow(t, `
+release:
+  steps:
+    - {id: notify, command: notify, needs: [build, test]}
+    - {id: build, command: build, needs: []}
+    - {id: test, command: test, needs: []}
+`, "release")
+
+	var order []string
+	for _, step := range wf.Steps {
+		order = append(order, step.ID)
+	}
+	if got := strings.Join(order, ","); got != "build,test,notify" {
+		t.Fatalf("order: got %s", got)
+	}
+}
+
+// TestWorkflowRegistryUnmarshalRejectsInvalid verifies step validation errors.
+func TestWorkflowRegistryUnmarshalRejectsInvalid(t *testing.T) {
+	cases := map[string]string{
+		"no steps":              "deploy: {steps: []}",
+		"missing command":       "deploy: {steps: [{id: a}]}",
+		"duplicate step":        "deploy: {steps: [{command: a}, {command: a}]}",
+		"unknown need":          "deploy: {steps: [{command: a, needs: [b]}]}",
+		"cycle":                 "deploy: {steps: [{command: a, needs: [b]}, {command: b, needs: [a]}]}",
+		"invalid on_failure":    "deploy: {steps: [{command: a, on_failure: retry}]}",
+		"compensate no command": "deploy: {steps: [{command: a, on_failure: compensate}]}",
+		"compensate on abort":   "deploy: {steps: [{command: a, compensate: b}]}",
+	}
+
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			var reg dispatch.WorkflowRegistry
+			if err := yaml.Unmarshal([]byte(input), &reg); err == nil {
+				t.Fatalf("expected error for %s", input)
+			}
+		})
+	}
+}
+
+// TestCommandRegistryAddWorkflowsChecksReferences verifies commands and IDs are checked across blocks.
+func TestCommandRegistryAddWorkflowsChecksReferences(t *testing.T) {
+	cases := map[string]string{
+		"unknown command":    "deploy: {steps: [{command: missing}]}",
+		"unknown compensate": "deploy: {steps: [{command: ok, on_failure: compensate, compensate: missing}]}",
+		"id collision":       "ok: {steps: [{command: ok}]}",
+	}
+
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			var workflows dispatch.WorkflowRegistry
+			if err := yaml.Unmarshal([]byte(input), &workflows); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			reg := dispatch.NewCommandRegistry(map[string]executor.Command{"ok": {Args: []string{"true"}}})
+			if err := reg.AddWorkflows(&workflows); err == nil {
+				t.Fatalf("expected error for %s", input)
+			}
+		})
+	}
+}
+
+func mustWorkflow(t *testing.T, input string, id string) dispatch.Workflow {
+	t.Helper()
+
+	var reg dispatch.WorkflowRegistry
+	if err := yaml.Unmarshal([]byte(input), &reg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	wf, ok := reg.Get(id)
+	if !ok {
+		t.Fatalf("workflow %s not found", id)
+	}
+	return wf
+}
@@ -163,3 +163,48 @@ func TestCommandUnmarshalRequiresArgs(t *testing.T) {
 		t.Fatalf("expected error for missing args")
 	}
 }
+
+func TestCommandUnmarshalStopSignal(t *testing.T) {
+	var got executor.Command
+	if err := yaml.Unmarshal([]byte(`{args: ["true"], stop_signal: int, stop_timeout: 2s}`), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if got.StopSignal != "SIGINT" {
+		t.Fatalf("stop_signal: got %q", got.StopSignal)
+	}
+	if got.StopTimeout != 2*time.Second {
+		t.Fatalf("stop_timeout: got %v", got.StopTimeout)
+	}
+}
+
+func TestCommandUnmarshalRejectsUnknownStopSignal(t *testing.T) {
+	var got executor.Command
+	if err := yaml.Unmarshal([]byte(`{args: ["true"], stop_signal: SIGBOGUS}`), &got); err == nil {
+		t.Fatalf("expected error for unknown stop signal")
+	}
+}
+
+func TestCommandUnmarshalPriority(t *testing.T) {
+	var got executor.Command
+	if err := yaml.Unmarshal([]byte(`{args: ["true"], priority: 7}`), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Priority != 7 {
+		t.Fatalf("priority: got %d", got.Priority)
+	}
+
+	if err := yaml.Unmarshal([]byte(`{args: ["true"], priority: 10}`), &got); err == nil {
+		t.Fatalf("expected error for priority out of range")
+	}
+}
+
+func TestCommandUnmarshalCoalesce(t *testing.T) {
+	var got executor.Command
+	if err := yaml.Unmarshal([]byte(`{args: ["reindex"], coalesce: true}`), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !got.Coalesce {
+		t.Fatalf("coalesce: got false")
+	}
+}
@@ -1,6 +1,7 @@
 package executor_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/goccy/go-yaml"
@@ -64,6 +65,58 @@ vals:
 	}
 }
 
+func TestEnvUnmarshalSecretValues(t *testing.T) {
+	input := []byte(`vals:
+  USER: deploy
+  DB_PASSWORD:
+    value: hunter2
+    secret: true
+  PORT:
+    value: 5432
+`)
+
+	var got executor.Env
+	if err := yaml.Unmarshal(input, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	want := executor.EnvMap{"USER": "deploy", "DB_PASSWORD": "hunter2", "PORT": "5432"}
+	if !envMapEqual(got.Vals, want) {
+		t.Fatalf("vals: got %#v want %#v", got.Vals, want)
+	}
+	if len(got.Secrets) != 1 || got.Secrets[0] != "DB_PASSWORD" {
+		t.Fatalf("secrets: got %v", got.Secrets)
+	}
+	if values := got.SecretValues(); len(values) != 1 || values[0] != "hunter2" {
+		t.Fatalf("secret values: got %v", values)
+	}
+
+	data, err := yaml.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var roundTrip executor.Env
+	if err := yaml.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("unmarshal round trip: %v", err)
+	}
+	if !envMapEqual(roundTrip.Vals, want) || len(roundTrip.Secrets) != 1 {
+		t.Fatalf("round trip: got %#v from %s", roundTrip, data)
+	}
+}
+
+func TestEnvUnmarshalRejectsInvalidSecretSpec(t *testing.T) {
+	for input, want := range map[string]string{
+		"vals: {KEY: {value: x, secret: yes please}}": "env KEY: secret must be true or false",
+		"vals: {KEY: {value: x, hidden: true}}":       "env KEY: unknown field \"hidden\"",
+	} {
+		var got executor.Env
+		err := yaml.Unmarshal([]byte(input), &got)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%q: got %v want %q", input, err, want)
+		}
+	}
+}
+
 func envMapEqual(a, b executor.EnvMap) bool {
 	if len(a) != len(b) {
 		return false
@@ -0,0 +1,47 @@
+package executor_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"poke/internal/server/executor"
+)
+
+func TestCheckExecutable(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data.txt"), nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cases := []struct {
+		cmd  executor.Command
+		want string
+	}{
+		{cmd: executor.Command{Executor: "bin", Args: []string{"sh"}}},
+		{cmd: executor.Command{Executor: "bin", Args: []string{"./run.sh"}, Workdir: dir}},
+		{cmd: executor.Command{Executor: "bin", Args: []string{filepath.Join(dir, "run.sh")}}},
+		{cmd: executor.Command{Executor: "other", Args: []string{"missing"}}},
+		{cmd: executor.Command{Executor: "bin", Args: []string{"poke-no-such-tool"}}, want: "not found in PATH"},
+		{cmd: executor.Command{Executor: "bin", Args: []string{"./data.txt"}, Workdir: dir}, want: "is not an executable file"},
+		{cmd: executor.Command{Executor: "bin", Args: []string{dir}}, want: "is not an executable file"},
+		{cmd: executor.Command{Executor: "bin", Args: []string{"./gone"}, Workdir: dir}, want: "no such file"},
+		{cmd: executor.Command{Executor: "bin"}, want: "command has no arguments"},
+	}
+	for _, tc := range cases {
+		err := executor.CheckExecutable(tc.cmd)
+		if tc.want == "" {
+			if err != nil {
+				t.Fatalf("%v: unexpected error %v", tc.cmd.Args, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%v: got %v want %q", tc.cmd.Args, err, tc.want)
+		}
+	}
+}
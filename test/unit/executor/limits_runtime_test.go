@@ -0,0 +1,76 @@
+//go:build linux
+
+package executor_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"poke/internal/server/bytesize"
+	"poke/internal/server/executor"
+)
+
+func TestExecuteBinaryAppliesOpenFilesLimit(t *testing.T) {
+	cmd := executor.Command{
+		ID:       "nofile",
+		Name:     "nofile",
+		Args:     []string{"sh", "-c", "ulimit -n"},
+		Env:      executor.NewEnvDefault(),
+		Executor: "bin",
+		Limits:   &executor.Limits{OpenFiles: 64},
+	}
+
+	result := executor.ExecuteBinary(context.Background(), cmd)
+	if result.Error != nil {
+		t.Fatalf("execute: %v (output %q)", result.Error, result.Output)
+	}
+	if got := strings.TrimSpace(string(result.Output)); got != "64" {
+		t.Fatalf("ulimit -n: got %q want %q", got, "64")
+	}
+	if result.Outcome != executor.OutcomeSucceeded {
+		t.Fatalf("outcome: got %q want %q", result.Outcome, executor.OutcomeSucceeded)
+	}
+}
+
+func TestExecuteBinaryAppliesCoreSizeLimit(t *testing.T) {
+	zero := bytesize.Size(0)
+	cmd := executor.Command{
+		ID:       "core",
+		Name:     "core",
+		Args:     []string{"sh", "-c", "ulimit -c"},
+		Env:      executor.NewEnvDefault(),
+		Executor: "bin",
+		Limits:   &executor.Limits{CoreSize: &zero},
+	}
+
+	result := executor.ExecuteBinary(context.Background(), cmd)
+	if result.Error != nil {
+		t.Fatalf("execute: %v (output %q)", result.Error, result.Output)
+	}
+	if got := strings.TrimSpace(string(result.Output)); got != "0" {
+		t.Fatalf("ulimit -c: got %q want %q", got, "0")
+	}
+}
+
+func TestExecuteBinaryWithLimitsReportsMissingBinary(t *testing.T) {
+	cmd := executor.Command{
+		ID:       "missing",
+		Name:     "missing",
+		Args:     []string{"__poke_binary_that_does_not_exist__"},
+		Env:      executor.NewEnvDefault(),
+		Executor: "bin",
+		Limits:   &executor.Limits{OpenFiles: 64},
+	}
+
+	result := executor.ExecuteBinary(context.Background(), cmd)
+	if result.Error == nil {
+		t.Fatalf("expected error")
+	}
+	if result.ExitCode != -1 {
+		t.Fatalf("exit_code: got %d want -1", result.ExitCode)
+	}
+	if result.Outcome != executor.OutcomeFailed {
+		t.Fatalf("outcome: got %q want %q", result.Outcome, executor.OutcomeFailed)
+	}
+}
@@ -0,0 +1,80 @@
+package executor_test
+
+import (
+	"testing"
+
+	"poke/internal/server/executor"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestCommandUnmarshalLimits(t *testing.T) {
+	input := []byte(`
+args: ["sleep", "1"]
+limits:
+  memory: 256M
+  cpu: 0.5
+  pids: 32
+  open_files: 128
+  core_size: 0
+`)
+
+	var got executor.Command
+	if err := yaml.Unmarshal(input, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if got.Limits == nil {
+		t.Fatalf("limits missing")
+	}
+	if got.Limits.Memory != 256<<20 {
+		t.Fatalf("memory: got %d", got.Limits.Memory)
+	}
+	if got.Limits.CPU != 0.5 {
+		t.Fatalf("cpu: got %v", got.Limits.CPU)
+	}
+	if got.Limits.PIDs != 32 {
+		t.Fatalf("pids: got %d", got.Limits.PIDs)
+	}
+	if got.Limits.OpenFiles != 128 {
+		t.Fatalf("open_files: got %d", got.Limits.OpenFiles)
+	}
+	if got.Limits.CoreSize == nil || *got.Limits.CoreSize != 0 {
+		t.Fatalf("core_size: got %v", got.Limits.CoreSize)
+	}
+}
+
+func TestCommandUnmarshalLimitsRejectsNegativeCPU(t *testing.T) {
+	input := []byte(`
+args: ["true"]
+limits:
+  cpu: -1
+`)
+
+	var got executor.Command
+	if err := yaml.Unmarshal(input, &got); err == nil {
+		t.Fatalf("expected error for negative cpu")
+	}
+}
+
+func TestCommandMarshalObjectWhenLimitsConfigured(t *testing.T) {
+	cmd := executor.Command{
+		Args:     []string{"true"},
+		Executor: "bin",
+		Env:      executor.NewEnvDefault(),
+		Limits:   &executor.Limits{OpenFiles: 64},
+	}
+
+	data, err := yaml.Marshal(cmd)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got["limits"] == nil {
+		t.Fatalf("limits missing from %s", data)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build linux
+
+package executor_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"poke/internal/server/executor"
+)
+
+func TestExecuteBinaryAppliesUmask(t *testing.T) {
+	mask := executor.Umask(0o027)
+	cmd := executor.Command{
+		ID:       "umask",
+		Name:     "umask",
+		Args:     []string{"sh", "-c", "umask"},
+		Env:      executor.NewEnvDefault(),
+		Executor: "bin",
+		Umask:    &mask,
+	}
+
+	result := executor.ExecuteBinary(context.Background(), cmd)
+	if result.Error != nil {
+		t.Fatalf("execute: %v (output %q)", result.Error, result.Output)
+	}
+	if got := strings.TrimSpace(string(result.Output)); got != "0027" {
+		t.Fatalf("umask: got %q want %q", got, "0027")
+	}
+}
@@ -0,0 +1,101 @@
+package executor_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"poke/internal/server/executor"
+)
+
+func TestExecuteBinaryUsesWorkdir(t *testing.T) {
+	dir := t.TempDir()
+	cmd := executor.Command{
+		ID:       "pwd",
+		Name:     "pwd",
+		Args:     []string{"sh", "-c", "pwd -P"},
+		Env:      executor.NewEnvDefault(),
+		Executor: "bin",
+		Workdir:  dir,
+	}
+
+	result := executor.ExecuteBinary(context.Background(), cmd)
+	if result.Error != nil {
+		t.Fatalf("execute: %v", result.Error)
+	}
+
+	want, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatalf("eval symlinks: %v", err)
+	}
+	if got := strings.TrimSpace(string(result.Output)); got != want {
+		t.Fatalf("pwd: got %q want %q", got, want)
+	}
+}
+
+func TestExecuteBinaryFeedsStdinSources(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "input")
+	if err := os.WriteFile(file, []byte("from file"), 0o600); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	cases := map[string]struct {
+		stdin   *executor.Stdin
+		payload []byte
+		want    string
+	}{
+		"text":    {stdin: &executor.Stdin{Text: "from text"}, want: "from text"},
+		"file":    {stdin: &executor.Stdin{File: file}, want: "from file"},
+		"payload": {stdin: &executor.Stdin{Payload: true, MaxSize: 64}, payload: []byte("from payload"), want: "from payload"},
+		"none":    {want: ""},
+	}
+
+	for name, tc := range cases {
+		cmd := executor.Command{
+			ID:       "cat",
+			Name:     name,
+			Args:     []string{"cat"},
+			Env:      executor.NewEnvDefault(),
+			Executor: "bin",
+			Stdin:    tc.stdin,
+			Payload:  tc.payload,
+		}
+
+		result := executor.ExecuteBinary(context.Background(), cmd)
+		if result.Error != nil {
+			t.Fatalf("%s: execute: %v", name, result.Error)
+		}
+		if string(result.Output) != tc.want {
+			t.Fatalf("%s: output got %q want %q", name, result.Output, tc.want)
+		}
+	}
+}
+
+func TestExecuteBinaryRejectsUnexpectedOrOversizedPayload(t *testing.T) {
+	cases := map[string]*executor.Stdin{
+		"no payload source": {Text: "static"},
+		"too large":         {Payload: true, MaxSize: 4},
+	}
+
+	for name, stdin := range cases {
+		cmd := executor.Command{
+			ID:       "cat",
+			Name:     name,
+			Args:     []string{"cat"},
+			Env:      executor.NewEnvDefault(),
+			Executor: "bin",
+			Stdin:    stdin,
+			Payload:  []byte("payload"),
+		}
+
+		result := executor.ExecuteBinary(context.Background(), cmd)
+		if result.Error == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+		if result.ExitCode != -1 {
+			t.Fatalf("%s: exit_code got %d want -1", name, result.ExitCode)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package executor_test
+
+import (
+	"testing"
+
+	"poke/internal/server/executor"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestCommandUnmarshalWorkdirUmaskStdin(t *testing.T) {
+	input := []byte(`
+args: ["cat"]
+workdir: /var/lib/poke
+umask: "0027"
+stdin:
+  text: "hello"
+`)
+
+	var got executor.Command
+	if err := yaml.Unmarshal(input, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if got.Workdir != "/var/lib/poke" {
+		t.Fatalf("workdir: got %q", got.Workdir)
+	}
+	if got.Umask == nil || *got.Umask != 0o027 {
+		t.Fatalf("umask: got %v", got.Umask)
+	}
+	if got.Stdin == nil || got.Stdin.Text != "hello" {
+		t.Fatalf("stdin: got %#v", got.Stdin)
+	}
+}
+
+func TestCommandUnmarshalRejectsRelativeWorkdir(t *testing.T) {
+	var got executor.Command
+	if err := yaml.Unmarshal([]byte(`{args: ["true"], workdir: "tmp"}`), &got); err == nil {
+		t.Fatalf("expected error for relative workdir")
+	}
+}
+
+func TestUmaskUnmarshalRejectsInvalidValues(t *testing.T) {
+	for _, input := range []string{`"0888"`, `"1777"`, `""`, `22`} {
+		var got executor.Umask
+		if err := yaml.Unmarshal([]byte(input), &got); err == nil {
+			t.Fatalf("%s: expected error", input)
+		}
+	}
+}
+
+func TestStdinUnmarshalPayloadDefaultsMaxSize(t *testing.T) {
+	var got executor.Stdin
+	if err := yaml.Unmarshal([]byte(`payload: true`), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !got.Payload {
+		t.Fatalf("payload: expected true")
+	}
+	if got.MaxSize != 64<<10 {
+		t.Fatalf("max_size: got %d want %d", got.MaxSize, 64<<10)
+	}
+}
+
+func TestStdinUnmarshalRequiresExactlyOneSource(t *testing.T) {
+	cases := map[string]string{
+		"none":             `{}`,
+		"text and file":    `{text: "a", file: "/tmp/a"}`,
+		"file and payload": `{file: "/tmp/a", payload: true}`,
+		"max_size on text": `{text: "a", max_size: 1K}`,
+		"empty file":       `{file: " "}`,
+	}
+
+	for name, input := range cases {
+		var got executor.Stdin
+		if err := yaml.Unmarshal([]byte(input), &got); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
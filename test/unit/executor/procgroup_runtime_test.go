@@ -0,0 +1,120 @@
+//go:build unix
+
+package executor_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"poke/internal/server/executor"
+)
+
+func TestExecuteBinaryTimeoutStopsProcessGroupWithStopSignal(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "grandchild.pid")
+	cmd := executor.Command{
+		ID:       "group",
+		Name:     "group",
+		Args:     []string{"sh", "-c", "sleep 30 & echo $! > " + pidFile + "; wait"},
+		Env:      executor.NewEnvDefault(),
+		Executor: "bin",
+		Timeout:  100 * time.Millisecond,
+	}
+
+	result := executor.ExecuteBinary(context.Background(), cmd)
+	if result.Outcome != executor.OutcomeTimedOut {
+		t.Fatalf("outcome: got %q want %q", result.Outcome, executor.OutcomeTimedOut)
+	}
+	if result.Signal != "SIGTERM" {
+		t.Fatalf("signal: got %q want %q", result.Signal, "SIGTERM")
+	}
+	assertProcessGone(t, pidFile)
+}
+
+func TestExecuteBinaryEscalatesToSIGKILLAfterStopTimeout(t *testing.T) {
+	cmd := executor.Command{
+		ID:          "stubborn",
+		Name:        "stubborn",
+		Args:        []string{"sh", "-c", "trap '' INT; while :; do sleep 0.05; done"},
+		Env:         executor.NewEnvDefault(),
+		Executor:    "bin",
+		Timeout:     50 * time.Millisecond,
+		StopSignal:  "SIGINT",
+		StopTimeout: 100 * time.Millisecond,
+	}
+
+	started := time.Now()
+	result := executor.ExecuteBinary(context.Background(), cmd)
+	elapsed := time.Since(started)
+
+	if result.Signal != "SIGKILL" {
+		t.Fatalf("signal: got %q want %q", result.Signal, "SIGKILL")
+	}
+	if elapsed < 150*time.Millisecond || elapsed >= 2*time.Second {
+		t.Fatalf("expected escalation after stop timeout, elapsed=%v", elapsed)
+	}
+}
+
+func TestExecuteBinaryKillsGroupMembersOutlivingTheLeader(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "grandchild.pid")
+	cmd := executor.Command{
+		ID:          "orphan",
+		Name:        "orphan",
+		Args:        []string{"sh", "-c", "(trap '' TERM; exec sleep 30) >/dev/null 2>&1 & echo $! > " + pidFile + "; trap 'exit 0' TERM; wait"},
+		Env:         executor.NewEnvDefault(),
+		Executor:    "bin",
+		Timeout:     100 * time.Millisecond,
+		StopTimeout: 300 * time.Millisecond,
+	}
+
+	result := executor.ExecuteBinary(context.Background(), cmd)
+	if result.Outcome != executor.OutcomeTimedOut {
+		t.Fatalf("outcome: got %q want %q", result.Outcome, executor.OutcomeTimedOut)
+	}
+	assertProcessGone(t, pidFile)
+}
+
+func TestExecuteBinaryReportsCancellation(t *testing.T) {
+	cmd := executor.Command{
+		ID:       "cancel",
+		Name:     "cancel",
+		Args:     []string{"sleep", "30"},
+		Env:      executor.NewEnvDefault(),
+		Executor: "bin",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	result := executor.ExecuteBinary(ctx, cmd)
+	if result.Outcome != executor.OutcomeCanceled {
+		t.Fatalf("outcome: got %q want %q", result.Outcome, executor.OutcomeCanceled)
+	}
+}
+
+// assertProcessGone waits briefly for the process recorded in pidFile to disappear.
+func assertProcessGone(t *testing.T, pidFile string) {
+	t.Helper()
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("read pid file: %v", err)
+	}
+	procDir := filepath.Join("/proc", strings.TrimSpace(string(data)))
+	if _, err := os.Stat("/proc/self"); err != nil {
+		t.Skip("procfs not available")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		stat, err := os.ReadFile(filepath.Join(procDir, "stat"))
+		if err != nil || strings.Contains(string(stat), ") Z ") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("grandchild %s still running", procDir)
+}
@@ -0,0 +1,25 @@
+package executor_test
+
+import (
+	"context"
+	"testing"
+
+	"poke/internal/server/executor"
+	"poke/internal/server/request"
+)
+
+func TestExecuteBinaryExportsRequestID(t *testing.T) {
+	cmd := executor.Command{Args: []string{"sh", "-c", "printf %s \"${POKE_REQUEST_ID-unset}\""}, Env: executor.NewEnvDefault(), Executor: "bin"}
+
+	result := executor.ExecuteBinary(request.ContextWithID(context.Background(), "req-1"), cmd)
+	if result.Error != nil {
+		t.Fatalf("execute: %v", result.Error)
+	}
+	if got := string(result.Output); got != "req-1" {
+		t.Fatalf("POKE_REQUEST_ID: got %q want %q", got, "req-1")
+	}
+
+	if got := string(executor.ExecuteBinary(context.Background(), cmd).Output); got != "unset" {
+		t.Fatalf("POKE_REQUEST_ID without request id: got %q want unset", got)
+	}
+}
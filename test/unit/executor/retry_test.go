@@ -0,0 +1,144 @@
+package executor_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"poke/internal/server/executor"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestCommandUnmarshalRetryDefaults(t *testing.T) {
+	input := []byte(`
+args: ["rsync", "-a", "src", "dst"]
+retry: {}
+`)
+
+	var got executor.Command
+	if err := yaml.Unmarshal(input, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if got.Retry == nil {
+		t.Fatalf("retry missing")
+	}
+	if got.Retry.MaxAttempts != 3 || got.Retry.Backoff != time.Second || got.Retry.MaxBackoff != time.Minute {
+		t.Fatalf("retry defaults: got %#v", got.Retry)
+	}
+	if got.Retry.On != nil || got.Retry.Jitter != 0 {
+		t.Fatalf("retry defaults: got %#v", got.Retry)
+	}
+}
+
+func TestCommandUnmarshalRetry(t *testing.T) {
+	input := []byte(`
+args: ["rsync", "-a", "src", "dst"]
+retry:
+  max_attempts: 5
+  backoff: 2s
+  max_backoff: 10s
+  jitter: 0.25
+  on:
+    exit_codes: [23, 30]
+    timeout: true
+`)
+
+	var got executor.Command
+	if err := yaml.Unmarshal(input, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	r := got.Retry
+	if r.MaxAttempts != 5 || r.Backoff != 2*time.Second || r.MaxBackoff != 10*time.Second || r.Jitter != 0.25 {
+		t.Fatalf("retry: got %#v", r)
+	}
+	if r.On == nil || len(r.On.ExitCodes) != 2 || r.On.ExitCodes[0] != 23 || !r.On.Timeout {
+		t.Fatalf("retry on: got %#v", r.On)
+	}
+}
+
+func TestCommandUnmarshalRetryRejectsInvalid(t *testing.T) {
+	cases := map[string]string{
+		"zero attempts":     "max_attempts: 0",
+		"too many attempts": "max_attempts: 1000",
+		"negative backoff":  "backoff: -1s",
+		"max below base":    "{backoff: 10s, max_backoff: 1s}",
+		"jitter above one":  "jitter: 1.5",
+		"empty on":          "on: {}",
+		"exit code zero":    "on: {exit_codes: [0]}",
+		"exit code 256":     "on: {exit_codes: [256]}",
+	}
+
+	for name, retry := range cases {
+		t.Run(name, func(t *testing.T) {
+			input := []byte("args: [\"true\"]\nretry: " + retry + "\n")
+			var got executor.Command
+			if err := yaml.Unmarshal(input, &got); err == nil {
+				t.Fatalf("expected error for %s", retry)
+			}
+		})
+	}
+}
+
+func TestRetryShouldRetry(t *testing.T) {
+	failed := func(code int) executor.Result {
+		return executor.Result{ExitCode: code, Outcome: executor.OutcomeFailed, Error: errors.New("exit status")}
+	}
+	timedOut := executor.Result{ExitCode: -1, Outcome: executor.OutcomeTimedOut, Signal: "SIGTERM", Error: errors.New("killed")}
+	canceled := executor.Result{ExitCode: -1, Outcome: executor.OutcomeCanceled, Error: errors.New("killed")}
+	killed := executor.Result{ExitCode: -1, Outcome: executor.OutcomeFailed, Signal: "SIGKILL", Error: errors.New("killed")}
+
+	anyFailure := &executor.Retry{MaxAttempts: 3}
+	exitCodes := &executor.Retry{MaxAttempts: 3, On: &executor.RetryOn{ExitCodes: []int{75}}}
+	timeoutOnly := &executor.Retry{MaxAttempts: 3, On: &executor.RetryOn{Timeout: true}}
+	var none *executor.Retry
+
+	cases := []struct {
+		name   string
+		policy *executor.Retry
+		result executor.Result
+		want   bool
+	}{
+		{"success", anyFailure, executor.Result{Outcome: executor.OutcomeSucceeded}, false},
+		{"any failure", anyFailure, failed(1), true},
+		{"any timeout", anyFailure, timedOut, true},
+		{"canceled", anyFailure, canceled, false},
+		{"listed exit code", exitCodes, failed(75), true},
+		{"unlisted exit code", exitCodes, failed(1), false},
+		{"signal with exit codes", exitCodes, killed, false},
+		{"timeout not listed", exitCodes, timedOut, false},
+		{"timeout only", timeoutOnly, timedOut, true},
+		{"timeout only failure", timeoutOnly, failed(1), false},
+		{"no policy", none, failed(1), false},
+	}
+
+	for _, tc := range cases {
+		if got := tc.policy.ShouldRetry(tc.result); got != tc.want {
+			t.Fatalf("%s: got %v want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRetryDelayBacksOffExponentiallyUpToMax(t *testing.T) {
+	r := &executor.Retry{MaxAttempts: 10, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := r.Delay(i + 1); got != w {
+			t.Fatalf("attempt %d: got %v want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestRetryDelayJitterStaysWithinSpread(t *testing.T) {
+	r := &executor.Retry{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: time.Minute, Jitter: 0.5}
+
+	for range 100 {
+		got := r.Delay(1)
+		if got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("delay out of jitter range: %v", got)
+		}
+	}
+}
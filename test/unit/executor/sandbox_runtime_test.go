@@ -0,0 +1,103 @@
+//go:build linux
+
+package executor_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"poke/internal/server/executor"
+)
+
+// requireSandbox skips tests when the kernel or privileges lack the requested isolation.
+func requireSandbox(t *testing.T, sb *executor.Sandbox) {
+	t.Helper()
+
+	if err := executor.ProbeSandbox(executor.Command{Args: []string{"true"}, Sandbox: sb}); err != nil {
+		t.Skipf("sandbox not supported here: %v", err)
+	}
+}
+
+func TestExecuteBinarySandboxReadOnlyMount(t *testing.T) {
+	dir := t.TempDir()
+	sb := &executor.Sandbox{ReadOnly: []string{dir}, Network: executor.SandboxNetworkHost}
+	requireSandbox(t, sb)
+
+	cmd := executor.Command{
+		ID:       "ro",
+		Name:     "ro",
+		Args:     []string{"sh", "-c", "touch " + filepath.Join(dir, "file")},
+		Env:      executor.NewEnvDefault(),
+		Executor: "bin",
+		Sandbox:  sb,
+	}
+
+	result := executor.ExecuteBinary(context.Background(), cmd)
+	if result.Error == nil {
+		t.Fatalf("expected write to read-only mount to fail, output %q", result.Output)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "file")); !os.IsNotExist(err) {
+		t.Fatalf("file should not exist outside sandbox, stat err=%v", err)
+	}
+}
+
+func TestExecuteBinarySandboxWithoutNetwork(t *testing.T) {
+	sb := &executor.Sandbox{Network: executor.SandboxNetworkNone}
+	requireSandbox(t, sb)
+
+	cmd := executor.Command{
+		ID:       "net",
+		Name:     "net",
+		Args:     []string{"cat", "/proc/net/dev"},
+		Env:      executor.NewEnvDefault(),
+		Executor: "bin",
+		Sandbox:  sb,
+	}
+
+	result := executor.ExecuteBinary(context.Background(), cmd)
+	if result.Error != nil {
+		t.Fatalf("execute: %v (output %q)", result.Error, result.Output)
+	}
+	for _, line := range strings.Split(string(result.Output), "\n")[2:] {
+		iface, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+		if iface != "" && iface != "lo" {
+			t.Fatalf("unexpected interface %q in private network namespace", iface)
+		}
+	}
+}
+
+func TestExecuteBinarySandboxLandlockDeniesUnlistedPaths(t *testing.T) {
+	allowed := t.TempDir()
+	denied := t.TempDir()
+	sb := &executor.Sandbox{
+		Network: executor.SandboxNetworkHost,
+		Landlock: &executor.LandlockRules{
+			Read:      []string{"/"},
+			ReadWrite: []string{allowed},
+		},
+	}
+	requireSandbox(t, sb)
+
+	cmd := executor.Command{
+		ID:       "landlock",
+		Name:     "landlock",
+		Args:     []string{"sh", "-c", "touch " + filepath.Join(allowed, "ok") + " && touch " + filepath.Join(denied, "nope")},
+		Env:      executor.NewEnvDefault(),
+		Executor: "bin",
+		Sandbox:  sb,
+	}
+
+	result := executor.ExecuteBinary(context.Background(), cmd)
+	if result.Error == nil {
+		t.Fatalf("expected landlock to deny write, output %q", result.Output)
+	}
+	if _, err := os.Stat(filepath.Join(allowed, "ok")); err != nil {
+		t.Fatalf("allowed write missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(denied, "nope")); !os.IsNotExist(err) {
+		t.Fatalf("denied write should not exist, stat err=%v", err)
+	}
+}
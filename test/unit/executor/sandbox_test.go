@@ -0,0 +1,72 @@
+package executor_test
+
+import (
+	"testing"
+
+	"poke/internal/server/executor"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestCommandUnmarshalSandbox(t *testing.T) {
+	input := []byte(`
+args: ["cat", "/etc/hostname"]
+sandbox:
+  read_only: ["/etc"]
+  network: none
+  landlock:
+    read: ["/usr", "/etc"]
+    read_write: ["/tmp"]
+`)
+
+	var got executor.Command
+	if err := yaml.Unmarshal(input, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if got.Sandbox == nil {
+		t.Fatalf("sandbox missing")
+	}
+	if len(got.Sandbox.ReadOnly) != 1 || got.Sandbox.ReadOnly[0] != "/etc" {
+		t.Fatalf("read_only: got %#v", got.Sandbox.ReadOnly)
+	}
+	if got.Sandbox.Network != executor.SandboxNetworkNone {
+		t.Fatalf("network: got %q", got.Sandbox.Network)
+	}
+	if got.Sandbox.Landlock == nil || len(got.Sandbox.Landlock.Read) != 2 || len(got.Sandbox.Landlock.ReadWrite) != 1 {
+		t.Fatalf("landlock: got %#v", got.Sandbox.Landlock)
+	}
+}
+
+func TestSandboxUnmarshalDefaultsToHostNetwork(t *testing.T) {
+	var got executor.Sandbox
+	if err := yaml.Unmarshal([]byte(`read_only: ["/usr"]`), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Network != executor.SandboxNetworkHost {
+		t.Fatalf("network: got %q want %q", got.Network, executor.SandboxNetworkHost)
+	}
+}
+
+func TestSandboxUnmarshalRejectsInvalidConfig(t *testing.T) {
+	cases := map[string]string{
+		"unknown network":     `network: bridge`,
+		"relative read_only":  `read_only: ["etc"]`,
+		"unclean read_only":   `read_only: ["/etc/../usr"]`,
+		"relative landlock":   `landlock: {read: ["usr"]}`,
+		"empty landlock path": `landlock: {read_write: [""]}`,
+	}
+
+	for name, input := range cases {
+		var got executor.Sandbox
+		if err := yaml.Unmarshal([]byte(input), &got); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestProbeSandboxSkipsCommandsWithoutSandbox(t *testing.T) {
+	if err := executor.ProbeSandbox(executor.Command{Args: []string{"true"}}); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+}
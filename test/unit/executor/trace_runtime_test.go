@@ -0,0 +1,33 @@
+package executor_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"poke/internal/server/executor"
+	"poke/internal/server/tracing"
+)
+
+func TestExecuteBinaryExportsTraceparent(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	remote, err := tracing.ParseTraceparent(traceparent)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	cmd := executor.Command{Args: []string{"sh", "-c", "printf %s \"$TRACEPARENT\""}, Env: executor.NewEnvDefault(), Executor: "bin"}
+
+	// Tracing is disabled by default, the caller's context is passed through as is.
+	result := executor.ExecuteBinary(tracing.ContextWithRemote(context.Background(), remote), cmd)
+	if result.Error != nil {
+		t.Fatalf("execute: %v", result.Error)
+	}
+	if got := strings.TrimSpace(string(result.Output)); got != traceparent {
+		t.Fatalf("TRACEPARENT: got %q want %q", got, traceparent)
+	}
+
+	untraced := executor.ExecuteBinary(context.Background(), cmd)
+	if got := string(untraced.Output); got != "" {
+		t.Fatalf("TRACEPARENT without trace: got %q want empty", got)
+	}
+}
@@ -0,0 +1,115 @@
+package health_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+
+	"poke/internal/server/health"
+)
+
+func serveChecker(t *testing.T, checker *health.Checker) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	checker.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func getReport(t *testing.T, url string, wantStatus int) health.Report {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("get %s: %v", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, wantStatus)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("content type: got %q", ct)
+	}
+	var report health.Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return report
+}
+
+func TestCheckerReportsEveryCheck(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Register("dispatcher", func() (string, error) { return "running", nil })
+	checker.Register("queue", func() (string, error) { return "16/16 buffered", errors.New("request buffer is full") })
+	srv := serveChecker(t, checker)
+
+	report := getReport(t, srv.URL+"/readyz", http.StatusServiceUnavailable)
+	if report.Status != "not_ready" {
+		t.Fatalf("status: got %q want not_ready", report.Status)
+	}
+	if got := report.Checks["dispatcher"]; got.Status != "ok" || got.Detail != "running" || got.Error != "" {
+		t.Fatalf("dispatcher check: got %#v", got)
+	}
+	if got := report.Checks["queue"]; got.Status != "failing" || got.Detail != "16/16 buffered" || got.Error != "request buffer is full" {
+		t.Fatalf("queue check: got %#v", got)
+	}
+
+	if got := getReport(t, srv.URL+"/healthz", http.StatusOK); got.Status != "ok" || len(got.Checks) != 0 {
+		t.Fatalf("liveness: got %#v", got)
+	}
+}
+
+func TestCheckerReadyWhenAllChecksPass(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Register("dispatcher", func() (string, error) { return "running", nil })
+	srv := serveChecker(t, checker)
+
+	if got := getReport(t, srv.URL+"/readyz", http.StatusOK); got.Status != "ready" {
+		t.Fatalf("status: got %q want ready", got.Status)
+	}
+
+	resp, err := http.Post(srv.URL+"/readyz", "application/json", nil)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("post status: got %d want 405", resp.StatusCode)
+	}
+}
+
+func TestConfigDefaultsAndValidation(t *testing.T) {
+	var cfg health.Config
+	if err := yaml.Unmarshal([]byte(`{}`), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !cfg.Enabled || cfg.Separate() {
+		t.Fatalf("defaults: got %#v", cfg)
+	}
+
+	if err := yaml.Unmarshal([]byte("port: 8081"), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !cfg.Separate() || cfg.Address() != "127.0.0.1:8081" {
+		t.Fatalf("separate listener: got %#v", cfg)
+	}
+
+	if err := yaml.Unmarshal([]byte("enabled: false\nport: 8081"), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if cfg.Separate() {
+		t.Fatalf("disabled config must not start a listener")
+	}
+
+	err := yaml.Unmarshal([]byte("port: 70000"), &cfg)
+	if err == nil || !strings.Contains(err.Error(), "health port") {
+		t.Fatalf("error: got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package jobs_test
+
+import (
+	"testing"
+	"time"
+
+	"poke/internal/server/jobs"
+)
+
+func TestRegistryCoalescesIdenticalQueuedSubmissions(t *testing.T) {
+	reg := coalescingRegistry("reindex")
+	sub := jobs.Submission{CommandID: "reindex", Payload: []byte("all")}
+
+	first := mustEnqueueSubmission(t, reg, sub)
+	second, admission, err := reg.Enqueue(sub)
+	if err != nil || admission != jobs.Coalesced || second.ID != first.ID {
+		t.Fatalf("second enqueue: got %q admission=%v err=%v", second.ID, admission, err)
+	}
+	if second.Coalesced != 1 {
+		t.Fatalf("coalesced: got %d want 1", second.Coalesced)
+	}
+}
+
+func TestRegistryCoalesceRequiresIdenticalQueuedJob(t *testing.T) {
+	priority := 5
+	cases := map[string]jobs.Submission{
+		"other payload":  {CommandID: "reindex", Payload: []byte("one")},
+		"other priority": {CommandID: "reindex", Payload: []byte("all"), Priority: &priority},
+		"scheduled":      {CommandID: "reindex", Payload: []byte("all"), RunAt: time.Now().Add(time.Minute)},
+		"not coalescing": {CommandID: "backup", Payload: []byte("all")},
+	}
+
+	for name, sub := range cases {
+		t.Run(name, func(t *testing.T) {
+			reg := coalescingRegistry("reindex")
+			mustEnqueueSubmission(t, reg, jobs.Submission{CommandID: "reindex", Payload: []byte("all")})
+			mustEnqueueSubmission(t, reg, jobs.Submission{CommandID: "backup", Payload: []byte("all")})
+
+			if _, admission, err := reg.Enqueue(sub); err != nil || admission != jobs.Admitted {
+				t.Fatalf("expected a new job, got admission=%v err=%v", admission, err)
+			}
+		})
+	}
+}
+
+func TestRegistryDoesNotCoalesceIntoStartedJob(t *testing.T) {
+	reg := coalescingRegistry("reindex")
+	sub := jobs.Submission{CommandID: "reindex"}
+	first := mustEnqueueSubmission(t, reg, sub)
+	if _, ok := reg.Start(first.ID, "reindex", func() {}); !ok {
+		t.Fatalf("expected job to start")
+	}
+
+	second, admission, err := reg.Enqueue(sub)
+	if err != nil || admission != jobs.Admitted || second.ID == first.ID {
+		t.Fatalf("expected a new job, got %q admission=%v err=%v", second.ID, admission, err)
+	}
+}
+
+func TestRegistryCoalescedSubmissionKeepsIdempotencyKey(t *testing.T) {
+	reg := coalescingRegistry("reindex")
+	first := mustEnqueueSubmission(t, reg, jobs.Submission{CommandID: "reindex"})
+	keyed := jobs.Submission{CommandID: "reindex", Principal: "http/api_token", IdempotencyKey: "hook-1", Window: time.Hour}
+	if _, admission, err := reg.Enqueue(keyed); err != nil || admission != jobs.Coalesced {
+		t.Fatalf("expected coalesced submission, got admission=%v err=%v", admission, err)
+	}
+
+	replay, admission, err := reg.Enqueue(keyed)
+	if err != nil || admission != jobs.Replayed || replay.ID != first.ID {
+		t.Fatalf("expected replay of coalesced job, got %q admission=%v err=%v", replay.ID, admission, err)
+	}
+}
+
+func TestRegistryDoesNotCoalesceIntoJobNotYetEnqueued(t *testing.T) {
+	reg := coalescingRegistry("reindex")
+	sub := jobs.Submission{CommandID: "reindex"}
+	first, _, err := reg.Enqueue(sub)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	second, admission, err := reg.Enqueue(sub)
+	if err != nil || admission != jobs.Admitted || second.ID == first.ID {
+		t.Fatalf("expected a new job, got %q admission=%v err=%v", second.ID, admission, err)
+	}
+	reg.Discard(first.ID)
+	if _, ok := reg.Get(second.ID); !ok {
+		t.Fatalf("job %s: lost when an unrelated job was discarded", second.ID)
+	}
+}
+
+func TestRegistryCoalescesIntoRecoveredJob(t *testing.T) {
+	cfg := fileQueue(t)
+	reg := mustOpen(t, cfg)
+	first, _, err := reg.Enqueue(jobs.Submission{CommandID: "reindex"})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	mustClose(t, reg)
+
+	reg = mustOpen(t, cfg)
+	defer mustClose(t, reg)
+	reg.SetCoalescePolicy(func(commandID string) bool { return commandID == "reindex" })
+	second, admission, err := reg.Enqueue(jobs.Submission{CommandID: "reindex"})
+	if err != nil || admission != jobs.Coalesced || second.ID != first.ID {
+		t.Fatalf("expected merge into recovered job, got %q admission=%v err=%v", second.ID, admission, err)
+	}
+}
+
+func coalescingRegistry(commandIDs ...string) *jobs.Registry {
+	reg := jobs.NewRegistry()
+	reg.SetCoalescePolicy(func(commandID string) bool {
+		for _, id := range commandIDs {
+			if id == commandID {
+				return true
+			}
+		}
+		return false
+	})
+	return reg
+}
+
+func mustEnqueueSubmission(t *testing.T, reg *jobs.Registry, sub jobs.Submission) jobs.Job {
+	t.Helper()
+
+	job, _, err := reg.Enqueue(sub)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	reg.MarkEnqueued(job.ID)
+	return job
+}
@@ -0,0 +1,169 @@
+package jobs_test
+
+import (
+	"testing"
+	"time"
+
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+)
+
+func TestRegistryEnqueueReplaysIdempotentSubmission(t *testing.T) {
+	reg := jobs.NewRegistry()
+	sub := jobs.Submission{CommandID: "deploy", Principal: "http/api_token", IdempotencyKey: "ci-42", Window: time.Hour}
+
+	first, admission, err := reg.Enqueue(sub)
+	if err != nil || admission != jobs.Admitted {
+		t.Fatalf("first enqueue: %v, admission=%v", err, admission)
+	}
+	second, admission, err := reg.Enqueue(sub)
+	if err != nil || admission != jobs.Replayed {
+		t.Fatalf("second enqueue: %v, admission=%v", err, admission)
+	}
+	if second.ID != first.ID {
+		t.Fatalf("replayed job: got %q want %q", second.ID, first.ID)
+	}
+	if first.SubmittedBy != "http/api_token" || first.IdempotencyKey != "ci-42" {
+		t.Fatalf("job: got %#v", first)
+	}
+}
+
+func TestRegistryEnqueueScopesIdempotencyKeys(t *testing.T) {
+	base := jobs.Submission{CommandID: "deploy", Principal: "http/api_token", IdempotencyKey: "ci-42", Window: time.Hour}
+	otherCommand := base
+	otherCommand.CommandID = "rollback"
+	otherPrincipal := base
+	otherPrincipal.Principal = "http/anonymous"
+	noWindow := base
+	noWindow.Window = 0
+	noKey := base
+	noKey.IdempotencyKey = ""
+
+	for name, sub := range map[string]jobs.Submission{
+		"other command":   otherCommand,
+		"other principal": otherPrincipal,
+		"zero window":     noWindow,
+		"no key":          noKey,
+	} {
+		t.Run(name, func(t *testing.T) {
+			reg := jobs.NewRegistry()
+			if _, _, err := reg.Enqueue(base); err != nil {
+				t.Fatalf("enqueue: %v", err)
+			}
+			if _, admission, err := reg.Enqueue(sub); err != nil || admission != jobs.Admitted {
+				t.Fatalf("expected a new job, got admission=%v err=%v", admission, err)
+			}
+		})
+	}
+}
+
+func TestRegistryEnqueueExpiresIdempotencyKeys(t *testing.T) {
+	reg := jobs.NewRegistry()
+	sub := jobs.Submission{CommandID: "deploy", Principal: "http/api_token", IdempotencyKey: "ci-42", Window: time.Millisecond}
+
+	first, _, err := reg.Enqueue(sub)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	second, admission, err := reg.Enqueue(sub)
+	if err != nil || admission != jobs.Admitted || second.ID == first.ID {
+		t.Fatalf("expected expired key to create a new job, got %q admission=%v err=%v", second.ID, admission, err)
+	}
+}
+
+func TestRegistryDiscardForgetsIdempotencyKey(t *testing.T) {
+	reg := jobs.NewRegistry()
+	sub := jobs.Submission{CommandID: "deploy", Principal: "http/api_token", IdempotencyKey: "ci-42", Window: time.Hour}
+
+	first, _, err := reg.Enqueue(sub)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	reg.Discard(first.ID)
+
+	if _, admission, err := reg.Enqueue(sub); err != nil || admission != jobs.Admitted {
+		t.Fatalf("discarded job must not be replayed, got admission=%v err=%v", admission, err)
+	}
+}
+
+func TestOpenFileQueueRestoresIdempotencyKeys(t *testing.T) {
+	cfg := fileQueue(t)
+	sub := jobs.Submission{CommandID: "deploy", Principal: "http/api_token", IdempotencyKey: "ci-42", Window: time.Hour}
+
+	reg := mustOpen(t, cfg)
+	first, _, err := reg.Enqueue(sub)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	mustClose(t, reg)
+
+	reg = mustOpen(t, cfg)
+	defer mustClose(t, reg)
+	second, admission, err := reg.Enqueue(sub)
+	if err != nil || admission != jobs.Replayed || second.ID != first.ID {
+		t.Fatalf("expected replay after restart, got %q admission=%v err=%v", second.ID, admission, err)
+	}
+}
+
+func TestRegistryKeepsIdempotencyKeyAfterJobLeavesRetention(t *testing.T) {
+	reg := jobs.NewRegistry()
+	sub := jobs.Submission{CommandID: "deploy", Principal: "http/api_token", IdempotencyKey: "ci-42", Window: time.Hour}
+
+	first := runKeyedJob(t, reg, sub)
+	evictFinishedJobs(reg)
+	if _, ok := reg.Get(first.ID); ok {
+		t.Fatalf("job %s: expected it to leave retention", first.ID)
+	}
+
+	second, admission, err := reg.Enqueue(sub)
+	if err != nil || admission != jobs.Replayed || second.ID != first.ID {
+		t.Fatalf("expected replay of the evicted job, got %q admission=%v err=%v", second.ID, admission, err)
+	}
+	if second.State != jobs.StateSucceeded {
+		t.Fatalf("replayed job: got state %q want %q", second.State, jobs.StateSucceeded)
+	}
+}
+
+func TestOpenFileQueueKeepsIdempotencyKeyOfEvictedJob(t *testing.T) {
+	cfg := fileQueue(t)
+	sub := jobs.Submission{CommandID: "deploy", Principal: "http/api_token", IdempotencyKey: "ci-42", Window: time.Hour}
+
+	reg := mustOpen(t, cfg)
+	first := runKeyedJob(t, reg, sub)
+	evictFinishedJobs(reg)
+	mustClose(t, reg)
+
+	// Open compacts the journal; the key must survive that and a second restart.
+	mustClose(t, mustOpen(t, cfg))
+	reg = mustOpen(t, cfg)
+	defer mustClose(t, reg)
+	second, admission, err := reg.Enqueue(sub)
+	if err != nil || admission != jobs.Replayed || second.ID != first.ID {
+		t.Fatalf("expected replay after compaction, got %q admission=%v err=%v", second.ID, admission, err)
+	}
+}
+
+// runKeyedJob enqueues sub and runs the job to success.
+func runKeyedJob(t *testing.T, reg *jobs.Registry, sub jobs.Submission) jobs.Job {
+	t.Helper()
+
+	job, _, err := reg.Enqueue(sub)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, ok := reg.Start(job.ID, sub.CommandID, func() {}); !ok {
+		t.Fatalf("start %s: refused", job.ID)
+	}
+	reg.Finish(job.ID, executor.Result{Outcome: executor.OutcomeSucceeded})
+	return job
+}
+
+// evictFinishedJobs finishes enough jobs to push earlier ones out of retention.
+func evictFinishedJobs(reg *jobs.Registry) {
+	for range 1000 {
+		done, _ := reg.Start("", "noop", func() {})
+		reg.Finish(done.ID, executor.Result{Outcome: executor.OutcomeSucceeded})
+	}
+}
@@ -0,0 +1,225 @@
+package jobs_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+	"poke/internal/server/request"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestQueueConfigDefaultsToMemory(t *testing.T) {
+	var cfg jobs.QueueConfig
+	if err := yaml.Unmarshal([]byte(`{}`), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if cfg.Type != jobs.QueueTypeMemory || cfg.Durable() {
+		t.Fatalf("queue config: got %#v", cfg)
+	}
+}
+
+func TestQueueConfigRejectsInvalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown type":       "type: redis",
+		"file without path":  "type: file",
+		"relative path":      "{type: file, path: queue.jsonl}",
+		"memory with a path": "{type: memory, path: /tmp/queue.jsonl}",
+	}
+
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			var cfg jobs.QueueConfig
+			if err := yaml.Unmarshal([]byte(input), &cfg); err == nil {
+				t.Fatalf("expected error for %s", input)
+			}
+		})
+	}
+}
+
+func TestOpenFileQueueRequeuesQueuedJobs(t *testing.T) {
+	cfg := fileQueue(t)
+
+	reg := mustOpen(t, cfg)
+	first, _, err := reg.Enqueue(jobs.Submission{CommandID: "import", Payload: []byte("data")})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	second := mustEnqueue(t, reg, "uptime")
+	mustClose(t, reg)
+
+	reg, pending := mustOpenPending(t, cfg)
+	defer mustClose(t, reg)
+
+	if len(pending) != 2 {
+		t.Fatalf("pending: got %d want 2", len(pending))
+	}
+	if pending[0].JobID != first.ID || pending[0].CommandID != "import" || string(pending[0].Payload) != "data" {
+		t.Fatalf("first pending: got %#v", pending[0])
+	}
+	if pending[1].JobID != second.ID {
+		t.Fatalf("second pending: got %#v", pending[1])
+	}
+	if job, ok := reg.Get(first.ID); !ok || job.State != jobs.StateQueued {
+		t.Fatalf("recovered job: got %#v, %v", job, ok)
+	}
+}
+
+func TestOpenFileQueueMarksRunningJobsInterrupted(t *testing.T) {
+	cfg := fileQueue(t)
+
+	reg := mustOpen(t, cfg)
+	job := mustEnqueue(t, reg, "deploy")
+	if _, ok := reg.Start(job.ID, "deploy", func() {}); !ok {
+		t.Fatalf("expected job to start")
+	}
+	mustClose(t, reg)
+
+	reg, pending := mustOpenPending(t, cfg)
+	defer mustClose(t, reg)
+
+	if len(pending) != 0 {
+		t.Fatalf("running job must not be requeued, got %#v", pending)
+	}
+	got, _ := reg.Get(job.ID)
+	if got.State != jobs.StateFailed || got.Outcome != executor.OutcomeInterrupted || got.FinishedAt == nil {
+		t.Fatalf("interrupted job: got %#v", got)
+	}
+}
+
+func TestOpenFileQueueKeepsFinishedAndCanceledJobs(t *testing.T) {
+	cfg := fileQueue(t)
+
+	reg := mustOpen(t, cfg)
+	done := mustEnqueue(t, reg, "ok")
+	reg.Start(done.ID, "ok", func() {})
+	reg.RecordAttempt(done.ID, jobs.Attempt{Attempt: 1, Outcome: executor.OutcomeSucceeded})
+	reg.Finish(done.ID, executor.Result{Outcome: executor.OutcomeSucceeded})
+	canceled := mustEnqueue(t, reg, "ok")
+	if _, err := reg.Cancel(canceled.ID, "http/api_token"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	discarded := mustEnqueue(t, reg, "ok")
+	reg.Discard(discarded.ID)
+	mustClose(t, reg)
+
+	reg, pending := mustOpenPending(t, cfg)
+	defer mustClose(t, reg)
+
+	if len(pending) != 0 {
+		t.Fatalf("pending: got %#v", pending)
+	}
+	if got, _ := reg.Get(done.ID); got.State != jobs.StateSucceeded || len(got.Attempts) != 1 {
+		t.Fatalf("finished job: got %#v", got)
+	}
+	if got, _ := reg.Get(canceled.ID); got.State != jobs.StateCanceled || got.CanceledBy != "http/api_token" {
+		t.Fatalf("canceled job: got %#v", got)
+	}
+	if _, ok := reg.Get(discarded.ID); ok {
+		t.Fatalf("discarded job must not be recovered")
+	}
+}
+
+func TestOpenFileQueueIgnoresTornLastRecord(t *testing.T) {
+	cfg := fileQueue(t)
+
+	reg := mustOpen(t, cfg)
+	job := mustEnqueue(t, reg, "ok")
+	mustClose(t, reg)
+
+	file, err := os.OpenFile(cfg.Path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	if _, err := file.WriteString(`{"job":{"id":"torn","sta`); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_ = file.Close()
+
+	reg, pending := mustOpenPending(t, cfg)
+	defer mustClose(t, reg)
+
+	if len(pending) != 1 || pending[0].JobID != job.ID {
+		t.Fatalf("pending: got %#v", pending)
+	}
+}
+
+func TestOpenFileQueueRejectsCorruptRecord(t *testing.T) {
+	cfg := fileQueue(t)
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o750); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(cfg.Path, []byte("not json\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, _, err := jobs.Open(cfg); err == nil {
+		t.Fatalf("expected corrupt journal error")
+	}
+}
+
+func TestMemoryQueueCancelHookStillWorks(t *testing.T) {
+	reg, pending, err := jobs.Open(jobs.QueueConfig{Type: jobs.QueueTypeMemory})
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("open: %v, %#v", err, pending)
+	}
+
+	job := mustEnqueue(t, reg, "sleep")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reg.Start(job.ID, "sleep", cancel)
+	if _, err := reg.Cancel(job.ID, "http/api_token"); err != nil || ctx.Err() == nil {
+		t.Fatalf("cancel: %v, ctx %v", err, ctx.Err())
+	}
+}
+
+func fileQueue(t *testing.T) jobs.QueueConfig {
+	t.Helper()
+	return jobs.QueueConfig{Type: jobs.QueueTypeFile, Path: filepath.Join(t.TempDir(), "state", "queue.jsonl")}
+}
+
+func mustOpen(t *testing.T, cfg jobs.QueueConfig) *jobs.Registry {
+	t.Helper()
+	reg, _ := mustOpenPending(t, cfg)
+	return reg
+}
+
+func mustOpenPending(t *testing.T, cfg jobs.QueueConfig) (*jobs.Registry, []request.CommandRequest) {
+	t.Helper()
+
+	reg, pending, err := jobs.Open(cfg)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return reg, pending
+}
+
+func mustClose(t *testing.T, reg *jobs.Registry) {
+	t.Helper()
+	if err := reg.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
+
+func TestOpenFileQueueRestoresPriorityOverride(t *testing.T) {
+	cfg := fileQueue(t)
+	priority := 7
+
+	reg := mustOpen(t, cfg)
+	job, _, err := reg.Enqueue(jobs.Submission{CommandID: "deploy", Priority: &priority})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	mustClose(t, reg)
+
+	reg, pending := mustOpenPending(t, cfg)
+	defer mustClose(t, reg)
+
+	if len(pending) != 1 || pending[0].JobID != job.ID || pending[0].Priority == nil || *pending[0].Priority != 7 {
+		t.Fatalf("pending: got %#v", pending)
+	}
+}
@@ -0,0 +1,193 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+)
+
+func TestRegistryEnqueueCreatesQueuedJob(t *testing.T) {
+	reg := jobs.NewRegistry()
+
+	job := mustEnqueue(t, reg, "uptime")
+	if job.ID == "" {
+		t.Fatalf("expected job id")
+	}
+	if job.State != jobs.StateQueued || job.CommandID != "uptime" {
+		t.Fatalf("job: got %#v", job)
+	}
+
+	got, ok := reg.Get(job.ID)
+	if !ok || got.ID != job.ID {
+		t.Fatalf("get: got %#v, %v", got, ok)
+	}
+}
+
+func TestRegistryCancelQueuedJobSkipsStart(t *testing.T) {
+	reg := jobs.NewRegistry()
+	job := mustEnqueue(t, reg, "uptime")
+
+	canceled, err := reg.Cancel(job.ID, "http/api_token")
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if canceled.State != jobs.StateCanceled || canceled.CanceledBy != "http/api_token" || canceled.FinishedAt == nil {
+		t.Fatalf("canceled job: got %#v", canceled)
+	}
+
+	if _, ok := reg.Start(job.ID, "uptime", func() {}); ok {
+		t.Fatalf("expected canceled job not to start")
+	}
+}
+
+func TestRegistryKeepsCanceledJobUntilDispatcherReachesIt(t *testing.T) {
+	reg := jobs.NewRegistry()
+	job := mustEnqueue(t, reg, "uptime")
+	if _, err := reg.Cancel(job.ID, "http/api_token"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	// Far more jobs than are retained finish while the request is still on
+	// the request channel.
+	for range 1100 {
+		done, _ := reg.Start("", "cmd", func() {})
+		reg.Finish(done.ID, executor.Result{Outcome: executor.OutcomeSucceeded})
+	}
+	if got, ok := reg.Get(job.ID); !ok || got.State != jobs.StateCanceled {
+		t.Fatalf("canceled job evicted before its request was dequeued: %#v, %v", got, ok)
+	}
+	if _, ok := reg.Start(job.ID, "uptime", func() {}); ok {
+		t.Fatalf("expected canceled job not to start")
+	}
+}
+
+func TestRegistryCancelWithdrawsQueuedRequest(t *testing.T) {
+	reg := jobs.NewRegistry()
+	job := mustEnqueue(t, reg, "uptime")
+
+	withdrawn := false
+	reg.Hold(job.ID, func() bool { withdrawn = true; return true })
+	if _, err := reg.Cancel(job.ID, "http/api_token"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if !withdrawn {
+		t.Fatalf("expected the queued request to be withdrawn")
+	}
+}
+
+func TestRegistryCancelAbandonsRetryingStep(t *testing.T) {
+	reg := jobs.NewRegistry()
+	job := mustEnqueue(t, reg, "deploy")
+	if _, ok := reg.Start(job.ID, "deploy", func() {}); !ok {
+		t.Fatalf("expected job to start")
+	}
+	reg.RecordStep(job.ID, jobs.Step{ID: "migrate", CommandID: "migrate", State: jobs.StepRetrying})
+	if !reg.Retry(job.ID, time.Now().Add(time.Hour), nil) {
+		t.Fatalf("expected job to be rescheduled")
+	}
+
+	canceled, err := reg.Cancel(job.ID, "http/api_token")
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if step := canceled.Steps[0]; step.State != jobs.StepFailed || step.Outcome != executor.OutcomeCanceled {
+		t.Fatalf("step waiting for retry: got %#v", step)
+	}
+}
+
+func TestRegistryStartRefusesUnknownJob(t *testing.T) {
+	reg := jobs.NewRegistry()
+
+	job, ok := reg.Start("f00d", "uptime", func() {})
+	if ok || job.ID != "" {
+		t.Fatalf("unknown job must not start: %#v, %v", job, ok)
+	}
+	if _, found := reg.Get("f00d"); found {
+		t.Fatalf("unknown job must not be registered")
+	}
+}
+
+func TestRegistryCancelRunningJobCallsCancel(t *testing.T) {
+	reg := jobs.NewRegistry()
+	job := mustEnqueue(t, reg, "sleep")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, ok := reg.Start(job.ID, "sleep", cancel); !ok {
+		t.Fatalf("expected job to start")
+	}
+
+	running, err := reg.Cancel(job.ID, "http/api_token")
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if running.State != jobs.StateRunning || running.CanceledBy != "http/api_token" {
+		t.Fatalf("running job: got %#v", running)
+	}
+	if ctx.Err() == nil {
+		t.Fatalf("expected job context to be canceled")
+	}
+
+	done := reg.Finish(job.ID, executor.Result{ExitCode: -1, Outcome: executor.OutcomeCanceled, Error: errors.New("signal: terminated")})
+	if done.State != jobs.StateCanceled || done.CanceledBy != "http/api_token" {
+		t.Fatalf("finished job: got %#v", done)
+	}
+}
+
+func TestRegistryFinishRecordsState(t *testing.T) {
+	tests := []struct {
+		name   string
+		result executor.Result
+		want   jobs.State
+	}{
+		{name: "succeeded", result: executor.Result{Outcome: executor.OutcomeSucceeded}, want: jobs.StateSucceeded},
+		{name: "failed", result: executor.Result{ExitCode: 1, Outcome: executor.OutcomeFailed, Error: errors.New("exit status 1")}, want: jobs.StateFailed},
+		{name: "timed out", result: executor.Result{ExitCode: -1, Outcome: executor.OutcomeTimedOut, Error: errors.New("killed")}, want: jobs.StateFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := jobs.NewRegistry()
+			job, _ := reg.Start("", "cmd", func() {})
+
+			got := reg.Finish(job.ID, tt.result)
+			if got.State != tt.want {
+				t.Fatalf("state: got %q want %q", got.State, tt.want)
+			}
+			if got.ExitCode == nil || *got.ExitCode != tt.result.ExitCode {
+				t.Fatalf("exit code: got %v want %d", got.ExitCode, tt.result.ExitCode)
+			}
+		})
+	}
+}
+
+func TestRegistryCancelRejectsUnknownAndFinishedJobs(t *testing.T) {
+	reg := jobs.NewRegistry()
+	if _, err := reg.Cancel("missing", "http/api_token"); !errors.Is(err, jobs.ErrJobNotFound) {
+		t.Fatalf("unknown job: got %v", err)
+	}
+
+	job, _ := reg.Start("", "cmd", func() {})
+	reg.Finish(job.ID, executor.Result{Outcome: executor.OutcomeSucceeded})
+	finished, err := reg.Cancel(job.ID, "http/api_token")
+	if !errors.Is(err, jobs.ErrJobFinished) {
+		t.Fatalf("finished job: got %v", err)
+	}
+	if finished.CanceledBy != "" {
+		t.Fatalf("finished job must not record canceled_by, got %q", finished.CanceledBy)
+	}
+}
+
+func mustEnqueue(t *testing.T, reg *jobs.Registry, commandID string) jobs.Job {
+	t.Helper()
+
+	job, _, err := reg.Enqueue(jobs.Submission{CommandID: commandID})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	return job
+}
@@ -0,0 +1,158 @@
+package jobs_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestRegistryEnqueueSchedulesFutureRunAt(t *testing.T) {
+	reg := jobs.NewRegistry()
+	runAt := time.Now().Add(time.Hour)
+
+	job, _, err := reg.Enqueue(jobs.Submission{CommandID: "backup", RunAt: runAt})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if job.State != jobs.StateScheduled || job.RunAt == nil || !job.RunAt.Equal(runAt) {
+		t.Fatalf("job: got %#v", job)
+	}
+}
+
+func TestRegistryEnqueueRejectsRunAtBeyondMaxDelay(t *testing.T) {
+	reg := jobs.NewRegistry()
+
+	_, _, err := reg.Enqueue(jobs.Submission{CommandID: "backup", RunAt: time.Now().Add(48 * time.Hour)})
+	if !errors.Is(err, jobs.ErrBeyondMaxDelay) {
+		t.Fatalf("expected ErrBeyondMaxDelay, got %v", err)
+	}
+}
+
+func TestRegistryCancelScheduledJobStopsTimer(t *testing.T) {
+	reg := jobs.NewRegistry()
+	runAt := time.Now().Add(time.Hour)
+	job, _, err := reg.Enqueue(jobs.Submission{CommandID: "backup", RunAt: runAt})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	stopped := false
+	if !reg.Schedule(job.ID, "backup", func() bool { stopped = true; return true }) {
+		t.Fatalf("expected job to be scheduled")
+	}
+	canceled, err := reg.Cancel(job.ID, "http/api_token")
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if !stopped || canceled.State != jobs.StateCanceled {
+		t.Fatalf("cancel: stopped=%v job=%#v", stopped, canceled)
+	}
+	if reg.Schedule(job.ID, "backup", func() bool { return true }) {
+		t.Fatalf("expected canceled job not to be scheduled again")
+	}
+}
+
+func TestOpenFileQueueRestoresScheduledJobs(t *testing.T) {
+	cfg := fileQueue(t)
+	runAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	reg := mustOpen(t, cfg)
+	job, _, err := reg.Enqueue(jobs.Submission{CommandID: "backup", RunAt: runAt})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	mustClose(t, reg)
+
+	reg, pending := mustOpenPending(t, cfg)
+	defer mustClose(t, reg)
+
+	if len(pending) != 1 || pending[0].JobID != job.ID || !pending[0].RunAt.Equal(runAt) {
+		t.Fatalf("pending: got %#v", pending)
+	}
+	if got, ok := reg.Get(job.ID); !ok || got.State != jobs.StateScheduled {
+		t.Fatalf("recovered job: got %#v, %v", got, ok)
+	}
+}
+
+func TestQueueConfigMaxDelay(t *testing.T) {
+	var cfg jobs.QueueConfig
+	if err := yaml.Unmarshal([]byte(`{}`), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if cfg.MaxDelay != 24*time.Hour {
+		t.Fatalf("default max_delay: got %s", cfg.MaxDelay)
+	}
+
+	if err := yaml.Unmarshal([]byte(`max_delay: 2h`), &cfg); err != nil || cfg.MaxDelay != 2*time.Hour {
+		t.Fatalf("max_delay: got %s, %v", cfg.MaxDelay, err)
+	}
+	for _, input := range []string{`max_delay: -1s`, `max_delay: 0s`} {
+		if err := yaml.Unmarshal([]byte(input), &cfg); err == nil {
+			t.Fatalf("%s: expected non-positive max_delay to be rejected", input)
+		}
+	}
+}
+
+func TestQueueConfigPriorityAging(t *testing.T) {
+	var cfg jobs.QueueConfig
+	if err := yaml.Unmarshal([]byte(`{}`), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if cfg.PriorityAging != jobs.DefaultPriorityAging {
+		t.Fatalf("default priority_aging: got %s", cfg.PriorityAging)
+	}
+	if registry := jobs.NewRegistry(); registry.PriorityAging() != jobs.DefaultPriorityAging {
+		t.Fatalf("registry default: got %s", registry.PriorityAging())
+	}
+
+	if err := yaml.Unmarshal([]byte(`priority_aging: 5s`), &cfg); err != nil || cfg.PriorityAging != 5*time.Second {
+		t.Fatalf("priority_aging: got %s, %v", cfg.PriorityAging, err)
+	}
+	registry, _, err := jobs.Open(cfg)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if registry.PriorityAging() != 5*time.Second {
+		t.Fatalf("registry priority_aging: got %s", registry.PriorityAging())
+	}
+	for _, input := range []string{`priority_aging: -1s`, `priority_aging: 0s`} {
+		if err := yaml.Unmarshal([]byte(input), &cfg); err == nil || !strings.Contains(err.Error(), "priority_aging must be positive") {
+			t.Fatalf("%s: got %v", input, err)
+		}
+	}
+}
+
+func TestOpenFileQueueRestoresJobWaitingForRetry(t *testing.T) {
+	cfg := fileQueue(t)
+	runAt := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	reg := mustOpen(t, cfg)
+	job, _, err := reg.Enqueue(jobs.Submission{CommandID: "backup", Payload: []byte("full")})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, ok := reg.Start(job.ID, "backup", func() {}); !ok {
+		t.Fatalf("expected job to start")
+	}
+	reg.RecordAttempt(job.ID, jobs.Attempt{Attempt: 1, Outcome: executor.OutcomeFailed, ExitCode: 1})
+	if !reg.Retry(job.ID, runAt, []byte("full")) {
+		t.Fatalf("expected job to be rescheduled")
+	}
+	mustClose(t, reg)
+
+	reg, pending := mustOpenPending(t, cfg)
+	defer mustClose(t, reg)
+	if len(pending) != 1 || !pending[0].RunAt.Equal(runAt) || string(pending[0].Payload) != "full" {
+		t.Fatalf("pending: got %#v", pending)
+	}
+	got, _ := reg.Get(job.ID)
+	if got.State != jobs.StateScheduled || len(got.Attempts) != 1 {
+		t.Fatalf("job: got %#v", got)
+	}
+}
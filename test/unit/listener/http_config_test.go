@@ -1,6 +1,7 @@
 package listener_test
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -190,3 +191,18 @@ auth:
 		t.Fatalf("expected error for unknown auth method")
 	}
 }
+
+func TestHTTPListenerConfigRejectsAdminAuthReusingCommandToken(t *testing.T) {
+	var cfg listener.HTTPListenerConfig
+	input := []byte(`
+auth:
+  api_token:
+    token: "secret"
+admin_auth:
+  api_token:
+    token: "secret"
+`)
+	if err := yaml.Unmarshal(input, &cfg); err == nil || !strings.Contains(err.Error(), "must not reuse") {
+		t.Fatalf("expected error for shared admin token, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package listener_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"poke/internal/server/auth"
+	"poke/internal/server/jobs"
+	"poke/internal/server/listener"
+	"poke/internal/server/logging"
+	"poke/internal/server/request"
+
+	"github.com/goccy/go-yaml"
+)
+
+var testAdminHeaders = map[string]string{
+	"Content-Type":       "application/json",
+	"X-Poke-Auth-Method": "api_token",
+	"X-Poke-API-Token":   "admin-token",
+}
+
+func TestHTTPListenerChangesLogLevel(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithAdminToken(t, port, "secret-token", "admin-token")
+	startHTTPListenerWithJobs(t, cfg, make(chan request.CommandRequest, 1), jobs.NewRegistry())
+	t.Cleanup(func() { logging.ResetLevel("") })
+	url := fmt.Sprintf("http://127.0.0.1:%d/admin/log-level", port)
+
+	for _, headers := range []map[string]string{nil, testAuthHeaders} {
+		resp := mustRequest(t, http.MethodGet, url, headers)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("status with %v: got %d want %d", headers, resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+
+	resp := mustBodyRequest(t, http.MethodPut, url, `{"level":"debug","component":"dispatcher","duration":"5m"}`)
+	var override logging.LevelOverride
+	decodeAdminResponse(t, resp, &override)
+	if override.Component != "dispatcher" || override.Level != "debug" || time.Until(override.Until) < 4*time.Minute {
+		t.Fatalf("override: got %#v", override)
+	}
+
+	var status logging.LevelStatus
+	decodeAdminResponse(t, mustRequest(t, http.MethodGet, url, testAdminHeaders), &status)
+	if len(status.Overrides) != 1 || status.Overrides[0].Component != "dispatcher" || status.MaxDuration != "24h0m0s" {
+		t.Fatalf("status: got %#v", status)
+	}
+
+	for _, body := range []string{`{"level":"trace"}`, `{"level":"debug","duration":"-1m"}`, `{"level":"debug","duration":"soon"}`, `{"level":"debug","duration":"25h"}`, `not json`} {
+		resp = mustBodyRequest(t, http.MethodPut, url, body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: status got %d want %d", body, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+
+	decodeAdminResponse(t, mustRequest(t, http.MethodDelete, url+"?component=dispatcher", testAdminHeaders), &status)
+	if len(status.Overrides) != 0 {
+		t.Fatalf("status after reset: got %#v", status)
+	}
+}
+
+func TestHTTPListenerForbidsAdminRoutesWithoutAdminAuth(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+	startHTTPListenerWithJobs(t, cfg, make(chan request.CommandRequest, 1), jobs.NewRegistry())
+
+	resp := mustRequest(t, http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/admin/log-level", port), testAuthHeaders)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func mustHTTPListenerConfigWithAdminToken(t *testing.T, port int, token string, adminToken string) listener.HTTPListenerConfig {
+	t.Helper()
+
+	input := fmt.Sprintf(`
+host: 127.0.0.1
+port: %d
+auth:
+  %s:
+    token: %q
+admin_auth:
+  %s:
+    token: %q
+`, port, auth.AuthTypeAPIToken, token, auth.AuthTypeAPIToken, adminToken)
+
+	var cfg listener.HTTPListenerConfig
+	if err := yaml.Unmarshal([]byte(input), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return cfg
+}
+
+func mustBodyRequest(t *testing.T, method, url, body string) *http.Response {
+	t.Helper()
+
+	resp, err := requestWithRetry(&http.Client{Timeout: 2 * time.Second}, method, url, body, testAdminHeaders, 2*time.Second)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	return resp
+}
+
+func decodeAdminResponse(t *testing.T, resp *http.Response, out any) {
+	t.Helper()
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusOK)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package listener_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"poke/internal/server/jobs"
+	"poke/internal/server/request"
+)
+
+func TestHTTPListenerCoalescesIdenticalRequests(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest, 4)
+	registry := jobs.NewRegistry()
+	registry.SetCoalescePolicy(func(commandID string) bool { return commandID == "reindex" })
+	startHTTPListenerWithJobs(t, cfg, reqCh, registry)
+	url := fmt.Sprintf("http://127.0.0.1:%d/", port)
+
+	first := putJSONRequestWithRetry(t, url, `{"command_id":"reindex"}`, testAuthHeaders)
+	if first.Header.Get("X-Poke-Coalesced") != "" {
+		t.Fatalf("first request must not be coalesced")
+	}
+	firstJob := decodeJobResponse(t, first, http.StatusAccepted)
+
+	second := putJSONRequestWithRetry(t, url, `{"command_id":"reindex"}`, testAuthHeaders)
+	if second.Header.Get("X-Poke-Coalesced") != "true" {
+		t.Fatalf("expected X-Poke-Coalesced header on merged request")
+	}
+	secondJob := decodeJobResponse(t, second, http.StatusAccepted)
+	if secondJob.ID != firstJob.ID || secondJob.Coalesced != 1 {
+		t.Fatalf("coalesced job: got %#v want id %q", secondJob, firstJob.ID)
+	}
+
+	other := decodeJobResponse(t, putJSONRequestWithRetry(t, url, `{"command_id":"uptime"}`, testAuthHeaders), http.StatusAccepted)
+	if other.ID == firstJob.ID {
+		t.Fatalf("requests for other commands must not be coalesced")
+	}
+	if len(reqCh) != 2 {
+		t.Fatalf("enqueued requests: got %d want 2", len(reqCh))
+	}
+}
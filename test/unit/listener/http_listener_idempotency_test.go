@@ -0,0 +1,79 @@
+package listener_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"poke/internal/server/jobs"
+	"poke/internal/server/listener"
+	"poke/internal/server/request"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestHTTPListenerReplaysIdempotentRequest(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest, 2)
+	startHTTPListenerWithJobs(t, cfg, reqCh, jobs.NewRegistry())
+
+	headers := map[string]string{"Idempotency-Key": "ci-42"}
+	for key, value := range testAuthHeaders {
+		headers[key] = value
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d/", port)
+
+	first := decodeJobResponse(t, putJSONRequestWithRetry(t, url, `{"command_id":"deploy"}`, headers), http.StatusAccepted)
+
+	resp := putJSONRequestWithRetry(t, url, `{"command_id":"deploy","idempotency_key":"ci-42"}`, testAuthHeaders)
+	if resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected replay header, got %v", resp.Header)
+	}
+	second := decodeJobResponse(t, resp, http.StatusOK)
+	if second.ID != first.ID {
+		t.Fatalf("replayed job: got %q want %q", second.ID, first.ID)
+	}
+
+	if len(reqCh) != 1 {
+		t.Fatalf("enqueued requests: got %d want 1", len(reqCh))
+	}
+}
+
+func TestHTTPListenerRejectsConflictingIdempotencyKeys(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest, 1)
+	startHTTPListenerWithJobs(t, cfg, reqCh, jobs.NewRegistry())
+
+	headers := map[string]string{"Idempotency-Key": "ci-42"}
+	for key, value := range testAuthHeaders {
+		headers[key] = value
+	}
+	resp := putJSONRequestWithRetry(t, fmt.Sprintf("http://127.0.0.1:%d/", port), `{"command_id":"deploy","idempotency_key":"ci-43"}`, headers)
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if len(reqCh) != 0 {
+		t.Fatalf("rejected request must not be enqueued")
+	}
+}
+
+func TestHTTPListenerConfigIdempotencyWindow(t *testing.T) {
+	var cfg listener.HTTPListenerConfig
+	if err := yaml.Unmarshal([]byte("auth:\n  api_token:\n    token: secret\n"), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if cfg.IdempotencyWindow != 24*time.Hour {
+		t.Fatalf("default window: got %v", cfg.IdempotencyWindow)
+	}
+
+	if err := yaml.Unmarshal([]byte("idempotency_window: -1s\nauth:\n  api_token:\n    token: secret\n"), &cfg); err == nil {
+		t.Fatalf("expected negative window error")
+	}
+}
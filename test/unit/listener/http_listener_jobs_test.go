@@ -0,0 +1,130 @@
+package listener_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"poke/internal/server/jobs"
+	"poke/internal/server/listener"
+	"poke/internal/server/request"
+)
+
+var testAuthHeaders = map[string]string{
+	"Content-Type":       "application/json",
+	"X-Poke-Auth-Method": "api_token",
+	"X-Poke-API-Token":   "secret-token",
+}
+
+func TestHTTPListenerRequestReturnsQueuedJob(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest, 1)
+	registry := jobs.NewRegistry()
+	startHTTPListenerWithJobs(t, cfg, reqCh, registry)
+
+	resp := putJSONRequestWithRetry(t, fmt.Sprintf("http://127.0.0.1:%d/", port), `{"command_id":"uptime"}`, testAuthHeaders)
+	job := decodeJobResponse(t, resp, http.StatusAccepted)
+	if job.ID == "" || job.State != jobs.StateQueued {
+		t.Fatalf("job: got %#v", job)
+	}
+
+	got := <-reqCh
+	if got.JobID != job.ID {
+		t.Fatalf("job id: got %q want %q", got.JobID, job.ID)
+	}
+}
+
+func TestHTTPListenerCancelsQueuedJob(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest, 1)
+	registry := jobs.NewRegistry()
+	startHTTPListenerWithJobs(t, cfg, reqCh, registry)
+	queued, _, err := registry.Enqueue(jobs.Submission{CommandID: "uptime"})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/jobs/%s", port, queued.ID)
+	resp := mustRequest(t, http.MethodDelete, url, testAuthHeaders)
+	job := decodeJobResponse(t, resp, http.StatusOK)
+	if job.State != jobs.StateCanceled || job.CanceledBy != "http/api_token" {
+		t.Fatalf("job: got %#v", job)
+	}
+
+	resp = mustRequest(t, http.MethodDelete, url, testAuthHeaders)
+	decodeJobResponse(t, resp, http.StatusConflict)
+
+	resp = mustRequest(t, http.MethodGet, url, testAuthHeaders)
+	if got := decodeJobResponse(t, resp, http.StatusOK); got.State != jobs.StateCanceled {
+		t.Fatalf("get job: got %#v", got)
+	}
+}
+
+func TestHTTPListenerCancelRequiresAuthAndKnownJob(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	registry := jobs.NewRegistry()
+	startHTTPListenerWithJobs(t, cfg, make(chan request.CommandRequest, 1), registry)
+	queued, _, err := registry.Enqueue(jobs.Submission{CommandID: "uptime"})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	resp := mustRequest(t, http.MethodDelete, fmt.Sprintf("http://127.0.0.1:%d/jobs/%s", port, queued.ID), nil)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status: got %d want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if job, _ := registry.Get(queued.ID); job.State != jobs.StateQueued {
+		t.Fatalf("unauthenticated cancel must not change job, got %#v", job)
+	}
+
+	resp = mustRequest(t, http.MethodDelete, fmt.Sprintf("http://127.0.0.1:%d/jobs/missing", port), testAuthHeaders)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown job status: got %d want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func startHTTPListenerWithJobs(t *testing.T, cfg listener.HTTPListenerConfig, reqCh chan<- request.CommandRequest, registry *jobs.Registry) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	if err := listener.NewHTTPListener(registry).Listen(ctx, cfg, reqCh); err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+}
+
+func mustRequest(t *testing.T, method, url string, headers map[string]string) *http.Response {
+	t.Helper()
+
+	resp, err := requestWithRetry(&http.Client{Timeout: 2 * time.Second}, method, url, "", headers, 2*time.Second)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	return resp
+}
+
+func decodeJobResponse(t *testing.T, resp *http.Response, wantStatus int) jobs.Job {
+	t.Helper()
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, wantStatus)
+	}
+	var job jobs.Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	return job
+}
@@ -0,0 +1,79 @@
+package listener_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"poke/internal/server/listener"
+	"poke/internal/server/metrics"
+	"poke/internal/server/request"
+)
+
+func TestHTTPListenerCountsRequestOutcomesAndAuthFailures(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest, 4)
+	startHTTPListener(t, cfg, reqCh)
+	url := fmt.Sprintf("http://127.0.0.1:%d/", port)
+
+	accepted := metrics.Requests.Value("http", metrics.OutcomeAccepted)
+	invalid := metrics.Requests.Value("http", "400")
+	unauthorized := metrics.Requests.Value("http", "401")
+	badToken := metrics.AuthFailures.Value("http", "api_token")
+	noMethod := metrics.AuthFailures.Value("http", "none")
+
+	putJSONRequestWithRetry(t, url, `{"command_id":"uptime"}`, testAuthHeaders).Body.Close()
+	putJSONRequestWithRetry(t, url, `{"command_id":`, testAuthHeaders).Body.Close()
+	putJSONRequestWithRetry(t, url, `{"command_id":"uptime"}`, map[string]string{
+		"Content-Type":       "application/json",
+		"X-Poke-Auth-Method": "api_token",
+		"X-Poke-API-Token":   "wrong-token",
+	}).Body.Close()
+	putJSONRequestWithRetry(t, url, `{"command_id":"uptime"}`, map[string]string{"Content-Type": "application/json"}).Body.Close()
+
+	checks := []struct {
+		name  string
+		got   float64
+		delta float64
+	}{
+		{"accepted", metrics.Requests.Value("http", metrics.OutcomeAccepted) - accepted, 1},
+		{"400", metrics.Requests.Value("http", "400") - invalid, 1},
+		{"401", metrics.Requests.Value("http", "401") - unauthorized, 2},
+		{"auth api_token", metrics.AuthFailures.Value("http", "api_token") - badToken, 1},
+		{"auth none", metrics.AuthFailures.Value("http", "none") - noMethod, 1},
+	}
+	for _, c := range checks {
+		if c.got != c.delta {
+			t.Fatalf("%s: got +%v want +%v", c.name, c.got, c.delta)
+		}
+	}
+}
+
+func TestHTTPListenerCountsUnavailableRequests(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest) // never read, enqueue blocks until ctx ends
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var l listener.HTTPListener
+	if err := l.Listen(ctx, cfg, reqCh); err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	unavailable := metrics.Requests.Value("http", "503")
+	time.AfterFunc(100*time.Millisecond, cancel)
+	resp := putJSONRequestWithRetry(t, fmt.Sprintf("http://127.0.0.1:%d/", port), `{"command_id":"uptime"}`, testAuthHeaders)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if delta := metrics.Requests.Value("http", "503") - unavailable; delta != 1 {
+		t.Fatalf("503 requests counter: got +%v want +1", delta)
+	}
+}
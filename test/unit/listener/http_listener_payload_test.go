@@ -0,0 +1,132 @@
+package listener_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"poke/internal/server/executor"
+	"poke/internal/server/jobs"
+	"poke/internal/server/listener"
+	"poke/internal/server/request"
+
+	"github.com/goccy/go-yaml"
+)
+
+// importPayloads accepts payloads of up to 4 bytes for "import" only.
+type importPayloads struct{}
+
+func (importPayloads) CheckPayload(commandID string, payload []byte) error {
+	switch {
+	case len(payload) == 0:
+		return nil
+	case commandID != "import":
+		return executor.ErrPayloadNotAccepted
+	case len(payload) > 4:
+		return executor.ErrPayloadTooLarge
+	}
+	return nil
+}
+
+func TestHTTPListenerRequestForwardsPayload(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest, 1)
+	startHTTPListener(t, cfg, reqCh)
+
+	resp := putJSONRequestWithRetry(
+		t,
+		fmt.Sprintf("http://127.0.0.1:%d/", port),
+		`{"command_id":"import","payload":"line one\nline two"}`,
+		map[string]string{
+			"Content-Type":       "application/json",
+			"X-Poke-Auth-Method": "api_token",
+			"X-Poke-API-Token":   "secret-token",
+		},
+	)
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusAccepted)
+	}
+
+	select {
+	case got := <-reqCh:
+		if string(got.Payload) != "line one\nline two" {
+			t.Fatalf("payload: got %q", got.Payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected command to be enqueued")
+	}
+}
+
+func TestHTTPListenerRequestRejectsOversizedBody(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest, 1)
+	startHTTPListener(t, cfg, reqCh)
+
+	body := `{"command_id":"import","payload":"` + strings.Repeat("x", 2<<20) + `"}`
+	resp := putJSONRequestWithRetry(
+		t,
+		fmt.Sprintf("http://127.0.0.1:%d/", port),
+		body,
+		map[string]string{
+			"Content-Type":       "application/json",
+			"X-Poke-Auth-Method": "api_token",
+			"X-Poke-API-Token":   "secret-token",
+		},
+	)
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+	if len(reqCh) != 0 {
+		t.Fatalf("oversized request must not be enqueued")
+	}
+}
+
+func TestHTTPListenerRejectsPayloadBeforeEnqueue(t *testing.T) {
+	port := reserveTCPPort(t)
+	var cfg listener.ListenerConfig
+	input := fmt.Sprintf("http: {host: 127.0.0.1, port: %d, auth: {api_token: {token: secret-token}}}", port)
+	if err := yaml.Unmarshal([]byte(input), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reqCh := make(chan request.CommandRequest, 1)
+	registry := jobs.NewRegistry()
+	if _, err := cfg.StartAll(ctx, reqCh, registry, nil, nil, importPayloads{}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/", port)
+	cases := map[string]int{
+		`{"command_id":"uptime","payload":"data"}`:  http.StatusBadRequest,
+		`{"command_id":"import","payload":"large"}`: http.StatusRequestEntityTooLarge,
+	}
+	for body, want := range cases {
+		resp := putJSONRequestWithRetry(t, url, body, testAuthHeaders)
+		_ = resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("%s: status got %d want %d", body, resp.StatusCode, want)
+		}
+	}
+	if len(reqCh) != 0 {
+		t.Fatalf("rejected payloads must not be enqueued")
+	}
+
+	resp := putJSONRequestWithRetry(t, url, `{"command_id":"import","payload":"data"}`, testAuthHeaders)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || len(reqCh) != 1 {
+		t.Fatalf("accepted payload: status %d, queued %d", resp.StatusCode, len(reqCh))
+	}
+}
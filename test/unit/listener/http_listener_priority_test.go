@@ -0,0 +1,128 @@
+package listener_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"poke/internal/server/jobs"
+	"poke/internal/server/listener"
+	"poke/internal/server/request"
+
+	"github.com/goccy/go-yaml"
+)
+
+type fixedQueueStats map[int]int
+
+func (s fixedQueueStats) QueueDepths() map[int]int { return s }
+
+func TestHTTPListenerForwardsAllowedPriority(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithPriority(t, port)
+
+	reqCh := make(chan request.CommandRequest, 1)
+	startHTTPListenerWithJobs(t, cfg, reqCh, jobs.NewRegistry())
+
+	resp := putJSONRequestWithRetry(t, fmt.Sprintf("http://127.0.0.1:%d/", port), `{"command_id":"uptime","priority":5}`, testAuthHeaders)
+	job := decodeJobResponse(t, resp, http.StatusAccepted)
+	if job.Priority == nil || *job.Priority != 5 {
+		t.Fatalf("job priority: got %v", job.Priority)
+	}
+	if got := <-reqCh; got.Priority == nil || *got.Priority != 5 {
+		t.Fatalf("request priority: got %v", got.Priority)
+	}
+}
+
+func TestHTTPListenerRejectsPriorityOutsideRange(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithPriority(t, port)
+
+	reqCh := make(chan request.CommandRequest, 1)
+	startHTTPListenerWithJobs(t, cfg, reqCh, jobs.NewRegistry())
+	url := fmt.Sprintf("http://127.0.0.1:%d/", port)
+
+	for _, body := range []string{`{"command_id":"uptime","priority":6}`, `{"command_id":"uptime","priority":42}`} {
+		resp := putJSONRequestWithRetry(t, url, body, testAuthHeaders)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("%s: status got %d want %d", body, resp.StatusCode, http.StatusForbidden)
+		}
+	}
+	if len(reqCh) != 0 {
+		t.Fatalf("expected no request to be enqueued")
+	}
+}
+
+func TestHTTPListenerRejectsPriorityWithoutConfiguredRange(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest, 1)
+	startHTTPListenerWithJobs(t, cfg, reqCh, jobs.NewRegistry())
+
+	resp := putJSONRequestWithRetry(t, fmt.Sprintf("http://127.0.0.1:%d/", port), `{"command_id":"uptime","priority":0}`, testAuthHeaders)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestHTTPListenerReportsQueueDepths(t *testing.T) {
+	port := reserveTCPPort(t)
+	var cfg listener.ListenerConfig
+	input := fmt.Sprintf("http: {host: 127.0.0.1, port: %d, auth: {api_token: {token: secret-token}}}", port)
+	if err := yaml.Unmarshal([]byte(input), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reqCh := make(chan request.CommandRequest, 4)
+	reqCh <- request.CommandRequest{CommandID: "uptime"}
+	if _, err := cfg.StartAll(ctx, reqCh, jobs.NewRegistry(), fixedQueueStats{9: 1, 0: 3}, nil, nil); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	resp := mustRequest(t, http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/queue", port), testAuthHeaders)
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusOK)
+	}
+	var body struct {
+		Depths  map[int]int `json:"depths"`
+		Backlog int         `json:"backlog"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Depths[9] != 1 || body.Depths[0] != 3 || body.Backlog != 1 {
+		t.Fatalf("queue: got %#v", body)
+	}
+
+	resp = mustRequest(t, http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/queue", port), nil)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status: got %d want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func mustHTTPListenerConfigWithPriority(t *testing.T, port int) listener.HTTPListenerConfig {
+	t.Helper()
+
+	input := fmt.Sprintf(`
+host: 127.0.0.1
+port: %d
+auth:
+  api_token:
+    token: secret-token
+    priority: {min: 0, max: 5}
+`, port)
+
+	var cfg listener.HTTPListenerConfig
+	if err := yaml.Unmarshal([]byte(input), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return cfg
+}
@@ -0,0 +1,69 @@
+package listener_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"poke/internal/server/jobs"
+	"poke/internal/server/request"
+)
+
+func TestHTTPListenerSchedulesDelayedRequest(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest, 2)
+	startHTTPListenerWithJobs(t, cfg, reqCh, jobs.NewRegistry())
+	url := fmt.Sprintf("http://127.0.0.1:%d/", port)
+
+	before := time.Now()
+	resp := putJSONRequestWithRetry(t, url, `{"command_id":"backup","delay":"10m"}`, testAuthHeaders)
+	job := decodeJobResponse(t, resp, http.StatusAccepted)
+	if job.State != jobs.StateScheduled || job.RunAt == nil || job.RunAt.Before(before.Add(10*time.Minute)) {
+		t.Fatalf("delayed job: got %#v", job)
+	}
+	if got := <-reqCh; !got.RunAt.Equal(*job.RunAt) {
+		t.Fatalf("request run_at: got %s want %s", got.RunAt, job.RunAt)
+	}
+
+	runAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	body := fmt.Sprintf(`{"command_id":"backup","run_at":%q}`, runAt.Format(time.RFC3339))
+	job = decodeJobResponse(t, putJSONRequestWithRetry(t, url, body, testAuthHeaders), http.StatusAccepted)
+	if job.State != jobs.StateScheduled || job.RunAt == nil || !job.RunAt.Equal(runAt) {
+		t.Fatalf("scheduled job: got %#v", job)
+	}
+}
+
+func TestHTTPListenerRejectsInvalidSchedule(t *testing.T) {
+	port := reserveTCPPort(t)
+	cfg := mustHTTPListenerConfigWithToken(t, port, "secret-token")
+
+	reqCh := make(chan request.CommandRequest, 1)
+	startHTTPListenerWithJobs(t, cfg, reqCh, jobs.NewRegistry())
+	url := fmt.Sprintf("http://127.0.0.1:%d/", port)
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	cases := map[string]string{
+		"both":             fmt.Sprintf(`{"command_id":"backup","delay":"1m","run_at":%q}`, past),
+		"negative delay":   `{"command_id":"backup","delay":"-1m"}`,
+		"invalid delay":    `{"command_id":"backup","delay":"soon"}`,
+		"past run_at":      fmt.Sprintf(`{"command_id":"backup","run_at":%q}`, past),
+		"beyond horizon":   `{"command_id":"backup","delay":"48h"}`,
+		"malformed run_at": `{"command_id":"backup","run_at":"tomorrow"}`,
+	}
+
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := putJSONRequestWithRetry(t, url, body, testAuthHeaders)
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusBadRequest)
+			}
+		})
+	}
+	if len(reqCh) != 0 {
+		t.Fatalf("expected no request to be enqueued")
+	}
+}
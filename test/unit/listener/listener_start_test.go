@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"poke/internal/server/jobs"
 	"poke/internal/server/listener"
 	"poke/internal/server/request"
 	"testing"
@@ -43,7 +44,7 @@ http:
 	defer cancel()
 
 	requests := make(chan request.CommandRequest, 1)
-	if _, err := cfg.StartAll(ctx, requests); err == nil {
+	if _, err := cfg.StartAll(ctx, requests, jobs.NewRegistry(), nil, nil, nil); err == nil {
 		t.Fatalf("expected listener start error while port is occupied")
 	}
 }
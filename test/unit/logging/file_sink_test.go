@@ -0,0 +1,279 @@
+package logging_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-yaml"
+
+	"poke/internal/server/logging"
+)
+
+func TestFileSinkConfigValidation(t *testing.T) {
+	var cfg logging.Config
+	err := yaml.Unmarshal([]byte(`
+sink:
+  type: file
+  file:
+    path: /var/log/poke/poke.log
+    max_size: 10M
+    daily: true
+    max_files: 7
+    compress: true
+`), &cfg)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	file := cfg.Sink[0].File
+	if file == nil || file.Path != "/var/log/poke/poke.log" || file.MaxSize != 10<<20 || !file.Daily || file.MaxFiles != 7 || !file.Compress {
+		t.Fatalf("file sink: got %#v", file)
+	}
+
+	cases := map[string]string{
+		"sink: {type: file}":                                                   "file config is required",
+		"sink: {type: file, file: {path: ''}}":                                 "file path is required",
+		"sink: {type: file, file: {path: poke.log}}":                           "must be absolute",
+		"sink: {type: file, file: {path: /p.log, daily: true, max_files: -1}}": "must not be negative",
+		"sink: {type: file, file: {path: /p.log, compress: true}}":             "require max_size or daily",
+		"sink: {type: file, file: {path: /p.log, max_size: 1X}}":               "invalid size",
+		"sink: {type: kafka}":                                                  "stdout, journald, file or syslog",
+	}
+	for input, want := range cases {
+		err := yaml.Unmarshal([]byte(input), &cfg)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%q: got %v want %q", input, err, want)
+		}
+	}
+}
+
+func TestFileSinkRotatesBySizeAndKeepsMaxFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "poke.log")
+	logger, err := logging.New(logging.Config{
+		Format: "json",
+		Sink: logging.SinkList{{Type: "file", File: &logging.FileSinkConfig{
+			Path:     path,
+			MaxSize:  512,
+			MaxFiles: 2,
+			Compress: true,
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	t.Cleanup(func() { _ = logging.Close() })
+
+	for i := range 40 {
+		logger.Info("rotation test", "event", "file_sink", "i", i, "padding", strings.Repeat("x", 64))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Size() > 512 {
+		t.Fatalf("active file size %d exceeds max_size", info.Size())
+	}
+
+	rotated := waitForRotated(t, path, func(names []string) bool {
+		if len(names) != 2 {
+			return false
+		}
+		for _, name := range names {
+			if !strings.HasSuffix(name, ".gz") {
+				return false
+			}
+		}
+		return true
+	})
+	if got := readGzip(t, rotated[len(rotated)-1]); !strings.Contains(got, `"event":"file_sink"`) {
+		t.Fatalf("rotated file content: %q", got)
+	}
+}
+
+func TestFileSinkRotatesDaily(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "poke.log")
+	if err := os.WriteFile(path, []byte("yesterday\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	yesterday := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(path, yesterday, yesterday); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	logger, err := logging.New(logging.Config{
+		Sink: logging.SinkList{{Type: "file", File: &logging.FileSinkConfig{Path: path, Daily: true}}},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	t.Cleanup(func() { _ = logging.Close() })
+	logger.Info("today", "event", "file_sink_daily")
+
+	rotated := waitForRotated(t, path, func(names []string) bool { return len(names) == 1 })
+	if got := readFile(t, rotated[0]); got != "yesterday\n" {
+		t.Fatalf("rotated file: got %q", got)
+	}
+	if got := readFile(t, path); strings.Contains(got, "yesterday") || !strings.Contains(got, "event=file_sink_daily") {
+		t.Fatalf("active file: got %q", got)
+	}
+}
+
+func TestFileSinkReopensMovedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "poke.log")
+	logger, err := logging.New(logging.Config{
+		Sink: logging.SinkList{{Type: "file", File: &logging.FileSinkConfig{Path: path}}},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	t.Cleanup(func() { _ = logging.Close() })
+
+	logger.Info("before", "event", "before_reopen")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := logging.Reopen(); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	logger.Info("after", "event", "after_reopen")
+
+	if got := readFile(t, path+".1"); !strings.Contains(got, "before_reopen") || strings.Contains(got, "after_reopen") {
+		t.Fatalf("moved file: got %q", got)
+	}
+	if got := readFile(t, path); !strings.Contains(got, "after_reopen") {
+		t.Fatalf("reopened file: got %q", got)
+	}
+}
+
+func TestFileSinkRetriesOpenAfterFailedReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "poke.log")
+	logger, err := logging.New(logging.Config{
+		Sink: logging.SinkList{{Type: "file", File: &logging.FileSinkConfig{Path: path}}},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	t.Cleanup(func() { _ = logging.Close() })
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := os.Mkdir(path, 0o750); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := logging.Reopen(); err == nil {
+		t.Fatal("reopen onto a directory: got nil error")
+	}
+	logger.Info("lost", "event", "while_failing")
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove directory: %v", err)
+	}
+	logger.Info("kept", "event", "after_recovery")
+	if got := readFile(t, path); strings.Contains(got, "while_failing") || !strings.Contains(got, "after_recovery") {
+		t.Fatalf("log file after recovery: got %q", got)
+	}
+}
+
+func TestFileSinkPrunesRotatedFilesByTimeAndCounter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "poke.log")
+	stamp := time.Now().Add(-time.Hour).Format("20060102-150405.000")
+	older := time.Now().Add(-2 * time.Hour).Format("20060102-150405.000")
+	for _, name := range []string{older + ".gz", stamp, stamp + "-2", stamp + "-10"} {
+		if err := os.WriteFile(path+"."+name, []byte(name+"\n"), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 60)+"\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	logger, err := logging.New(logging.Config{
+		Sink: logging.SinkList{{Type: "file", File: &logging.FileSinkConfig{Path: path, MaxSize: 64, MaxFiles: 2}}},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	t.Cleanup(func() { _ = logging.Close() })
+	logger.Info("rotate", "event", "file_sink_prune")
+
+	rotated := waitForRotated(t, path, func(names []string) bool { return len(names) == 2 })
+	if !slices.Contains(rotated, path+"."+stamp+"-10") || slices.Contains(rotated, path+"."+stamp+"-2") {
+		t.Fatalf("kept rotated files: got %v want the new one and %s-10", rotated, stamp)
+	}
+}
+
+func TestFileSinkFailsWhenPathIsUnwritable(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "not-a-dir")
+	if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	_, err := logging.New(logging.Config{
+		Sink: logging.SinkList{{Type: "file", File: &logging.FileSinkConfig{Path: filepath.Join(blocker, "poke.log")}}},
+	})
+	if err == nil {
+		t.Fatalf("expected error for a log path below a file")
+	}
+}
+
+// waitForRotated waits until the rotated files of path satisfy done, which
+// happens in the background once compression and pruning finish.
+func waitForRotated(t *testing.T, path string, done func([]string) bool) []string {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	var names []string
+	for time.Now().Before(deadline) {
+		matches, err := filepath.Glob(path + ".2*")
+		if err != nil {
+			t.Fatalf("glob: %v", err)
+		}
+		sort.Strings(matches)
+		names = matches
+		if done(names) {
+			return names
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("rotated files of %s: got %v", path, names)
+	return nil
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close() //nolint:errcheck // Read-only handle.
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip %s: %v", path, err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("gunzip %s: %v", path, err)
+	}
+	return string(data)
+}
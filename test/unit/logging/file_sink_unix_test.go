@@ -0,0 +1,49 @@
+//go:build unix
+
+package logging_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"poke/internal/server/logging"
+)
+
+func TestFileSinkReopensOnSIGUSR1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "poke.log")
+	logger, err := logging.New(logging.Config{
+		Sink: logging.SinkList{{Type: "file", File: &logging.FileSinkConfig{Path: path}}},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	t.Cleanup(func() { _ = logging.Close() })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logging.ReopenOnSignal(ctx)
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			logger.Info("after signal", "event", "after_sigusr1")
+			if got := readFile(t, path); !strings.Contains(got, "after_sigusr1") {
+				t.Fatalf("reopened file: got %q", got)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("log file was not reopened after SIGUSR1")
+}
@@ -0,0 +1,138 @@
+package logging_test
+
+import (
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-yaml"
+
+	"poke/internal/server/logging"
+)
+
+func TestSetLevelOverridesComponentOnly(t *testing.T) {
+	logger, path := newLevelTestLogger(t)
+	dispatcher := logger.With("component", "dispatcher")
+	listener := logger.With("component", "listener/http")
+
+	override, err := logging.SetLevel("dispatcher", "DEBUG", time.Minute)
+	if err != nil {
+		t.Fatalf("set level: %v", err)
+	}
+	if override.Component != "dispatcher" || override.Level != "debug" || time.Until(override.Until) <= 0 {
+		t.Fatalf("override: got %#v", override)
+	}
+	dispatcher.Debug("dispatcher detail", "event", "dispatcher_debug")
+	dispatcher.WithGroup("req").Debug("grouped detail", "event", "grouped_debug")
+	listener.Debug("listener detail", "event", "listener_debug")
+
+	logging.ResetLevel("dispatcher")
+	dispatcher.Debug("after reset", "event", "dispatcher_reset_debug")
+
+	logs := readFile(t, path)
+	for _, want := range []string{"event=dispatcher_debug", "event=grouped_debug"} {
+		if !strings.Contains(logs, want) {
+			t.Fatalf("expected %s in logs, got %q", want, logs)
+		}
+	}
+	for _, unwanted := range []string{"event=listener_debug", "event=dispatcher_reset_debug"} {
+		if strings.Contains(logs, unwanted) {
+			t.Fatalf("unexpected %s in logs, got %q", unwanted, logs)
+		}
+	}
+}
+
+func TestSetLevelRevertsAfterDuration(t *testing.T) {
+	logger, path := newLevelTestLogger(t)
+
+	if _, err := logging.SetLevel("", "debug", 50*time.Millisecond); err != nil {
+		t.Fatalf("set level: %v", err)
+	}
+	if status := logging.Levels(); len(status.Overrides) != 1 || status.Overrides[0].Component != "" {
+		t.Fatalf("status: got %#v", status)
+	}
+	logger.Debug("while overridden", "event", "overridden_debug")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(logging.Levels().Overrides) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("override was not reverted: %#v", logging.Levels())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	logger.Debug("after revert", "event", "reverted_debug")
+
+	logs := readFile(t, path)
+	if !strings.Contains(logs, "event=overridden_debug") || strings.Contains(logs, "event=reverted_debug") {
+		t.Fatalf("unexpected logs %q", logs)
+	}
+	if status := logging.Levels(); strings.Join(status.Sinks, ",") != "info" || status.RevertAfter != "15m0s" {
+		t.Fatalf("status: got %#v", status)
+	}
+}
+
+func TestToggleDebugSwitchesBack(t *testing.T) {
+	logger, path := newLevelTestLogger(t)
+
+	override, on := logging.ToggleDebug()
+	if !on || override.Level != "debug" {
+		t.Fatalf("toggle on: got %#v %v", override, on)
+	}
+	logger.Debug("toggled on", "event", "toggled_debug")
+	if _, on := logging.ToggleDebug(); on {
+		t.Fatal("second toggle must revert")
+	}
+	logger.Debug("toggled off", "event", "untoggled_debug")
+
+	logs := readFile(t, path)
+	if !strings.Contains(logs, "event=toggled_debug") || strings.Contains(logs, "event=untoggled_debug") {
+		t.Fatalf("unexpected logs %q", logs)
+	}
+}
+
+func TestSetLevelRejectsUnknownLevel(t *testing.T) {
+	if _, err := logging.SetLevel("", "trace", time.Minute); err == nil || !strings.Contains(err.Error(), "unsupported logging level") {
+		t.Fatalf("got %v", err)
+	}
+
+	var cfg logging.Config
+	if err := yaml.Unmarshal([]byte("level_revert_after: -1m"), &cfg); err == nil || !strings.Contains(err.Error(), "level_revert_after") {
+		t.Fatalf("config: got %v", err)
+	}
+}
+
+func TestSetLevelRejectsDurationAboveMax(t *testing.T) {
+	newLevelTestLogger(t)
+
+	if _, err := logging.SetLevel("", "debug", 25*time.Hour); err == nil || !strings.Contains(err.Error(), "exceeds level_override_max 24h0m0s") {
+		t.Fatalf("got %v", err)
+	}
+	if status := logging.Levels(); len(status.Overrides) != 0 || status.MaxDuration != "24h0m0s" {
+		t.Fatalf("status: got %#v", status)
+	}
+
+	var cfg logging.Config
+	if err := yaml.Unmarshal([]byte("{level_revert_after: 2h, level_override_max: 1h}"), &cfg); err == nil || !strings.Contains(err.Error(), "must not exceed level_override_max") {
+		t.Fatalf("config: got %v", err)
+	}
+}
+
+func newLevelTestLogger(t *testing.T) (*slog.Logger, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "poke.log")
+	logger, err := logging.New(logging.Config{
+		Level: "info",
+		Sink:  logging.SinkList{{Type: "file", File: &logging.FileSinkConfig{Path: path}}},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	t.Cleanup(func() {
+		logging.ResetLevel("")
+		_ = logging.Close()
+	})
+	return logger, path
+}
@@ -19,7 +19,7 @@ func TestNewWritesToStdoutSink(t *testing.T) {
 			StaticFields: map[string]string{
 				"service": "poke",
 			},
-			Sink: logging.SinkConfig{Type: "stdout"},
+			Sink: logging.SinkList{{Type: "stdout"}},
 		})
 		if err != nil {
 			return err
@@ -49,13 +49,13 @@ func TestNewFallsBackWhenJournaldSocketUnavailable(t *testing.T) {
 		logger, err := logging.New(logging.Config{
 			Level:  "info",
 			Format: "text",
-			Sink: logging.SinkConfig{
+			Sink: logging.SinkList{{
 				Type: "journald",
 				Journald: &logging.JournaldSinkConfig{
 					Identifier: "poke-test",
 					Fallback:   "stdout",
 				},
-			},
+			}},
 		})
 		if err != nil {
 			return err
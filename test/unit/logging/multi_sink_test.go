@@ -0,0 +1,138 @@
+package logging_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+
+	"poke/internal/server/logging"
+)
+
+func TestSinkListConfigAcceptsMappingOrList(t *testing.T) {
+	var single logging.Config
+	if err := yaml.Unmarshal([]byte("sink: {type: stdout, level: warn}"), &single); err != nil {
+		t.Fatalf("unmarshal single: %v", err)
+	}
+	if len(single.Sink) != 1 || single.Sink[0].Type != "stdout" || single.Sink[0].Level != "warn" {
+		t.Fatalf("single sink: got %#v", single.Sink)
+	}
+
+	var list logging.Config
+	err := yaml.Unmarshal([]byte(`
+level: info
+sink:
+  - type: file
+    level: debug
+    format: json
+    file:
+      path: /var/log/poke/debug.log
+  - type: journald
+    level: WARN
+    events: ["job_*", audit_write_failed]
+    journald:
+      identifier: poke
+`), &list)
+	if err != nil {
+		t.Fatalf("unmarshal list: %v", err)
+	}
+	if len(list.Sink) != 2 {
+		t.Fatalf("sinks: got %#v", list.Sink)
+	}
+	if got := list.Sink[0]; got.Type != "file" || got.Level != "debug" || got.Format != "json" {
+		t.Fatalf("first sink: got %#v", got)
+	}
+	if got := list.Sink[1]; got.Type != "journald" || got.Level != "warn" || got.Format != "" || strings.Join(got.Events, ",") != "job_*,audit_write_failed" {
+		t.Fatalf("second sink: got %#v", got)
+	}
+}
+
+func TestSinkListConfigValidation(t *testing.T) {
+	cases := map[string]string{
+		"sink: []": "must not be empty",
+		"sink: [{type: stdout}, {type: file, file: {path: /p.log}}, {type: file, file: {path: /tmp/../p.log}}]": "logging sink 3: file path \"/tmp/../p.log\" is used by another sink",
+		"sink: {type: stdout, level: trace}":                    "sink level must be one of",
+		"sink: [{type: stdout}, {type: stdout, format: xml}]":   "sink format must be one of",
+		"sink: {type: stdout, events: ['job_[']}":               "event pattern \"job_[\" is invalid",
+		"sink: {type: stdout, events: ['']}":                    "must not contain empty names",
+		"sink: [{type: stdout}, {type: file, file: {path: x}}]": "logging sink file path \"x\" must be absolute",
+	}
+	for input, want := range cases {
+		var cfg logging.Config
+		err := yaml.Unmarshal([]byte(input), &cfg)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%q: got %v want %q", input, err, want)
+		}
+	}
+
+	_, err := logging.New(logging.Config{Sink: logging.SinkList{{Type: "stdout"}, {Type: "stdout", Level: "trace"}}})
+	if err == nil || !strings.Contains(err.Error(), "logging sink 2: logging sink level") {
+		t.Fatalf("new: got %v", err)
+	}
+}
+
+func TestMultipleSinksApplyOwnLevelFormatAndEvents(t *testing.T) {
+	dir := t.TempDir()
+	debugPath := filepath.Join(dir, "debug.log")
+	warnPath := filepath.Join(dir, "warn.log")
+	jobsPath := filepath.Join(dir, "jobs.log")
+
+	logger, err := logging.New(logging.Config{
+		Level:        "info",
+		StaticFields: map[string]string{"service": "poke"},
+		Sink: logging.SinkList{
+			{Type: "file", Level: "debug", Format: "json", File: &logging.FileSinkConfig{Path: debugPath}},
+			{Type: "file", Level: "warn", File: &logging.FileSinkConfig{Path: warnPath}},
+			{Type: "file", Events: []string{"job_*"}, File: &logging.FileSinkConfig{Path: jobsPath}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	t.Cleanup(func() { _ = logging.Close() })
+
+	component := logger.With("component", "dispatcher")
+	component.Debug("dequeued", "event", "job_dequeued")
+	component.Info("started", "event", "job_started")
+	component.Info("listening", "event", "listener_started")
+	component.Warn("slow", "event", "job_slow")
+	component.With("event", "job_failed").Error("failed")
+	component.WithGroup("detail").Info("nested", "event", "job_nested")
+	component.Error("no event")
+
+	debugLines := strings.Split(strings.TrimSpace(readFile(t, debugPath)), "\n")
+	if len(debugLines) != 7 {
+		t.Fatalf("debug sink: got %d lines %q", len(debugLines), debugLines)
+	}
+	var first map[string]any
+	if err := json.Unmarshal([]byte(debugLines[0]), &first); err != nil {
+		t.Fatalf("debug sink must be json: %v", err)
+	}
+	if first["event"] != "job_dequeued" || first["service"] != "poke" || first["component"] != "dispatcher" {
+		t.Fatalf("debug record: got %#v", first)
+	}
+
+	warn := readFile(t, warnPath)
+	for _, want := range []string{"level=WARN msg=slow", "level=ERROR msg=failed", "level=ERROR msg=\"no event\""} {
+		if !strings.Contains(warn, want) {
+			t.Fatalf("warn sink: missing %q in %q", want, warn)
+		}
+	}
+	if strings.Count(warn, "\n") != 3 {
+		t.Fatalf("warn sink: got %q", warn)
+	}
+
+	jobs := readFile(t, jobsPath)
+	for _, want := range []string{"event=job_started", "event=job_slow", "event=job_failed"} {
+		if !strings.Contains(jobs, want) {
+			t.Fatalf("events sink: missing %q in %q", want, jobs)
+		}
+	}
+	for _, unwanted := range []string{"job_dequeued", "listener_started", "job_nested", "no event"} {
+		if strings.Contains(jobs, unwanted) {
+			t.Fatalf("events sink: unexpected %q in %q", unwanted, jobs)
+		}
+	}
+}
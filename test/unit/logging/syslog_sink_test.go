@@ -0,0 +1,288 @@
+package logging_test
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-yaml"
+
+	"poke/internal/server/logging"
+)
+
+var syslogHeaderPattern = regexp.MustCompile(`^<(\d+)>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}\S+ testhost poke-test \d+ (\S+) `)
+
+func TestSyslogSinkConfigDefaultsAndValidation(t *testing.T) {
+	var cfg logging.Config
+	if err := yaml.Unmarshal([]byte("sink: {type: syslog, syslog: {}}"), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	got := cfg.Sink[0].Syslog
+	if got.Network != "unix" || got.Address != "/dev/log" || got.Facility != "daemon" || got.AppName != "poke" || got.Fallback != "stdout" {
+		t.Fatalf("defaults: got %#v", got)
+	}
+
+	cases := map[string]string{
+		"sink: {type: syslog}":                                                                          "syslog config is required",
+		"sink: {type: syslog, syslog: {network: sctp}}":                                                 "network must be one of",
+		"sink: {type: syslog, syslog: {network: udp}}":                                                  "must be host:port",
+		"sink: {type: syslog, syslog: {network: tcp, address: relay}}":                                  "must be host:port",
+		"sink: {type: syslog, syslog: {facility: local9}}":                                              "facility \"local9\" is unknown",
+		"sink: {type: syslog, syslog: {app_name: 'poke server'}}":                                       "app_name must be",
+		"sink: {type: syslog, syslog: {fallback: stderr}}":                                              "fallback must be stdout",
+		"sink: {type: syslog, syslog: {tls: {ca_file: /ca.pem}}}":                                       "only valid with network tls",
+		"sink: {type: syslog, syslog: {network: tls, address: 'relay:6514', tls: {cert_file: /c.pem}}}": "must be set together",
+	}
+	for input, want := range cases {
+		err := yaml.Unmarshal([]byte(input), &cfg)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%q: got %v want %q", input, err, want)
+		}
+	}
+}
+
+func TestSyslogSinkSendsRFC5424OverUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close() //nolint:errcheck // Test cleanup.
+
+	logger := newSyslogLogger(t, logging.SyslogSinkConfig{Network: "udp", Address: conn.LocalAddr().String(), Facility: "local0"})
+	logger.With("component", "listener/http").WithGroup("req").Warn("request rejected", "event", "auth_failed", "note", `a "quoted"] \value`)
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	msg := string(buf[:n])
+
+	match := syslogHeaderPattern.FindStringSubmatch(msg)
+	if match == nil {
+		t.Fatalf("header: got %q", msg)
+	}
+	if match[1] != strconv.Itoa(16*8+4) {
+		t.Fatalf("PRI: got %s want local0.warning", match[1])
+	}
+	if match[2] != "-" {
+		t.Fatalf("MSGID: grouped event must not become MSGID, got %q", match[2])
+	}
+	wantSD := `[poke@32473 component="listener/http" req.event="auth_failed" req.note="a \"quoted\"\] \\value"] request rejected`
+	if !strings.HasSuffix(msg, wantSD) {
+		t.Fatalf("structured data: got %q want suffix %q", msg, wantSD)
+	}
+}
+
+func TestSyslogSinkFramesMessagesOverTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close() //nolint:errcheck // Test cleanup.
+
+	logger := newSyslogLogger(t, logging.SyslogSinkConfig{Network: "tcp", Address: ln.Addr().String()})
+	messages := acceptFramedMessages(t, ln, 2)
+	logger.Info("first", "event", "syslog_tcp")
+	logger.Info("second\nline", "event", "syslog_tcp")
+
+	got := collect(t, messages, 2)
+	if m := syslogHeaderPattern.FindStringSubmatch(got[0]); m == nil || m[1] != "30" || m[2] != "syslog_tcp" {
+		t.Fatalf("first message: got %q", got[0])
+	}
+	if !strings.HasSuffix(got[1], `[poke@32473 event="syslog_tcp"] second`+"\nline") {
+		t.Fatalf("second message: got %q", got[1])
+	}
+}
+
+func TestSyslogSinkSendsOverTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeSyslogTLSFiles(t, dir)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("load key pair: %v", err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close() //nolint:errcheck // Test cleanup.
+
+	messages := acceptFramedMessages(t, ln, 1)
+	logger := newSyslogLogger(t, logging.SyslogSinkConfig{
+		Network: "tls",
+		Address: ln.Addr().String(),
+		TLS:     &logging.SyslogTLSConfig{CAFile: certFile},
+	})
+	logger.Error("over tls", "event", "syslog_tls")
+
+	got := collect(t, messages, 1)
+	if m := syslogHeaderPattern.FindStringSubmatch(got[0]); m == nil || m[1] != "27" || !strings.HasSuffix(got[0], "over tls") {
+		t.Fatalf("message: got %q", got[0])
+	}
+
+	_, err = logging.New(logging.Config{Sink: logging.SinkList{{Type: "syslog", Syslog: &logging.SyslogSinkConfig{
+		Network: "tls",
+		Address: ln.Addr().String(),
+		TLS:     &logging.SyslogTLSConfig{CAFile: filepath.Join(dir, "missing.pem")},
+	}}}})
+	if err == nil || !strings.Contains(err.Error(), "ca_file") {
+		t.Fatalf("missing ca_file: got %v", err)
+	}
+}
+
+func TestSyslogSinkWritesToLocalDatagramSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close() //nolint:errcheck // Test cleanup.
+
+	logger := newSyslogLogger(t, logging.SyslogSinkConfig{Address: path})
+	logger.Info("local", "event", "syslog_local")
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if msg := string(buf[:n]); !syslogHeaderPattern.MatchString(msg) || !strings.HasSuffix(msg, "local") {
+		t.Fatalf("message: got %q", msg)
+	}
+}
+
+func TestSyslogSinkFallsBackToStdoutWhenRelayUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	address := ln.Addr().String()
+	_ = ln.Close()
+
+	logs := captureStdout(t, func() error {
+		logger, err := logging.New(logging.Config{
+			Format: "text",
+			Sink:   logging.SinkList{{Type: "syslog", Syslog: &logging.SyslogSinkConfig{Network: "tcp", Address: address}}},
+		})
+		if err != nil {
+			return err
+		}
+		logger.Info("fallback", "event", "syslog_fallback")
+		return nil
+	})
+
+	if !strings.Contains(logs, "event=syslog_fallback") {
+		t.Fatalf("expected fallback log on stdout, got %q", logs)
+	}
+}
+
+func newSyslogLogger(t *testing.T, cfg logging.SyslogSinkConfig) *slog.Logger {
+	t.Helper()
+
+	cfg.Hostname = "testhost"
+	cfg.AppName = "poke-test"
+	logger, err := logging.New(logging.Config{Sink: logging.SinkList{{Type: "syslog", Syslog: &cfg}}})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	return logger
+}
+
+// acceptFramedMessages reads n octet-counted messages from the first connection on ln.
+func acceptFramedMessages(t *testing.T, ln net.Listener, n int) <-chan string {
+	t.Helper()
+
+	out := make(chan string, n)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() //nolint:errcheck // Test cleanup.
+
+		reader := bufio.NewReader(conn)
+		for range n {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			size, err := strconv.Atoi(strings.TrimSpace(length))
+			if err != nil {
+				return
+			}
+			msg := make([]byte, size)
+			if _, err := io.ReadFull(reader, msg); err != nil {
+				return
+			}
+			out <- string(msg)
+		}
+	}()
+	return out
+}
+
+func collect(t *testing.T, messages <-chan string, n int) []string {
+	t.Helper()
+
+	var got []string
+	for range n {
+		select {
+		case msg := <-messages:
+			got = append(got, msg)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d of %d messages: %q", len(got), n, got)
+		}
+	}
+	return got
+}
+
+func writeSyslogTLSFiles(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(5 * time.Minute),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "relay.crt")
+	keyFile := filepath.Join(dir, "relay.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certFile, keyFile
+}
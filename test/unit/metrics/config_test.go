@@ -0,0 +1,109 @@
+package metrics_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+
+	"poke/internal/server/metrics"
+)
+
+func TestConfigDefaults(t *testing.T) {
+	var cfg metrics.Config
+	if err := yaml.Unmarshal([]byte(`{}`), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !cfg.Enabled || cfg.Address() != "127.0.0.1:9464" {
+		t.Fatalf("defaults: got %#v", cfg)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "custom address", yaml: "host: 0.0.0.0\nport: 9100"},
+		{name: "disabled ignores port", yaml: "enabled: false\nport: 0"},
+		{name: "port out of range", yaml: "port: 70000", wantErr: "metrics port"},
+		{name: "empty host", yaml: "host: ' '", wantErr: "metrics host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg metrics.Config
+			err := yaml.Unmarshal([]byte(tt.yaml), &cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error: got %v want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListenServesMetricsPath(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reg := metrics.NewRegistry()
+	reg.NewCounterVec("test_total", "Test.").Inc()
+	addr, err := metrics.Listen(ctx, metrics.Config{Enabled: true, Host: "127.0.0.1", Port: reservePort(t)}, reg)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/metrics", addr))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "test_total 1\n") {
+		t.Fatalf("body: got %q", body)
+	}
+
+	other, err := http.Get(fmt.Sprintf("http://%s/", addr))
+	if err != nil {
+		t.Fatalf("get /: %v", err)
+	}
+	other.Body.Close()
+	if other.StatusCode != http.StatusNotFound {
+		t.Fatalf("other path status: got %d want 404", other.StatusCode)
+	}
+}
+
+func TestListenReportsBindFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := metrics.Config{Enabled: true, Host: "127.0.0.1", Port: reservePort(t)}
+	if _, err := metrics.Listen(ctx, cfg, metrics.NewRegistry()); err != nil {
+		t.Fatalf("first listen: %v", err)
+	}
+	if _, err := metrics.Listen(ctx, cfg, metrics.NewRegistry()); err == nil {
+		t.Fatalf("expected bind error for a port in use")
+	}
+}
+
+func reservePort(t *testing.T) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
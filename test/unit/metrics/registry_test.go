@@ -0,0 +1,142 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"poke/internal/server/metrics"
+)
+
+func writeText(t *testing.T, reg *metrics.Registry) string {
+	t.Helper()
+
+	var b strings.Builder
+	if err := reg.WriteText(&b); err != nil {
+		t.Fatalf("write text: %v", err)
+	}
+	return b.String()
+}
+
+func TestRegistryWritesCountersAndGauges(t *testing.T) {
+	reg := metrics.NewRegistry()
+	requests := reg.NewCounterVec("test_requests_total", "Requests.", "listener", "outcome")
+	depth := reg.NewGaugeFunc("test_depth", "Depth.")
+	levels := reg.NewGaugeVec("test_levels", "Levels.", "priority")
+
+	requests.Inc("http", "accepted")
+	requests.Inc("http", "accepted")
+	requests.Inc("http", "401")
+	depth.SetFunc(func() float64 { return 3 })
+	levels.Set(2, "5")
+
+	want := `# HELP test_depth Depth.
+# TYPE test_depth gauge
+test_depth 3
+# HELP test_levels Levels.
+# TYPE test_levels gauge
+test_levels{priority="5"} 2
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{listener="http",outcome="401"} 1
+test_requests_total{listener="http",outcome="accepted"} 2
+`
+	if got := writeText(t, reg); got != want {
+		t.Fatalf("text output:\n%s\nwant:\n%s", got, want)
+	}
+	if got := requests.Value("http", "accepted"); got != 2 {
+		t.Fatalf("counter value: got %v want 2", got)
+	}
+}
+
+func TestRegistryWritesHistogramBuckets(t *testing.T) {
+	reg := metrics.NewRegistry()
+	wait := reg.NewHistogramVec("test_wait_seconds", "Wait.", []float64{1, 0.1}, "command_id")
+
+	wait.Observe(0.05, "a")
+	wait.Observe(0.1, "a")
+	wait.Observe(0.5, "a")
+	wait.Observe(2, "a")
+
+	want := `# HELP test_wait_seconds Wait.
+# TYPE test_wait_seconds histogram
+test_wait_seconds_bucket{command_id="a",le="0.1"} 2
+test_wait_seconds_bucket{command_id="a",le="1"} 3
+test_wait_seconds_bucket{command_id="a",le="+Inf"} 4
+test_wait_seconds_sum{command_id="a"} 2.65
+test_wait_seconds_count{command_id="a"} 4
+`
+	if got := writeText(t, reg); got != want {
+		t.Fatalf("text output:\n%s\nwant:\n%s", got, want)
+	}
+	if got := wait.Count("a"); got != 4 {
+		t.Fatalf("histogram count: got %d want 4", got)
+	}
+}
+
+func TestRegistryEscapesLabelValuesAndHelp(t *testing.T) {
+	reg := metrics.NewRegistry()
+	c := reg.NewCounterVec("test_total", "Line one\nline two \\ end.", "command_id")
+	c.Inc("say \"hi\"\\\n")
+
+	want := `# HELP test_total Line one\nline two \\ end.
+# TYPE test_total counter
+test_total{command_id="say \"hi\"\\\n"} 1
+`
+	if got := writeText(t, reg); got != want {
+		t.Fatalf("text output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRegistryRejectsDuplicateNamesAndLabelMismatch(t *testing.T) {
+	reg := metrics.NewRegistry()
+	c := reg.NewCounterVec("test_total", "Test.", "a", "b")
+
+	assertPanics(t, "duplicate name", func() { reg.NewGaugeVec("test_total", "Test.") })
+	assertPanics(t, "label mismatch", func() { c.Inc("only-one") })
+}
+
+func assertPanics(t *testing.T, name string, fn func()) {
+	t.Helper()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("%s: expected panic", name)
+		}
+	}()
+	fn()
+}
+
+func TestRegistryHandlerServesTextFormat(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.NewCounterVec("test_total", "Test.").Inc()
+	srv := httptest.NewServer(reg.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status: got %d want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("content type: got %q", ct)
+	}
+	if !strings.Contains(string(body), "test_total 1\n") {
+		t.Fatalf("body: got %q", body)
+	}
+
+	post, err := http.Post(srv.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	post.Body.Close()
+	if post.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("post status: got %d want 405", post.StatusCode)
+	}
+}
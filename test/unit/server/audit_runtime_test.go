@@ -0,0 +1,166 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"poke/internal/server"
+	"poke/internal/server/audit"
+	"poke/internal/server/jobs"
+)
+
+func TestStartAuditsRequestsAndExecutions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	port := reserveFreePort(t)
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  hello:
+    args: ["true"]
+    stdin:
+      payload: true
+listeners:
+  http:
+    host: 127.0.0.1
+    port: %d
+    auth:
+      api_token:
+        token: "secret"
+audit:
+  path: %s
+`, port, path))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runtime, err := server.Start(ctx, cfg)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() { audit.SetDefault(nil) })
+
+	resp := serverRequest(t, port, http.MethodPut, "/", `{"command_id":"hello","payload":"hi"}`, "secret", nil)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status: got %d want 202", resp.StatusCode)
+	}
+	var job jobs.Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	waitForJob(t, runtime, job.ID)
+	if resp := serverRequest(t, port, http.MethodPut, "/", `{"command_id":"hello"}`, "wrong", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status: got %d want 401", resp.StatusCode)
+	}
+
+	if _, err := audit.Verify(path, nil); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	records := readAuditRecords(t, path)
+	if len(records) != 3 {
+		t.Fatalf("records: got %d want 3: %#v", len(records), records)
+	}
+
+	accepted, execution, rejected := records[0], records[1], records[2]
+	if accepted.Event != audit.EventRequest || accepted.Decision != audit.DecisionAccepted || accepted.Status != http.StatusAccepted ||
+		accepted.Principal != "http/api_token" || accepted.SourceIP != "127.0.0.1" || accepted.JobID != job.ID ||
+		accepted.Parameters == nil || accepted.Parameters.PayloadBytes != 2 {
+		t.Fatalf("accepted request record: got %#v", accepted)
+	}
+	if execution.Event != audit.EventExecution || execution.JobID != job.ID || execution.State != string(jobs.StateSucceeded) ||
+		execution.Principal != "http/api_token" || execution.ExitCode == nil || *execution.ExitCode != 0 || execution.Attempts != 1 {
+		t.Fatalf("execution record: got %#v", execution)
+	}
+	if rejected.Decision != audit.DecisionRejected || rejected.Status != http.StatusUnauthorized || rejected.Principal != "" || rejected.CommandID != "hello" {
+		t.Fatalf("rejected request record: got %#v", rejected)
+	}
+}
+
+func TestStartAuditsCancelOfScheduledJob(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	port := reserveFreePort(t)
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  hello: ["true"]
+listeners:
+  http:
+    host: 127.0.0.1
+    port: %d
+    auth:
+      api_token:
+        token: "secret"
+audit:
+  path: %s
+`, port, path))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runtime, err := server.Start(ctx, cfg)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() { audit.SetDefault(nil) })
+
+	runAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	resp := serverRequest(t, port, http.MethodPut, "/", `{"command_id":"hello","run_at":"`+runAt+`"}`, "secret", nil)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status: got %d want 202", resp.StatusCode)
+	}
+	var job jobs.Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+
+	if del := serverRequest(t, port, http.MethodDelete, "/jobs/"+job.ID, "", "secret", nil); del.StatusCode != http.StatusOK {
+		t.Fatalf("delete status: got %d want 200", del.StatusCode)
+	}
+	waitForJobState(t, runtime, job.ID, jobs.StateCanceled)
+
+	var records []audit.Record
+	deadline := time.Now().Add(2 * time.Second)
+	for len(records) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		records = readAuditRecords(t, path)
+	}
+	if _, err := audit.Verify(path, nil); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("records: got %d want 3: %#v", len(records), records)
+	}
+
+	canceled, execution := records[1], records[2]
+	if canceled.Event != audit.EventCancel || canceled.JobID != job.ID || canceled.Principal != "http/api_token" ||
+		canceled.CommandID != "hello" || canceled.Status != http.StatusOK {
+		t.Fatalf("cancel record: got %#v", canceled)
+	}
+	if execution.Event != audit.EventExecution || execution.JobID != job.ID || execution.State != string(jobs.StateCanceled) ||
+		execution.CanceledBy != "http/api_token" || execution.Attempts != 0 {
+		t.Fatalf("execution record: got %#v", execution)
+	}
+}
+
+func readAuditRecords(t *testing.T, path string) []audit.Record {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open audit file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []audit.Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec audit.Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("decode audit record: %v", err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
@@ -0,0 +1,113 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"poke/internal/server"
+	"poke/internal/server/jobs"
+)
+
+func TestRuntimeCloseDrainsQueuedJobsBeforeClosingJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	job := enqueueStoredJob(t, path, "slow")
+
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  slow: ["sleep", "0.2"]
+queue:
+  type: file
+  path: %s
+`, path))
+
+	runtime, err := server.Start(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := runtime.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	reopened, _, err := jobs.Open(jobs.QueueConfig{Type: jobs.QueueTypeFile, Path: path})
+	if err != nil {
+		t.Fatalf("reopen queue: %v", err)
+	}
+	defer func() { _ = reopened.Close() }()
+	if got, _ := reopened.Get(job.ID); got.State != jobs.StateSucceeded {
+		t.Fatalf("job after close: got state %q want %q", got.State, jobs.StateSucceeded)
+	}
+}
+
+func TestRuntimeCloseCancelsRunningJobWhenDeadlinePasses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	job := enqueueStoredJob(t, path, "hang")
+
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  hang: ["sleep", "10"]
+queue:
+  type: file
+  path: %s
+`, path))
+
+	runtime, err := server.Start(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	waitForJobState(t, runtime, job.ID, jobs.StateRunning)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if err := runtime.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("close: got %v want deadline exceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("close: took %s, running job was not canceled", elapsed)
+	}
+	if err := runtime.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second close: got %v want the first result", err)
+	}
+	if got, _ := runtime.Jobs.Get(job.ID); got.FinishedAt == nil || got.State == jobs.StateSucceeded {
+		t.Fatalf("job after close: got state %q want a failed or canceled job", got.State)
+	}
+}
+
+// enqueueStoredJob writes a queued job for commandID to the file queue at path.
+func enqueueStoredJob(t *testing.T, path string, commandID string) jobs.Job {
+	t.Helper()
+
+	registry, _, err := jobs.Open(jobs.QueueConfig{Type: jobs.QueueTypeFile, Path: path})
+	if err != nil {
+		t.Fatalf("open queue: %v", err)
+	}
+	job, _, err := registry.Enqueue(jobs.Submission{CommandID: commandID})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if err := registry.Close(); err != nil {
+		t.Fatalf("close queue: %v", err)
+	}
+	return job
+}
+
+func waitForJobState(t *testing.T, runtime *server.Runtime, id string, want jobs.State) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ := runtime.Jobs.Get(id); got.State == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	got, _ := runtime.Jobs.Get(id)
+	t.Fatalf("job %s: got state %q want %q", id, got.State, want)
+}
@@ -1,6 +1,7 @@
 package server_test
 
 import (
+	"strings"
 	"testing"
 
 	"poke/internal/server"
@@ -94,3 +95,57 @@ listeners:
 		t.Fatalf("expected error for invalid command config")
 	}
 }
+
+// TestConfigParseQueue verifies the queue block defaults to memory and accepts file queues.
+func TestConfigParseQueue(t *testing.T) {
+	cfg, err := server.Parse([]byte(`commands: {}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cfg.Queue.Durable() {
+		t.Fatalf("queue: expected in-memory default, got %#v", cfg.Queue)
+	}
+
+	cfg, err = server.Parse([]byte(`
+queue:
+  type: file
+  path: /var/lib/poke/queue.jsonl
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !cfg.Queue.Durable() || cfg.Queue.Path != "/var/lib/poke/queue.jsonl" {
+		t.Fatalf("queue: got %#v", cfg.Queue)
+	}
+}
+
+func TestConfigParseWorkflows(t *testing.T) {
+	cfg, err := server.Parse([]byte(`
+commands:
+  stop: "true"
+  start: "true"
+workflows:
+  restart:
+    steps:
+      - command: stop
+      - command: start
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if wf, ok := cfg.Commands.Workflow("restart"); !ok || len(wf.Steps) != 2 {
+		t.Fatalf("workflow: got %#v, %v", wf, ok)
+	}
+
+	_, err = server.Parse([]byte(`
+commands:
+  stop: "true"
+workflows:
+  restart:
+    steps:
+      - command: start
+`))
+	if err == nil || !strings.Contains(err.Error(), `unknown command "start"`) {
+		t.Fatalf("expected unknown command error, got %v", err)
+	}
+}
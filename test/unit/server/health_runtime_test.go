@@ -0,0 +1,118 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"poke/internal/server"
+	"poke/internal/server/health"
+)
+
+func TestStartServesReadinessOnSeparateListener(t *testing.T) {
+	listenerPort := reserveFreePort(t)
+	healthPort := reserveFreePort(t)
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  ok: ["true"]
+listeners:
+  http:
+    host: 127.0.0.1
+    port: %d
+    auth:
+      api_token:
+        token: "secret"
+health:
+  port: %d
+`, listenerPort, healthPort))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runtime, err := server.Start(ctx, cfg)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/readyz", healthPort)
+	report := waitForReadiness(t, url, "ready")
+	for name, want := range map[string]string{"dispatcher": "running", "queue": "0/16 buffered", "listeners": "1/1 started"} {
+		if got := report.Checks[name]; got.Status != "ok" || got.Detail != want {
+			t.Fatalf("check %s: got %#v want detail %q", name, got, want)
+		}
+	}
+
+	if status := getStatus(t, fmt.Sprintf("http://127.0.0.1:%d/healthz", listenerPort)); status != http.StatusMethodNotAllowed {
+		t.Fatalf("HTTP listener must not serve /healthz with a separate health listener: got %d", status)
+	}
+
+	// Once the dispatcher stops the instance must report not ready.
+	close(runtime.RequestChannel)
+	report = waitForReadiness(t, url, "not_ready")
+	if got := report.Checks["dispatcher"]; got.Status != "failing" {
+		t.Fatalf("dispatcher check after shutdown: got %#v", got)
+	}
+}
+
+func TestStartServesHealthOnHTTPListenerByDefault(t *testing.T) {
+	port := reserveFreePort(t)
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  ok: ["true"]
+listeners:
+  http:
+    host: 127.0.0.1
+    port: %d
+    auth:
+      api_token:
+        token: "secret"
+`, port))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runtime, err := server.Start(ctx, cfg)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer close(runtime.RequestChannel)
+
+	if status := getStatus(t, fmt.Sprintf("http://127.0.0.1:%d/healthz", port)); status != http.StatusOK {
+		t.Fatalf("healthz status without credentials: got %d want 200", status)
+	}
+	waitForReadiness(t, fmt.Sprintf("http://127.0.0.1:%d/readyz", port), "ready")
+}
+
+func waitForReadiness(t *testing.T, url string, want string) health.Report {
+	t.Helper()
+
+	client := &http.Client{Timeout: time.Second}
+	deadline := time.Now().Add(2 * time.Second)
+	var report health.Report
+	for time.Now().Before(deadline) {
+		resp, err := client.Get(url)
+		if err == nil {
+			report = health.Report{}
+			_ = json.NewDecoder(resp.Body).Decode(&report)
+			_ = resp.Body.Close()
+			if report.Status == want {
+				return report
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("readiness %s: got %#v want status %q", url, report, want)
+	return report
+}
+
+func getStatus(t *testing.T, url string) int {
+	t.Helper()
+
+	resp, err := (&http.Client{Timeout: time.Second}).Get(url)
+	if err != nil {
+		t.Fatalf("get %s: %v", url, err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
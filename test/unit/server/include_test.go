@@ -0,0 +1,168 @@
+package server_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"poke/internal/server"
+)
+
+func TestLoadMergesIncludedCommands(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFiles(t, dir, map[string]string{
+		"poke.yml": `
+include: ["teams/*.yml", "extra.yml"]
+commands:
+  hello: [echo, hello]
+workflows:
+  nightly:
+    steps:
+      - command: backup
+      - command: report
+`,
+		"teams/backup.yml": "commands:\n  backup: [/usr/bin/backup]\n",
+		"teams/empty.yml":  "",
+		"extra.yml":        "commands:\n  report:\n    args: [/usr/bin/report]\n",
+		"conf.d/ops.yml":   "commands:\n  rotate: [logrotate]\n",
+		"conf.d/skip.yaml": "commands:\n  skipped: [true]\n",
+	})
+
+	cfg, err := server.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got, want := cfg.Commands.IDs(), []string{"backup", "hello", "report", "rotate"}; !slices.Equal(got, want) {
+		t.Fatalf("commands: got %v want %v", got, want)
+	}
+	if _, ok := cfg.Commands.Workflow("nightly"); !ok {
+		t.Fatalf("workflow using included commands must be registered")
+	}
+
+	parsed, err := server.Parse([]byte("include: [extra.yml]\ncommands:\n  hello: [echo]\n"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := parsed.Commands.IDs(); !slices.Equal(got, []string{"hello"}) || !slices.Equal(parsed.Include, []string{"extra.yml"}) {
+		t.Fatalf("parse must not read included files: commands %v include %v", got, parsed.Include)
+	}
+}
+
+func TestLoadNamesSourceFileInErrors(t *testing.T) {
+	cases := map[string]struct {
+		files map[string]string
+		want  []string
+	}{
+		"duplicate of main": {
+			files: map[string]string{
+				"poke.yml":     "commands:\n  hello: [echo]\n",
+				"conf.d/a.yml": "commands:\n  hello: [printf]\n",
+			},
+			want: []string{filepath.Join("conf.d", "a.yml") + `: duplicate command id "hello", already defined in `, "poke.yml"},
+		},
+		"duplicate across includes": {
+			files: map[string]string{
+				"poke.yml": "include: [a.yml, b.yml]\n",
+				"a.yml":    "commands:\n  deploy: [deploy]\n",
+				"b.yml":    "commands:\n  deploy: [deploy, --now]\n",
+			},
+			want: []string{`b.yml: duplicate command id "deploy", already defined in `, "a.yml"},
+		},
+		"invalid command": {
+			files: map[string]string{
+				"poke.yml":     "{}\n",
+				"conf.d/a.yml": "commands:\n  bad:\n    args: [x]\n    timeout: soon\n",
+			},
+			want: []string{filepath.Join("conf.d", "a.yml") + ": command bad:"},
+		},
+		"other block": {
+			files: map[string]string{
+				"poke.yml":     "{}\n",
+				"conf.d/a.yml": "listeners: {}\n",
+			},
+			want: []string{`a.yml: included files may only set commands, found "listeners"`},
+		},
+		"missing file": {
+			files: map[string]string{"poke.yml": "include: [missing.yml]\n"},
+			want:  []string{`missing.yml": file not found`},
+		},
+	}
+
+	for name, tc := range cases {
+		path := writeConfigFiles(t, t.TempDir(), tc.files)
+		_, err := server.Load(path)
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Fatalf("%s: got %v want %q", name, err, want)
+			}
+		}
+	}
+}
+
+func TestValidateChecksIncludedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFiles(t, dir, map[string]string{
+		"poke.yml": `commands:
+  hello: ["true"]
+workflows:
+  nightly:
+    steps:
+      - command: backup
+`,
+		"conf.d/a.yml": `commands:
+  backup: ["true"]
+  hello: ["true"]
+`,
+		"conf.d/b.yml": `commands:
+  slow:
+    args: ["true"]
+    timeout: soon
+queue: {}
+`,
+	})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	problems := server.Validate(data, server.ValidateOptions{Path: path})
+	want := []struct{ file, text string }{
+		{"a.yml", `3:3: commands: duplicate key "hello", already defined in ` + path},
+		{"b.yml", `2:3: command slow: time: invalid duration "soon"`},
+		{"b.yml", `5:1: included files may only set commands, found "queue"`},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems: got %d want %d:\n%s", len(problems), len(want), strings.Join(problemStrings(problems), "\n"))
+	}
+	for i, w := range want {
+		if filepath.Base(problems[i].File) != w.file || !strings.HasPrefix(problems[i].String(), w.text) {
+			t.Fatalf("problem %d: got %s %q want %s %q", i, problems[i].File, problems[i], w.file, w.text)
+		}
+	}
+
+	if got := server.Validate(data, server.ValidateOptions{}); len(got) != 1 || !strings.Contains(got[0].Message, `unknown command "backup"`) {
+		t.Fatalf("without path included files must not be read: %v", problemStrings(got))
+	}
+}
+
+// writeConfigFiles writes files, keyed by path relative to dir, and returns
+// the path of dir/poke.yml.
+func writeConfigFiles(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	return filepath.Join(dir, "poke.yml")
+}
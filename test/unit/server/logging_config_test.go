@@ -3,6 +3,7 @@ package server_test
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"poke/internal/server"
 	"poke/internal/server/logging"
@@ -26,8 +27,8 @@ commands:
 	if cfg.Logging.Format != "text" {
 		t.Fatalf("logging format: got %q want %q", cfg.Logging.Format, "text")
 	}
-	if cfg.Logging.Sink.Type != "stdout" {
-		t.Fatalf("logging sink type: got %q want %q", cfg.Logging.Sink.Type, "stdout")
+	if cfg.Logging.Sink[0].Type != "stdout" {
+		t.Fatalf("logging sink type: got %q want %q", cfg.Logging.Sink[0].Type, "stdout")
 	}
 }
 
@@ -62,13 +63,15 @@ logging:
 			"service": "poke",
 			"env":     "prod",
 		},
-		Sink: logging.SinkConfig{
+		Sink: logging.SinkList{{
 			Type: "journald",
 			Journald: &logging.JournaldSinkConfig{
 				Identifier: "poke-server",
 				Fallback:   "stdout",
 			},
-		},
+		}},
+		LevelRevertAfter: 15 * time.Minute,
+		LevelOverrideMax: 24 * time.Hour,
 	}
 
 	if !reflect.DeepEqual(cfg.Logging, want) {
@@ -0,0 +1,67 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"poke/internal/server"
+)
+
+func TestConfigParseMetrics(t *testing.T) {
+	cfg := mustParseServerConfig(t, `commands: {}`)
+	if cfg.Metrics.Enabled {
+		t.Fatalf("metrics: expected disabled without a metrics block, got %#v", cfg.Metrics)
+	}
+
+	cfg = mustParseServerConfig(t, `
+metrics:
+  port: 9100
+`)
+	if !cfg.Metrics.Enabled || cfg.Metrics.Address() != "127.0.0.1:9100" {
+		t.Fatalf("metrics: got %#v", cfg.Metrics)
+	}
+
+	if _, err := server.Parse([]byte("metrics:\n  port: 0\n")); err == nil {
+		t.Fatalf("expected invalid metrics port to be rejected")
+	}
+}
+
+func TestStartServesMetrics(t *testing.T) {
+	port := reserveFreePort(t)
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  ok: ["true"]
+metrics:
+  port: %d
+`, port))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runtime, err := server.Start(ctx, cfg)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer close(runtime.RequestChannel)
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+	if err != nil {
+		t.Fatalf("get metrics: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+
+	for _, want := range []string{"# TYPE poke_requests_total counter", "poke_request_queue_depth 0", "# TYPE poke_queue_wait_seconds histogram"} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("metrics body missing %q:\n%s", want, body)
+		}
+	}
+}
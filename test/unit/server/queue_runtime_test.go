@@ -0,0 +1,34 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"poke/internal/server"
+	"poke/internal/server/jobs"
+)
+
+func TestStartRunsJobsRecoveredFromFileQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	job := enqueueStoredJob(t, path, "ok")
+
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  ok: ["true"]
+queue:
+  type: file
+  path: %s
+`, path))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runtime, err := server.Start(ctx, cfg)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer func() { _ = runtime.Jobs.Close() }()
+
+	waitForJobState(t, runtime, job.ID, jobs.StateSucceeded)
+}
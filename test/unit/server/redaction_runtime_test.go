@@ -0,0 +1,131 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"poke/internal/server"
+	"poke/internal/server/jobs"
+	"poke/internal/server/logging"
+	"poke/internal/server/redact"
+)
+
+func TestStartRedactsSecretsFromLogsAndJobs(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "poke.log")
+	port := reserveFreePort(t)
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  leaky:
+    args: ["/nonexistent/tok-7f3a9c", "--password=hunter2", "db-pass-1234"]
+    env:
+      vals:
+        DB_PASSWORD:
+          value: db-pass-1234
+          secret: true
+listeners:
+  http:
+    host: 127.0.0.1
+    port: %d
+    auth:
+      api_token:
+        token: "tok-7f3a9c"
+redaction:
+  patterns: ['--password=(\S+)']
+logging:
+  level: debug
+  sink:
+    type: file
+    file:
+      path: %s
+`, port, logPath))
+
+	logger, err := logging.New(cfg.Logging)
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		redact.SetDefault(nil)
+		_ = logging.Close()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runtime, err := server.Start(ctx, cfg)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	resp := serverRequest(t, port, http.MethodPut, "/", `{"command_id":"leaky"}`, "tok-7f3a9c", nil)
+	var job jobs.Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	waitForJob(t, runtime, job.ID)
+
+	get := serverRequest(t, port, http.MethodGet, "/jobs/"+job.ID, "", "tok-7f3a9c", nil)
+	data, err := io.ReadAll(get.Body)
+	if err != nil || get.StatusCode != http.StatusOK {
+		t.Fatalf("get job: status %d: %s, %v", get.StatusCode, data, err)
+	}
+	body := string(data)
+	logs, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read logs: %v", err)
+	}
+
+	for name, text := range map[string]string{"job": body, "logs": string(logs)} {
+		for _, secret := range []string{"tok-7f3a9c", "hunter2", "db-pass-1234"} {
+			if strings.Contains(text, secret) {
+				t.Fatalf("%s leak %q: %s", name, secret, text)
+			}
+		}
+	}
+	if !strings.Contains(body, "/nonexistent/[REDACTED]") {
+		t.Fatalf("job error must keep redacted context: %s", body)
+	}
+	if !strings.Contains(string(logs), `args="[/nonexistent/[REDACTED] --password=[REDACTED] [REDACTED]]"`) {
+		t.Fatalf("debug args must be redacted: %s", logs)
+	}
+}
+
+func TestStartRedactsAdminTokens(t *testing.T) {
+	port := reserveFreePort(t)
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  hello: ["true"]
+listeners:
+  http:
+    host: 127.0.0.1
+    port: %d
+    auth:
+      api_token:
+        token: "tok-command"
+    admin_auth:
+      api_token:
+        token: "tok-admin-5e1d"
+`, port))
+	t.Cleanup(func() { redact.SetDefault(nil) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := server.Start(ctx, cfg); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	got := redact.Default().String("admin=tok-admin-5e1d command=tok-command")
+	if strings.Contains(got, "tok-admin-5e1d") || strings.Contains(got, "tok-command") {
+		t.Fatalf("redacted: got %q", got)
+	}
+}
@@ -0,0 +1,126 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"poke/internal/server"
+	"poke/internal/server/jobs"
+	"poke/internal/server/logging"
+)
+
+func TestStartCorrelatesRequestIDAcrossComponents(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "poke.log")
+	envFile := filepath.Join(dir, "request_id")
+	port := reserveFreePort(t)
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  failing: ["sh", "-c", "printf %%s \"$POKE_REQUEST_ID\" > %s; exit 3"]
+listeners:
+  http:
+    host: 127.0.0.1
+    port: %d
+    auth:
+      api_token:
+        token: "secret"
+logging:
+  format: json
+  sink:
+    type: file
+    file:
+      path: %s
+`, envFile, port, logPath))
+
+	logger, err := logging.New(cfg.Logging)
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		_ = logging.Close()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runtime, err := server.Start(ctx, cfg)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	resp := serverRequest(t, port, http.MethodPut, "/", `{"command_id":"failing"}`, "secret", map[string]string{"X-Request-ID": "req-abc-123"})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusAccepted)
+	}
+	if got := resp.Header.Get("X-Request-ID"); got != "req-abc-123" {
+		t.Fatalf("response header: got %q", got)
+	}
+	var job jobs.Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	if job.RequestID != "req-abc-123" {
+		t.Fatalf("job request id: got %q", job.RequestID)
+	}
+	waitForJob(t, runtime, job.ID)
+
+	env, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("read POKE_REQUEST_ID: %v", err)
+	}
+	if string(env) != "req-abc-123" {
+		t.Fatalf("POKE_REQUEST_ID: got %q", env)
+	}
+
+	components := map[string]bool{}
+	events := map[string]bool{}
+	for _, rec := range readJSONLogs(t, logPath) {
+		if rec["request_id"] == "req-abc-123" {
+			components[fmt.Sprint(rec["component"])] = true
+			events[fmt.Sprint(rec["event"])] = true
+		}
+	}
+	for _, component := range []string{"listener/http", "dispatcher", "executor/bin"} {
+		if !components[component] {
+			t.Fatalf("no %s log with the request id, got %v", component, components)
+		}
+	}
+	if !events["request_received"] || !events["command_execution_failed"] {
+		t.Fatalf("listener and failure logs must share the request id, got %v", events)
+	}
+
+	generated := serverRequest(t, port, http.MethodPut, "/", `{"command_id":"failing"}`, "secret", map[string]string{"X-Request-ID": "bad\tid"})
+	if got := generated.Header.Get("X-Request-ID"); len(got) != 32 || got == "bad\tid" {
+		t.Fatalf("generated request id: got %q", got)
+	}
+}
+
+func readJSONLogs(t *testing.T, path string) []map[string]any {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open logs: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("decode log line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
@@ -0,0 +1,499 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+
+	"poke/internal/server"
+	"poke/internal/server/audit"
+	"poke/internal/server/auth"
+	"poke/internal/server/dispatch"
+	"poke/internal/server/executor"
+	"poke/internal/server/health"
+	"poke/internal/server/jobs"
+	"poke/internal/server/listener"
+	"poke/internal/server/logging"
+	"poke/internal/server/metrics"
+	"poke/internal/server/redact"
+	"poke/internal/server/tracing"
+)
+
+func TestSchemaPropertiesMatchConfigTypes(t *testing.T) {
+	root := loadSchema(t)
+	types := map[string]reflect.Type{
+		"":                                      reflect.TypeFor[server.Config](),
+		"$defs/commandSpec":                     reflect.TypeFor[executor.Command](),
+		"$defs/env":                             reflect.TypeFor[executor.Env](),
+		"$defs/limits":                          reflect.TypeFor[executor.Limits](),
+		"$defs/sandbox":                         reflect.TypeFor[executor.Sandbox](),
+		"$defs/sandbox/properties/landlock":     reflect.TypeFor[executor.LandlockRules](),
+		"$defs/stdin":                           reflect.TypeFor[executor.Stdin](),
+		"$defs/retry":                           reflect.TypeFor[executor.Retry](),
+		"$defs/retry/properties/on":             reflect.TypeFor[executor.RetryOn](),
+		"$defs/workflow":                        reflect.TypeFor[dispatch.Workflow](),
+		"$defs/workflow/properties/steps/items": reflect.TypeFor[dispatch.WorkflowStep](),
+		"$defs/httpListener":                    reflect.TypeFor[listener.HTTPListenerConfig](),
+		"$defs/httpListener/properties/tls":     reflect.TypeFor[listener.HTTPListenerTLSConfig](),
+		"$defs/priorityRange":                   reflect.TypeFor[auth.PriorityRange](),
+		"$defs/logging":                         reflect.TypeFor[logging.Config](),
+		"$defs/sink":                            reflect.TypeFor[logging.SinkConfig](),
+		"$defs/sink/properties/journald":        reflect.TypeFor[logging.JournaldSinkConfig](),
+		"$defs/sink/properties/file":            reflect.TypeFor[logging.FileSinkConfig](),
+		"$defs/sink/properties/syslog":          reflect.TypeFor[logging.SyslogSinkConfig](),
+		"$defs/sink/properties/syslog/properties/tls": reflect.TypeFor[logging.SyslogTLSConfig](),
+		"$defs/queue":     reflect.TypeFor[jobs.QueueConfig](),
+		"$defs/metrics":   reflect.TypeFor[metrics.Config](),
+		"$defs/tracing":   reflect.TypeFor[tracing.Config](),
+		"$defs/health":    reflect.TypeFor[health.Config](),
+		"$defs/audit":     reflect.TypeFor[audit.Config](),
+		"$defs/redaction": reflect.TypeFor[redact.Config](),
+	}
+
+	for path, typ := range types {
+		node := root
+		for _, part := range strings.Split(path, "/") {
+			if part != "" {
+				node, _ = node[part].(map[string]any)
+			}
+		}
+		properties, _ := node["properties"].(map[string]any)
+		got := slices.Sorted(maps.Keys(properties))
+		if want := yamlFields(typ); !slices.Equal(got, want) {
+			t.Fatalf("schema %q properties %v, %s has %v", path, got, typ, want)
+		}
+	}
+}
+
+func TestSchemaAgreesWithParse(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatalf("write token: %v", err)
+	}
+	t.Setenv("POKE_SCHEMA_TOKEN", "env-token")
+
+	cases := []struct {
+		name   string
+		config string
+		valid  bool
+	}{
+		{"command shorthands", "commands:\n  one: uptime\n  list: [echo, hi]\n", true},
+		{"command object", `
+commands:
+  backup:
+    name: Backup
+    args: [/usr/bin/backup, --all]
+    executor: bin
+    env:
+      strategy: extend
+      vals:
+        MODE: full
+        RETRIES: 3
+        DB_PASSWORD: {value: s3cret, secret: true}
+    timeout: 1m30s
+    stop_signal: term
+    stop_timeout: 2s
+    limits: {memory: 512MiB, cpu: 0.5, pids: 64, open_files: 1024, core_size: 0}
+    sandbox: {read_only: [/etc], network: none, landlock: {read: [/usr], read_write: [/tmp]}}
+    workdir: /var/lib/poke
+    umask: "0027"
+    stdin: {payload: true, max_size: 1K}
+    retry: {max_attempts: 5, backoff: 2s, max_backoff: 1m, jitter: 0.2, on: {exit_codes: [75], timeout: true}}
+    priority: 7
+    coalesce: true
+`, true},
+		{"env strategies", "commands:\n  a: {args: [a], env: {strategy: inherit}}\n  b: {args: [b], env: {strategy: isolate, vals: {}}}\n  c: {args: [c], env: {strategy: override}}\n", true},
+		{"workflow", `
+commands:
+  build: [make]
+  undo: [make, clean]
+workflows:
+  release:
+    priority: 3
+    steps:
+      - command: build
+      - id: again
+        command: build
+        needs: [build]
+        on_failure: compensate
+        compensate: undo
+`, true},
+		{"api token sources", fmt.Sprintf(`
+listeners:
+  http:
+    host: 0.0.0.0
+    port: 8443
+    read_timeout: 5s
+    idempotency_window: 0s
+    auth:
+      api_token:
+        file: %s
+        priority: {min: 2}
+`, tokenFile), true},
+		{"api token env", "listeners:\n  http:\n    auth:\n      api_token: {env: POKE_SCHEMA_TOKEN}\n", true},
+		{"sinks", `
+logging:
+  level: debug
+  format: json
+  level_revert_after: 5m
+  static_fields: {service: poke}
+  sink:
+    - type: stdout
+      events: ["job_*"]
+    - type: file
+      level: warn
+      file: {path: /var/log/poke.log, max_size: 10M, max_files: 3, compress: true}
+    - type: syslog
+      syslog: {network: tls, address: "logs:6514", facility: local3, tls: {server_name: logs}}
+    - type: journald
+      journald: {identifier: poke}
+`, true},
+		{"other blocks", `
+queue: {type: file, path: /var/lib/poke/queue, max_delay: 1h}
+metrics: {enabled: false}
+tracing: {exporter: file, path: /var/log/poke-traces.jsonl}
+health: {port: 0}
+audit: {enabled: false}
+redaction: {patterns: ['token=(\S+)'], replacement: "***"}
+`, true},
+		{"empty command", "commands:\n  bad:\n", false},
+		{"command without args", "commands:\n  bad: {name: Bad}\n", false},
+		{"numeric duration", "commands:\n  bad: {args: [a], timeout: 30}\n", false},
+		{"relative workdir", "commands:\n  bad: {args: [a], workdir: tmp}\n", false},
+		{"unquoted umask", "commands:\n  bad: {args: [a], umask: 27}\n", false},
+		{"two stdin sources", "commands:\n  bad: {args: [a], stdin: {text: hi, file: /etc/hosts}}\n", false},
+		{"retry jitter", "commands:\n  bad: {args: [a], retry: {jitter: 2}}\n", false},
+		{"priority", "commands:\n  bad: {args: [a], priority: 10}\n", false},
+		{"bad byte size", "commands:\n  bad: {args: [a], limits: {memory: 1X}}\n", false},
+		{"sandbox network", "commands:\n  bad: {args: [a], sandbox: {network: bridge}}\n", false},
+		{"workflow without steps", "workflows:\n  bad: {name: Bad}\n", false},
+		{"listener type", "listeners:\n  grpc: {}\n", false},
+		{"listener without auth", "listeners:\n  http: {port: 8008}\n", false},
+		{"listener port", "listeners:\n  http: {port: 70000, auth: {api_token: {token: t}}}\n", false},
+		{"auth method", "listeners:\n  http:\n    auth: {basic: {user: u}}\n", false},
+		{"two token sources", "listeners:\n  http:\n    auth: {api_token: {token: t, env: POKE_SCHEMA_TOKEN}}\n", false},
+		{"log level", "logging: {level: verbose}\n", false},
+		{"empty sink list", "logging: {sink: []}\n", false},
+		{"file sink without file", "logging: {sink: {type: file}}\n", false},
+		{"journald without identifier", "logging: {sink: {type: journald, journald: {fallback: stdout}}}\n", false},
+		{"syslog facility", "logging: {sink: {type: syslog, syslog: {facility: mars}}}\n", false},
+		{"queue path", "queue: {type: file}\n", false},
+		{"audit path", "audit: {}\n", false},
+	}
+
+	root := loadSchema(t)
+	for _, tc := range cases {
+		_, err := server.Parse([]byte(tc.config))
+		if parsed := err == nil; parsed != tc.valid {
+			t.Fatalf("%s: parse error %v, want valid=%v", tc.name, err, tc.valid)
+		}
+		problems := validateSchema(t, root, tc.config)
+		if matched := len(problems) == 0; matched != tc.valid {
+			t.Fatalf("%s: schema problems %v, want valid=%v", tc.name, problems, tc.valid)
+		}
+	}
+}
+
+func TestSchemaRejectsKeysParseIgnores(t *testing.T) {
+	root := loadSchema(t)
+	config := "logging: {levle: debug}\ndefaults: {}\n"
+	if _, err := server.Parse([]byte(config)); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	problems := validateSchema(t, root, config)
+	want := []string{"/: unexpected property \"defaults\"", "/logging: unexpected property \"levle\""}
+	for _, problem := range want {
+		if !slices.Contains(problems, problem) {
+			t.Fatalf("problems %v, want %q", problems, problem)
+		}
+	}
+}
+
+func TestSchemaAcceptsDocumentedExamples(t *testing.T) {
+	t.Setenv("POKE_API_TOKEN", "doc-token")
+	root := loadSchema(t)
+
+	files, err := filepath.Glob("../../../docs/*/*.md")
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	blocks := yamlFields(reflect.TypeFor[server.Config]())
+	examples := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		for i, block := range regexp.MustCompile("(?s)```yaml\n(.*?)```").FindAllStringSubmatch(string(data), -1) {
+			var keys map[string]any
+			if yaml.Unmarshal([]byte(block[1]), &keys) != nil || !isConfig(keys, blocks) {
+				continue // snippet of a block
+			}
+			examples[fmt.Sprintf("%s #%d", file, i+1)] = block[1]
+		}
+	}
+	data, err := os.ReadFile("../../../docs/configuration/config.example.yaml")
+	if err != nil {
+		t.Fatalf("read example: %v", err)
+	}
+	examples["config.example.yaml"] = string(data)
+
+	checked := 0
+	for name, example := range examples {
+		// Examples that need files or a host setup poke checks on parse are skipped.
+		if _, err := server.Parse([]byte(example)); err != nil {
+			continue
+		}
+		checked++
+		if problems := validateSchema(t, root, example); len(problems) > 0 {
+			t.Fatalf("%s: schema problems %v:\n%s", name, problems, example)
+		}
+	}
+	if checked < len(examples)/2 {
+		t.Fatalf("only %d of %d examples parsed", checked, len(examples))
+	}
+}
+
+// isConfig reports whether the top-level keys of doc are all config blocks.
+func isConfig(doc map[string]any, blocks []string) bool {
+	for key := range doc {
+		if !slices.Contains(blocks, key) {
+			return false
+		}
+	}
+	return len(doc) > 0
+}
+
+func loadSchema(t *testing.T) map[string]any {
+	t.Helper()
+
+	var root map[string]any
+	if err := json.Unmarshal(server.Schema(), &root); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+	return root
+}
+
+// yamlFields returns the sorted YAML keys of the fields of struct type typ.
+func yamlFields(typ reflect.Type) []string {
+	var names []string
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// validateSchema checks a YAML config against root with schemaValidator.
+func validateSchema(t *testing.T, root map[string]any, config string) []string {
+	t.Helper()
+
+	var raw any
+	if err := yaml.Unmarshal([]byte(config), &raw); err != nil {
+		t.Fatalf("yaml: %v", err)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+
+	v := &schemaValidator{root: root}
+	v.validate(root, value, "/")
+	return v.problems
+}
+
+// schemaValidator implements the JSON Schema keywords used by server.Schema.
+type schemaValidator struct {
+	root     map[string]any
+	problems []string
+}
+
+func (v *schemaValidator) validate(schema any, value any, path string) {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		if schema == false {
+			v.fail(path, "no value allowed")
+		}
+		return
+	}
+	if ref, ok := s["$ref"].(string); ok {
+		v.validate(v.resolve(ref), value, path)
+	}
+	v.validateGeneric(s, value, path)
+	v.validateCombinators(s, value, path)
+	switch value := value.(type) {
+	case map[string]any:
+		v.validateObject(s, value, path)
+	case []any:
+		v.validateArray(s, value, path)
+	case string:
+		v.validateString(s, value, path)
+	case float64:
+		v.validateNumber(s, value, path)
+	}
+}
+
+func (v *schemaValidator) validateGeneric(s map[string]any, value any, path string) {
+	if types, ok := s["type"]; ok {
+		allowed, isList := types.([]any)
+		if !isList {
+			allowed = []any{types}
+		}
+		if !slices.ContainsFunc(allowed, func(typ any) bool { return jsonTypeMatches(typ.(string), value) }) {
+			v.fail(path, fmt.Sprintf("type must be %v", types))
+		}
+	}
+	if enum, ok := s["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return reflect.DeepEqual(e, value) }) {
+		v.fail(path, fmt.Sprintf("must be one of %v", enum))
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, value) {
+		v.fail(path, fmt.Sprintf("must be %v", c))
+	}
+}
+
+func (v *schemaValidator) validateCombinators(s map[string]any, value any, path string) {
+	for _, sub := range asList(s["allOf"]) {
+		v.validate(sub, value, path)
+	}
+	if anyOf := asList(s["anyOf"]); anyOf != nil && v.count(anyOf, value, path) == 0 {
+		v.fail(path, "matches none of anyOf")
+	}
+	if oneOf := asList(s["oneOf"]); oneOf != nil && v.count(oneOf, value, path) != 1 {
+		v.fail(path, "must match exactly one of oneOf")
+	}
+	if not, ok := s["not"]; ok && v.count([]any{not}, value, path) == 1 {
+		v.fail(path, "must not match not")
+	}
+	if cond, ok := s["if"]; ok {
+		branch := s["else"]
+		if v.count([]any{cond}, value, path) == 1 {
+			branch = s["then"]
+		}
+		if branch != nil {
+			v.validate(branch, value, path)
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(s map[string]any, value map[string]any, path string) {
+	properties, _ := s["properties"].(map[string]any)
+	for _, key := range slices.Sorted(maps.Keys(value)) {
+		sub, known := properties[key]
+		if !known {
+			sub, known = s["additionalProperties"]
+		}
+		if !known {
+			continue
+		}
+		if sub == false {
+			v.fail(path, fmt.Sprintf("unexpected property %q", key))
+			continue
+		}
+		v.validate(sub, value[key], strings.TrimSuffix(path, "/")+"/"+key)
+	}
+	for _, required := range asList(s["required"]) {
+		if _, ok := value[required.(string)]; !ok {
+			v.fail(path, fmt.Sprintf("missing property %q", required))
+		}
+	}
+	if limit, ok := s["minProperties"].(float64); ok && float64(len(value)) < limit {
+		v.fail(path, fmt.Sprintf("needs at least %v properties", limit))
+	}
+}
+
+func (v *schemaValidator) validateArray(s map[string]any, value []any, path string) {
+	if limit, ok := s["minItems"].(float64); ok && float64(len(value)) < limit {
+		v.fail(path, fmt.Sprintf("needs at least %v items", limit))
+	}
+	if items, ok := s["items"]; ok {
+		for i, item := range value {
+			v.validate(items, item, fmt.Sprintf("%s/%d", strings.TrimSuffix(path, "/"), i))
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(s map[string]any, value string, path string) {
+	if limit, ok := s["minLength"].(float64); ok && float64(len([]rune(value))) < limit {
+		v.fail(path, fmt.Sprintf("needs at least %v characters", limit))
+	}
+	if pattern, ok := s["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(value) {
+		v.fail(path, fmt.Sprintf("must match %s", pattern))
+	}
+}
+
+func (v *schemaValidator) validateNumber(s map[string]any, value float64, path string) {
+	if limit, ok := s["minimum"].(float64); ok && value < limit {
+		v.fail(path, fmt.Sprintf("must be at least %v", limit))
+	}
+	if limit, ok := s["maximum"].(float64); ok && value > limit {
+		v.fail(path, fmt.Sprintf("must be at most %v", limit))
+	}
+}
+
+// count returns how many of schemas value matches, without reporting problems.
+func (v *schemaValidator) count(schemas []any, value any, path string) int {
+	matches := 0
+	for _, schema := range schemas {
+		sub := &schemaValidator{root: v.root}
+		sub.validate(schema, value, path)
+		if len(sub.problems) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+func (v *schemaValidator) resolve(ref string) any {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	defs, _ := v.root["$defs"].(map[string]any)
+	if !ok || defs[name] == nil {
+		v.fail(ref, "unresolved $ref")
+		return true
+	}
+	return defs[name]
+}
+
+func (v *schemaValidator) fail(path string, message string) {
+	v.problems = append(v.problems, path+": "+message)
+}
+
+func jsonTypeMatches(typ string, value any) bool {
+	switch value := value.(type) {
+	case nil:
+		return typ == "null"
+	case bool:
+		return typ == "boolean"
+	case string:
+		return typ == "string"
+	case float64:
+		return typ == "number" || (typ == "integer" && value == math.Trunc(value))
+	case []any:
+		return typ == "array"
+	case map[string]any:
+		return typ == "object"
+	default:
+		return false
+	}
+}
+
+func asList(value any) []any {
+	list, _ := value.([]any)
+	return list
+}
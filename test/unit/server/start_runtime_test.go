@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"poke/internal/server"
 )
@@ -88,6 +91,29 @@ func mustParseServerConfig(t *testing.T, input string) server.Config {
 	return cfg
 }
 
+// serverRequest sends an api_token authenticated request with extra headers
+// to the HTTP listener on port. The response body is closed at test end.
+func serverRequest(t *testing.T, port int, method string, path string, body string, token string, headers map[string]string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("X-Poke-Auth-Method", "api_token")
+	req.Header.Set("X-Poke-API-Token", token)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := (&http.Client{Timeout: 2 * time.Second}).Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
 func reserveFreePort(t *testing.T) int {
 	t.Helper()
 
@@ -106,3 +132,20 @@ func reserveFreePort(t *testing.T) int {
 
 	return addr.Port
 }
+
+func TestStartRejectsUnavailableSandbox(t *testing.T) {
+	cfg := mustParseServerConfig(t, `
+commands:
+  isolated:
+    args: ["true"]
+    sandbox:
+      read_only: ["/__poke_path_that_does_not_exist__"]
+`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := server.Start(ctx, cfg); err == nil {
+		t.Fatalf("expected sandbox probe error")
+	}
+}
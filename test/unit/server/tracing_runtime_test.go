@@ -0,0 +1,130 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"poke/internal/server"
+	"poke/internal/server/jobs"
+	"poke/internal/server/tracing"
+)
+
+func TestStartTracesRequestThroughDispatcherAndExecutor(t *testing.T) {
+	dir := t.TempDir()
+	traces := filepath.Join(dir, "traces.jsonl")
+	envFile := filepath.Join(dir, "traceparent")
+	port := reserveFreePort(t)
+	cfg := mustParseServerConfig(t, fmt.Sprintf(`
+commands:
+  traced: ["sh", "-c", "printf %%s \"$TRACEPARENT\" > %s"]
+listeners:
+  http:
+    host: 127.0.0.1
+    port: %d
+    auth:
+      api_token:
+        token: "secret"
+tracing:
+  exporter: file
+  path: %s
+`, envFile, port, traces))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runtime, err := server.Start(ctx, cfg)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() { tracing.SetDefault(nil) })
+
+	const incoming = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	resp := serverRequest(t, port, http.MethodPut, "/", `{"command_id":"traced"}`, "secret", map[string]string{"traceparent": incoming})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusAccepted)
+	}
+	var job jobs.Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	waitForJob(t, runtime, job.ID)
+	if err := runtime.Tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("tracer shutdown: %v", err)
+	}
+
+	spans := readExportedSpans(t, traces)
+	for _, name := range []string{"poke.http.request", "poke.auth", "poke.enqueue", "poke.queue_wait", "poke.dispatch", "poke.exec"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("missing span %q, got %v", name, spans)
+		}
+		if span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Fatalf("span %q: trace id %q is not the incoming trace", name, span.TraceID)
+		}
+	}
+	if spans["poke.http.request"].ParentSpanID != "00f067aa0ba902b7" {
+		t.Fatalf("http span must continue the caller's span: got %#v", spans["poke.http.request"])
+	}
+	if spans["poke.exec"].ParentSpanID != spans["poke.dispatch"].SpanID {
+		t.Fatalf("exec span must be a child of the dispatch span")
+	}
+
+	env, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("read TRACEPARENT: %v", err)
+	}
+	want := fmt.Sprintf("00-%s-%s-01", spans["poke.exec"].TraceID, spans["poke.exec"].SpanID)
+	if string(env) != want {
+		t.Fatalf("TRACEPARENT: got %q want %q", env, want)
+	}
+}
+
+func waitForJob(t *testing.T, runtime *server.Runtime, id string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ := runtime.Jobs.Get(id); got.FinishedAt != nil {
+			// The dispatch span ends right after the job finishes.
+			time.Sleep(50 * time.Millisecond)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+}
+
+// readExportedSpans returns the spans in an OTLP/JSON lines file by name.
+func readExportedSpans(t *testing.T, path string) map[string]tracing.OTLPSpan {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open traces: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	spans := make(map[string]tracing.OTLPSpan)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var batch tracing.OTLPTraces
+		if err := json.Unmarshal(scanner.Bytes(), &batch); err != nil {
+			t.Fatalf("decode traces: %v", err)
+		}
+		for _, rs := range batch.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					spans[span.Name] = span
+				}
+			}
+		}
+	}
+	return spans
+}
@@ -0,0 +1,111 @@
+package server_test
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"poke/internal/server"
+)
+
+func TestValidateReportsEveryProblemWithPosition(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() { _ = busy.Close() }()
+	port := busy.Addr().(*net.TCPAddr).Port
+
+	config := fmt.Sprintf(`commands:
+  ok: ["sh", "-c", "true"]
+  slow:
+    args: ["sh"]
+    timeout: soon
+  missing: ["/nonexistent/poke-tool"]
+  ok: ["true"]
+listner: {}
+logging:
+  level: loud
+listeners:
+  http:
+    host: 127.0.0.1
+    port: %d
+    auth:
+      api_token:
+        token: secret
+`, port)
+
+	got := problemStrings(server.Validate([]byte(config), server.ValidateOptions{}))
+	want := []string{
+		`3:3: command slow: time: invalid duration "soon"`,
+		`6:3: command missing: executable "/nonexistent/poke-tool"`,
+		`7:3: commands: duplicate key "ok"`,
+		`8:1: unknown top-level key "listner"`,
+		`9:1: logging level must be one of`,
+		fmt.Sprintf("12:3: listener http address 127.0.0.1:%d is not available", port),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("problems: got %d want %d:\n%s", len(got), len(want), strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Fatalf("problem %d: got %q want prefix %q", i, got[i], want[i])
+		}
+	}
+
+	skipped := problemStrings(server.Validate([]byte(config), server.ValidateOptions{SkipPorts: true}))
+	if len(skipped) != len(want)-1 {
+		t.Fatalf("skip ports must not check the listener: %s", strings.Join(skipped, "\n"))
+	}
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	config := fmt.Sprintf(`
+defaults: &sh ["sh", "-c", "true"]
+commands:
+  stop: *sh
+  start:
+    args: ["sh", "-c", "true"]
+workflows:
+  restart:
+    steps:
+      - command: stop
+      - command: start
+listeners:
+  http:
+    host: 127.0.0.1
+    port: %d
+    auth:
+      api_token:
+        token: secret
+`, reserveFreePort(t))
+
+	problems := server.Validate([]byte(config), server.ValidateOptions{})
+	if len(problems) != 1 || problems[0].String() != `2:1: unknown top-level key "defaults"` {
+		t.Fatalf("aliases must resolve across blocks, got %v", problemStrings(problems))
+	}
+}
+
+func TestValidateReportsSyntaxAndCrossBlockProblems(t *testing.T) {
+	cases := map[string]string{
+		"commands: [": "1:11: sequence end token ']' not found",
+		"- a\n- b":    "1:1: config must be a mapping",
+		"auth: {}":    "1:1: top-level auth is no longer supported",
+		"commands:\n  a: [sh]\nworkflows:\n  w:\n    steps:\n      - command: b\n": `3:1: workflow w: step b: unknown command "b"`,
+	}
+	for input, want := range cases {
+		got := problemStrings(server.Validate([]byte(input), server.ValidateOptions{}))
+		if len(got) != 1 || !strings.HasPrefix(got[0], want) {
+			t.Fatalf("%q: got %q want %q", input, got, want)
+		}
+	}
+}
+
+func problemStrings(problems []server.Problem) []string {
+	out := make([]string, len(problems))
+	for i, problem := range problems {
+		out[i] = problem.String()
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package sidelistener_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"poke/internal/server/sidelistener"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      sidelistener.Config
+		zeroPort string
+		wantErr  string
+	}{
+		{name: "valid", cfg: sidelistener.Config{Enabled: true, Host: "127.0.0.1", Port: 9100}},
+		{name: "disabled", cfg: sidelistener.Config{Port: -1}},
+		{name: "empty host", cfg: sidelistener.Config{Enabled: true, Port: 9100}, wantErr: "status host must not be empty"},
+		{name: "zero port", cfg: sidelistener.Config{Enabled: true, Host: "127.0.0.1"}, wantErr: "status port must be between 1 and 65535"},
+		{name: "zero port allowed", cfg: sidelistener.Config{Enabled: true, Host: "127.0.0.1"}, zeroPort: "share"},
+		{name: "port out of range", cfg: sidelistener.Config{Enabled: true, Host: "127.0.0.1", Port: 70000}, zeroPort: "share", wantErr: "or 0 to share"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate("status", tt.zeroPort)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error: got %v want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListenServesUntilContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	addr, err := sidelistener.Listen(ctx, "status", "127.0.0.1:0", handler)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	client := &http.Client{Timeout: time.Second}
+	url := "http://" + addr.String() + "/"
+
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status: got %d want %d", resp.StatusCode, http.StatusNoContent)
+	}
+
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return
+		}
+		_ = resp.Body.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("listener still serving after context was canceled")
+}
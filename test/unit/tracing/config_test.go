@@ -0,0 +1,54 @@
+package tracing_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-yaml"
+
+	"poke/internal/server/tracing"
+)
+
+func TestConfigDefaults(t *testing.T) {
+	var cfg tracing.Config
+	if err := yaml.Unmarshal([]byte(`{}`), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := tracing.Config{Enabled: true, Exporter: "otlp", Endpoint: "http://127.0.0.1:4318/v1/traces", Timeout: 10 * time.Second, ServiceName: "poke"}
+	if cfg.Enabled != want.Enabled || cfg.Exporter != want.Exporter || cfg.Endpoint != want.Endpoint || cfg.Timeout != want.Timeout || cfg.ServiceName != want.ServiceName {
+		t.Fatalf("defaults: got %#v want %#v", cfg, want)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "file exporter", yaml: "exporter: file\npath: /var/log/poke/traces.jsonl"},
+		{name: "disabled", yaml: "enabled: false\nexporter: nope"},
+		{name: "unknown exporter", yaml: "exporter: zipkin", wantErr: "exporter must be one of"},
+		{name: "bad endpoint", yaml: "endpoint: 127.0.0.1:4318", wantErr: "http or https URL"},
+		{name: "relative file path", yaml: "exporter: file\npath: traces.jsonl", wantErr: "absolute path"},
+		{name: "path with otlp", yaml: "path: /tmp/traces.jsonl", wantErr: "only valid with exporter file"},
+		{name: "endpoint with file", yaml: "exporter: file\npath: /tmp/t\nendpoint: http://x", wantErr: "only valid with exporter otlp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg tracing.Config
+			err := yaml.Unmarshal([]byte(tt.yaml), &cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error: got %v want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
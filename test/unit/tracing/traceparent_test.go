@@ -0,0 +1,57 @@
+package tracing_test
+
+import (
+	"testing"
+
+	"poke/internal/server/tracing"
+)
+
+func TestParseTraceparentRoundTrip(t *testing.T) {
+	const value = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, err := tracing.ParseTraceparent(value)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !sc.IsValid() || !sc.Sampled || !sc.Remote {
+		t.Fatalf("span context: got %#v", sc)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("ids: got %s %s", sc.TraceID, sc.SpanID)
+	}
+	if got := sc.Traceparent(); got != value {
+		t.Fatalf("traceparent: got %q want %q", got, value)
+	}
+}
+
+func TestParseTraceparentAcceptsFutureVersionsAndUnsampled(t *testing.T) {
+	sc, err := tracing.ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if sc.Sampled {
+		t.Fatalf("expected unsampled span context")
+	}
+}
+
+func TestParseTraceparentRejectsInvalidValues(t *testing.T) {
+	tests := map[string]string{
+		"empty":           "",
+		"version ff":      "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"trailing data":   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-x",
+		"uppercase":       "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"zero trace id":   "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"zero parent id":  "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"bad separators":  "00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01",
+		"non-hex flags":   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+		"short parent id": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-0001",
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := tracing.ParseTraceparent(value); err == nil {
+				t.Fatalf("expected %q to be rejected", value)
+			}
+		})
+	}
+}
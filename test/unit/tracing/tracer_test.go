@@ -0,0 +1,177 @@
+package tracing_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"poke/internal/server/tracing"
+)
+
+// collectorStub is an in-process OTLP/HTTP collector.
+type collectorStub struct {
+	mu       sync.Mutex
+	requests []tracing.OTLPTraces
+	headers  []http.Header
+}
+
+func (c *collectorStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body tracing.OTLPTraces
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/traces" || json.NewDecoder(r.Body).Decode(&body) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.requests = append(c.requests, body)
+	c.headers = append(c.headers, r.Header.Clone())
+	c.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *collectorStub) spans() []tracing.OTLPSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var out []tracing.OTLPSpan
+	for _, req := range c.requests {
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				out = append(out, ss.Spans...)
+			}
+		}
+	}
+	return out
+}
+
+func TestTracerExportsSpanTreeToOTLPCollector(t *testing.T) {
+	collector := &collectorStub{}
+	srv := httptest.NewServer(collector)
+	defer srv.Close()
+
+	tracer := tracing.NewTracer(tracing.NewOTLPExporter(srv.URL+"/v1/traces", map[string]string{"Authorization": "Bearer t"}, time.Second), tracing.Resource{ServiceName: "poke-test"})
+	remote, _ := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, parent := tracer.Start(tracing.ContextWithRemote(context.Background(), remote), "parent", tracing.WithKind(tracing.SpanKindServer), tracing.WithAttrs("http.response.status_code", 202))
+	_, child := tracer.Start(ctx, "child", tracing.WithStartTime(time.Now().Add(-time.Second)))
+	child.RecordError(errors.New("boom"))
+	child.End()
+	parent.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	spans := collector.spans()
+	if len(spans) != 2 {
+		t.Fatalf("spans: got %d want 2", len(spans))
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+	if parentSpan.TraceID != remote.TraceID.String() || parentSpan.ParentSpanID != remote.SpanID.String() || parentSpan.Kind != int(tracing.SpanKindServer) {
+		t.Fatalf("parent span: got %#v", parentSpan)
+	}
+	if childSpan.TraceID != parentSpan.TraceID || childSpan.ParentSpanID != parentSpan.SpanID {
+		t.Fatalf("child span must be a child of parent: got %#v", childSpan)
+	}
+	if childSpan.Status.Code != 2 || childSpan.Status.Message != "boom" {
+		t.Fatalf("child status: got %#v", childSpan.Status)
+	}
+	if a := parentSpan.Attributes; len(a) != 1 || a[0].Value.IntValue == nil || *a[0].Value.IntValue != "202" {
+		t.Fatalf("parent attributes: got %#v", a)
+	}
+	if got := collector.headers[0].Get("Authorization"); got != "Bearer t" {
+		t.Fatalf("authorization header: got %q", got)
+	}
+	if got := *collector.requests[0].ResourceSpans[0].Resource.Attributes[0].Value.StringValue; got != "poke-test" {
+		t.Fatalf("service.name: got %q", got)
+	}
+}
+
+func TestTracerReportsCollectorErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	tracer := tracing.NewTracer(tracing.NewOTLPExporter(srv.URL, nil, time.Second), tracing.Resource{ServiceName: "poke"})
+	defer func() { _ = tracer.Shutdown(context.Background()) }()
+	_, span := tracer.Start(context.Background(), "op")
+	span.End()
+
+	if err := tracer.ForceFlush(context.Background()); err == nil {
+		t.Fatalf("expected export error for a 503 collector response")
+	}
+}
+
+func TestDisabledTracerPropagatesRemoteContext(t *testing.T) {
+	var tracer *tracing.Tracer
+	remote, _ := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, span := tracer.Start(tracing.ContextWithRemote(context.Background(), remote), "op")
+	span.End()
+	if got := tracing.TraceparentFromContext(ctx); got != remote.Traceparent() {
+		t.Fatalf("traceparent: got %q want %q", got, remote.Traceparent())
+	}
+
+	_, root := tracer.Start(context.Background(), "op")
+	if root.Context().IsValid() {
+		t.Fatalf("disabled tracer must not invent trace ids")
+	}
+}
+
+func TestTracerDoesNotRecordUnsampledTraces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	exporter, err := tracing.NewFileExporter(path)
+	if err != nil {
+		t.Fatalf("file exporter: %v", err)
+	}
+	tracer := tracing.NewTracer(exporter, tracing.Resource{ServiceName: "poke"})
+
+	remote, _ := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	ctx, span := tracer.Start(tracing.ContextWithRemote(context.Background(), remote), "op")
+	span.End()
+	if got := tracing.TraceparentFromContext(ctx); got != remote.Traceparent() {
+		t.Fatalf("traceparent: got %q want %q", got, remote.Traceparent())
+	}
+	_, sampled := tracer.Start(context.Background(), "sampled")
+	sampled.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	lines := readTraceFile(t, path)
+	if len(lines) != 1 {
+		t.Fatalf("batches: got %d want 1", len(lines))
+	}
+	spans := lines[0].ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 || spans[0].Name != "sampled" {
+		t.Fatalf("exported spans: got %#v", spans)
+	}
+}
+
+func readTraceFile(t *testing.T, path string) []tracing.OTLPTraces {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open trace file: %v", err)
+	}
+	defer f.Close()
+
+	var out []tracing.OTLPTraces
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var batch tracing.OTLPTraces
+		if err := json.Unmarshal(scanner.Bytes(), &batch); err != nil {
+			t.Fatalf("decode line: %v", err)
+		}
+		out = append(out, batch)
+	}
+	return out
+}